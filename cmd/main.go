@@ -1,16 +1,59 @@
 package main
 
 import (
+	"TrainerConnect/internal/auth"
+	"TrainerConnect/internal/availability"
+	"TrainerConnect/internal/booking"
+	"TrainerConnect/internal/conversation"
+	"TrainerConnect/internal/credit"
+	"TrainerConnect/internal/events"
+	"TrainerConnect/internal/goal"
+	"TrainerConnect/internal/handlers"
+	"TrainerConnect/internal/invoice"
+	"TrainerConnect/internal/jobs"
+	"TrainerConnect/internal/metrics"
+	"TrainerConnect/internal/notify"
+	"TrainerConnect/internal/payment"
+	"TrainerConnect/internal/program"
+	"TrainerConnect/internal/reminder"
+	"TrainerConnect/internal/review"
+	"TrainerConnect/internal/roster"
+	"TrainerConnect/internal/trainer"
 	"TrainerConnect/internal/user"
+	"TrainerConnect/internal/workout"
 	postgres "TrainerConnect/pkg/postgresql"
 	"TrainerConnect/pkg/postgresql/config"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 )
 
+const (
+	accessTokenTTL  = 15 * time.Minute
+	refreshTokenTTL = 30 * 24 * time.Hour
+
+	// Буфер событий на одно соединение /events и интервал heartbeat
+	eventBufferSize = 64
+	eventHeartbeat  = 15 * time.Second
+
+	// Очередь уведомлений и число фоновых отправителей
+	notifyQueueSize = 1024
+	notifyWorkers   = 4
+
+	// Сколько ждать завершения начатых запросов при остановке сервера
+	shutdownTimeout = 15 * time.Second
+)
+
 func main() {
 	cfg, err := config.ReadConfig("../pkg/postgresql/config/database.json")
 	if err != nil {
@@ -23,29 +66,173 @@ func main() {
 	}
 	defer db.Close()
 
-	// Создаем экземпляр *user.Storage, передавая *sql.DB
-	storage := user.NewStorage(db)
+	// Каждая операция хранилища ограничена сроком, чтобы зависший запрос не занимал соединение
+	if cfg.QueryTimeout != "" {
+		if postgres.QueryTimeout, err = time.ParseDuration(cfg.QueryTimeout); err != nil {
+			log.Fatalf("Invalid query_timeout: %v", err)
+		}
+	}
+
+	// Подкоманда migrate управляет схемой базы данных без запуска сервера
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(db, os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// Секрет для подписи JWT берется из окружения, чтобы не хранить его в репозитории
+	jwtSecret := os.Getenv("JWT_SECRET")
+	if jwtSecret == "" {
+		log.Fatal("JWT_SECRET environment variable is not set")
+	}
+	provider, err := paymentProvider()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Перед стартом сервер применяет новые миграции. Advisory-блокировка не дает
+	// нескольким экземплярам выполнять их одновременно.
+	migrator, err := postgres.NewMigrator(db)
+	if err != nil {
+		log.Fatal(err)
+	}
+	applied, err := migrator.Up(context.Background())
+	for _, m := range applied {
+		log.Printf("Applied migration %04d_%s", m.Version, m.Name)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Сервис выдачи токенов хранит refresh-токены в той же базе данных
+	tokens := auth.NewTokenManager(jwtSecret, accessTokenTTL, refreshTokenTTL)
+	authService := auth.NewService(tokens, auth.NewStorage(db))
+
+	// Подсистемы публикуют события в шину, откуда они уходят в открытые потоки /events
+	hub := events.NewHub(eventBufferSize)
 
-	router := setupRouter(storage)
-	startServer(router)
+	// Уведомления доставляются в фоне, чтобы медленный SMTP-сервер не задерживал запросы
+	notifyStorage := notify.NewStorage(db)
+	dispatcher := notify.NewDispatcher(map[notify.Channel]notify.Notifier{
+		notify.ChannelEmail: emailNotifier(),
+		notify.ChannelInApp: notify.NewInAppNotifier(hub),
+	}, notifyStorage, notifyStorage, notifyQueueSize)
+	dispatcher.Start(notifyWorkers)
+
+	// Фоновые задачи: очередь в PostgreSQL выдерживает несколько экземпляров сервера
+	jobStorage := jobs.NewStorage(db)
+	runner := jobs.NewRunner(jobStorage)
+	reminder.New(booking.NewStorage(db), jobStorage, dispatcher).Register(runner)
+	credit.NewStorage(db).RegisterExpiry(runner)
+	ctx, cancel := context.WithCancel(context.Background())
+	runnerDone := make(chan struct{})
+	go func() {
+		defer close(runnerDone)
+		runner.Run(ctx)
+	}()
+
+	router := setupRouter(db, authService, hub, dispatcher, provider)
+	err = startServer(router)
+
+	// Сервер больше не принимает запросы: останавливаем фоновые задачи
+	// и доставляем уведомления, оставшиеся в очереди
+	cancel()
+	<-runnerDone
+	dispatcher.Stop()
+	if err != nil {
+		log.Fatal(err)
+	}
 }
 
-func setupRouter(storage *user.Storage) *chi.Mux {
+// emailNotifier возвращает отправку почты через SMTP из окружения. Если SMTP_ADDR
+// не задан, письма только пишутся в лог.
+func emailNotifier() notify.Notifier {
+	addr := os.Getenv("SMTP_ADDR")
+	if addr == "" {
+		log.Println("SMTP_ADDR is not set, emails will be written to the log")
+		return notify.NewLogNotifier(log.Writer(), notify.ChannelEmail)
+	}
+	return notify.NewSMTPNotifier(addr, os.Getenv("SMTP_FROM"), os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"))
+}
+
+// paymentProvider возвращает Stripe с ключами из окружения. Фиктивный провайдер
+// подтверждает оплаты без списания денег, поэтому включается только явно через
+// PAYMENT_PROVIDER=fake и подписывает события случайным секретом.
+func paymentProvider() (payment.PaymentProvider, error) {
+	if os.Getenv("PAYMENT_PROVIDER") == "fake" {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, err
+		}
+		log.Println("PAYMENT_PROVIDER is fake, payments will not be charged")
+		return payment.NewFakeProvider(hex.EncodeToString(secret)), nil
+	}
+
+	key, secret := os.Getenv("STRIPE_SECRET_KEY"), os.Getenv("STRIPE_WEBHOOK_SECRET")
+	if key == "" || secret == "" {
+		return nil, errors.New("STRIPE_SECRET_KEY and STRIPE_WEBHOOK_SECRET must be set, or PAYMENT_PROVIDER=fake for local development")
+	}
+	return payment.NewStripeProvider(key, secret), nil
+}
+
+func setupRouter(db *sql.DB, authService *auth.Service, hub *events.Hub, dispatcher *notify.Dispatcher, provider payment.PaymentProvider) *chi.Mux {
 	router := chi.NewRouter()
 
 	// Добавляем базовые middleware, такие, как логирование
 	router.Use(middleware.Logger)
 
-	// Создаем экземпляр *user.Handler, передавая *user.Storage
-	userHandler := user.NewHandler(storage)
+	// Проверяем bearer-токен и сохраняем пользователя в контексте запроса
+	router.Use(auth.Middleware(authService.Tokens))
+
+	// Доступ тренеров к данным клиентов определяется их отношениями
+	rosterStorage := roster.NewStorage(db)
+	policy := auth.NewPolicy(rosterStorage)
 
-	// Регистрируем обработчик в созданном ранее маршрутизаторе
-	userHandler.Register(router)
+	// Занятость тренера в расписании определяется его бронированиями,
+	// а подтвержденные занятия резервируют кредиты клиента из купленных пакетов
+	creditStorage := credit.NewStorage(db)
+	bookingStorage := booking.NewStorage(db)
+	bookingStorage.Ledger = creditStorage
+
+	// За каждую успешную оплату выставляется счет
+	invoiceStorage := invoice.NewStorage(db)
+	availabilityStorage := availability.NewStorage(db)
+
+	// Прогресс целей вычисляется по журналу тренировок и показателям тела
+	workoutStorage := workout.NewStorage(db)
+	metricsStorage := metrics.NewStorage(db)
+
+	// Регистрируем обработчики всех подсистем в созданном ранее маршрутизаторе
+	for _, h := range []handlers.Handler{
+		user.NewHandler(user.NewStorage(db), authService, policy),
+		auth.NewHandler(authService),
+		trainer.NewHandler(trainer.NewStorage(db)),
+		availability.NewHandler(availabilityStorage, bookingStorage),
+		booking.NewHandler(bookingStorage, availabilityStorage, hub, dispatcher),
+		roster.NewHandler(rosterStorage),
+		program.NewHandler(program.NewStorage(db), rosterStorage, hub),
+		workout.NewHandler(workoutStorage, policy),
+		metrics.NewHandler(metricsStorage, policy),
+		conversation.NewHandler(conversation.NewStorage(db), rosterStorage, hub, dispatcher),
+		events.NewHandler(hub, eventHeartbeat),
+		notify.NewHandler(notify.NewStorage(db), policy),
+		jobs.NewHandler(jobs.NewStorage(db)),
+		review.NewHandler(review.NewStorage(db)),
+		credit.NewHandler(creditStorage),
+		payment.NewHandler(payment.NewService(payment.NewStorage(db), provider, creditStorage, invoiceStorage)),
+		invoice.NewHandler(invoiceStorage),
+		goal.NewHandler(goal.NewStorage(db), goal.NewTracker(metricsStorage, workoutStorage), policy),
+	} {
+		h.Register(router)
+	}
 
 	return router
 }
 
-func startServer(router *chi.Mux) {
+// startServer обслуживает запросы до SIGINT или SIGTERM, после чего дожидается
+// завершения начатых запросов не дольше shutdownTimeout
+func startServer(router *chi.Mux) error {
 	server := &http.Server{
 		Addr:         "0.0.0.0:1234",
 		Handler:      router,
@@ -53,5 +240,22 @@ func startServer(router *chi.Mux) {
 		ReadTimeout:  15 * time.Second,
 	}
 
-	log.Fatal(server.ListenAndServe())
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+	log.Println("Shutting down")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	return server.Shutdown(shutdownCtx)
 }
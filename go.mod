@@ -3,17 +3,13 @@ module TrainerConnect
 go 1.21
 
 require (
-	github.com/go-chi/chi v1.5.5
+	github.com/DATA-DOG/go-sqlmock v1.5.0
 	github.com/go-chi/chi/v5 v5.0.10
+	github.com/golang-jwt/jwt/v5 v5.1.0
 	github.com/lib/pq v1.10.9
 	github.com/stretchr/testify v1.8.4
 )
 
-require (
-	github.com/DATA-DOG/go-sqlmock v1.5.0 // indirect
-	github.com/stretchr/objx v0.5.0 // indirect
-)
-
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
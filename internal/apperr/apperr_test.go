@@ -0,0 +1,133 @@
+package apperr_test
+
+import (
+	"TrainerConnect/internal/apperr"
+	postgres "TrainerConnect/pkg/postgresql"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// write отправляет ошибку через Write и разбирает ответ
+func write(t *testing.T, err error) (*httptest.ResponseRecorder, apperr.Problem) {
+	rr := httptest.NewRecorder()
+	apperr.Write(rr, err)
+	assert.Equal(t, "application/problem+json", rr.Header().Get("Content-Type"))
+
+	var p apperr.Problem
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&p))
+	assert.Equal(t, rr.Code, p.Status)
+	return rr, p
+}
+
+func TestWriteTyped(t *testing.T) {
+	errNotFound := apperr.NotFound("booking_not_found", "booking not found")
+
+	// Причина видна только в логах, клиент получает код и сообщение
+	_, p := write(t, fmt.Errorf("loading: %w", errNotFound.Wrap(sql.ErrNoRows)))
+	assert.Equal(t, apperr.Problem{
+		Type:   "urn:trainerconnect:problem:booking_not_found",
+		Title:  "Not Found",
+		Status: http.StatusNotFound,
+		Detail: "booking not found",
+		Code:   "booking_not_found",
+	}, p)
+
+	_, p = write(t, apperr.Validation(
+		apperr.FieldError{Field: "email", Code: "email", Message: "must be a valid email"},
+		apperr.FieldError{Field: "username", Code: "required", Message: "is required"},
+	))
+	assert.Equal(t, http.StatusUnprocessableEntity, p.Status)
+	assert.Equal(t, "validation_failed", p.Code)
+	assert.Len(t, p.Errors, 2)
+
+	_, p = write(t, apperr.Unauthorized("token_expired", "token expired"))
+	assert.Equal(t, http.StatusUnauthorized, p.Status)
+	assert.Equal(t, "token_expired", p.Code)
+}
+
+func TestWriteExtensions(t *testing.T) {
+	errRefused := apperr.Forbidden("policy_refused", "refused by policy")
+	err := errRefused.With("decision", map[string]string{"reason": "too late"}).With("code", "ignored")
+	assert.ErrorIs(t, err, errRefused)
+	assert.True(t, apperr.Expected(fmt.Errorf("cancel: %w", err)))
+	assert.False(t, apperr.Expected(errors.New("boom")))
+
+	rr := httptest.NewRecorder()
+	apperr.Write(rr, err)
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+
+	// Расширения — члены верхнего уровня и не подменяют стандартные
+	var body map[string]interface{}
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&body))
+	assert.Equal(t, "policy_refused", body["code"])
+	assert.Equal(t, map[string]interface{}{"reason": "too late"}, body["decision"])
+	assert.Nil(t, errRefused.Extensions)
+}
+
+func TestWriteDatabaseErrors(t *testing.T) {
+	duplicate := &pq.Error{Code: "23505", Message: `duplicate key value violates unique constraint "users_email_key"`}
+	_, p := write(t, duplicate)
+	assert.Equal(t, http.StatusConflict, p.Status)
+	assert.Equal(t, "duplicate", p.Code)
+	assert.NotContains(t, p.Detail, "users_email_key")
+
+	_, p = write(t, sql.ErrNoRows)
+	assert.Equal(t, http.StatusNotFound, p.Status)
+
+	_, p = write(t, fmt.Errorf("%w: pq: canceling statement", postgres.ErrTimeout))
+	assert.Equal(t, http.StatusGatewayTimeout, p.Status)
+	assert.Equal(t, "timeout", p.Code)
+
+	_, p = write(t, fmt.Errorf("%w: context canceled", postgres.ErrCanceled))
+	assert.Equal(t, apperr.StatusClientClosedRequest, p.Status)
+	assert.Equal(t, "canceled", p.Code)
+
+	// Неизвестные ошибки не раскрываются клиенту
+	_, p = write(t, &pq.Error{Code: "42P01", Message: `relation "users" does not exist`})
+	assert.Equal(t, http.StatusInternalServerError, p.Status)
+	assert.Equal(t, "internal", p.Code)
+	assert.Equal(t, "Internal server error", p.Detail)
+}
+
+func TestFromDB(t *testing.T) {
+	assert.NoError(t, apperr.FromDB(nil))
+
+	err := apperr.FromDB(&pq.Error{Code: "23503"})
+	assert.ErrorIs(t, err, apperr.ErrReference)
+	var pqErr *pq.Error
+	assert.True(t, errors.As(err, &pqErr), "the cause must be kept")
+
+	// Уже переведенные ошибки не меняются
+	own := apperr.Conflict("user_exists", "user already exists")
+	assert.Equal(t, error(own), apperr.FromDB(own))
+}
+
+func TestRespond(t *testing.T) {
+	rr := httptest.NewRecorder()
+	apperr.Respond(rr, http.StatusForbidden, "Forbidden")
+
+	var p apperr.Problem
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&p))
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	assert.Equal(t, "forbidden", p.Code)
+	assert.Equal(t, "urn:trainerconnect:problem:forbidden", p.Type)
+}
+
+func TestViolations(t *testing.T) {
+	// Ошибки распознаются и внутри обертки
+	wrapped := fmt.Errorf("deleting user: %w", &pq.Error{Code: "23503"})
+	assert.True(t, apperr.IsForeignKeyViolation(wrapped))
+	assert.False(t, apperr.IsUniqueViolation(wrapped))
+	assert.True(t, apperr.IsUniqueViolation(&pq.Error{Code: "23505"}))
+	assert.True(t, apperr.IsExclusionViolation(fmt.Errorf("booking: %w", &pq.Error{Code: "23P01"})))
+	assert.False(t, apperr.IsExclusionViolation(errors.New("23P01")))
+	assert.False(t, apperr.IsForeignKeyViolation(nil))
+}
@@ -0,0 +1,190 @@
+// Package apperr описывает ошибки приложения, которые хранилища и обработчики
+// передают клиенту: вид ошибки определяет HTTP-статус, а код — стабильный
+// машиночитаемый идентификатор, по которому фронтенд различает ошибки.
+package apperr
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/lib/pq"
+)
+
+// Kind — вид ошибки, определяющий HTTP-статус ответа
+type Kind string
+
+const (
+	KindNotFound     Kind = "not_found"
+	KindConflict     Kind = "conflict"
+	KindValidation   Kind = "validation_failed"
+	KindUnauthorized Kind = "unauthorized"
+	KindForbidden    Kind = "forbidden"
+)
+
+// FieldError — ошибка в одном поле запроса
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error — ошибка приложения. Message показывается клиенту, поэтому не должен
+// содержать деталей SQL; исходная ошибка хранится в Err и видна только в логах.
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	Fields  []FieldError
+	// Extensions — дополнительные члены ответа problem+json, например решение политики
+	Extensions map[string]interface{}
+	Err        error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is сравнивает ошибки по коду, чтобы errors.Is находил копию ошибки с другой причиной
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Kind == e.Kind && t.Code == e.Code
+}
+
+// Wrap возвращает копию ошибки с причиной err
+func (e *Error) Wrap(err error) *Error {
+	c := *e
+	c.Err = err
+	return &c
+}
+
+// With возвращает копию ошибки с дополнительным членом ответа key
+func (e *Error) With(key string, value interface{}) *Error {
+	c := *e
+	c.Extensions = make(map[string]interface{}, len(e.Extensions)+1)
+	for k, v := range e.Extensions {
+		c.Extensions[k] = v
+	}
+	c.Extensions[key] = value
+	return &c
+}
+
+// Expected сообщает, что err — ошибка приложения, которая описывает запрос или состояние
+// данных, а не сбой. Обработчики записывают в лог только неожиданные ошибки.
+func Expected(err error) bool {
+	var appErr *Error
+	return errors.As(err, &appErr)
+}
+
+// NotFound — запрошенный объект не существует
+func NotFound(code, message string) *Error {
+	return &Error{Kind: KindNotFound, Code: code, Message: message}
+}
+
+// Conflict — запрос противоречит текущему состоянию, например имя пользователя уже занято
+func Conflict(code, message string) *Error {
+	return &Error{Kind: KindConflict, Code: code, Message: message}
+}
+
+// Validation — запрос не прошел проверку; fields перечисляет ошибки по полям
+func Validation(fields ...FieldError) *Error {
+	return &Error{Kind: KindValidation, Code: string(KindValidation), Message: "request validation failed", Fields: fields}
+}
+
+// InvalidField — поле запроса field не прошло проверку. Ответ такой же, как у Validation
+// с одной ошибкой поля.
+func InvalidField(field, code, message string) *Error {
+	return Validation(FieldError{Field: field, Code: code, Message: message})
+}
+
+// Invalid — одно значение запроса не прошло проверку
+func Invalid(code, message string) *Error {
+	return &Error{Kind: KindValidation, Code: code, Message: message}
+}
+
+// Unauthorized — пользователь не аутентифицирован или его учетные данные неверны
+func Unauthorized(code, message string) *Error {
+	return &Error{Kind: KindUnauthorized, Code: code, Message: message}
+}
+
+// Forbidden — у пользователя нет права на действие
+func Forbidden(code, message string) *Error {
+	return &Error{Kind: KindForbidden, Code: code, Message: message}
+}
+
+// Коды ошибок PostgreSQL, которые переводятся в ошибки приложения
+const (
+	uniqueViolation     = "23505"
+	foreignKeyViolation = "23503"
+	exclusionViolation  = "23P01"
+	checkViolation      = "23514"
+	notNullViolation    = "23502"
+	invalidText         = "22P02"
+)
+
+// Общие ошибки, в которые FromDB переводит ошибки базы данных без более точного описания
+var (
+	ErrNotFound  = NotFound("not_found", "resource not found")
+	ErrDuplicate = Conflict("duplicate", "resource already exists")
+	ErrReference = NotFound("reference_not_found", "referenced resource not found")
+	ErrInUse     = Conflict("in_use", "resource is referenced by other records")
+	ErrInvalid   = Invalid("invalid_value", "invalid value")
+)
+
+// FromDB переводит ошибку database/sql или lib/pq в ошибку приложения. Хранилища
+// вызывают ее для ошибок, которые не перевели сами; прочие ошибки возвращаются как есть.
+// Нарушение внешнего ключа считается ссылкой на несуществующую строку: хранилища, которые
+// удаляют строки, сами переводят его в ErrInUse или собственную ошибку конфликта.
+func FromDB(err error) error {
+	if err == nil {
+		return nil
+	}
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return err
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound.Wrap(err)
+	}
+
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+	switch pqErr.Code {
+	case uniqueViolation, exclusionViolation:
+		return ErrDuplicate.Wrap(err)
+	case foreignKeyViolation:
+		return ErrReference.Wrap(err)
+	case checkViolation, notNullViolation, invalidText:
+		return ErrInvalid.Wrap(err)
+	}
+	return err
+}
+
+// IsUniqueViolation сообщает, что err — нарушение уникальности в PostgreSQL
+func IsUniqueViolation(err error) bool {
+	return hasCode(err, uniqueViolation)
+}
+
+// IsForeignKeyViolation сообщает, что err — нарушение внешнего ключа в PostgreSQL:
+// запись ссылается на несуществующую строку или удаляемая строка еще используется
+func IsForeignKeyViolation(err error) bool {
+	return hasCode(err, foreignKeyViolation)
+}
+
+// IsExclusionViolation сообщает, что err — нарушение ограничения исключения в PostgreSQL,
+// например пересечение интервалов времени
+func IsExclusionViolation(err error) bool {
+	return hasCode(err, exclusionViolation)
+}
+
+func hasCode(err error, code pq.ErrorCode) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == code
+}
@@ -0,0 +1,133 @@
+package apperr
+
+import (
+	postgres "TrainerConnect/pkg/postgresql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// StatusClientClosedRequest — нестандартный статус для запроса, который клиент
+// отменил до ответа (принят в nginx). Сам ответ клиент уже не получит.
+const StatusClientClosedRequest = 499
+
+// Problem — тело ответа об ошибке по RFC 7807
+type Problem struct {
+	Type   string       `json:"type"`
+	Title  string       `json:"title"`
+	Status int          `json:"status"`
+	Detail string       `json:"detail,omitempty"`
+	Code   string       `json:"code"`
+	Errors []FieldError `json:"errors,omitempty"`
+	// Extensions сериализуются как члены верхнего уровня рядом со стандартными
+	Extensions map[string]interface{} `json:"-"`
+}
+
+// MarshalJSON добавляет члены расширения к стандартным полям. Расширение не может
+// подменить стандартный член.
+func (p Problem) MarshalJSON() ([]byte, error) {
+	type problem Problem
+	base, err := json.Marshal(problem(p))
+	if err != nil || len(p.Extensions) == 0 {
+		return base, err
+	}
+
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(base, &members); err != nil {
+		return nil, err
+	}
+	for k, v := range p.Extensions {
+		if _, ok := members[k]; ok {
+			continue
+		}
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		members[k] = raw
+	}
+	return json.Marshal(members)
+}
+
+// problemType строит URI типа ошибки из ее кода
+func problemType(code string) string {
+	return "urn:trainerconnect:problem:" + code
+}
+
+var kindStatus = map[Kind]int{
+	KindNotFound:     http.StatusNotFound,
+	KindConflict:     http.StatusConflict,
+	KindValidation:   http.StatusUnprocessableEntity,
+	KindUnauthorized: http.StatusUnauthorized,
+	KindForbidden:    http.StatusForbidden,
+}
+
+// Write отправляет ошибку err в виде application/problem+json. Ошибки приложения
+// и ошибки базы данных, которые переводит FromDB, передаются клиенту с кодом и сообщением,
+// истечение срока запроса к базе — как 504, а остальные ошибки скрываются за 500,
+// чтобы в ответ не попали детали SQL. Записывать причину в лог должен обработчик.
+func Write(w http.ResponseWriter, err error) {
+	var appErr *Error
+	switch {
+	case errors.As(FromDB(err), &appErr):
+		writeProblem(w, Problem{
+			Status:     kindStatus[appErr.Kind],
+			Detail:     appErr.Message,
+			Code:       appErr.Code,
+			Errors:     appErr.Fields,
+			Extensions: appErr.Extensions,
+		})
+	case errors.Is(err, postgres.ErrTimeout):
+		Respond(w, http.StatusGatewayTimeout, "The request took too long, try again later")
+	case errors.Is(err, postgres.ErrCanceled):
+		Respond(w, StatusClientClosedRequest, "The request was canceled")
+	default:
+		Respond(w, http.StatusInternalServerError, "Internal server error")
+	}
+}
+
+// Respond отправляет ошибку со статусом status и сообщением detail. Код ошибки
+// выводится из статуса, например 404 — not_found.
+func Respond(w http.ResponseWriter, status int, detail string) {
+	writeProblem(w, Problem{Status: status, Detail: detail, Code: statusCode(status)})
+}
+
+func writeProblem(w http.ResponseWriter, p Problem) {
+	p.Type = problemType(p.Code)
+	p.Title = statusTitle(p.Status)
+
+	h := w.Header()
+	// Заголовки успешного ответа, выставленные до ошибки, к ней не относятся
+	h.Del("Content-Length")
+	h.Set("Content-Type", "application/problem+json")
+	h.Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
+
+func statusTitle(status int) string {
+	if status == StatusClientClosedRequest {
+		return "Client Closed Request"
+	}
+	return http.StatusText(status)
+}
+
+// statusCode возвращает код ошибки для статуса: Unprocessable Entity — unprocessable_entity
+func statusCode(status int) string {
+	switch status {
+	case http.StatusInternalServerError:
+		return "internal"
+	case http.StatusUnprocessableEntity:
+		return string(KindValidation)
+	case StatusClientClosedRequest:
+		return "canceled"
+	case http.StatusGatewayTimeout:
+		return "timeout"
+	}
+	title := strings.ToLower(http.StatusText(status))
+	if title == "" {
+		return "error"
+	}
+	return strings.NewReplacer(" ", "_", "-", "_", "'", "").Replace(title)
+}
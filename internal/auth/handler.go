@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"TrainerConnect/internal/apperr"
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"log"
+	"net/http"
+)
+
+type Handler struct {
+	Service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{Service: service}
+}
+
+func (h *Handler) Register(router *chi.Mux) {
+	router.Post("/auth/refresh", h.Refresh)
+	router.Post("/auth/logout", h.Logout)
+}
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Refresh выдает новую пару токенов в обмен на действующий refresh-токен
+func (h *Handler) Refresh(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req refreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		apperr.Respond(w, http.StatusBadRequest, "Missing refresh token")
+		return
+	}
+
+	pair, err := h.Service.Refresh(r.Context(), req.RefreshToken)
+	if err != nil {
+		if errors.Is(err, ErrRefreshTokenNotFound) || errors.Is(err, ErrRefreshTokenExpired) || errors.Is(err, ErrRefreshTokenReused) {
+			log.Printf("Refresh token rejected: %v", err)
+			apperr.Respond(w, http.StatusUnauthorized, "Invalid refresh token")
+			return
+		}
+		log.Printf("Error refreshing token: %v", err)
+		apperr.Write(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(pair)
+}
+
+// Logout отзывает refresh-токен, после чего им нельзя получить новый access-токен
+func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
+	var req refreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		apperr.Respond(w, http.StatusBadRequest, "Missing refresh token")
+		return
+	}
+
+	if err := h.Service.Logout(r.Context(), req.RefreshToken); err != nil {
+		if !apperr.Expected(err) {
+			log.Printf("Error revoking refresh token: %v", err)
+		}
+		apperr.Write(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"TrainerConnect/internal/apperr"
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// Principal — аутентифицированный пользователь, выполняющий запрос
+type Principal struct {
+	UserID string
+	Role   string
+}
+
+// IsAdmin сообщает, является ли пользователь администратором
+func (p *Principal) IsAdmin() bool {
+	return p != nil && p.Role == RoleAdmin
+}
+
+type principalKey struct{}
+
+// WithPrincipal возвращает копию контекста с сохраненным в нем пользователем
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext извлекает пользователя, сохраненного middleware в контексте запроса
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok && p != nil
+}
+
+// Middleware проверяет bearer-токен из заголовка Authorization и сохраняет
+// пользователя в контексте запроса. Запросы без заголовка пропускаются дальше
+// анонимно, запросы с недействительным токеном отклоняются.
+func Middleware(tokens *TokenManager) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header := r.Header.Get("Authorization")
+			if header == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			scheme, token, found := strings.Cut(header, " ")
+			if !found || !strings.EqualFold(scheme, tokenType) || token == "" {
+				unauthorized(w, ErrMalformedHeader)
+				return
+			}
+
+			claims, err := tokens.ParseAccessToken(token)
+			if err != nil {
+				if errors.Is(err, ErrTokenExpired) {
+					unauthorized(w, ErrTokenExpired)
+					return
+				}
+				unauthorized(w, ErrInvalidToken)
+				return
+			}
+
+			principal := &Principal{UserID: claims.UserID(), Role: claims.Role}
+			next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), principal)))
+		})
+	}
+}
+
+// RequireAuth пропускает только запросы, прошедшие аутентификацию в Middleware
+func RequireAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := PrincipalFromContext(r.Context()); !ok {
+			unauthorized(w, ErrAuthRequired)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Ошибки аутентификации запроса; истекший токен клиент отличает по коду token_expired
+var (
+	ErrMalformedHeader = apperr.Unauthorized("malformed_authorization", "malformed authorization header")
+	ErrAuthRequired    = apperr.Unauthorized("authentication_required", "authentication required")
+)
+
+func unauthorized(w http.ResponseWriter, err *apperr.Error) {
+	w.Header().Set("WWW-Authenticate", tokenType)
+	apperr.Write(w, err)
+}
@@ -0,0 +1,96 @@
+package auth_test
+
+import (
+	"TrainerConnect/internal/auth"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func serveWithMiddleware(tokens *auth.TokenManager, header string) (*httptest.ResponseRecorder, *auth.Principal) {
+	var principal *auth.Principal
+	handler := auth.Middleware(tokens)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, _ = auth.PrincipalFromContext(r.Context())
+	}))
+
+	req := httptest.NewRequest("GET", "/users/1", nil)
+	if header != "" {
+		req.Header.Set("Authorization", header)
+	}
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr, principal
+}
+
+func TestMiddleware(t *testing.T) {
+	tokens := auth.NewTokenManager("secret", time.Minute, time.Hour)
+	token, err := tokens.IssueAccessToken("7", auth.RoleClient)
+	assert.NoError(t, err)
+
+	// Запрос без заголовка проходит анонимно
+	rr, principal := serveWithMiddleware(tokens, "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Nil(t, principal)
+
+	// Действительный токен кладет пользователя в контекст
+	rr, principal = serveWithMiddleware(tokens, "Bearer "+token)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, &auth.Principal{UserID: "7", Role: auth.RoleClient}, principal)
+
+	// Испорченный токен и чужая схема отклоняются
+	rr, _ = serveWithMiddleware(tokens, "Bearer "+token+"x")
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	rr, _ = serveWithMiddleware(tokens, "Basic "+token)
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+}
+
+type stubRoster map[string]string
+
+func (s stubRoster) HasClient(ctx context.Context, trainerID, clientID string) (bool, error) {
+	return s[clientID] == trainerID, nil
+}
+
+func TestPolicy(t *testing.T) {
+	ctx := context.Background()
+	policy := auth.NewPolicy(stubRoster{"2": "10"})
+	client := &auth.Principal{UserID: "2", Role: auth.RoleClient}
+	trainer := &auth.Principal{UserID: "10", Role: auth.RoleTrainer}
+	admin := &auth.Principal{UserID: "1", Role: auth.RoleAdmin}
+
+	// Клиент видит и меняет только себя
+	allowed, _ := policy.CanReadUser(ctx, client, "2")
+	assert.True(t, allowed)
+	allowed, _ = policy.CanReadUser(ctx, client, "3")
+	assert.False(t, allowed)
+	assert.True(t, policy.CanEditUser(client, "2"))
+	assert.False(t, policy.CanEditUser(client, "3"))
+
+	// Тренер видит своих клиентов, но не может их менять
+	allowed, _ = policy.CanReadUser(ctx, trainer, "2")
+	assert.True(t, allowed)
+	allowed, _ = policy.CanReadUser(ctx, trainer, "3")
+	assert.False(t, allowed)
+	assert.False(t, policy.CanEditUser(trainer, "2"))
+
+	// Доступ определяется отношениями, а не ролью: без них роль "trainer" ничего не дает
+	stranger := &auth.Principal{UserID: "11", Role: auth.RoleTrainer}
+	allowed, _ = policy.CanReadUser(ctx, stranger, "2")
+	assert.False(t, allowed)
+
+	// Показатели тела видят сам пользователь и его тренер, но не администратор
+	allowed, _ = policy.CanReadHealthData(ctx, trainer, "2")
+	assert.True(t, allowed)
+	allowed, _ = policy.CanReadHealthData(ctx, admin, "2")
+	assert.False(t, allowed)
+	allowed, _ = policy.CanReadHealthData(ctx, stranger, "2")
+	assert.False(t, allowed)
+
+	// Список и удаление доступны только администратору
+	assert.False(t, policy.CanListUsers(trainer))
+	assert.True(t, policy.CanListUsers(admin))
+	assert.False(t, policy.CanDeleteUser(client))
+	assert.True(t, policy.CanDeleteUser(admin))
+}
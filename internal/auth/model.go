@@ -0,0 +1,35 @@
+package auth
+
+import (
+	"github.com/golang-jwt/jwt/v5"
+	"time"
+)
+
+// Claims описывает полезную нагрузку access-токена
+type Claims struct {
+	Role string `json:"role"`
+	jwt.RegisteredClaims
+}
+
+// UserID возвращает идентификатор пользователя, которому выдан токен
+func (c *Claims) UserID() string {
+	return c.Subject
+}
+
+// TokenPair — пара токенов, выдаваемая клиенту после аутентификации
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+// RefreshToken — запись о refresh-токене, хранящаяся на сервере.
+// Сам токен не хранится, только его SHA-256 хэш.
+type RefreshToken struct {
+	ID        int64
+	UserID    string
+	Role      string
+	ExpiresAt time.Time
+	RevokedAt *time.Time
+}
@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"context"
+)
+
+// Роли пользователей, хранящиеся в поле User.Role
+const (
+	RoleClient  = "client"
+	RoleTrainer = "trainer"
+	RoleAdmin   = "admin"
+)
+
+// Roster отвечает на вопрос, тренируется ли клиент у тренера
+type Roster interface {
+	HasClient(ctx context.Context, trainerID, clientID string) (bool, error)
+}
+
+// Policy решает, может ли пользователь выполнить действие над данными другого пользователя
+type Policy struct {
+	Roster Roster
+}
+
+// NewPolicy создает политику доступа. Roster может быть nil:
+// тогда тренеры не получают доступа к данным клиентов.
+func NewPolicy(roster Roster) *Policy {
+	return &Policy{Roster: roster}
+}
+
+// CanListUsers — просматривать список всех пользователей может только администратор
+func (p *Policy) CanListUsers(principal *Principal) bool {
+	return principal.IsAdmin()
+}
+
+// CanReadUser — пользователь видит себя, администратор видит всех,
+// тренер видит своих клиентов. Доступ тренера определяется отношениями
+// в Roster, а не ролью пользователя.
+func (p *Policy) CanReadUser(ctx context.Context, principal *Principal, userID string) (bool, error) {
+	if principal == nil {
+		return false, nil
+	}
+	if principal.UserID == userID || principal.IsAdmin() {
+		return true, nil
+	}
+	return p.IsTrainerOf(ctx, principal, userID)
+}
+
+// IsTrainerOf сообщает, тренируется ли клиент clientID у пользователя principal
+func (p *Policy) IsTrainerOf(ctx context.Context, principal *Principal, clientID string) (bool, error) {
+	if principal == nil || p.Roster == nil {
+		return false, nil
+	}
+	return p.Roster.HasClient(ctx, principal.UserID, clientID)
+}
+
+// CanReadHealthData — показатели тела видят только сам пользователь и его тренеры из Roster.
+// В отличие от CanReadUser, администратор к ним доступа не получает.
+func (p *Policy) CanReadHealthData(ctx context.Context, principal *Principal, userID string) (bool, error) {
+	if principal == nil {
+		return false, nil
+	}
+	if principal.UserID == userID {
+		return true, nil
+	}
+	return p.IsTrainerOf(ctx, principal, userID)
+}
+
+// CanEditUser — изменять профиль может только сам пользователь или администратор
+func (p *Policy) CanEditUser(principal *Principal, userID string) bool {
+	if principal == nil {
+		return false
+	}
+	return principal.UserID == userID || principal.IsAdmin()
+}
+
+// CanDeleteUser — удалять пользователей может только администратор
+func (p *Policy) CanDeleteUser(principal *Principal) bool {
+	return principal.IsAdmin()
+}
+
+// CanAssignRole — назначать роли может только администратор
+func (p *Policy) CanAssignRole(principal *Principal) bool {
+	return principal.IsAdmin()
+}
@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"context"
+	"time"
+)
+
+// Service выдает, обновляет и отзывает пары токенов
+type Service struct {
+	Tokens  *TokenManager
+	Storage *Storage
+}
+
+func NewService(tokens *TokenManager, storage *Storage) *Service {
+	return &Service{Tokens: tokens, Storage: storage}
+}
+
+// Issue выдает новую пару токенов пользователю после успешной аутентификации
+func (s *Service) Issue(ctx context.Context, userID, role string) (*TokenPair, error) {
+	refreshToken, refreshHash, expiresAt, err := s.Tokens.NewRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Storage.SaveRefreshToken(ctx, userID, refreshHash, expiresAt); err != nil {
+		return nil, err
+	}
+
+	return s.newPair(userID, role, refreshToken)
+}
+
+// Refresh обменивает действующий refresh-токен на новую пару токенов.
+// Предъявленный токен при этом отзывается.
+func (s *Service) Refresh(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	newToken, newHash, expiresAt, err := s.Tokens.NewRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	rt, err := s.Storage.RotateRefreshToken(ctx, HashRefreshToken(refreshToken), newHash, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.newPair(rt.UserID, rt.Role, newToken)
+}
+
+// Logout отзывает refresh-токен
+func (s *Service) Logout(ctx context.Context, refreshToken string) error {
+	return s.Storage.RevokeRefreshToken(ctx, HashRefreshToken(refreshToken))
+}
+
+func (s *Service) newPair(userID, role, refreshToken string) (*TokenPair, error) {
+	accessToken, err := s.Tokens.IssueAccessToken(userID, role)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    tokenType,
+		ExpiresIn:    int64(s.Tokens.accessTTL / time.Second),
+	}, nil
+}
@@ -0,0 +1,112 @@
+package auth
+
+import (
+	"TrainerConnect/internal/apperr"
+	postgres "TrainerConnect/pkg/postgresql"
+	"context"
+	"database/sql"
+	"time"
+)
+
+var (
+	ErrRefreshTokenNotFound = apperr.Unauthorized("refresh_token_not_found", "refresh token not found")
+	ErrRefreshTokenExpired  = apperr.Unauthorized("refresh_token_expired", "refresh token expired")
+	ErrRefreshTokenReused   = apperr.Unauthorized("refresh_token_reused", "refresh token reused")
+)
+
+type Storage struct {
+	*sql.DB
+}
+
+func NewStorage(db *sql.DB) *Storage {
+	return &Storage{DB: db}
+}
+
+// SaveRefreshToken сохраняет хэш нового refresh-токена пользователя
+func (s *Storage) SaveRefreshToken(ctx context.Context, userID, tokenHash string, expiresAt time.Time) (err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	_, err = s.DB.ExecContext(ctx, "INSERT INTO refresh_tokens (user_id, token_hash, expires_at) VALUES ($1, $2, $3)",
+		userID, tokenHash, expiresAt)
+	return err
+}
+
+// RotateRefreshToken отзывает действующий refresh-токен и сохраняет вместо него новый.
+// Повторное предъявление уже отозванного токена считается признаком кражи:
+// в этом случае отзываются все токены пользователя.
+func (s *Storage) RotateRefreshToken(ctx context.Context, oldHash, newHash string, newExpiresAt time.Time) (_ *RefreshToken, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	row := tx.QueryRowContext(ctx, `SELECT rt.id, rt.user_id, u.role, rt.expires_at, rt.revoked_at
+		FROM refresh_tokens rt JOIN users u ON u.user_id = rt.user_id
+		WHERE rt.token_hash = $1 FOR UPDATE OF rt`, oldHash)
+
+	var rt RefreshToken
+	var revokedAt sql.NullTime
+	if err := row.Scan(&rt.ID, &rt.UserID, &rt.Role, &rt.ExpiresAt, &revokedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrRefreshTokenNotFound
+		}
+		return nil, err
+	}
+
+	if revokedAt.Valid {
+		if _, err := tx.ExecContext(ctx, "UPDATE refresh_tokens SET revoked_at = now() WHERE user_id = $1 AND revoked_at IS NULL", rt.UserID); err != nil {
+			return nil, err
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+	if time.Now().After(rt.ExpiresAt) {
+		return nil, ErrRefreshTokenExpired
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE refresh_tokens SET revoked_at = now() WHERE id = $1", rt.ID); err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, "INSERT INTO refresh_tokens (user_id, token_hash, expires_at) VALUES ($1, $2, $3)",
+		rt.UserID, newHash, newExpiresAt); err != nil {
+		return nil, err
+	}
+
+	return &rt, tx.Commit()
+}
+
+// RevokeRefreshToken отзывает refresh-токен по его хэшу
+func (s *Storage) RevokeRefreshToken(ctx context.Context, tokenHash string) (err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	res, err := s.DB.ExecContext(ctx, "UPDATE refresh_tokens SET revoked_at = now() WHERE token_hash = $1 AND revoked_at IS NULL", tokenHash)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrRefreshTokenNotFound
+	}
+	return nil
+}
+
+// RevokeAllRefreshTokens отзывает все действующие refresh-токены пользователя
+func (s *Storage) RevokeAllRefreshTokens(ctx context.Context, userID string) (err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	_, err = s.DB.ExecContext(ctx, "UPDATE refresh_tokens SET revoked_at = now() WHERE user_id = $1 AND revoked_at IS NULL", userID)
+	return err
+}
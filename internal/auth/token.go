@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"TrainerConnect/internal/apperr"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"time"
+)
+
+const (
+	tokenIssuer = "trainer-connect"
+	tokenType   = "Bearer"
+)
+
+var (
+	ErrInvalidToken = apperr.Unauthorized("invalid_token", "invalid token")
+	ErrTokenExpired = apperr.Unauthorized("token_expired", "token expired")
+)
+
+// TokenManager подписывает и проверяет access-токены (JWT, HS256)
+type TokenManager struct {
+	secret     []byte
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+	now        func() time.Time
+}
+
+func NewTokenManager(secret string, accessTTL, refreshTTL time.Duration) *TokenManager {
+	return &TokenManager{
+		secret:     []byte(secret),
+		accessTTL:  accessTTL,
+		refreshTTL: refreshTTL,
+		now:        time.Now,
+	}
+}
+
+// IssueAccessToken создает подписанный access-токен для пользователя с указанной ролью
+func (m *TokenManager) IssueAccessToken(userID, role string) (string, error) {
+	now := m.now()
+	claims := Claims{
+		Role: role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    tokenIssuer,
+			Subject:   userID,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(m.accessTTL)),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(m.secret)
+}
+
+// ParseAccessToken проверяет подпись и срок действия токена и возвращает его полезную нагрузку
+func (m *TokenManager) ParseAccessToken(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
+		return m.secret, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(tokenIssuer),
+		jwt.WithTimeFunc(m.now),
+	)
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, ErrTokenExpired
+		}
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if claims.Subject == "" {
+		return nil, ErrInvalidToken
+	}
+
+	return claims, nil
+}
+
+// NewRefreshToken генерирует случайный refresh-токен и возвращает его вместе с хэшем для хранения
+func (m *TokenManager) NewRefreshToken() (token, hash string, expiresAt time.Time, err error) {
+	tokenBytes := make([]byte, 32)
+	if _, err = rand.Read(tokenBytes); err != nil {
+		return "", "", time.Time{}, err
+	}
+
+	token = hex.EncodeToString(tokenBytes)
+	return token, HashRefreshToken(token), m.now().Add(m.refreshTTL), nil
+}
+
+// HashRefreshToken вычисляет хэш refresh-токена, под которым он хранится в базе данных
+func HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,50 @@
+package auth_test
+
+import (
+	"TrainerConnect/internal/auth"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestIssueAndParseAccessToken(t *testing.T) {
+	tokens := auth.NewTokenManager("secret", time.Minute, time.Hour)
+
+	token, err := tokens.IssueAccessToken("42", "trainer")
+	assert.NoError(t, err)
+
+	claims, err := tokens.ParseAccessToken(token)
+	assert.NoError(t, err)
+	assert.Equal(t, "42", claims.UserID())
+	assert.Equal(t, "trainer", claims.Role)
+}
+
+func TestParseAccessTokenRejectsForeignSignature(t *testing.T) {
+	issuer := auth.NewTokenManager("other-secret", time.Minute, time.Hour)
+	token, err := issuer.IssueAccessToken("42", "admin")
+	assert.NoError(t, err)
+
+	// Токен, подписанный другим ключом, не должен приниматься
+	_, err = auth.NewTokenManager("secret", time.Minute, time.Hour).ParseAccessToken(token)
+	assert.ErrorIs(t, err, auth.ErrInvalidToken)
+}
+
+func TestParseAccessTokenRejectsExpiredToken(t *testing.T) {
+	tokens := auth.NewTokenManager("secret", -time.Minute, time.Hour)
+
+	token, err := tokens.IssueAccessToken("42", "client")
+	assert.NoError(t, err)
+
+	_, err = tokens.ParseAccessToken(token)
+	assert.ErrorIs(t, err, auth.ErrTokenExpired)
+}
+
+func TestNewRefreshTokenHash(t *testing.T) {
+	tokens := auth.NewTokenManager("secret", time.Minute, time.Hour)
+
+	token, hash, expiresAt, err := tokens.NewRefreshToken()
+	assert.NoError(t, err)
+	assert.NotEqual(t, token, hash)
+	assert.Equal(t, auth.HashRefreshToken(token), hash)
+	assert.True(t, expiresAt.After(time.Now()))
+}
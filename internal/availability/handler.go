@@ -0,0 +1,285 @@
+package availability
+
+import (
+	"TrainerConnect/internal/apperr"
+	"TrainerConnect/internal/auth"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	availabilityURL = "/trainers/{id}/availability"
+	slotsURL        = "/trainers/{id}/slots"
+
+	defaultSlotDuration = 60 * time.Minute
+	maxSlotsRange       = 31 * 24 * time.Hour
+)
+
+type Handler struct {
+	Storage *Storage
+	Busy    BusyProvider
+}
+
+// NewHandler создает обработчик расписания. busy учитывает уже занятое время тренера и может быть nil.
+func NewHandler(storage *Storage, busy BusyProvider) *Handler {
+	return &Handler{Storage: storage, Busy: busy}
+}
+
+func (h *Handler) Register(router *chi.Mux) {
+	router.Get(availabilityURL, h.GetSchedule)
+	router.Get(slotsURL, h.GetSlots)
+
+	router.Group(func(r chi.Router) {
+		r.Use(auth.RequireAuth)
+		r.Put(availabilityURL+"/weekly", h.ReplaceWeekly)
+		r.Post(availabilityURL+"/extra", h.AddExtraSlot)
+		r.Delete(availabilityURL+"/extra/{slotID}", h.DeleteExtraSlot)
+		r.Post(availabilityURL+"/blackouts", h.AddBlackout)
+		r.Delete(availabilityURL+"/blackouts/{blackoutID}", h.DeleteBlackout)
+	})
+}
+
+// trainerID извлекает ID тренера из URL и при ошибке сам отправляет ответ
+func trainerID(w http.ResponseWriter, r *http.Request) (string, bool) {
+	id := chi.URLParam(r, "id")
+	if _, err := strconv.Atoi(id); err != nil {
+		apperr.Respond(w, http.StatusBadRequest, "Invalid trainer ID")
+		return "", false
+	}
+	return id, true
+}
+
+// canManage проверяет, что расписание меняет сам тренер или администратор
+func canManage(w http.ResponseWriter, r *http.Request, trainerID string) bool {
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	if principal.IsAdmin() || (principal.UserID == trainerID && principal.Role == auth.RoleTrainer) {
+		return true
+	}
+	apperr.Respond(w, http.StatusForbidden, "Forbidden")
+	return false
+}
+
+// timeRange разбирает интервал [from, to) из параметров запроса и при ошибке сам отправляет ответ
+func timeRange(w http.ResponseWriter, r *http.Request) (from, to time.Time, ok bool) {
+	q := r.URL.Query()
+	from, err := time.Parse(time.RFC3339, q.Get("from"))
+	if err != nil {
+		apperr.Write(w, apperr.InvalidField("from", "invalid_time", "must be an RFC 3339 time"))
+		return from, to, false
+	}
+	to, err = time.Parse(time.RFC3339, q.Get("to"))
+	if err != nil {
+		apperr.Write(w, apperr.InvalidField("to", "invalid_time", "must be an RFC 3339 time"))
+		return from, to, false
+	}
+	if !to.After(from) || to.Sub(from) > maxSlotsRange {
+		apperr.Write(w, apperr.InvalidField("to", "invalid_range", "range must be positive and not longer than 31 days"))
+		return from, to, false
+	}
+	return from, to, true
+}
+
+// GetSchedule возвращает расписание тренера в интервале [from, to)
+func (h *Handler) GetSchedule(w http.ResponseWriter, r *http.Request) {
+	id, ok := trainerID(w, r)
+	if !ok {
+		return
+	}
+	from, to, ok := timeRange(w, r)
+	if !ok {
+		return
+	}
+
+	schedule, err := h.Storage.GetSchedule(r.Context(), id, from, to)
+	if err != nil {
+		log.Printf("Error getting schedule of trainer %s: %v", id, err)
+		apperr.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(schedule)
+}
+
+// GetSlots возвращает свободные слоты тренера в интервале [from, to)
+func (h *Handler) GetSlots(w http.ResponseWriter, r *http.Request) {
+	id, ok := trainerID(w, r)
+	if !ok {
+		return
+	}
+	from, to, ok := timeRange(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	duration := defaultSlotDuration
+	if d := q.Get("duration"); d != "" {
+		minutes, err := strconv.Atoi(d)
+		if err != nil || minutes < 15 || minutes > 480 {
+			apperr.Write(w, apperr.InvalidField("duration", "out_of_range", "must be between 15 and 480 minutes"))
+			return
+		}
+		duration = time.Duration(minutes) * time.Minute
+	}
+
+	// Прошедшее время забронировать нельзя
+	if now := time.Now(); from.Before(now) {
+		from = now.Truncate(time.Minute)
+	}
+
+	slots, err := h.Storage.FreeSlots(r.Context(), id, from, to, duration, h.Busy)
+	if err != nil {
+		log.Printf("Error computing slots of trainer %s: %v", id, err)
+		apperr.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(slots)
+}
+
+// ReplaceWeekly заменяет недельное расписание тренера
+func (h *Handler) ReplaceWeekly(w http.ResponseWriter, r *http.Request) {
+	id, ok := trainerID(w, r)
+	if !ok || !canManage(w, r, id) {
+		return
+	}
+
+	var windows []WeeklyWindow
+	if err := json.NewDecoder(r.Body).Decode(&windows); err != nil {
+		apperr.Respond(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	for _, window := range windows {
+		if err := window.Validate(); err != nil {
+			apperr.Write(w, err)
+			return
+		}
+	}
+
+	if err := h.Storage.ReplaceWeekly(r.Context(), id, windows); err != nil {
+		log.Printf("Error replacing weekly schedule of trainer %s: %v", id, err)
+		apperr.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(windows)
+}
+
+// AddExtraSlot добавляет разовое окно
+func (h *Handler) AddExtraSlot(w http.ResponseWriter, r *http.Request) {
+	id, ok := trainerID(w, r)
+	if !ok || !canManage(w, r, id) {
+		return
+	}
+
+	var slot ExtraSlot
+	if err := json.NewDecoder(r.Body).Decode(&slot); err != nil {
+		apperr.Respond(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if !slot.EndsAt.After(slot.StartsAt) {
+		apperr.Write(w, apperr.InvalidField("ends_at", "before_start", "ends_at must be after starts_at"))
+		return
+	}
+	slot.TrainerID = id
+
+	if err := h.Storage.AddExtraSlot(r.Context(), &slot); err != nil {
+		log.Printf("Error adding extra slot for trainer %s: %v", id, err)
+		apperr.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(slot)
+}
+
+// DeleteExtraSlot удаляет разовое окно
+func (h *Handler) DeleteExtraSlot(w http.ResponseWriter, r *http.Request) {
+	id, ok := trainerID(w, r)
+	if !ok || !canManage(w, r, id) {
+		return
+	}
+
+	slotID := chi.URLParam(r, "slotID")
+	if _, err := strconv.Atoi(slotID); err != nil {
+		apperr.Respond(w, http.StatusBadRequest, "Invalid slot ID")
+		return
+	}
+
+	found, err := h.Storage.DeleteExtraSlot(r.Context(), id, slotID)
+	if err != nil {
+		log.Printf("Error deleting extra slot of trainer %s: %v", id, err)
+		apperr.Write(w, err)
+		return
+	}
+	if !found {
+		apperr.Write(w, ErrSlotNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AddBlackout добавляет выходной день
+func (h *Handler) AddBlackout(w http.ResponseWriter, r *http.Request) {
+	id, ok := trainerID(w, r)
+	if !ok || !canManage(w, r, id) {
+		return
+	}
+
+	var blackout Blackout
+	if err := json.NewDecoder(r.Body).Decode(&blackout); err != nil {
+		apperr.Respond(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := blackout.Validate(); err != nil {
+		apperr.Write(w, err)
+		return
+	}
+	blackout.TrainerID = id
+
+	if err := h.Storage.AddBlackout(r.Context(), &blackout); err != nil {
+		log.Printf("Error adding blackout for trainer %s: %v", id, err)
+		apperr.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(blackout)
+}
+
+// DeleteBlackout удаляет выходной день
+func (h *Handler) DeleteBlackout(w http.ResponseWriter, r *http.Request) {
+	id, ok := trainerID(w, r)
+	if !ok || !canManage(w, r, id) {
+		return
+	}
+
+	blackoutID := chi.URLParam(r, "blackoutID")
+	if _, err := strconv.Atoi(blackoutID); err != nil {
+		apperr.Respond(w, http.StatusBadRequest, "Invalid blackout ID")
+		return
+	}
+
+	found, err := h.Storage.DeleteBlackout(r.Context(), id, blackoutID)
+	if err != nil {
+		log.Printf("Error deleting blackout of trainer %s: %v", id, err)
+		apperr.Write(w, err)
+		return
+	}
+	if !found {
+		apperr.Write(w, ErrBlackoutNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,58 @@
+package availability
+
+import (
+	"context"
+	"time"
+)
+
+// WeeklyWindow — повторяющееся каждую неделю окно, в которое тренер готов проводить занятия.
+// Время задается в часовом поясе окна в формате "ЧЧ:ММ", окно не переходит через полночь.
+type WeeklyWindow struct {
+	ID        string       `json:"id"`
+	TrainerID string       `json:"trainer_id"`
+	Weekday   time.Weekday `json:"weekday"`
+	StartTime string       `json:"start_time"`
+	EndTime   string       `json:"end_time"`
+	TimeZone  string       `json:"time_zone"`
+}
+
+// ExtraSlot — разовое дополнительное окно вне недельного расписания
+type ExtraSlot struct {
+	ID        string    `json:"id"`
+	TrainerID string    `json:"trainer_id"`
+	StartsAt  time.Time `json:"starts_at"`
+	EndsAt    time.Time `json:"ends_at"`
+}
+
+// Blackout — день, в который тренер не работает, в формате "ГГГГ-ММ-ДД" в указанном часовом поясе
+type Blackout struct {
+	ID        string `json:"id"`
+	TrainerID string `json:"trainer_id"`
+	Date      string `json:"date"`
+	TimeZone  string `json:"time_zone"`
+	Reason    string `json:"reason"`
+}
+
+// Schedule — полное расписание тренера
+type Schedule struct {
+	Weekly    []WeeklyWindow `json:"weekly"`
+	Extra     []ExtraSlot    `json:"extra"`
+	Blackouts []Blackout     `json:"blackouts"`
+}
+
+// Slot — конкретный интервал времени, доступный для бронирования
+type Slot struct {
+	StartsAt time.Time `json:"starts_at"`
+	EndsAt   time.Time `json:"ends_at"`
+}
+
+// Interval — занятый интервал времени тренера
+type Interval struct {
+	Start time.Time
+	End   time.Time
+}
+
+// BusyProvider возвращает интервалы, в которые тренер уже занят (например, забронированные занятия)
+type BusyProvider interface {
+	BusyIntervals(ctx context.Context, trainerID string, from, to time.Time) ([]Interval, error)
+}
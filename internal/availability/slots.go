@@ -0,0 +1,146 @@
+package availability
+
+import (
+	"TrainerConnect/internal/apperr"
+	"fmt"
+	"sort"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+// parseClock разбирает время в формате "ЧЧ:ММ" и возвращает часы и минуты
+func parseClock(s string) (int, int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid time %q, expected HH:MM", s)
+	}
+	return t.Hour(), t.Minute(), nil
+}
+
+// invalidTimeZone возвращает ошибку поля time_zone, если tz не является зоной из базы IANA
+func invalidTimeZone(tz string) error {
+	if _, err := time.LoadLocation(tz); err != nil || tz == "" {
+		return apperr.InvalidField("time_zone", "unknown_time_zone", fmt.Sprintf("unknown time zone %q", tz))
+	}
+	return nil
+}
+
+// Validate проверяет корректность недельного окна
+func (w WeeklyWindow) Validate() error {
+	if w.Weekday < time.Sunday || w.Weekday > time.Saturday {
+		return apperr.InvalidField("weekday", "out_of_range", "weekday must be between 0 (Sunday) and 6 (Saturday)")
+	}
+	if err := invalidTimeZone(w.TimeZone); err != nil {
+		return err
+	}
+	sh, sm, err := parseClock(w.StartTime)
+	if err != nil {
+		return apperr.InvalidField("start_time", "invalid_time", err.Error())
+	}
+	eh, em, err := parseClock(w.EndTime)
+	if err != nil {
+		return apperr.InvalidField("end_time", "invalid_time", err.Error())
+	}
+	if eh*60+em <= sh*60+sm {
+		return apperr.InvalidField("end_time", "before_start", "end_time must be after start_time")
+	}
+	return nil
+}
+
+// Validate проверяет корректность выходного дня
+func (b Blackout) Validate() error {
+	if _, err := time.Parse(dateLayout, b.Date); err != nil {
+		return apperr.InvalidField("date", "invalid_date", fmt.Sprintf("invalid date %q, expected YYYY-MM-DD", b.Date))
+	}
+	return invalidTimeZone(b.TimeZone)
+}
+
+// Expand разворачивает расписание в конкретные слоты длительностью duration в интервале [from, to).
+// Слоты, попадающие на выходные дни или пересекающиеся с занятыми интервалами, отбрасываются.
+func Expand(schedule Schedule, from, to time.Time, duration time.Duration, busy []Interval) ([]Slot, error) {
+	if duration <= 0 {
+		return nil, fmt.Errorf("slot duration must be positive")
+	}
+
+	var windows []Interval
+	for _, w := range schedule.Weekly {
+		loc, err := time.LoadLocation(w.TimeZone)
+		if err != nil {
+			return nil, err
+		}
+		sh, sm, err := parseClock(w.StartTime)
+		if err != nil {
+			return nil, err
+		}
+		eh, em, err := parseClock(w.EndTime)
+		if err != nil {
+			return nil, err
+		}
+
+		// Перебираем локальные даты окна, захватывая по дню с каждой стороны из-за разницы часовых поясов
+		first := from.In(loc).AddDate(0, 0, -1)
+		last := to.In(loc).AddDate(0, 0, 1)
+		for d := time.Date(first.Year(), first.Month(), first.Day(), 0, 0, 0, 0, loc); !d.After(last); d = d.AddDate(0, 0, 1) {
+			if d.Weekday() != w.Weekday {
+				continue
+			}
+			windows = append(windows, Interval{
+				Start: time.Date(d.Year(), d.Month(), d.Day(), sh, sm, 0, 0, loc),
+				End:   time.Date(d.Year(), d.Month(), d.Day(), eh, em, 0, 0, loc),
+			})
+		}
+	}
+	for _, e := range schedule.Extra {
+		windows = append(windows, Interval{Start: e.StartsAt, End: e.EndsAt})
+	}
+
+	blocked := append([]Interval(nil), busy...)
+	for _, b := range schedule.Blackouts {
+		loc, err := time.LoadLocation(b.TimeZone)
+		if err != nil {
+			return nil, err
+		}
+		day, err := time.ParseInLocation(dateLayout, b.Date, loc)
+		if err != nil {
+			return nil, err
+		}
+		blocked = append(blocked, Interval{Start: day, End: day.AddDate(0, 0, 1)})
+	}
+
+	var slots []Slot
+	for _, w := range windows {
+		for start := w.Start; !start.Add(duration).After(w.End); start = start.Add(duration) {
+			slot := Interval{Start: start, End: start.Add(duration)}
+			if slot.Start.Before(from) || slot.End.After(to) || overlapsAny(slot, blocked) {
+				continue
+			}
+			slots = append(slots, Slot{StartsAt: slot.Start.UTC(), EndsAt: slot.End.UTC()})
+		}
+	}
+
+	// Недельные и разовые окна могут пересекаться: оставляем только непересекающиеся слоты
+	sort.Slice(slots, func(i, j int) bool { return slots[i].StartsAt.Before(slots[j].StartsAt) })
+	result := []Slot{}
+	for _, s := range slots {
+		if n := len(result); n > 0 && s.StartsAt.Before(result[n-1].EndsAt) {
+			continue
+		}
+		result = append(result, s)
+	}
+	return result, nil
+}
+
+// Overlaps сообщает, пересекаются ли два полуоткрытых интервала
+func (i Interval) Overlaps(other Interval) bool {
+	return i.Start.Before(other.End) && other.Start.Before(i.End)
+}
+
+func overlapsAny(slot Interval, intervals []Interval) bool {
+	for _, i := range intervals {
+		if slot.Overlaps(i) {
+			return true
+		}
+	}
+	return false
+}
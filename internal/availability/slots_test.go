@@ -0,0 +1,90 @@
+package availability_test
+
+import (
+	"TrainerConnect/internal/availability"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func mustTime(t *testing.T, s string) time.Time {
+	tm, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		t.Fatalf("Error parsing time: %v", err)
+	}
+	return tm
+}
+
+func TestExpandWeeklyWindow(t *testing.T) {
+	// Понедельник 09:00–11:00 по Москве (UTC+3)
+	schedule := availability.Schedule{
+		Weekly: []availability.WeeklyWindow{
+			{Weekday: time.Monday, StartTime: "09:00", EndTime: "11:00", TimeZone: "Europe/Moscow"},
+		},
+	}
+
+	slots, err := availability.Expand(schedule,
+		mustTime(t, "2026-10-19T00:00:00Z"), mustTime(t, "2026-10-26T00:00:00Z"), time.Hour, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []availability.Slot{
+		{StartsAt: mustTime(t, "2026-10-19T06:00:00Z"), EndsAt: mustTime(t, "2026-10-19T07:00:00Z")},
+		{StartsAt: mustTime(t, "2026-10-19T07:00:00Z"), EndsAt: mustTime(t, "2026-10-19T08:00:00Z")},
+	}, slots)
+}
+
+func TestExpandRespectsBlackoutsAndBusy(t *testing.T) {
+	schedule := availability.Schedule{
+		Weekly: []availability.WeeklyWindow{
+			{Weekday: time.Monday, StartTime: "09:00", EndTime: "12:00", TimeZone: "UTC"},
+			{Weekday: time.Tuesday, StartTime: "09:00", EndTime: "10:00", TimeZone: "UTC"},
+		},
+		Extra: []availability.ExtraSlot{
+			// Разовое окно пересекается с недельным и не должно давать дублей
+			{StartsAt: mustTime(t, "2026-10-19T11:30:00Z"), EndsAt: mustTime(t, "2026-10-19T13:30:00Z")},
+		},
+		Blackouts: []availability.Blackout{
+			{Date: "2026-10-20", TimeZone: "UTC"},
+		},
+	}
+	busy := []availability.Interval{
+		{Start: mustTime(t, "2026-10-19T10:00:00Z"), End: mustTime(t, "2026-10-19T11:00:00Z")},
+	}
+
+	slots, err := availability.Expand(schedule,
+		mustTime(t, "2026-10-19T00:00:00Z"), mustTime(t, "2026-10-21T00:00:00Z"), time.Hour, busy)
+	assert.NoError(t, err)
+	assert.Equal(t, []availability.Slot{
+		{StartsAt: mustTime(t, "2026-10-19T09:00:00Z"), EndsAt: mustTime(t, "2026-10-19T10:00:00Z")},
+		{StartsAt: mustTime(t, "2026-10-19T11:00:00Z"), EndsAt: mustTime(t, "2026-10-19T12:00:00Z")},
+		{StartsAt: mustTime(t, "2026-10-19T12:30:00Z"), EndsAt: mustTime(t, "2026-10-19T13:30:00Z")},
+	}, slots)
+}
+
+func TestExpandAcrossDaylightSavingChange(t *testing.T) {
+	// В Берлине 25 октября 2026 переход на зимнее время: локальное 09:00 сдвигается в UTC
+	schedule := availability.Schedule{
+		Weekly: []availability.WeeklyWindow{
+			{Weekday: time.Sunday, StartTime: "09:00", EndTime: "10:00", TimeZone: "Europe/Berlin"},
+		},
+	}
+
+	slots, err := availability.Expand(schedule,
+		mustTime(t, "2026-10-18T00:00:00Z"), mustTime(t, "2026-10-26T00:00:00Z"), time.Hour, nil)
+	assert.NoError(t, err)
+	assert.Len(t, slots, 2)
+	assert.Equal(t, mustTime(t, "2026-10-18T07:00:00Z"), slots[0].StartsAt)
+	assert.Equal(t, mustTime(t, "2026-10-25T08:00:00Z"), slots[1].StartsAt)
+}
+
+func TestWeeklyWindowValidate(t *testing.T) {
+	valid := availability.WeeklyWindow{Weekday: time.Friday, StartTime: "18:00", EndTime: "21:30", TimeZone: "Asia/Yekaterinburg"}
+	assert.NoError(t, valid.Validate())
+
+	invalid := valid
+	invalid.EndTime = "17:00"
+	assert.Error(t, invalid.Validate())
+
+	invalid = valid
+	invalid.TimeZone = "Mars/Olympus"
+	assert.Error(t, invalid.Validate())
+}
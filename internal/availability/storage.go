@@ -0,0 +1,178 @@
+package availability
+
+import (
+	"TrainerConnect/internal/apperr"
+	postgres "TrainerConnect/pkg/postgresql"
+	"context"
+	"database/sql"
+	"time"
+)
+
+var (
+	ErrSlotNotFound     = apperr.NotFound("slot_not_found", "extra slot not found")
+	ErrBlackoutNotFound = apperr.NotFound("blackout_not_found", "blackout not found")
+)
+
+type Storage struct {
+	*sql.DB
+}
+
+func NewStorage(db *sql.DB) *Storage {
+	return &Storage{DB: db}
+}
+
+// GetSchedule возвращает недельное расписание тренера, а также разовые окна и выходные дни,
+// пересекающиеся с интервалом [from, to)
+func (s *Storage) GetSchedule(ctx context.Context, trainerID string, from, to time.Time) (_ *Schedule, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	schedule := &Schedule{Weekly: []WeeklyWindow{}, Extra: []ExtraSlot{}, Blackouts: []Blackout{}}
+
+	rows, err := s.DB.QueryContext(ctx, `SELECT id, trainer_id, weekday, to_char(start_time, 'HH24:MI'), to_char(end_time, 'HH24:MI'), time_zone
+		FROM availability_weekly WHERE trainer_id = $1 ORDER BY weekday, start_time`, trainerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var w WeeklyWindow
+		if err := rows.Scan(&w.ID, &w.TrainerID, &w.Weekday, &w.StartTime, &w.EndTime, &w.TimeZone); err != nil {
+			return nil, err
+		}
+		schedule.Weekly = append(schedule.Weekly, w)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = s.DB.QueryContext(ctx, `SELECT id, trainer_id, starts_at, ends_at
+		FROM availability_extra_slots WHERE trainer_id = $1 AND starts_at < $3 AND ends_at > $2 ORDER BY starts_at`, trainerID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var e ExtraSlot
+		if err := rows.Scan(&e.ID, &e.TrainerID, &e.StartsAt, &e.EndsAt); err != nil {
+			return nil, err
+		}
+		schedule.Extra = append(schedule.Extra, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Выходной день длится с полуночи до полуночи в своем часовом поясе
+	rows, err = s.DB.QueryContext(ctx, `SELECT id, trainer_id, to_char(day, 'YYYY-MM-DD'), time_zone, reason
+		FROM availability_blackouts
+		WHERE trainer_id = $1 AND day::timestamp AT TIME ZONE time_zone < $3 AND (day + 1)::timestamp AT TIME ZONE time_zone > $2
+		ORDER BY day`, trainerID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var b Blackout
+		if err := rows.Scan(&b.ID, &b.TrainerID, &b.Date, &b.TimeZone, &b.Reason); err != nil {
+			return nil, err
+		}
+		schedule.Blackouts = append(schedule.Blackouts, b)
+	}
+	return schedule, rows.Err()
+}
+
+// ReplaceWeekly заменяет недельное расписание тренера целиком
+func (s *Storage) ReplaceWeekly(ctx context.Context, trainerID string, windows []WeeklyWindow) (err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM availability_weekly WHERE trainer_id = $1", trainerID); err != nil {
+		return err
+	}
+	for i := range windows {
+		w := &windows[i]
+		w.TrainerID = trainerID
+		err := tx.QueryRowContext(ctx, `INSERT INTO availability_weekly (trainer_id, weekday, start_time, end_time, time_zone)
+			VALUES ($1, $2, $3, $4, $5) RETURNING id`,
+			trainerID, int(w.Weekday), w.StartTime, w.EndTime, w.TimeZone).Scan(&w.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// AddExtraSlot добавляет разовое окно
+func (s *Storage) AddExtraSlot(ctx context.Context, e *ExtraSlot) (err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	return s.DB.QueryRowContext(ctx, "INSERT INTO availability_extra_slots (trainer_id, starts_at, ends_at) VALUES ($1, $2, $3) RETURNING id",
+		e.TrainerID, e.StartsAt, e.EndsAt).Scan(&e.ID)
+}
+
+// DeleteExtraSlot удаляет разовое окно тренера. Возвращает false, если окно не найдено.
+func (s *Storage) DeleteExtraSlot(ctx context.Context, trainerID, slotID string) (_ bool, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	return s.deleteRow(ctx, "DELETE FROM availability_extra_slots WHERE trainer_id = $1 AND id = $2", trainerID, slotID)
+}
+
+// AddBlackout добавляет выходной день
+func (s *Storage) AddBlackout(ctx context.Context, b *Blackout) (err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	return s.DB.QueryRowContext(ctx, `INSERT INTO availability_blackouts (trainer_id, day, time_zone, reason) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (trainer_id, day) DO UPDATE SET time_zone = EXCLUDED.time_zone, reason = EXCLUDED.reason
+		RETURNING id`,
+		b.TrainerID, b.Date, b.TimeZone, b.Reason).Scan(&b.ID)
+}
+
+// DeleteBlackout удаляет выходной день тренера. Возвращает false, если он не найден.
+func (s *Storage) DeleteBlackout(ctx context.Context, trainerID, blackoutID string) (_ bool, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	return s.deleteRow(ctx, "DELETE FROM availability_blackouts WHERE trainer_id = $1 AND id = $2", trainerID, blackoutID)
+}
+
+func (s *Storage) deleteRow(ctx context.Context, query string, args ...interface{}) (bool, error) {
+	res, err := s.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// FreeSlots разворачивает расписание тренера в свободные слоты с учетом занятости из busy.
+// busy может быть nil, если занятость не учитывается.
+func (s *Storage) FreeSlots(ctx context.Context, trainerID string, from, to time.Time, duration time.Duration, busy BusyProvider) (_ []Slot, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	schedule, err := s.GetSchedule(ctx, trainerID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	var intervals []Interval
+	if busy != nil {
+		intervals, err = busy.BusyIntervals(ctx, trainerID, from, to)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return Expand(*schedule, from, to, duration, intervals)
+}
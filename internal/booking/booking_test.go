@@ -0,0 +1,176 @@
+package booking_test
+
+import (
+	"TrainerConnect/internal/auth"
+	"TrainerConnect/internal/booking"
+	postgres "TrainerConnect/pkg/postgresql"
+	"TrainerConnect/pkg/postgresql/config"
+	"context"
+	"database/sql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"log"
+	"math/rand"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+)
+
+var db *sql.DB
+
+func TestMain(m *testing.M) {
+	// Тесты работают с тестовой БД и пропускаются, если она недоступна
+	cfg, err := config.ReadConfig("../../pkg/postgresql/config/database_test.json")
+	if err == nil {
+		db, err = postgres.NewDB(cfg)
+	}
+	if err == nil {
+		var migrator *postgres.Migrator
+		if migrator, err = postgres.NewMigrator(db); err == nil {
+			_, err = migrator.Up(context.Background())
+		}
+	}
+	if err != nil {
+		log.Printf("Test database is unavailable, skipping database tests: %v", err)
+		db = nil
+	}
+
+	exitCode := m.Run()
+	if db != nil {
+		db.Close()
+	}
+	os.Exit(exitCode)
+}
+
+func requireDB(t *testing.T) {
+	if db == nil {
+		t.Skip("test database is unavailable")
+	}
+}
+
+// ID пользователей берутся из диапазона, которого не касаются другие тесты с той же БД
+var nextUserID = 910000000 + rand.New(rand.NewSource(time.Now().UnixNano())).Intn(1000000)*100
+
+// newUser создает пользователя с ролью role и удаляет его вместе с занятиями после теста
+func newUser(t *testing.T, role string) string {
+	nextUserID++
+	id := strconv.Itoa(nextUserID)
+	_, err := db.Exec(`INSERT INTO users (user_id, username, password, salt, role, email)
+		VALUES ($1, $2, 'hash', 'salt', $3, $4)`, id, "booking"+id, role, "booking"+id+"@example.com")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		db.Exec("DELETE FROM bookings WHERE trainer_id = $1 OR client_id = $1", id)
+		db.Exec("DELETE FROM users WHERE user_id = $1", id)
+	})
+	return id
+}
+
+// newTrainer создает тренера с часовой ставкой hourlyRate
+func newTrainer(t *testing.T, hourlyRate int64) string {
+	id := newUser(t, auth.RoleTrainer)
+	_, err := db.Exec("INSERT INTO trainer_profiles (user_id, hourly_rate, currency) VALUES ($1, $2, 'EUR')", id, hourlyRate)
+	require.NoError(t, err)
+	return id
+}
+
+// newBooking создает занятие длительностью duration, начинающееся в startsAt
+func newBooking(t *testing.T, storage *booking.Storage, trainer, client string, startsAt time.Time, duration time.Duration) *booking.Booking {
+	b := &booking.Booking{TrainerID: trainer, ClientID: client, StartsAt: startsAt, EndsAt: startsAt.Add(duration)}
+	require.NoError(t, storage.Create(context.Background(), b))
+	return b
+}
+
+// nextWeek возвращает начало часа через неделю: занятие в это время можно переносить и отменять по политике
+func nextWeek() time.Time {
+	return time.Now().UTC().Add(7 * 24 * time.Hour).Truncate(time.Hour)
+}
+
+func TestStorageCreateOverlap(t *testing.T) {
+	requireDB(t)
+	ctx := context.Background()
+	storage := booking.NewStorage(db)
+	trainer := newTrainer(t, 6000)
+	first, second := newUser(t, auth.RoleClient), newUser(t, auth.RoleClient)
+
+	startsAt := nextWeek()
+	newBooking(t, storage, trainer, first, startsAt, time.Hour)
+
+	// Другой клиент не может занять пересекающееся время того же тренера
+	b := &booking.Booking{TrainerID: trainer, ClientID: second, StartsAt: startsAt.Add(30 * time.Minute), EndsAt: startsAt.Add(90 * time.Minute)}
+	assert.ErrorIs(t, storage.Create(ctx, b), booking.ErrConflict)
+
+	// Занятие сразу после первого не пересекается с ним
+	b = &booking.Booking{TrainerID: trainer, ClientID: second, StartsAt: startsAt.Add(time.Hour), EndsAt: startsAt.Add(2 * time.Hour)}
+	assert.NoError(t, storage.Create(ctx, b))
+}
+
+func TestStorageTransitionHistory(t *testing.T) {
+	requireDB(t)
+	ctx := context.Background()
+	storage := booking.NewStorage(db)
+	trainer, client := newTrainer(t, 6000), newUser(t, auth.RoleClient)
+
+	// Отметить занятие проведенным можно только после его начала
+	b := newBooking(t, storage, trainer, client, time.Now().UTC().Add(-2*time.Hour), time.Hour)
+	_, err := storage.Transition(ctx, b.ID, booking.StatusConfirmed, trainer, false, "")
+	require.NoError(t, err)
+	completed, err := storage.Transition(ctx, b.ID, booking.StatusCompleted, trainer, false, "done")
+	require.NoError(t, err)
+	assert.Equal(t, booking.StatusCompleted, completed.Status)
+
+	// Проведенное занятие отменить нельзя ни клиенту, ни тренеру
+	_, _, err = storage.Cancel(ctx, b.ID, client, false, "")
+	assert.ErrorIs(t, err, booking.ErrInvalidTransition)
+	_, _, err = storage.Cancel(ctx, b.ID, trainer, false, "")
+	assert.ErrorIs(t, err, booking.ErrInvalidTransition)
+
+	history, err := storage.History(ctx, b.ID)
+	require.NoError(t, err)
+	require.Len(t, history, 3)
+	expected := []struct {
+		from, to booking.Status
+		actorID  string
+		actor    booking.Actor
+	}{
+		{"", booking.StatusRequested, client, booking.ActorClient},
+		{booking.StatusRequested, booking.StatusConfirmed, trainer, booking.ActorTrainer},
+		{booking.StatusConfirmed, booking.StatusCompleted, trainer, booking.ActorTrainer},
+	}
+	for i, e := range expected {
+		assert.Equal(t, e.from, history[i].From, "transition %d", i)
+		assert.Equal(t, e.to, history[i].To, "transition %d", i)
+		assert.Equal(t, e.actorID, history[i].ActorID, "transition %d", i)
+		assert.Equal(t, e.actor, history[i].Actor, "transition %d", i)
+	}
+	assert.Equal(t, "done", history[2].Reason)
+}
+
+func TestStorageReschedulePrice(t *testing.T) {
+	requireDB(t)
+	ctx := context.Background()
+	storage := booking.NewStorage(db)
+	trainer, client := newTrainer(t, 6000), newUser(t, auth.RoleClient)
+
+	b := newBooking(t, storage, trainer, client, nextWeek(), time.Hour)
+	assert.Equal(t, int64(6000), b.Price)
+	assert.Equal(t, "EUR", b.Currency)
+
+	// Перенос без изменения длительности сохраняет согласованную цену, даже если ставка изменилась
+	_, err := db.Exec("UPDATE trainer_profiles SET hourly_rate = 9000 WHERE user_id = $1", trainer)
+	require.NoError(t, err)
+	startsAt := b.StartsAt.Add(24 * time.Hour)
+	moved, _, err := storage.Reschedule(ctx, b.ID, client, false, startsAt, startsAt.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, int64(6000), moved.Price)
+
+	// Более длинное занятие стоит пропорционально больше по текущей ставке
+	moved, _, err = storage.Reschedule(ctx, b.ID, trainer, false, startsAt, startsAt.Add(90*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, int64(13500), moved.Price)
+
+	stored, err := storage.Get(ctx, b.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(13500), stored.Price)
+	assert.Equal(t, startsAt.Add(90*time.Minute), stored.EndsAt.UTC())
+}
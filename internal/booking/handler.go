@@ -0,0 +1,410 @@
+package booking
+
+import (
+	"TrainerConnect/internal/apperr"
+	"TrainerConnect/internal/auth"
+	"TrainerConnect/internal/availability"
+	"TrainerConnect/internal/events"
+	"TrainerConnect/internal/notify"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	bookingURL = "/bookings/"
+	policyURL  = "/trainers/{id}/booking-policy"
+)
+
+type Handler struct {
+	Storage      *Storage
+	Availability *availability.Storage
+	Events       events.Publisher
+	Notify       notify.Sender
+}
+
+// NewHandler создает обработчик бронирований. Если availability не nil,
+// новое занятие должно совпадать со свободным слотом в расписании тренера.
+// Об изменениях бронирования сразу узнают оба участника через publisher,
+// об отмене вторая сторона дополнительно получает уведомление.
+func NewHandler(storage *Storage, availability *availability.Storage, publisher events.Publisher, sender notify.Sender) *Handler {
+	return &Handler{Storage: storage, Availability: availability, Events: publisher, Notify: sender}
+}
+
+// publish сообщает тренеру и клиенту об изменении бронирования
+func (h *Handler) publish(eventType string, b *Booking) {
+	h.Events.Publish(eventType, b, b.TrainerID, b.ClientID)
+}
+
+func (h *Handler) Register(router *chi.Mux) {
+	router.Get(policyURL, h.GetPolicy)
+
+	router.Group(func(r chi.Router) {
+		r.Use(auth.RequireAuth)
+		r.Put(policyURL, h.UpdatePolicy)
+		r.Get("/bookings", h.List)
+		r.Post("/bookings", h.Create)
+		r.Get(bookingURL+"{id}", h.Get)
+		r.Get(bookingURL+"{id}/history", h.History)
+		r.Post(bookingURL+"{id}/confirm", h.transition(StatusConfirmed))
+		r.Post(bookingURL+"{id}/decline", h.transition(StatusDeclined))
+		r.Post(bookingURL+"{id}/cancel", h.Cancel)
+		r.Post(bookingURL+"{id}/reschedule", h.Reschedule)
+		r.Post(bookingURL+"{id}/complete", h.transition(StatusCompleted))
+		r.Post(bookingURL+"{id}/no-show", h.transition(StatusNoShow))
+	})
+}
+
+type createRequest struct {
+	TrainerID string    `json:"trainer_id"`
+	StartsAt  time.Time `json:"starts_at"`
+	EndsAt    time.Time `json:"ends_at"`
+	Note      string    `json:"note"`
+}
+
+// Create создает запрос клиента на занятие с тренером
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	principal, _ := auth.PrincipalFromContext(r.Context())
+
+	var req createRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apperr.Respond(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if _, err := strconv.Atoi(req.TrainerID); err != nil {
+		apperr.Respond(w, http.StatusBadRequest, "Invalid trainer ID")
+		return
+	}
+	if req.TrainerID == principal.UserID {
+		apperr.Write(w, apperr.InvalidField("trainer_id", "self_booking", "cannot book a session with yourself"))
+		return
+	}
+	if !req.EndsAt.After(req.StartsAt) {
+		apperr.Write(w, apperr.InvalidField("ends_at", "before_start", "ends_at must be after starts_at"))
+		return
+	}
+	if req.StartsAt.Before(time.Now()) {
+		apperr.Write(w, apperr.InvalidField("starts_at", "in_past", "cannot book a session in the past"))
+		return
+	}
+
+	if h.Availability != nil {
+		free, err := h.isFree(r.Context(), req.TrainerID, req.StartsAt, req.EndsAt, h.Storage)
+		if err != nil {
+			log.Printf("Error checking availability of trainer %s: %v", req.TrainerID, err)
+			apperr.Write(w, err)
+			return
+		}
+		if !free {
+			apperr.Write(w, ErrUnavailable)
+			return
+		}
+	}
+
+	b := &Booking{
+		TrainerID: req.TrainerID,
+		ClientID:  principal.UserID,
+		StartsAt:  req.StartsAt.UTC(),
+		EndsAt:    req.EndsAt.UTC(),
+		Note:      req.Note,
+	}
+	if err := h.Storage.Create(r.Context(), b); err != nil {
+		if !apperr.Expected(err) {
+			log.Printf("Error creating booking: %v", err)
+		}
+		apperr.Write(w, err)
+		return
+	}
+	h.publish("booking.created", b)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(b)
+}
+
+// isFree проверяет, что запрошенное время совпадает со свободным слотом расписания тренера
+func (h *Handler) isFree(ctx context.Context, trainerID string, startsAt, endsAt time.Time, busy availability.BusyProvider) (bool, error) {
+	duration := endsAt.Sub(startsAt)
+	slots, err := h.Availability.FreeSlots(ctx, trainerID, startsAt, endsAt, duration, busy)
+	if err != nil {
+		return false, err
+	}
+	for _, s := range slots {
+		if s.StartsAt.Equal(startsAt) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// List возвращает занятия текущего пользователя, опционально отфильтрованные по состоянию
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	principal, _ := auth.PrincipalFromContext(r.Context())
+
+	bookings, err := h.Storage.ListForUser(r.Context(), principal.UserID, Status(r.URL.Query().Get("status")))
+	if err != nil {
+		log.Printf("Error listing bookings of user %s: %v", principal.UserID, err)
+		apperr.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(bookings)
+}
+
+// load загружает бронирование и проверяет, что текущий пользователь имеет к нему доступ
+func (h *Handler) load(w http.ResponseWriter, r *http.Request) (*Booking, bool) {
+	id := chi.URLParam(r, "id")
+	if _, err := strconv.Atoi(id); err != nil {
+		apperr.Respond(w, http.StatusBadRequest, "Invalid booking ID")
+		return nil, false
+	}
+
+	b, err := h.Storage.Get(r.Context(), id)
+	if err != nil {
+		if !apperr.Expected(err) {
+			log.Printf("Error getting booking %s: %v", id, err)
+		}
+		apperr.Write(w, err)
+		return nil, false
+	}
+
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	if _, ok := b.ActorFor(principal.UserID, principal.IsAdmin()); !ok {
+		apperr.Respond(w, http.StatusForbidden, "Forbidden")
+		return nil, false
+	}
+	return b, true
+}
+
+// Get возвращает бронирование участнику занятия
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
+	b, ok := h.load(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(b)
+}
+
+// History возвращает журнал переходов бронирования
+func (h *Handler) History(w http.ResponseWriter, r *http.Request) {
+	b, ok := h.load(w, r)
+	if !ok {
+		return
+	}
+
+	history, err := h.Storage.History(r.Context(), b.ID)
+	if err != nil {
+		log.Printf("Error getting history of booking %s: %v", b.ID, err)
+		apperr.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(history)
+}
+
+type transitionRequest struct {
+	Reason string `json:"reason"`
+}
+
+// transition возвращает обработчик, переводящий бронирование в состояние to
+func (h *Handler) transition(to Status) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+		if _, err := strconv.Atoi(id); err != nil {
+			apperr.Respond(w, http.StatusBadRequest, "Invalid booking ID")
+			return
+		}
+
+		// Тело с причиной необязательно
+		var req transitionRequest
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				apperr.Respond(w, http.StatusBadRequest, "Invalid request body")
+				return
+			}
+		}
+
+		principal, _ := auth.PrincipalFromContext(r.Context())
+		b, err := h.Storage.Transition(r.Context(), id, to, principal.UserID, principal.IsAdmin(), req.Reason)
+		if err != nil {
+			if !apperr.Expected(err) {
+				log.Printf("Error updating booking %s: %v", id, err)
+			}
+			apperr.Write(w, err)
+			return
+		}
+		h.publish("booking.updated", b)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(b)
+	}
+}
+
+type cancelResponse struct {
+	Booking  *Booking  `json:"booking"`
+	Decision *Decision `json:"decision"`
+}
+
+// Cancel отменяет занятие по правилам политики тренера и возвращает удержанный сбор
+func (h *Handler) Cancel(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if _, err := strconv.Atoi(id); err != nil {
+		apperr.Respond(w, http.StatusBadRequest, "Invalid booking ID")
+		return
+	}
+
+	var req transitionRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apperr.Respond(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	b, decision, err := h.Storage.Cancel(r.Context(), id, principal.UserID, principal.IsAdmin(), req.Reason)
+	if err != nil {
+		if !apperr.Expected(err) {
+			log.Printf("Error cancelling booking %s: %v", id, err)
+		}
+		apperr.Write(w, err)
+		return
+	}
+	h.publish("booking.updated", b)
+
+	// Уведомляем участников, кроме отменившего; при отмене администратором — обоих
+	for _, userID := range []string{b.TrainerID, b.ClientID} {
+		if userID == principal.UserID {
+			continue
+		}
+		h.Notify.Notify(notify.Notification{
+			UserID:  userID,
+			Kind:    notify.KindBookingCancelled,
+			Subject: "Session cancelled",
+			Body:    fmt.Sprintf("The session on %s UTC has been cancelled.", b.StartsAt.UTC().Format("2006-01-02 15:04")),
+			Data:    b,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(cancelResponse{Booking: b, Decision: decision})
+}
+
+type rescheduleRequest struct {
+	StartsAt time.Time `json:"starts_at"`
+	EndsAt   time.Time `json:"ends_at"`
+}
+
+// Reschedule переносит занятие на другое свободное время по правилам политики тренера
+func (h *Handler) Reschedule(w http.ResponseWriter, r *http.Request) {
+	b, ok := h.load(w, r)
+	if !ok {
+		return
+	}
+
+	var req rescheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apperr.Respond(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if !req.EndsAt.After(req.StartsAt) {
+		apperr.Write(w, apperr.InvalidField("ends_at", "before_start", "ends_at must be after starts_at"))
+		return
+	}
+	if req.StartsAt.Before(time.Now()) {
+		apperr.Write(w, apperr.InvalidField("starts_at", "in_past", "cannot reschedule a session into the past"))
+		return
+	}
+
+	if h.Availability != nil {
+		free, err := h.isFree(r.Context(), b.TrainerID, req.StartsAt, req.EndsAt, h.Storage.ExceptBooking(b.ID))
+		if err != nil {
+			log.Printf("Error checking availability of trainer %s: %v", b.TrainerID, err)
+			apperr.Write(w, err)
+			return
+		}
+		if !free {
+			apperr.Write(w, ErrUnavailable)
+			return
+		}
+	}
+
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	updated, decision, err := h.Storage.Reschedule(r.Context(), b.ID, principal.UserID, principal.IsAdmin(), req.StartsAt.UTC(), req.EndsAt.UTC())
+	if err != nil {
+		if !apperr.Expected(err) {
+			log.Printf("Error rescheduling booking %s: %v", b.ID, err)
+		}
+		apperr.Write(w, err)
+		return
+	}
+	h.publish("booking.updated", updated)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(cancelResponse{Booking: updated, Decision: decision})
+}
+
+// GetPolicy возвращает правила отмены и переноса занятий тренера
+func (h *Handler) GetPolicy(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if _, err := strconv.Atoi(id); err != nil {
+		apperr.Respond(w, http.StatusBadRequest, "Invalid trainer ID")
+		return
+	}
+
+	policy, err := h.Storage.GetPolicy(r.Context(), id)
+	if err != nil {
+		log.Printf("Error getting booking policy of trainer %s: %v", id, err)
+		apperr.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(policy)
+}
+
+// UpdatePolicy сохраняет правила отмены и переноса. Менять их может сам тренер или администратор.
+func (h *Handler) UpdatePolicy(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if _, err := strconv.Atoi(id); err != nil {
+		apperr.Respond(w, http.StatusBadRequest, "Invalid trainer ID")
+		return
+	}
+
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	if principal.UserID != id && !principal.IsAdmin() {
+		apperr.Respond(w, http.StatusForbidden, "Forbidden")
+		return
+	}
+
+	var policy Policy
+	if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+		apperr.Respond(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	policy.TrainerID = id
+	if err := policy.Validate(); err != nil {
+		apperr.Write(w, err)
+		return
+	}
+
+	if err := h.Storage.SavePolicy(r.Context(), policy); err != nil {
+		if !apperr.Expected(err) {
+			log.Printf("Error saving booking policy of trainer %s: %v", id, err)
+		}
+		apperr.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(policy)
+}
@@ -0,0 +1,71 @@
+package booking
+
+import (
+	"time"
+)
+
+// Status — состояние бронирования занятия
+type Status string
+
+const (
+	StatusRequested Status = "requested"
+	StatusConfirmed Status = "confirmed"
+	StatusDeclined  Status = "declined"
+	StatusCancelled Status = "cancelled"
+	StatusCompleted Status = "completed"
+	StatusNoShow    Status = "no_show"
+)
+
+// Actor — сторона, выполняющая переход бронирования в новое состояние
+type Actor string
+
+const (
+	ActorClient  Actor = "client"
+	ActorTrainer Actor = "trainer"
+	ActorAdmin   Actor = "admin"
+)
+
+// Booking — занятие клиента с тренером
+type Booking struct {
+	ID        string    `json:"id"`
+	TrainerID string    `json:"trainer_id"`
+	ClientID  string    `json:"client_id"`
+	StartsAt  time.Time `json:"starts_at"`
+	EndsAt    time.Time `json:"ends_at"`
+	Status    Status    `json:"status"`
+	Note      string    `json:"note"`
+
+	// Стоимость занятия по ставке тренера на момент бронирования, в минимальных единицах валюты
+	Price           int64  `json:"price"`
+	Currency        string `json:"currency"`
+	CancellationFee int64  `json:"cancellation_fee"`
+	RescheduleCount int    `json:"reschedule_count"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ActorFor определяет, в качестве какой стороны пользователь userID действует над бронированием.
+// Возвращает false, если пользователь не участник занятия и не администратор.
+func (b *Booking) ActorFor(userID string, admin bool) (Actor, bool) {
+	switch {
+	case userID == b.ClientID:
+		return ActorClient, true
+	case userID == b.TrainerID:
+		return ActorTrainer, true
+	case admin:
+		return ActorAdmin, true
+	}
+	return "", false
+}
+
+// Transition — запись журнала переходов: кто и когда перевел бронирование в новое состояние
+type Transition struct {
+	BookingID string    `json:"booking_id"`
+	From      Status    `json:"from,omitempty"`
+	To        Status    `json:"to"`
+	ActorID   string    `json:"actor_id"`
+	Actor     Actor     `json:"actor"`
+	Reason    string    `json:"reason,omitempty"`
+	At        time.Time `json:"at"`
+}
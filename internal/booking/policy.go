@@ -0,0 +1,124 @@
+package booking
+
+import (
+	"TrainerConnect/internal/apperr"
+	"fmt"
+	"time"
+)
+
+// ErrPolicyRefused возвращается, если политика тренера запрещает отмену или перенос.
+// Решение с причиной отказа передается клиенту в члене ответа decision.
+var ErrPolicyRefused = apperr.Invalid("policy_refused", "refused by trainer policy")
+
+// Policy — правила отмены и переноса занятий, которые задает тренер
+type Policy struct {
+	TrainerID string `json:"trainer_id"`
+
+	// Клиент может бесплатно отменить занятие не позднее чем за FreeCancellationHours часов до начала.
+	// При более поздней отмене удерживается LateCancellationFeePercent процентов стоимости.
+	// Кредит из пакета неделим, поэтому он удерживается, только если удерживается вся стоимость.
+	FreeCancellationHours      int `json:"free_cancellation_hours"`
+	LateCancellationFeePercent int `json:"late_cancellation_fee_percent"`
+
+	// Клиент может перенести занятие не более MaxReschedules раз
+	// и не позднее чем за MinRescheduleNoticeHours часов до начала
+	MaxReschedules           int `json:"max_reschedules"`
+	MinRescheduleNoticeHours int `json:"min_reschedule_notice_hours"`
+}
+
+// DefaultPolicy применяется к тренерам, которые не задали свои правила
+func DefaultPolicy(trainerID string) Policy {
+	return Policy{
+		TrainerID:                  trainerID,
+		FreeCancellationHours:      24,
+		LateCancellationFeePercent: 50,
+		MaxReschedules:             2,
+		MinRescheduleNoticeHours:   12,
+	}
+}
+
+// Validate проверяет, что значения правил имеют смысл
+func (p Policy) Validate() error {
+	switch {
+	case p.FreeCancellationHours < 0:
+		return apperr.InvalidField("free_cancellation_hours", "negative", "must not be negative")
+	case p.MaxReschedules < 0:
+		return apperr.InvalidField("max_reschedules", "negative", "must not be negative")
+	case p.MinRescheduleNoticeHours < 0:
+		return apperr.InvalidField("min_reschedule_notice_hours", "negative", "must not be negative")
+	case p.LateCancellationFeePercent < 0 || p.LateCancellationFeePercent > 100:
+		return apperr.InvalidField("late_cancellation_fee_percent", "out_of_range", "must be between 0 and 100")
+	}
+	return nil
+}
+
+// Decision — результат применения политики к запросу на отмену или перенос.
+// ForfeitsCredit сообщает, что зарезервированный под занятие кредит не возвращается клиенту.
+type Decision struct {
+	Allowed        bool   `json:"allowed"`
+	Fee            int64  `json:"fee"`
+	Currency       string `json:"currency"`
+	ForfeitsCredit bool   `json:"forfeits_credit"`
+	Reason         string `json:"reason"`
+}
+
+func refuse(reason string, args ...interface{}) Decision {
+	return Decision{Allowed: false, Reason: fmt.Sprintf(reason, args...)}
+}
+
+// EvaluateCancellation вычисляет, может ли actor отменить занятие в момент now и какой сбор удерживается.
+// Сбор платит только клиент; отмена тренером или администратором бесплатна.
+func (p Policy) EvaluateCancellation(b *Booking, actor Actor, now time.Time) Decision {
+	if !b.Status.IsActive() {
+		return refuse("booking in status %s cannot be cancelled", b.Status)
+	}
+	if actor != ActorClient {
+		return Decision{Allowed: true, Currency: b.Currency, Reason: "cancelled by " + string(actor)}
+	}
+	if !now.Before(b.StartsAt) {
+		return refuse("session has already started")
+	}
+	if b.Status == StatusRequested {
+		return Decision{Allowed: true, Currency: b.Currency, Reason: "booking was not confirmed yet"}
+	}
+
+	deadline := b.StartsAt.Add(-time.Duration(p.FreeCancellationHours) * time.Hour)
+	if now.Before(deadline) {
+		return Decision{Allowed: true, Currency: b.Currency,
+			Reason: fmt.Sprintf("free cancellation up to %dh before the session", p.FreeCancellationHours)}
+	}
+
+	return Decision{
+		Allowed:        true,
+		Fee:            b.Price * int64(p.LateCancellationFeePercent) / 100,
+		Currency:       b.Currency,
+		ForfeitsCredit: p.LateCancellationFeePercent == 100,
+		Reason: fmt.Sprintf("late cancellation less than %dh before the session: %d%% fee",
+			p.FreeCancellationHours, p.LateCancellationFeePercent),
+	}
+}
+
+// EvaluateReschedule вычисляет, может ли actor перенести занятие в момент now.
+// Ограничения на число и срок переносов действуют только для клиента.
+func (p Policy) EvaluateReschedule(b *Booking, actor Actor, now time.Time) Decision {
+	if !b.Status.IsActive() {
+		return refuse("booking in status %s cannot be rescheduled", b.Status)
+	}
+	if !now.Before(b.StartsAt) {
+		return refuse("session has already started")
+	}
+	if actor != ActorClient {
+		return Decision{Allowed: true, Currency: b.Currency, Reason: "rescheduled by " + string(actor)}
+	}
+	if b.RescheduleCount >= p.MaxReschedules {
+		return refuse("booking has already been rescheduled %d times, the limit is %d", b.RescheduleCount, p.MaxReschedules)
+	}
+
+	deadline := b.StartsAt.Add(-time.Duration(p.MinRescheduleNoticeHours) * time.Hour)
+	if !now.Before(deadline) {
+		return refuse("rescheduling requires at least %dh notice", p.MinRescheduleNoticeHours)
+	}
+
+	return Decision{Allowed: true, Currency: b.Currency,
+		Reason: fmt.Sprintf("reschedule %d of %d", b.RescheduleCount+1, p.MaxReschedules)}
+}
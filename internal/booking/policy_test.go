@@ -0,0 +1,93 @@
+package booking_test
+
+import (
+	"TrainerConnect/internal/booking"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestEvaluateCancellation(t *testing.T) {
+	now := time.Now()
+	policy := booking.DefaultPolicy("9")
+	confirmed := &booking.Booking{Status: booking.StatusConfirmed, StartsAt: now.Add(48 * time.Hour), Price: 300000, Currency: "RUB"}
+
+	// За двое суток отмена бесплатна
+	decision := policy.EvaluateCancellation(confirmed, booking.ActorClient, now)
+	assert.True(t, decision.Allowed)
+	assert.Equal(t, int64(0), decision.Fee)
+
+	// Менее чем за сутки удерживается половина стоимости
+	decision = policy.EvaluateCancellation(confirmed, booking.ActorClient, now.Add(30*time.Hour))
+	assert.True(t, decision.Allowed)
+	assert.Equal(t, int64(150000), decision.Fee)
+	assert.Equal(t, "RUB", decision.Currency)
+	assert.False(t, decision.ForfeitsCredit)
+
+	// Тренер отменяет без сбора с клиента
+	decision = policy.EvaluateCancellation(confirmed, booking.ActorTrainer, now.Add(47*time.Hour))
+	assert.True(t, decision.Allowed)
+	assert.Equal(t, int64(0), decision.Fee)
+
+	// После начала занятия клиент отменить его уже не может
+	decision = policy.EvaluateCancellation(confirmed, booking.ActorClient, now.Add(49*time.Hour))
+	assert.False(t, decision.Allowed)
+	assert.NotEmpty(t, decision.Reason)
+
+	// Проведенное занятие отменить нельзя
+	completed := &booking.Booking{Status: booking.StatusCompleted, StartsAt: now.Add(-time.Hour)}
+	assert.False(t, policy.EvaluateCancellation(completed, booking.ActorAdmin, now).Allowed)
+}
+
+func TestEvaluateCancellationCredit(t *testing.T) {
+	now := time.Now()
+	policy := booking.DefaultPolicy("9")
+	// Занятие оплачено кредитом из пакета, поэтому в самом бронировании цены нет
+	paid := &booking.Booking{Status: booking.StatusConfirmed, StartsAt: now.Add(48 * time.Hour), Currency: "RUB"}
+
+	// Кредит неделим: при удержании половины стоимости он возвращается
+	decision := policy.EvaluateCancellation(paid, booking.ActorClient, now.Add(30*time.Hour))
+	assert.True(t, decision.Allowed)
+	assert.False(t, decision.ForfeitsCredit)
+
+	// При удержании полной стоимости кредит сгорает, даже если сбор в деньгах нулевой
+	policy.LateCancellationFeePercent = 100
+	decision = policy.EvaluateCancellation(paid, booking.ActorClient, now.Add(30*time.Hour))
+	assert.True(t, decision.Allowed)
+	assert.Equal(t, int64(0), decision.Fee)
+	assert.True(t, decision.ForfeitsCredit)
+
+	// Своевременная отмена и отмена тренером кредит не сжигают
+	assert.False(t, policy.EvaluateCancellation(paid, booking.ActorClient, now).ForfeitsCredit)
+	assert.False(t, policy.EvaluateCancellation(paid, booking.ActorTrainer, now.Add(30*time.Hour)).ForfeitsCredit)
+}
+
+func TestEvaluateReschedule(t *testing.T) {
+	now := time.Now()
+	policy := booking.DefaultPolicy("9")
+	b := &booking.Booking{Status: booking.StatusConfirmed, StartsAt: now.Add(48 * time.Hour)}
+
+	assert.True(t, policy.EvaluateReschedule(b, booking.ActorClient, now).Allowed)
+
+	// Не позднее чем за 12 часов
+	decision := policy.EvaluateReschedule(b, booking.ActorClient, now.Add(40*time.Hour))
+	assert.False(t, decision.Allowed)
+	assert.Contains(t, decision.Reason, "12h")
+
+	// Не более двух переносов
+	b.RescheduleCount = 2
+	decision = policy.EvaluateReschedule(b, booking.ActorClient, now)
+	assert.False(t, decision.Allowed)
+	assert.Contains(t, decision.Reason, "limit is 2")
+
+	// На тренера ограничения не распространяются
+	assert.True(t, policy.EvaluateReschedule(b, booking.ActorTrainer, now).Allowed)
+}
+
+func TestPolicyValidate(t *testing.T) {
+	policy := booking.DefaultPolicy("9")
+	assert.NoError(t, policy.Validate())
+
+	policy.LateCancellationFeePercent = 120
+	assert.Error(t, policy.Validate())
+}
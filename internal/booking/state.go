@@ -0,0 +1,62 @@
+package booking
+
+import (
+	"TrainerConnect/internal/apperr"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrInvalidTransition = apperr.Conflict("invalid_transition", "invalid booking transition")
+	ErrActorNotAllowed   = apperr.Forbidden("transition_not_allowed", "actor is not allowed to perform this transition")
+	ErrTooEarly          = apperr.Conflict("session_not_started", "session has not started yet")
+)
+
+// transitions описывает допустимые переходы и стороны, которые могут их выполнять.
+// Администратор может выполнить любой допустимый переход.
+var transitions = map[Status]map[Status][]Actor{
+	StatusRequested: {
+		StatusConfirmed: {ActorTrainer},
+		StatusDeclined:  {ActorTrainer},
+		StatusCancelled: {ActorClient, ActorTrainer},
+	},
+	StatusConfirmed: {
+		StatusCancelled: {ActorClient, ActorTrainer},
+		StatusCompleted: {ActorTrainer},
+		StatusNoShow:    {ActorTrainer},
+	},
+}
+
+// IsTerminal сообщает, что из состояния s переходов нет
+func (s Status) IsTerminal() bool {
+	return len(transitions[s]) == 0
+}
+
+// IsActive сообщает, что занятие в состоянии s занимает время тренера
+func (s Status) IsActive() bool {
+	return s == StatusRequested || s == StatusConfirmed
+}
+
+// CheckTransition проверяет, может ли actor перевести бронирование в состояние to в момент now
+func (b *Booking) CheckTransition(to Status, actor Actor, now time.Time) error {
+	allowed, ok := transitions[b.Status][to]
+	if !ok {
+		return fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, b.Status, to)
+	}
+
+	permitted := actor == ActorAdmin
+	for _, a := range allowed {
+		if a == actor {
+			permitted = true
+		}
+	}
+	if !permitted {
+		return fmt.Errorf("%w: %s cannot move booking to %s", ErrActorNotAllowed, actor, to)
+	}
+
+	// Отметить занятие проведенным или неявку можно только после его начала
+	if (to == StatusCompleted || to == StatusNoShow) && now.Before(b.StartsAt) {
+		return ErrTooEarly
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package booking_test
+
+import (
+	"TrainerConnect/internal/booking"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestCheckTransition(t *testing.T) {
+	now := time.Now()
+	upcoming := func(status booking.Status) *booking.Booking {
+		return &booking.Booking{Status: status, StartsAt: now.Add(time.Hour), EndsAt: now.Add(2 * time.Hour)}
+	}
+	past := func(status booking.Status) *booking.Booking {
+		return &booking.Booking{Status: status, StartsAt: now.Add(-2 * time.Hour), EndsAt: now.Add(-time.Hour)}
+	}
+
+	// Тренер подтверждает или отклоняет запрос, клиент не может подтвердить его сам
+	assert.NoError(t, upcoming(booking.StatusRequested).CheckTransition(booking.StatusConfirmed, booking.ActorTrainer, now))
+	assert.NoError(t, upcoming(booking.StatusRequested).CheckTransition(booking.StatusDeclined, booking.ActorTrainer, now))
+	assert.ErrorIs(t, upcoming(booking.StatusRequested).CheckTransition(booking.StatusConfirmed, booking.ActorClient, now),
+		booking.ErrActorNotAllowed)
+
+	// Отменить может любая сторона
+	assert.NoError(t, upcoming(booking.StatusConfirmed).CheckTransition(booking.StatusCancelled, booking.ActorClient, now))
+	assert.NoError(t, upcoming(booking.StatusConfirmed).CheckTransition(booking.StatusCancelled, booking.ActorTrainer, now))
+
+	// Проведенное занятие отменить нельзя
+	assert.ErrorIs(t, past(booking.StatusCompleted).CheckTransition(booking.StatusCancelled, booking.ActorClient, now),
+		booking.ErrInvalidTransition)
+	assert.ErrorIs(t, past(booking.StatusCompleted).CheckTransition(booking.StatusCancelled, booking.ActorAdmin, now),
+		booking.ErrInvalidTransition)
+
+	// Отметить проведенным можно только начавшееся занятие
+	assert.ErrorIs(t, upcoming(booking.StatusConfirmed).CheckTransition(booking.StatusCompleted, booking.ActorTrainer, now),
+		booking.ErrTooEarly)
+	assert.NoError(t, past(booking.StatusConfirmed).CheckTransition(booking.StatusCompleted, booking.ActorTrainer, now))
+	assert.NoError(t, past(booking.StatusConfirmed).CheckTransition(booking.StatusNoShow, booking.ActorAdmin, now))
+
+	// Неподтвержденное занятие нельзя отметить проведенным
+	assert.ErrorIs(t, past(booking.StatusRequested).CheckTransition(booking.StatusCompleted, booking.ActorTrainer, now),
+		booking.ErrInvalidTransition)
+}
+
+func TestStatusTerminal(t *testing.T) {
+	for _, s := range []booking.Status{booking.StatusDeclined, booking.StatusCancelled, booking.StatusCompleted, booking.StatusNoShow} {
+		assert.True(t, s.IsTerminal(), s)
+		assert.False(t, s.IsActive(), s)
+	}
+	assert.False(t, booking.StatusRequested.IsTerminal())
+	assert.True(t, booking.StatusConfirmed.IsActive())
+}
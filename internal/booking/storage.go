@@ -0,0 +1,527 @@
+package booking
+
+import (
+	"TrainerConnect/internal/apperr"
+	"TrainerConnect/internal/availability"
+	postgres "TrainerConnect/pkg/postgresql"
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrNotFound        = apperr.NotFound("booking_not_found", "booking not found")
+	ErrConflict        = apperr.Conflict("slot_taken", "time slot is already booked")
+	ErrUnavailable     = apperr.Conflict("trainer_unavailable", "trainer is not available at this time")
+	ErrNotParticipant  = apperr.Forbidden("not_participant", "user is not a participant of the booking")
+	ErrTrainerNotFound = apperr.NotFound("trainer_not_found", "trainer profile not found")
+)
+
+// Пространство ключей advisory-блокировок для расписания пользователей
+const scheduleLockSpace = 1001
+
+// Ledger резервирует и списывает кредиты клиента за занятия в той же транзакции,
+// что и переход бронирования
+type Ledger interface {
+	// Hold резервирует кредит под подтвержденное занятие
+	Hold(ctx context.Context, tx *sql.Tx, b *Booking) error
+	// Settle закрывает резерв занятия, перешедшего в конечное состояние.
+	// forfeit сообщает, что при отмене кредит удерживается по решению политики.
+	Settle(ctx context.Context, tx *sql.Tx, b *Booking, forfeit bool) error
+}
+
+type Storage struct {
+	*sql.DB
+	// Ledger необязателен: без него занятия оплачиваются только по ставке тренера
+	Ledger Ledger
+}
+
+func NewStorage(db *sql.DB) *Storage {
+	return &Storage{DB: db}
+}
+
+const bookingColumns = `id, trainer_id, client_id, starts_at, ends_at, status, note,
+	price, currency, cancellation_fee, reschedule_count, created_at, updated_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanBooking(row rowScanner) (*Booking, error) {
+	b := &Booking{}
+	err := row.Scan(&b.ID, &b.TrainerID, &b.ClientID, &b.StartsAt, &b.EndsAt, &b.Status, &b.Note,
+		&b.Price, &b.Currency, &b.CancellationFee, &b.RescheduleCount, &b.CreatedAt, &b.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return b, nil
+}
+
+// lockSchedules сериализует изменения расписаний тренера и клиента до конца транзакции.
+// Блокировки берутся в порядке возрастания ID, чтобы избежать взаимных блокировок.
+func lockSchedules(ctx context.Context, tx *sql.Tx, userIDs ...string) error {
+	ids := make([]int, 0, len(userIDs))
+	for _, id := range userIDs {
+		n, err := strconv.Atoi(id)
+		if err != nil {
+			return err
+		}
+		ids = append(ids, n)
+	}
+	if len(ids) == 2 && ids[0] > ids[1] {
+		ids[0], ids[1] = ids[1], ids[0]
+	}
+
+	for _, id := range ids {
+		if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1, $2)", scheduleLockSpace, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Create создает запрос на занятие. Если у тренера или клиента уже есть активное занятие,
+// пересекающееся по времени, возвращается ErrConflict.
+func (s *Storage) Create(ctx context.Context, b *Booking) (err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := lockSchedules(ctx, tx, b.TrainerID, b.ClientID); err != nil {
+		return err
+	}
+
+	if err := checkOverlap(ctx, tx, b, ""); err != nil {
+		return err
+	}
+
+	if err := setPrice(ctx, tx, b); err != nil {
+		return err
+	}
+
+	b.Status = StatusRequested
+	err = tx.QueryRowContext(ctx, `INSERT INTO bookings (trainer_id, client_id, starts_at, ends_at, status, note, price, currency)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id, created_at, updated_at`,
+		b.TrainerID, b.ClientID, b.StartsAt, b.EndsAt, b.Status, b.Note, b.Price, b.Currency).
+		Scan(&b.ID, &b.CreatedAt, &b.UpdatedAt)
+	if err != nil {
+		if apperr.IsExclusionViolation(err) {
+			return ErrConflict
+		}
+		return err
+	}
+
+	if err := insertTransition(ctx, tx, Transition{BookingID: b.ID, To: b.Status, ActorID: b.ClientID, Actor: ActorClient}); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// setPrice фиксирует стоимость занятия по текущей ставке тренера пропорционально длительности
+func setPrice(ctx context.Context, tx *sql.Tx, b *Booking) error {
+	var hourlyRate int64
+	err := tx.QueryRowContext(ctx, "SELECT hourly_rate, currency FROM trainer_profiles WHERE user_id = $1", b.TrainerID).
+		Scan(&hourlyRate, &b.Currency)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrTrainerNotFound
+		}
+		return err
+	}
+	b.Price = hourlyRate * int64(b.EndsAt.Sub(b.StartsAt)/time.Minute) / 60
+	return nil
+}
+
+// checkOverlap возвращает ErrConflict, если у тренера или клиента есть другое активное занятие,
+// пересекающееся с b. Занятие с ID exceptID (переносимое) не учитывается.
+func checkOverlap(ctx context.Context, tx *sql.Tx, b *Booking, exceptID string) error {
+	var exists bool
+	err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM bookings
+		WHERE (trainer_id = $1 OR client_id = $2) AND status IN ('requested', 'confirmed')
+			AND starts_at < $4 AND ends_at > $3 AND ($5 = '' OR id::text <> $5))`,
+		b.TrainerID, b.ClientID, b.StartsAt, b.EndsAt, exceptID).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return ErrConflict
+	}
+	return nil
+}
+
+// Get возвращает бронирование по ID
+func (s *Storage) Get(ctx context.Context, id string) (_ *Booking, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	return scanBooking(s.DB.QueryRowContext(ctx, "SELECT "+bookingColumns+" FROM bookings WHERE id = $1", id))
+}
+
+// Transition переводит бронирование в состояние to от имени пользователя actorID
+// и записывает переход в журнал. Допустимость перехода проверяется под блокировкой строки.
+// Отмена выполняется через Cancel, чтобы применить политику тренера.
+func (s *Storage) Transition(ctx context.Context, id string, to Status, actorID string, admin bool, reason string) (_ *Booking, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	b, err := scanBooking(tx.QueryRowContext(ctx, "SELECT "+bookingColumns+" FROM bookings WHERE id = $1 FOR UPDATE", id))
+	if err != nil {
+		return nil, err
+	}
+
+	actor, ok := b.ActorFor(actorID, admin)
+	if !ok {
+		return nil, ErrNotParticipant
+	}
+	if err := b.CheckTransition(to, actor, time.Now()); err != nil {
+		return nil, err
+	}
+
+	if to == StatusCancelled {
+		return nil, fmt.Errorf("%w: use Cancel to cancel a booking", ErrInvalidTransition)
+	}
+
+	from := b.Status
+	err = tx.QueryRowContext(ctx, "UPDATE bookings SET status = $1, updated_at = now() WHERE id = $2 RETURNING updated_at",
+		to, id).Scan(&b.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	b.Status = to
+
+	t := Transition{BookingID: id, From: from, To: to, ActorID: actorID, Actor: actor, Reason: reason}
+	if err := insertTransition(ctx, tx, t); err != nil {
+		return nil, err
+	}
+	if err := s.updateCredits(ctx, tx, b, false); err != nil {
+		return nil, err
+	}
+
+	return b, tx.Commit()
+}
+
+// Cancel отменяет занятие от имени пользователя actorID по правилам политики тренера.
+// Если политика запрещает отмену, возвращается ErrPolicyRefused вместе с решением, объясняющим причину.
+func (s *Storage) Cancel(ctx context.Context, id, actorID string, admin bool, reason string) (_ *Booking, _ *Decision, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	b, err := scanBooking(tx.QueryRowContext(ctx, "SELECT "+bookingColumns+" FROM bookings WHERE id = $1 FOR UPDATE", id))
+	if err != nil {
+		return nil, nil, err
+	}
+	actor, ok := b.ActorFor(actorID, admin)
+	if !ok {
+		return nil, nil, ErrNotParticipant
+	}
+
+	now := time.Now()
+	if err := b.CheckTransition(StatusCancelled, actor, now); err != nil {
+		return nil, nil, err
+	}
+
+	policy, err := getPolicy(ctx, tx, b.TrainerID)
+	if err != nil {
+		return nil, nil, err
+	}
+	decision := policy.EvaluateCancellation(b, actor, now)
+	if !decision.Allowed {
+		return nil, &decision, ErrPolicyRefused.With("decision", decision)
+	}
+
+	from := b.Status
+	err = tx.QueryRowContext(ctx, `UPDATE bookings SET status = $1, cancellation_fee = $2, updated_at = now()
+		WHERE id = $3 RETURNING updated_at`, StatusCancelled, decision.Fee, id).Scan(&b.UpdatedAt)
+	if err != nil {
+		return nil, nil, err
+	}
+	b.Status, b.CancellationFee = StatusCancelled, decision.Fee
+
+	if reason == "" {
+		reason = decision.Reason
+	}
+	t := Transition{BookingID: id, From: from, To: StatusCancelled, ActorID: actorID, Actor: actor, Reason: reason}
+	if err := insertTransition(ctx, tx, t); err != nil {
+		return nil, nil, err
+	}
+	if err := s.updateCredits(ctx, tx, b, decision.ForfeitsCredit); err != nil {
+		return nil, nil, err
+	}
+
+	return b, &decision, tx.Commit()
+}
+
+// Reschedule переносит занятие на новое время по правилам политики тренера.
+// Перенос клиентом подтвержденного занятия требует повторного подтверждения тренером.
+// Если меняется длительность, стоимость пересчитывается по текущей ставке тренера.
+func (s *Storage) Reschedule(ctx context.Context, id, actorID string, admin bool, startsAt, endsAt time.Time) (_ *Booking, _ *Decision, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	b, err := scanBooking(tx.QueryRowContext(ctx, "SELECT "+bookingColumns+" FROM bookings WHERE id = $1 FOR UPDATE", id))
+	if err != nil {
+		return nil, nil, err
+	}
+	actor, ok := b.ActorFor(actorID, admin)
+	if !ok {
+		return nil, nil, ErrNotParticipant
+	}
+
+	policy, err := getPolicy(ctx, tx, b.TrainerID)
+	if err != nil {
+		return nil, nil, err
+	}
+	decision := policy.EvaluateReschedule(b, actor, time.Now())
+	if !decision.Allowed {
+		return nil, &decision, ErrPolicyRefused.With("decision", decision)
+	}
+
+	if err := lockSchedules(ctx, tx, b.TrainerID, b.ClientID); err != nil {
+		return nil, nil, err
+	}
+	previous := *b
+	b.StartsAt, b.EndsAt = startsAt, endsAt
+	if err := checkOverlap(ctx, tx, b, id); err != nil {
+		return nil, nil, err
+	}
+	// Стоимость пересчитывается только при изменении длительности: перенос того же занятия
+	// на другое время сохраняет согласованную цену
+	if b.EndsAt.Sub(b.StartsAt) != previous.EndsAt.Sub(previous.StartsAt) {
+		if err := setPrice(ctx, tx, b); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if actor == ActorClient {
+		b.RescheduleCount++
+		b.Status = StatusRequested
+	}
+	err = tx.QueryRowContext(ctx, `UPDATE bookings SET starts_at = $1, ends_at = $2, status = $3, reschedule_count = $4,
+			price = $5, currency = $6, updated_at = now()
+		WHERE id = $7 RETURNING updated_at`,
+		b.StartsAt, b.EndsAt, b.Status, b.RescheduleCount, b.Price, b.Currency, id).Scan(&b.UpdatedAt)
+	if err != nil {
+		if apperr.IsExclusionViolation(err) {
+			return nil, nil, ErrConflict
+		}
+		return nil, nil, err
+	}
+
+	t := Transition{
+		BookingID: id, From: previous.Status, To: b.Status, ActorID: actorID, Actor: actor,
+		Reason: fmt.Sprintf("rescheduled from %s to %s", previous.StartsAt.Format(time.RFC3339), b.StartsAt.Format(time.RFC3339)),
+	}
+	if err := insertTransition(ctx, tx, t); err != nil {
+		return nil, nil, err
+	}
+
+	return b, &decision, tx.Commit()
+}
+
+// updateCredits резервирует кредит при подтверждении занятия и закрывает резерв,
+// когда занятие переходит в конечное состояние
+func (s *Storage) updateCredits(ctx context.Context, tx *sql.Tx, b *Booking, forfeit bool) error {
+	switch {
+	case s.Ledger == nil:
+		return nil
+	case b.Status == StatusConfirmed:
+		return s.Ledger.Hold(ctx, tx, b)
+	case b.Status.IsTerminal():
+		return s.Ledger.Settle(ctx, tx, b, forfeit)
+	}
+	return nil
+}
+
+func insertTransition(ctx context.Context, tx *sql.Tx, t Transition) error {
+	_, err := tx.ExecContext(ctx, `INSERT INTO booking_transitions (booking_id, from_status, to_status, actor_id, actor, reason)
+		VALUES ($1, NULLIF($2, ''), $3, $4, $5, $6)`,
+		t.BookingID, t.From, t.To, t.ActorID, t.Actor, t.Reason)
+	return err
+}
+
+// ListForUser возвращает занятия, в которых пользователь участвует как тренер или клиент.
+// Пустой status означает занятия в любом состоянии.
+func (s *Storage) ListForUser(ctx context.Context, userID string, status Status) (_ []Booking, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	rows, err := s.DB.QueryContext(ctx, "SELECT "+bookingColumns+` FROM bookings
+		WHERE (trainer_id = $1 OR client_id = $1) AND ($2 = '' OR status = $2)
+		ORDER BY starts_at DESC`, userID, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	bookings := []Booking{}
+	for rows.Next() {
+		b, err := scanBooking(rows)
+		if err != nil {
+			return nil, err
+		}
+		bookings = append(bookings, *b)
+	}
+	return bookings, rows.Err()
+}
+
+// StartingBetween возвращает занятия в состоянии status, начинающиеся в интервале (from, to]
+func (s *Storage) StartingBetween(ctx context.Context, status Status, from, to time.Time) (_ []Booking, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	rows, err := s.DB.QueryContext(ctx, "SELECT "+bookingColumns+` FROM bookings
+		WHERE status = $1 AND starts_at > $2 AND starts_at <= $3
+		ORDER BY starts_at`, status, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	bookings := []Booking{}
+	for rows.Next() {
+		b, err := scanBooking(rows)
+		if err != nil {
+			return nil, err
+		}
+		bookings = append(bookings, *b)
+	}
+	return bookings, rows.Err()
+}
+
+// History возвращает журнал переходов бронирования в хронологическом порядке
+func (s *Storage) History(ctx context.Context, id string) (_ []Transition, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	rows, err := s.DB.QueryContext(ctx, `SELECT booking_id, COALESCE(from_status, ''), to_status, actor_id, actor, reason, created_at
+		FROM booking_transitions WHERE booking_id = $1 ORDER BY id`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []Transition{}
+	for rows.Next() {
+		var t Transition
+		if err := rows.Scan(&t.BookingID, &t.From, &t.To, &t.ActorID, &t.Actor, &t.Reason, &t.At); err != nil {
+			return nil, err
+		}
+		history = append(history, t)
+	}
+	return history, rows.Err()
+}
+
+// BusyIntervals возвращает активные занятия тренера в интервале [from, to).
+// Реализует availability.BusyProvider.
+func (s *Storage) BusyIntervals(ctx context.Context, trainerID string, from, to time.Time) (_ []availability.Interval, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	return s.busyIntervals(ctx, trainerID, from, to, "")
+}
+
+// ExceptBooking возвращает BusyProvider, не учитывающий занятие bookingID (например, при его переносе)
+func (s *Storage) ExceptBooking(bookingID string) availability.BusyProvider {
+	return exceptBooking{storage: s, bookingID: bookingID}
+}
+
+type exceptBooking struct {
+	storage   *Storage
+	bookingID string
+}
+
+func (e exceptBooking) BusyIntervals(ctx context.Context, trainerID string, from, to time.Time) ([]availability.Interval, error) {
+	return e.storage.busyIntervals(ctx, trainerID, from, to, e.bookingID)
+}
+
+func (s *Storage) busyIntervals(ctx context.Context, trainerID string, from, to time.Time, exceptID string) ([]availability.Interval, error) {
+	rows, err := s.DB.QueryContext(ctx, `SELECT starts_at, ends_at FROM bookings
+		WHERE trainer_id = $1 AND status IN ('requested', 'confirmed') AND starts_at < $3 AND ends_at > $2
+			AND ($4 = '' OR id::text <> $4)
+		ORDER BY starts_at`, trainerID, from, to, exceptID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var intervals []availability.Interval
+	for rows.Next() {
+		var i availability.Interval
+		if err := rows.Scan(&i.Start, &i.End); err != nil {
+			return nil, err
+		}
+		intervals = append(intervals, i)
+	}
+	return intervals, rows.Err()
+}
+
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+func getPolicy(ctx context.Context, q queryRower, trainerID string) (Policy, error) {
+	p := Policy{TrainerID: trainerID}
+	err := q.QueryRowContext(ctx, `SELECT free_cancellation_hours, late_cancellation_fee_percent, max_reschedules, min_reschedule_notice_hours
+		FROM booking_policies WHERE trainer_id = $1`, trainerID).
+		Scan(&p.FreeCancellationHours, &p.LateCancellationFeePercent, &p.MaxReschedules, &p.MinRescheduleNoticeHours)
+	if err == sql.ErrNoRows {
+		return DefaultPolicy(trainerID), nil
+	}
+	return p, err
+}
+
+// GetPolicy возвращает политику отмены и переноса тренера или политику по умолчанию
+func (s *Storage) GetPolicy(ctx context.Context, trainerID string) (_ Policy, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	return getPolicy(ctx, s.DB, trainerID)
+}
+
+// SavePolicy сохраняет политику отмены и переноса тренера
+func (s *Storage) SavePolicy(ctx context.Context, p Policy) (err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	_, err = s.DB.ExecContext(ctx, `INSERT INTO booking_policies (trainer_id, free_cancellation_hours, late_cancellation_fee_percent,
+			max_reschedules, min_reschedule_notice_hours)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (trainer_id) DO UPDATE SET free_cancellation_hours = EXCLUDED.free_cancellation_hours,
+			late_cancellation_fee_percent = EXCLUDED.late_cancellation_fee_percent,
+			max_reschedules = EXCLUDED.max_reschedules, min_reschedule_notice_hours = EXCLUDED.min_reschedule_notice_hours,
+			updated_at = now()`,
+		p.TrainerID, p.FreeCancellationHours, p.LateCancellationFeePercent, p.MaxReschedules, p.MinRescheduleNoticeHours)
+	if apperr.IsForeignKeyViolation(err) {
+		return ErrTrainerNotFound
+	}
+	return err
+}
@@ -0,0 +1,266 @@
+package conversation
+
+import (
+	"TrainerConnect/internal/apperr"
+	"TrainerConnect/internal/auth"
+	"TrainerConnect/internal/events"
+	"TrainerConnect/internal/notify"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+const conversationURL = "/conversations/"
+
+type Handler struct {
+	Storage *Storage
+	Roster  auth.Roster
+	Events  events.Publisher
+	Notify  notify.Sender
+}
+
+// NewHandler создает обработчик переписки. Начать переписку можно только с тренером
+// или клиентом из roster, читать и писать в нее — только ее участникам.
+// Новые сообщения и отметки о прочтении доставляются участникам через publisher,
+// о новом сообщении собеседник дополнительно получает уведомление.
+func NewHandler(storage *Storage, roster auth.Roster, publisher events.Publisher, sender notify.Sender) *Handler {
+	return &Handler{Storage: storage, Roster: roster, Events: publisher, Notify: sender}
+}
+
+func (h *Handler) Register(router *chi.Mux) {
+	router.Group(func(r chi.Router) {
+		r.Use(auth.RequireAuth)
+		r.Get("/conversations", h.Inbox)
+		r.Post("/conversations", h.OpenConversation)
+		r.Get(conversationURL+"{id}/messages", h.ListMessages)
+		r.Post(conversationURL+"{id}/messages", h.SendMessage)
+		r.Post(conversationURL+"{id}/read", h.MarkRead)
+	})
+}
+
+// Inbox возвращает переписки текущего пользователя с последним сообщением
+func (h *Handler) Inbox(w http.ResponseWriter, r *http.Request) {
+	principal, _ := auth.PrincipalFromContext(r.Context())
+
+	conversations, err := h.Storage.Inbox(r.Context(), principal.UserID)
+	if err != nil {
+		log.Printf("Error listing conversations of user %s: %v", principal.UserID, err)
+		apperr.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(conversations)
+}
+
+type openRequest struct {
+	ParticipantID string `json:"participant_id"`
+}
+
+// OpenConversation возвращает переписку с собеседником, создавая ее при необходимости.
+// Тренер пишет своему клиенту, клиент — своему тренеру.
+func (h *Handler) OpenConversation(w http.ResponseWriter, r *http.Request) {
+	principal, _ := auth.PrincipalFromContext(r.Context())
+
+	var req openRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apperr.Respond(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if _, err := strconv.Atoi(req.ParticipantID); err != nil {
+		apperr.Respond(w, http.StatusBadRequest, "Invalid participant ID")
+		return
+	}
+
+	var trainerID, clientID string
+	switch principal.Role {
+	case auth.RoleTrainer:
+		trainerID, clientID = principal.UserID, req.ParticipantID
+	case auth.RoleClient:
+		trainerID, clientID = req.ParticipantID, principal.UserID
+	default:
+		apperr.Respond(w, http.StatusForbidden, "Only trainers and clients can start conversations")
+		return
+	}
+
+	ok, err := h.Roster.HasClient(r.Context(), trainerID, clientID)
+	if err != nil {
+		log.Printf("Error checking roster of trainer %s: %v", trainerID, err)
+		apperr.Write(w, err)
+		return
+	}
+	if !ok {
+		apperr.Write(w, ErrNotOnRoster)
+		return
+	}
+
+	c, err := h.Storage.Open(r.Context(), trainerID, clientID)
+	if err != nil {
+		log.Printf("Error opening conversation: %v", err)
+		apperr.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(c)
+}
+
+// load загружает переписку по ID из URL и проверяет, что текущий пользователь — ее участник
+func (h *Handler) load(w http.ResponseWriter, r *http.Request) (*Conversation, bool) {
+	id := chi.URLParam(r, "id")
+	if _, err := strconv.Atoi(id); err != nil {
+		apperr.Respond(w, http.StatusBadRequest, "Invalid conversation ID")
+		return nil, false
+	}
+
+	c, err := h.Storage.Get(r.Context(), id)
+	if err != nil {
+		if !apperr.Expected(err) {
+			log.Printf("Error getting conversation %s: %v", id, err)
+		}
+		apperr.Write(w, err)
+		return nil, false
+	}
+
+	// Чужая переписка неотличима от несуществующей, даже для администратора
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	if !c.HasParticipant(principal.UserID) {
+		apperr.Write(w, ErrNotFound)
+		return nil, false
+	}
+	return c, true
+}
+
+// ListMessages возвращает страницу истории. Параметры: before — ID сообщения из предыдущей
+// страницы, limit — размер страницы (по умолчанию 50, не больше 100).
+func (h *Handler) ListMessages(w http.ResponseWriter, r *http.Request) {
+	c, ok := h.load(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	before := q.Get("before")
+	if before != "" {
+		if _, err := strconv.Atoi(before); err != nil {
+			apperr.Respond(w, http.StatusBadRequest, "Invalid before parameter")
+			return
+		}
+	}
+	limit := 0
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			apperr.Respond(w, http.StatusBadRequest, "Invalid limit parameter")
+			return
+		}
+		limit = n
+	}
+
+	page, err := h.Storage.History(r.Context(), c.ID, before, PageSize(limit))
+	if err != nil {
+		log.Printf("Error listing messages of conversation %s: %v", c.ID, err)
+		apperr.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+// SendMessage отправляет сообщение в переписку
+func (h *Handler) SendMessage(w http.ResponseWriter, r *http.Request) {
+	c, ok := h.load(w, r)
+	if !ok {
+		return
+	}
+
+	var m Message
+	if err := json.NewDecoder(r.Body).Decode(&m); err != nil {
+		apperr.Respond(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := m.Validate(); err != nil {
+		apperr.Write(w, err)
+		return
+	}
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	m.ID = ""
+	m.ConversationID = c.ID
+	m.SenderID = principal.UserID
+	m.ReadAt = nil
+
+	if err := h.Storage.AddMessage(r.Context(), &m); err != nil {
+		log.Printf("Error sending message to conversation %s: %v", c.ID, err)
+		apperr.Write(w, err)
+		return
+	}
+	// Отправителю тоже: у него могут быть открыты другие устройства
+	h.Events.Publish("message.created", m, c.TrainerID, c.ClientID)
+
+	recipient := c.TrainerID
+	if recipient == principal.UserID {
+		recipient = c.ClientID
+	}
+	h.Notify.Notify(notify.Notification{
+		UserID:  recipient,
+		Kind:    notify.KindNewMessage,
+		Subject: "New message",
+		Body:    m.Body,
+		Data:    m,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(m)
+}
+
+type readRequest struct {
+	UpTo string `json:"up_to"`
+}
+
+type readResponse struct {
+	ConversationID string   `json:"conversation_id"`
+	ReaderID       string   `json:"reader_id"`
+	MessageIDs     []string `json:"message_ids"`
+}
+
+// MarkRead отмечает сообщения собеседника прочитанными до up_to включительно
+// или все, если up_to не указан
+func (h *Handler) MarkRead(w http.ResponseWriter, r *http.Request) {
+	c, ok := h.load(w, r)
+	if !ok {
+		return
+	}
+
+	var req readRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apperr.Respond(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+	if req.UpTo != "" {
+		if _, err := strconv.Atoi(req.UpTo); err != nil {
+			apperr.Respond(w, http.StatusBadRequest, "Invalid up_to message ID")
+			return
+		}
+	}
+	principal, _ := auth.PrincipalFromContext(r.Context())
+
+	ids, err := h.Storage.MarkRead(r.Context(), c.ID, principal.UserID, req.UpTo)
+	if err != nil {
+		log.Printf("Error marking conversation %s read: %v", c.ID, err)
+		apperr.Write(w, err)
+		return
+	}
+	receipt := readResponse{ConversationID: c.ID, ReaderID: principal.UserID, MessageIDs: ids}
+	if len(ids) > 0 {
+		h.Events.Publish("message.read", receipt, c.TrainerID, c.ClientID)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(receipt)
+}
@@ -0,0 +1,95 @@
+package conversation
+
+import (
+	"TrainerConnect/internal/apperr"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	maxBodyLength   = 4000
+	maxAttachments  = 10
+	defaultPageSize = 50
+	maxPageSize     = 100
+)
+
+// Attachment — ссылка на файл, загруженный во внешнее хранилище. Сами файлы сервис не хранит.
+type Attachment struct {
+	URL         string `json:"url"`
+	Name        string `json:"name"`
+	ContentType string `json:"content_type"`
+	SizeBytes   int64  `json:"size_bytes"`
+}
+
+// Message — сообщение в переписке. ReadAt заполняется, когда его прочитал собеседник.
+type Message struct {
+	ID             string       `json:"id"`
+	ConversationID string       `json:"conversation_id"`
+	SenderID       string       `json:"sender_id"`
+	Body           string       `json:"body"`
+	Attachments    []Attachment `json:"attachments"`
+	CreatedAt      time.Time    `json:"created_at"`
+	ReadAt         *time.Time   `json:"read_at,omitempty"`
+}
+
+// Validate проверяет текст и вложения сообщения. Сообщение без текста допустимо,
+// если в нем есть вложения.
+func (m *Message) Validate() error {
+	m.Body = strings.TrimSpace(m.Body)
+	if m.Attachments == nil {
+		m.Attachments = []Attachment{}
+	}
+	if m.Body == "" && len(m.Attachments) == 0 {
+		return apperr.InvalidField("body", "required", "message must have a body or attachments")
+	}
+	if utf8.RuneCountInString(m.Body) > maxBodyLength {
+		return apperr.InvalidField("body", "too_long", fmt.Sprintf("must be at most %d characters long", maxBodyLength))
+	}
+	if len(m.Attachments) > maxAttachments {
+		return apperr.InvalidField("attachments", "too_many", fmt.Sprintf("must contain at most %d attachments", maxAttachments))
+	}
+	for _, a := range m.Attachments {
+		if !strings.HasPrefix(a.URL, "https://") && !strings.HasPrefix(a.URL, "http://") {
+			return apperr.InvalidField("attachments", "invalid_url", "attachment url must be an http(s) URL")
+		}
+		if a.SizeBytes < 0 {
+			return apperr.InvalidField("attachments", "negative", "attachment size must not be negative")
+		}
+	}
+	return nil
+}
+
+// Conversation — переписка тренера с клиентом. На каждую пару одна переписка.
+type Conversation struct {
+	ID          string    `json:"id"`
+	TrainerID   string    `json:"trainer_id"`
+	ClientID    string    `json:"client_id"`
+	CreatedAt   time.Time `json:"created_at"`
+	LastMessage *Message  `json:"last_message,omitempty"`
+	UnreadCount int       `json:"unread_count"`
+}
+
+// HasParticipant сообщает, участвует ли пользователь в переписке
+func (c *Conversation) HasParticipant(userID string) bool {
+	return c.TrainerID == userID || c.ClientID == userID
+}
+
+// Page — страница истории сообщений от новых к старым. Before передается
+// в следующий запрос, пока страница не станет последней.
+type Page struct {
+	Messages []Message `json:"messages"`
+	Before   string    `json:"before,omitempty"`
+}
+
+// PageSize приводит запрошенный размер страницы к допустимому
+func PageSize(limit int) int {
+	if limit <= 0 {
+		return defaultPageSize
+	}
+	if limit > maxPageSize {
+		return maxPageSize
+	}
+	return limit
+}
@@ -0,0 +1,36 @@
+package conversation_test
+
+import (
+	"TrainerConnect/internal/conversation"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestMessageValidate(t *testing.T) {
+	m := &conversation.Message{Body: "  Привет!  "}
+	assert.NoError(t, m.Validate())
+	assert.Equal(t, "Привет!", m.Body)
+	assert.Equal(t, []conversation.Attachment{}, m.Attachments)
+
+	// Только вложение, без текста
+	m = &conversation.Message{Attachments: []conversation.Attachment{{URL: "https://cdn.example.com/plan.pdf", Name: "plan.pdf"}}}
+	assert.NoError(t, m.Validate())
+
+	assert.Error(t, (&conversation.Message{Body: "   "}).Validate())
+	assert.Error(t, (&conversation.Message{Body: strings.Repeat("я", 4001)}).Validate())
+	assert.Error(t, (&conversation.Message{Attachments: []conversation.Attachment{{URL: "file:///etc/passwd"}}}).Validate())
+}
+
+func TestConversationParticipants(t *testing.T) {
+	c := &conversation.Conversation{TrainerID: "10", ClientID: "2"}
+	assert.True(t, c.HasParticipant("10"))
+	assert.True(t, c.HasParticipant("2"))
+	assert.False(t, c.HasParticipant("1"))
+}
+
+func TestPageSize(t *testing.T) {
+	assert.Equal(t, 50, conversation.PageSize(0))
+	assert.Equal(t, 20, conversation.PageSize(20))
+	assert.Equal(t, 100, conversation.PageSize(1000))
+}
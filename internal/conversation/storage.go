@@ -0,0 +1,181 @@
+package conversation
+
+import (
+	"TrainerConnect/internal/apperr"
+	postgres "TrainerConnect/pkg/postgresql"
+	"context"
+	"database/sql"
+	"encoding/json"
+)
+
+var (
+	ErrNotFound = apperr.NotFound("conversation_not_found", "conversation not found")
+	// ErrNotOnRoster возвращается, если переписку открывают с клиентом, который не тренируется у тренера
+	ErrNotOnRoster = apperr.Invalid("not_on_roster", "client is not on the trainer's roster")
+)
+
+type Storage struct {
+	*sql.DB
+}
+
+func NewStorage(db *sql.DB) *Storage {
+	return &Storage{DB: db}
+}
+
+// Open возвращает переписку тренера с клиентом, создавая ее при первом обращении
+func (s *Storage) Open(ctx context.Context, trainerID, clientID string) (_ *Conversation, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	c := &Conversation{TrainerID: trainerID, ClientID: clientID}
+	err = s.DB.QueryRowContext(ctx, `INSERT INTO conversations (trainer_id, client_id) VALUES ($1, $2)
+		ON CONFLICT (trainer_id, client_id) DO UPDATE SET trainer_id = EXCLUDED.trainer_id
+		RETURNING id, created_at`, trainerID, clientID).Scan(&c.ID, &c.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Get возвращает переписку без сообщений
+func (s *Storage) Get(ctx context.Context, id string) (_ *Conversation, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	c := &Conversation{}
+	err = s.DB.QueryRowContext(ctx, "SELECT id, trainer_id, client_id, created_at FROM conversations WHERE id = $1", id).
+		Scan(&c.ID, &c.TrainerID, &c.ClientID, &c.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return c, nil
+}
+
+// Inbox возвращает переписки пользователя с последним сообщением и числом непрочитанных,
+// отсортированные по времени последнего сообщения. Выполняется одним запросом:
+// последнее сообщение каждой переписки берется через LATERAL по индексу (conversation_id, id).
+func (s *Storage) Inbox(ctx context.Context, userID string) (_ []Conversation, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	rows, err := s.DB.QueryContext(ctx, `SELECT c.id, c.trainer_id, c.client_id, c.created_at,
+			m.id, m.sender_id, m.body, m.attachments, m.created_at, m.read_at,
+			(SELECT COUNT(*) FROM messages u
+				WHERE u.conversation_id = c.id AND u.sender_id <> $1 AND u.read_at IS NULL)
+		FROM conversations c
+		LEFT JOIN LATERAL (
+			SELECT id, sender_id, body, attachments, created_at, read_at FROM messages
+			WHERE conversation_id = c.id ORDER BY id DESC LIMIT 1
+		) m ON true
+		WHERE c.trainer_id = $1 OR c.client_id = $1
+		ORDER BY COALESCE(m.created_at, c.created_at) DESC, c.id DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	conversations := []Conversation{}
+	for rows.Next() {
+		var c Conversation
+		var id, senderID, body sql.NullString
+		var attachments []byte
+		var createdAt sql.NullTime
+		var m Message
+		err := rows.Scan(&c.ID, &c.TrainerID, &c.ClientID, &c.CreatedAt,
+			&id, &senderID, &body, &attachments, &createdAt, &m.ReadAt, &c.UnreadCount)
+		if err != nil {
+			return nil, err
+		}
+		// Переписка без сообщений приходит с NULL во всех полях сообщения
+		if id.Valid {
+			m.ID, m.ConversationID, m.SenderID, m.Body = id.String, c.ID, senderID.String, body.String
+			m.CreatedAt = createdAt.Time
+			if err := json.Unmarshal(attachments, &m.Attachments); err != nil {
+				return nil, err
+			}
+			c.LastMessage = &m
+		}
+		conversations = append(conversations, c)
+	}
+	return conversations, rows.Err()
+}
+
+// AddMessage сохраняет сообщение
+func (s *Storage) AddMessage(ctx context.Context, m *Message) (err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	attachments, err := json.Marshal(m.Attachments)
+	if err != nil {
+		return err
+	}
+	return s.DB.QueryRowContext(ctx, `INSERT INTO messages (conversation_id, sender_id, body, attachments)
+		VALUES ($1, $2, $3, $4) RETURNING id, created_at`,
+		m.ConversationID, m.SenderID, m.Body, attachments).Scan(&m.ID, &m.CreatedAt)
+}
+
+// History возвращает страницу сообщений переписки от новых к старым. Если before не пуст,
+// возвращаются сообщения старше сообщения с этим ID.
+func (s *Storage) History(ctx context.Context, conversationID, before string, limit int) (_ *Page, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	rows, err := s.DB.QueryContext(ctx, `SELECT id, sender_id, body, attachments, created_at, read_at FROM messages
+		WHERE conversation_id = $1 AND ($2 = '' OR id < $2::bigint)
+		ORDER BY id DESC LIMIT $3`, conversationID, before, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	page := &Page{Messages: []Message{}}
+	for rows.Next() {
+		m := Message{ConversationID: conversationID}
+		var attachments []byte
+		if err := rows.Scan(&m.ID, &m.SenderID, &m.Body, &attachments, &m.CreatedAt, &m.ReadAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(attachments, &m.Attachments); err != nil {
+			return nil, err
+		}
+		page.Messages = append(page.Messages, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Лишняя строка означает, что есть более старые сообщения
+	if len(page.Messages) > limit {
+		page.Messages = page.Messages[:limit]
+		page.Before = page.Messages[limit-1].ID
+	}
+	return page, nil
+}
+
+// MarkRead отмечает прочитанными сообщения собеседника вплоть до upTo включительно
+// (все, если upTo пуст) и возвращает ID отмеченных сообщений
+func (s *Storage) MarkRead(ctx context.Context, conversationID, readerID, upTo string) (_ []string, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	rows, err := s.DB.QueryContext(ctx, `UPDATE messages SET read_at = now()
+		WHERE conversation_id = $1 AND sender_id <> $2 AND read_at IS NULL AND ($3 = '' OR id <= $3::bigint)
+		RETURNING id`, conversationID, readerID, upTo)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
@@ -0,0 +1,200 @@
+package credit_test
+
+import (
+	"TrainerConnect/internal/auth"
+	"TrainerConnect/internal/booking"
+	"TrainerConnect/internal/credit"
+	postgres "TrainerConnect/pkg/postgresql"
+	"TrainerConnect/pkg/postgresql/config"
+	"context"
+	"database/sql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"log"
+	"math/rand"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+)
+
+var db *sql.DB
+
+func TestMain(m *testing.M) {
+	// Тесты работают с тестовой БД и пропускаются, если она недоступна
+	cfg, err := config.ReadConfig("../../pkg/postgresql/config/database_test.json")
+	if err == nil {
+		db, err = postgres.NewDB(cfg)
+	}
+	if err == nil {
+		var migrator *postgres.Migrator
+		if migrator, err = postgres.NewMigrator(db); err == nil {
+			_, err = migrator.Up(context.Background())
+		}
+	}
+	if err != nil {
+		log.Printf("Test database is unavailable, skipping database tests: %v", err)
+		db = nil
+	}
+
+	exitCode := m.Run()
+	if db != nil {
+		db.Close()
+	}
+	os.Exit(exitCode)
+}
+
+func requireDB(t *testing.T) {
+	if db == nil {
+		t.Skip("test database is unavailable")
+	}
+}
+
+// ID пользователей берутся из диапазона, которого не касаются другие тесты с той же БД
+var nextUserID = 920000000 + rand.New(rand.NewSource(time.Now().UnixNano())).Intn(1000000)*100
+
+// newUser создает пользователя с ролью role. Журнал кредитов нельзя изменять, поэтому
+// пользователи, их начисления и занятия остаются в тестовой БД.
+func newUser(t *testing.T, role string) string {
+	nextUserID++
+	id := strconv.Itoa(nextUserID)
+	_, err := db.Exec(`INSERT INTO users (user_id, username, password, salt, role, email)
+		VALUES ($1, $2, 'hash', 'salt', $3, $4)`, id, "credit"+id, role, "credit"+id+"@example.com")
+	require.NoError(t, err)
+	return id
+}
+
+// newPair создает тренера с профилем и клиента, которому начислено sessions кредитов
+// со сроком действия validityDays дней
+func newPair(t *testing.T, storage *credit.Storage, sessions, validityDays int) (trainer, client string, grant *credit.Grant) {
+	ctx := context.Background()
+	trainer, client = newUser(t, auth.RoleTrainer), newUser(t, auth.RoleClient)
+	_, err := db.Exec("INSERT INTO trainer_profiles (user_id, hourly_rate, currency) VALUES ($1, 6000, 'EUR')", trainer)
+	require.NoError(t, err)
+
+	kind := credit.KindPack
+	if validityDays > 0 {
+		kind = credit.KindSubscription
+	}
+	p := &credit.Product{TrainerID: trainer, Kind: kind, Name: "Pack", Sessions: sessions, Price: 50000,
+		Currency: "EUR", ValidityDays: validityDays, Active: true}
+	require.NoError(t, storage.CreateProduct(ctx, p))
+	grant, err = storage.Grant(ctx, client, p.ID, trainer, "")
+	require.NoError(t, err)
+	return trainer, client, grant
+}
+
+// requireBalance проверяет доступные и зарезервированные кредиты клиента у тренера
+func requireBalance(t *testing.T, storage *credit.Storage, client, trainer string, available, held int) {
+	b, err := storage.Balance(context.Background(), client, trainer, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, available, b.Available, "available")
+	assert.Equal(t, held, b.Held, "held")
+}
+
+// entryKinds возвращает виды записей журнала по занятию bookingID в хронологическом порядке
+func entryKinds(t *testing.T, storage *credit.Storage, client, trainer, bookingID string) []credit.EntryKind {
+	entries, err := storage.Entries(context.Background(), client, trainer)
+	require.NoError(t, err)
+	var kinds []credit.EntryKind
+	for _, e := range entries {
+		if e.BookingID == bookingID {
+			kinds = append(kinds, e.Kind)
+		}
+	}
+	return kinds
+}
+
+func TestLedgerBookingLifecycle(t *testing.T) {
+	requireDB(t)
+	ctx := context.Background()
+	credits := credit.NewStorage(db)
+	bookings := booking.NewStorage(db)
+	bookings.Ledger = credits
+	trainer, client, _ := newPair(t, credits, 5, 0)
+	requireBalance(t, credits, client, trainer, 5, 0)
+
+	// Подтверждение резервирует кредит, проведенное занятие его списывает
+	startsAt := time.Now().UTC().Add(-2 * time.Hour)
+	done := &booking.Booking{TrainerID: trainer, ClientID: client, StartsAt: startsAt, EndsAt: startsAt.Add(time.Hour)}
+	require.NoError(t, bookings.Create(ctx, done))
+	_, err := bookings.Transition(ctx, done.ID, booking.StatusConfirmed, trainer, false, "")
+	require.NoError(t, err)
+	requireBalance(t, credits, client, trainer, 4, 1)
+
+	_, err = bookings.Transition(ctx, done.ID, booking.StatusCompleted, trainer, false, "")
+	require.NoError(t, err)
+	requireBalance(t, credits, client, trainer, 4, 0)
+	assert.Equal(t, []credit.EntryKind{credit.EntryHold, credit.EntryRelease, credit.EntrySession},
+		entryKinds(t, credits, client, trainer, done.ID))
+
+	// Отмена клиентом задолго до начала возвращает зарезервированный кредит
+	startsAt = time.Now().UTC().Add(7 * 24 * time.Hour).Truncate(time.Hour)
+	cancelled := &booking.Booking{TrainerID: trainer, ClientID: client, StartsAt: startsAt, EndsAt: startsAt.Add(time.Hour)}
+	require.NoError(t, bookings.Create(ctx, cancelled))
+	_, err = bookings.Transition(ctx, cancelled.ID, booking.StatusConfirmed, trainer, false, "")
+	require.NoError(t, err)
+	requireBalance(t, credits, client, trainer, 3, 1)
+
+	_, decision, err := bookings.Cancel(ctx, cancelled.ID, client, false, "")
+	require.NoError(t, err)
+	assert.False(t, decision.ForfeitsCredit)
+	requireBalance(t, credits, client, trainer, 4, 0)
+	assert.Equal(t, []credit.EntryKind{credit.EntryHold, credit.EntryRefund},
+		entryKinds(t, credits, client, trainer, cancelled.ID))
+}
+
+func TestLedgerExpire(t *testing.T) {
+	requireDB(t)
+	ctx := context.Background()
+	credits := credit.NewStorage(db)
+	bookings := booking.NewStorage(db)
+	bookings.Ledger = credits
+	trainer, client, grant := newPair(t, credits, 4, 30)
+
+	// Зарезервированный кредит остается за занятием и по сроку не списывается
+	startsAt := time.Now().UTC().Add(7 * 24 * time.Hour).Truncate(time.Hour)
+	b := &booking.Booking{TrainerID: trainer, ClientID: client, StartsAt: startsAt, EndsAt: startsAt.Add(time.Hour)}
+	require.NoError(t, bookings.Create(ctx, b))
+	_, err := bookings.Transition(ctx, b.ID, booking.StatusConfirmed, trainer, false, "")
+	require.NoError(t, err)
+
+	// До истечения срока списывать нечего
+	_, err = credits.Expire(ctx, time.Now())
+	require.NoError(t, err)
+	requireBalance(t, credits, client, trainer, 3, 1)
+
+	expired, err := credits.Expire(ctx, grant.ExpiresAt.Add(time.Minute))
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, expired, 1)
+
+	entries, err := credits.Entries(ctx, client, trainer)
+	require.NoError(t, err)
+	last := entries[len(entries)-1]
+	assert.Equal(t, credit.EntryExpiry, last.Kind)
+	assert.Equal(t, -3, last.Amount)
+
+	// Повторный проход ничего не записывает
+	_, err = credits.Expire(ctx, grant.ExpiresAt.Add(time.Minute))
+	require.NoError(t, err)
+	again, err := credits.Entries(ctx, client, trainer)
+	require.NoError(t, err)
+	assert.Len(t, again, len(entries))
+}
+
+func TestLedgerAppendOnly(t *testing.T) {
+	requireDB(t)
+	credits := credit.NewStorage(db)
+	_, _, grant := newPair(t, credits, 2, 0)
+
+	_, err := db.Exec("UPDATE credit_entries SET amount = 100 WHERE grant_id = $1", grant.ID)
+	assert.ErrorContains(t, err, "append-only")
+	_, err = db.Exec("DELETE FROM credit_entries WHERE grant_id = $1", grant.ID)
+	assert.ErrorContains(t, err, "append-only")
+	_, err = db.Exec("UPDATE credit_grants SET quantity = 100 WHERE id = $1", grant.ID)
+	assert.ErrorContains(t, err, "append-only")
+
+	var remaining int
+	require.NoError(t, db.QueryRow("SELECT SUM(amount) FROM credit_entries WHERE grant_id = $1", grant.ID).Scan(&remaining))
+	assert.Equal(t, 2, remaining)
+}
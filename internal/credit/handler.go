@@ -0,0 +1,279 @@
+package credit
+
+import (
+	"TrainerConnect/internal/apperr"
+	"TrainerConnect/internal/auth"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	productsURL = "/trainers/{id}/products"
+	productURL  = "/products/"
+	creditsURL  = "/trainers/{id}/clients/{clientID}/credits"
+)
+
+type Handler struct {
+	Storage *Storage
+}
+
+func NewHandler(storage *Storage) *Handler {
+	return &Handler{Storage: storage}
+}
+
+func (h *Handler) Register(router *chi.Mux) {
+	// Каталог тренера публичен, как и его профиль
+	router.Get(productsURL, h.ListProducts)
+	router.Get(productURL+"{id}", h.GetProduct)
+
+	router.Group(func(r chi.Router) {
+		r.Use(auth.RequireAuth)
+		r.Post(productsURL, h.CreateProduct)
+		r.Put(productURL+"{id}", h.UpdateProduct)
+		r.Get(creditsURL, h.GetBalance)
+		r.Post(creditsURL, h.GrantCredits)
+		r.Get(creditsURL+"/ledger", h.ListEntries)
+	})
+}
+
+// canManage — каталогом и продажами тренера управляет он сам или администратор
+func canManage(r *http.Request, trainerID string) bool {
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	return ok && (principal.UserID == trainerID || principal.IsAdmin())
+}
+
+// ListProducts возвращает продукты тренера в продаже. Тренер и администратор
+// могут запросить и снятые с продажи параметром all=true.
+func (h *Handler) ListProducts(w http.ResponseWriter, r *http.Request) {
+	trainerID := chi.URLParam(r, "id")
+	if _, err := strconv.Atoi(trainerID); err != nil {
+		apperr.Respond(w, http.StatusBadRequest, "Invalid trainer ID")
+		return
+	}
+	includeInactive := r.URL.Query().Get("all") == "true" && canManage(r, trainerID)
+
+	products, err := h.Storage.ListProducts(r.Context(), trainerID, includeInactive)
+	if err != nil {
+		log.Printf("Error listing products of trainer %s: %v", trainerID, err)
+		apperr.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(products)
+}
+
+// CreateProduct добавляет продукт в каталог тренера
+func (h *Handler) CreateProduct(w http.ResponseWriter, r *http.Request) {
+	trainerID := chi.URLParam(r, "id")
+	if _, err := strconv.Atoi(trainerID); err != nil {
+		apperr.Respond(w, http.StatusBadRequest, "Invalid trainer ID")
+		return
+	}
+	if !canManage(r, trainerID) {
+		apperr.Respond(w, http.StatusForbidden, "Forbidden")
+		return
+	}
+
+	p := Product{Active: true}
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		apperr.Respond(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	p.TrainerID = trainerID
+	if err := p.Validate(); err != nil {
+		apperr.Write(w, err)
+		return
+	}
+
+	if err := h.Storage.CreateProduct(r.Context(), &p); err != nil {
+		if !apperr.Expected(err) {
+			log.Printf("Error creating product of trainer %s: %v", trainerID, err)
+		}
+		apperr.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(p)
+}
+
+// loadProduct загружает продукт по ID из URL и при ошибке сам отправляет ответ
+func (h *Handler) loadProduct(w http.ResponseWriter, r *http.Request) (*Product, bool) {
+	id := chi.URLParam(r, "id")
+	if _, err := strconv.Atoi(id); err != nil {
+		apperr.Respond(w, http.StatusBadRequest, "Invalid product ID")
+		return nil, false
+	}
+
+	p, err := h.Storage.GetProduct(r.Context(), id)
+	if err != nil {
+		if !apperr.Expected(err) {
+			log.Printf("Error getting product %s: %v", id, err)
+		}
+		apperr.Write(w, err)
+		return nil, false
+	}
+	return p, true
+}
+
+// GetProduct возвращает продукт
+func (h *Handler) GetProduct(w http.ResponseWriter, r *http.Request) {
+	p, ok := h.loadProduct(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(p)
+}
+
+// UpdateProduct изменяет продукт; снять его с продажи можно, передав active=false
+func (h *Handler) UpdateProduct(w http.ResponseWriter, r *http.Request) {
+	p, ok := h.loadProduct(w, r)
+	if !ok {
+		return
+	}
+	if !canManage(r, p.TrainerID) {
+		apperr.Respond(w, http.StatusForbidden, "Forbidden")
+		return
+	}
+
+	updated := Product{Active: true}
+	if err := json.NewDecoder(r.Body).Decode(&updated); err != nil {
+		apperr.Respond(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	updated.ID, updated.TrainerID = p.ID, p.TrainerID
+	if err := updated.Validate(); err != nil {
+		apperr.Write(w, err)
+		return
+	}
+
+	if err := h.Storage.UpdateProduct(r.Context(), &updated); err != nil {
+		if !apperr.Expected(err) {
+			log.Printf("Error updating product %s: %v", p.ID, err)
+		}
+		apperr.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+// pairIDs извлекает ID тренера и клиента из URL и проверяет, что текущий пользователь —
+// один из них или администратор
+func pairIDs(w http.ResponseWriter, r *http.Request) (string, string, bool) {
+	trainerID, clientID := chi.URLParam(r, "id"), chi.URLParam(r, "clientID")
+	if _, err := strconv.Atoi(trainerID); err != nil {
+		apperr.Respond(w, http.StatusBadRequest, "Invalid trainer ID")
+		return "", "", false
+	}
+	if _, err := strconv.Atoi(clientID); err != nil {
+		apperr.Respond(w, http.StatusBadRequest, "Invalid client ID")
+		return "", "", false
+	}
+
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	if principal.UserID != trainerID && principal.UserID != clientID && !principal.IsAdmin() {
+		apperr.Respond(w, http.StatusForbidden, "Forbidden")
+		return "", "", false
+	}
+	return trainerID, clientID, true
+}
+
+// GetBalance возвращает доступные и зарезервированные кредиты клиента у тренера
+func (h *Handler) GetBalance(w http.ResponseWriter, r *http.Request) {
+	trainerID, clientID, ok := pairIDs(w, r)
+	if !ok {
+		return
+	}
+
+	balance, err := h.Storage.Balance(r.Context(), clientID, trainerID, time.Now())
+	if err != nil {
+		log.Printf("Error getting credits of client %s at trainer %s: %v", clientID, trainerID, err)
+		apperr.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(balance)
+}
+
+type grantRequest struct {
+	ProductID string `json:"product_id"`
+	Note      string `json:"note"`
+}
+
+// GrantCredits фиксирует покупку продукта клиентом вне приложения и начисляет кредиты.
+// Доступно тренеру-владельцу продукта и администратору.
+func (h *Handler) GrantCredits(w http.ResponseWriter, r *http.Request) {
+	trainerID, clientID, ok := pairIDs(w, r)
+	if !ok {
+		return
+	}
+	if !canManage(r, trainerID) {
+		apperr.Respond(w, http.StatusForbidden, "Forbidden")
+		return
+	}
+
+	var req grantRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apperr.Respond(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if _, err := strconv.Atoi(req.ProductID); err != nil {
+		apperr.Respond(w, http.StatusBadRequest, "Invalid product ID")
+		return
+	}
+	p, err := h.Storage.GetProduct(r.Context(), req.ProductID)
+	if err == nil && p.TrainerID != trainerID {
+		err = ErrProductNotFound
+	}
+	if err != nil {
+		writeGrantError(w, err)
+		return
+	}
+
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	g, err := h.Storage.Grant(r.Context(), clientID, p.ID, principal.UserID, req.Note)
+	if err != nil {
+		writeGrantError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(g)
+}
+
+func writeGrantError(w http.ResponseWriter, err error) {
+	if !apperr.Expected(err) {
+		log.Printf("Error granting credits: %v", err)
+	}
+	apperr.Write(w, err)
+}
+
+// ListEntries возвращает журнал кредитов клиента у тренера
+func (h *Handler) ListEntries(w http.ResponseWriter, r *http.Request) {
+	trainerID, clientID, ok := pairIDs(w, r)
+	if !ok {
+		return
+	}
+
+	entries, err := h.Storage.Entries(r.Context(), clientID, trainerID)
+	if err != nil {
+		log.Printf("Error listing credit entries of client %s at trainer %s: %v", clientID, trainerID, err)
+		apperr.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
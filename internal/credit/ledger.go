@@ -0,0 +1,205 @@
+package credit
+
+import (
+	"TrainerConnect/internal/booking"
+	"TrainerConnect/internal/jobs"
+	postgres "TrainerConnect/pkg/postgresql"
+	"context"
+	"database/sql"
+	"log"
+	"time"
+)
+
+// openHold возвращает начисление, из которого зарезервирован кредит под занятие,
+// или пустую строку, если резерва нет
+func openHold(ctx context.Context, tx *sql.Tx, bookingID string) (string, error) {
+	var grantID string
+	err := tx.QueryRowContext(ctx, `SELECT grant_id FROM credit_entries
+		WHERE booking_id = $1 AND kind IN ('hold', 'release', 'refund')
+		GROUP BY grant_id
+		HAVING SUM(amount) < 0`, bookingID).Scan(&grantID)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return grantID, err
+}
+
+// Hold резервирует кредит клиента под подтвержденное занятие. Кредит берется из начисления,
+// которое истекает раньше других, но не раньше начала занятия. Если подходящих кредитов нет,
+// занятие оплачивается по ставке тренера и журнал не меняется. Реализует booking.Ledger.
+func (s *Storage) Hold(ctx context.Context, tx *sql.Tx, b *booking.Booking) (err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	// После переноса клиентом занятие подтверждается заново, а резерв остается прежним
+	grantID, err := openHold(ctx, tx, b.ID)
+	if err != nil || grantID != "" {
+		return err
+	}
+
+	// Блокируем начисления пары, чтобы параллельные подтверждения не зарезервировали один кредит дважды
+	_, err = tx.ExecContext(ctx, `SELECT id FROM credit_grants WHERE client_id = $1 AND trainer_id = $2 ORDER BY id FOR UPDATE`,
+		b.ClientID, b.TrainerID)
+	if err != nil {
+		return err
+	}
+
+	err = tx.QueryRowContext(ctx, `SELECT g.id FROM credit_grants g
+		JOIN credit_entries e ON e.grant_id = g.id
+		WHERE g.client_id = $1 AND g.trainer_id = $2 AND (g.expires_at IS NULL OR g.expires_at > $3)
+		GROUP BY g.id
+		HAVING SUM(e.amount) > 0
+		ORDER BY g.expires_at NULLS LAST, g.id
+		LIMIT 1`, b.ClientID, b.TrainerID, b.StartsAt).Scan(&grantID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return insertEntry(ctx, tx, Entry{GrantID: grantID, BookingID: b.ID, Kind: EntryHold, Amount: -1})
+}
+
+// Settle закрывает резерв занятия, перешедшего в конечное состояние: за проведенное занятие
+// и неявку кредит списывается, за отмену — только если forfeit, то есть политика тренера
+// удерживает полную стоимость. В остальных случаях кредит возвращается. Реализует booking.Ledger.
+func (s *Storage) Settle(ctx context.Context, tx *sql.Tx, b *booking.Booking, forfeit bool) (err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	grantID, err := openHold(ctx, tx, b.ID)
+	if err != nil || grantID == "" {
+		return err
+	}
+	// Сериализуем с истечением срока начисления
+	if _, err := tx.ExecContext(ctx, "SELECT id FROM credit_grants WHERE id = $1 FOR UPDATE", grantID); err != nil {
+		return err
+	}
+
+	var charge EntryKind
+	switch {
+	case b.Status == booking.StatusCompleted:
+		charge = EntrySession
+	case b.Status == booking.StatusNoShow:
+		charge = EntryNoShow
+	case b.Status == booking.StatusCancelled && forfeit:
+		charge = EntryLateCancellation
+	default:
+		return insertEntry(ctx, tx, Entry{GrantID: grantID, BookingID: b.ID, Kind: EntryRefund, Amount: 1})
+	}
+
+	if err := insertEntry(ctx, tx, Entry{GrantID: grantID, BookingID: b.ID, Kind: EntryRelease, Amount: 1}); err != nil {
+		return err
+	}
+	return insertEntry(ctx, tx, Entry{GrantID: grantID, BookingID: b.ID, Kind: charge, Amount: -1})
+}
+
+// Expire списывает неиспользованные кредиты начислений, срок которых истек к моменту now,
+// и возвращает число обработанных начислений. Зарезервированные кредиты не списываются:
+// они закрываются вместе с занятием. Безопасно выполнять в нескольких экземплярах сервера.
+func (s *Storage) Expire(ctx context.Context, now time.Time) (int, error) {
+	grantIDs, err := s.expiredGrants(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+
+	// Срок QueryTimeout действует на каждое начисление отдельно, а не на весь проход
+	expired := 0
+	for _, id := range grantIDs {
+		ok, err := s.expireGrant(ctx, id)
+		if err != nil {
+			return expired, err
+		}
+		if ok {
+			expired++
+		}
+	}
+	return expired, nil
+}
+
+// expiredGrants возвращает истекшие к now начисления с положительным остатком
+func (s *Storage) expiredGrants(ctx context.Context, now time.Time) (_ []string, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	rows, err := s.DB.QueryContext(ctx, `SELECT g.id FROM credit_grants g
+		JOIN credit_entries e ON e.grant_id = g.id
+		WHERE g.expires_at <= $1
+		GROUP BY g.id
+		HAVING SUM(e.amount) > 0`, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var grantIDs []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		grantIDs = append(grantIDs, id)
+	}
+	return grantIDs, rows.Err()
+}
+
+// expireGrant списывает остаток начисления. Остаток пересчитывается под блокировкой,
+// поэтому повторный вызов из другого экземпляра ничего не запишет.
+func (s *Storage) expireGrant(ctx context.Context, grantID string) (_ bool, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "SELECT id FROM credit_grants WHERE id = $1 FOR UPDATE", grantID); err != nil {
+		return false, err
+	}
+	var remaining int
+	err = tx.QueryRowContext(ctx, "SELECT COALESCE(SUM(amount), 0) FROM credit_entries WHERE grant_id = $1", grantID).Scan(&remaining)
+	if err != nil {
+		return false, err
+	}
+	if remaining <= 0 {
+		return false, nil
+	}
+
+	if err := insertEntry(ctx, tx, Entry{GrantID: grantID, Kind: EntryExpiry, Amount: -remaining}); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// Revoke списывает неиспользованные кредиты начисления, например после возврата оплаты.
+// Зарезервированные кредиты остаются за уже подтвержденными занятиями.
+func (s *Storage) Revoke(ctx context.Context, tx *sql.Tx, grantID, note string) (err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	if _, err := tx.ExecContext(ctx, "SELECT id FROM credit_grants WHERE id = $1 FOR UPDATE", grantID); err != nil {
+		return err
+	}
+	var remaining int
+	err = tx.QueryRowContext(ctx, "SELECT COALESCE(SUM(amount), 0) FROM credit_entries WHERE grant_id = $1", grantID).Scan(&remaining)
+	if err != nil || remaining <= 0 {
+		return err
+	}
+	return insertEntry(ctx, tx, Entry{GrantID: grantID, Kind: EntryRevoke, Amount: -remaining, Note: note})
+}
+
+// ExpiryInterval — как часто списываются кредиты с истекшим сроком
+const ExpiryInterval = time.Hour
+
+// RegisterExpiry добавляет периодическое списание истекших кредитов в обработчик фоновых задач
+func (s *Storage) RegisterExpiry(runner *jobs.Runner) {
+	runner.Every(ExpiryInterval, "credit expiry", func(ctx context.Context) error {
+		n, err := s.Expire(ctx, time.Now())
+		if n > 0 {
+			log.Printf("Expired unused credits of %d grants", n)
+		}
+		return err
+	})
+}
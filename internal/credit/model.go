@@ -0,0 +1,156 @@
+package credit
+
+import (
+	"TrainerConnect/internal/apperr"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Kind — вид продукта в каталоге тренера
+type Kind string
+
+const (
+	// KindSingle — разовое занятие
+	KindSingle Kind = "single"
+	// KindPack — пакет из нескольких занятий, например «10 по цене 9»
+	KindPack Kind = "pack"
+	// KindSubscription — абонемент: Sessions занятий на период ValidityDays дней
+	KindSubscription Kind = "subscription"
+)
+
+// Product — продукт, который тренер продает клиентам. Покупка начисляет клиенту Sessions кредитов.
+type Product struct {
+	ID          string `json:"id"`
+	TrainerID   string `json:"trainer_id"`
+	Kind        Kind   `json:"kind"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Sessions    int    `json:"sessions"`
+	// Цена в минимальных единицах валюты
+	Price    int64  `json:"price"`
+	Currency string `json:"currency"`
+	// Срок действия начисленных кредитов в днях; 0 — бессрочно
+	ValidityDays int  `json:"validity_days"`
+	Active       bool `json:"active"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Validate проверяет продукт и приводит поля к каноническому виду
+func (p *Product) Validate() error {
+	p.Name = strings.TrimSpace(p.Name)
+	p.Currency = strings.ToUpper(p.Currency)
+	if p.Name == "" || utf8.RuneCountInString(p.Name) > 100 {
+		return apperr.InvalidField("name", "out_of_range", "name must be between 1 and 100 characters")
+	}
+	if utf8.RuneCountInString(p.Description) > 2000 {
+		return apperr.InvalidField("description", "too_long", "description must be at most 2000 characters")
+	}
+
+	switch p.Kind {
+	case KindSingle:
+		if p.Sessions == 0 {
+			p.Sessions = 1
+		}
+		if p.Sessions != 1 {
+			return apperr.InvalidField("sessions", "out_of_range", "single session product must grant exactly one session")
+		}
+	case KindPack:
+		if p.Sessions < 2 {
+			return apperr.InvalidField("sessions", "out_of_range", "pack must contain at least two sessions")
+		}
+	case KindSubscription:
+		if p.Sessions < 1 {
+			return apperr.InvalidField("sessions", "out_of_range", "subscription must grant at least one session per period")
+		}
+		if p.ValidityDays <= 0 {
+			return apperr.InvalidField("validity_days", "required", "subscription requires validity_days as its period")
+		}
+	default:
+		return apperr.InvalidField("kind", "oneof", "kind must be one of single, pack, subscription")
+	}
+
+	if p.Sessions > 1000 {
+		return apperr.InvalidField("sessions", "out_of_range", "sessions must be at most 1000")
+	}
+	if p.ValidityDays < 0 {
+		return apperr.InvalidField("validity_days", "negative", "validity_days must not be negative")
+	}
+	if p.Price < 0 {
+		return apperr.InvalidField("price", "negative", "price must not be negative")
+	}
+	if len(p.Currency) != 3 {
+		return apperr.InvalidField("currency", "invalid_currency", "currency must be a three-letter ISO 4217 code")
+	}
+	return nil
+}
+
+// ExpiresAt возвращает срок действия кредитов, купленных в момент at, или nil для бессрочных
+func (p *Product) ExpiresAt(at time.Time) *time.Time {
+	if p.ValidityDays == 0 {
+		return nil
+	}
+	expires := at.AddDate(0, 0, p.ValidityDays)
+	return &expires
+}
+
+// EntryKind — вид записи журнала кредитов
+type EntryKind string
+
+const (
+	// EntryPurchase начисляет кредиты покупки
+	EntryPurchase EntryKind = "purchase"
+	// EntryHold резервирует кредит под подтвержденное занятие
+	EntryHold EntryKind = "hold"
+	// EntryRelease снимает резерв, когда занятие завершилось и кредит списывается другой записью
+	EntryRelease EntryKind = "release"
+	// EntryRefund возвращает зарезервированный кредит при отмене по правилам
+	EntryRefund EntryKind = "refund"
+	// EntrySession списывает кредит за проведенное занятие
+	EntrySession EntryKind = "session"
+	// EntryLateCancellation списывает кредит при поздней отмене
+	EntryLateCancellation EntryKind = "late_cancellation"
+	// EntryNoShow списывает кредит при неявке клиента
+	EntryNoShow EntryKind = "no_show"
+	// EntryExpiry списывает неиспользованные кредиты по истечении срока
+	EntryExpiry EntryKind = "expiry"
+	// EntryRevoke списывает неиспользованные кредиты после возврата оплаты
+	EntryRevoke EntryKind = "revoke"
+)
+
+// Grant — начисление кредитов клиенту за покупку продукта тренера
+type Grant struct {
+	ID        string     `json:"id"`
+	ClientID  string     `json:"client_id"`
+	TrainerID string     `json:"trainer_id"`
+	ProductID string     `json:"product_id"`
+	Quantity  int        `json:"quantity"`
+	Remaining int        `json:"remaining"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// Entry — запись журнала кредитов. Журнал только дополняется: баланс всегда
+// равен сумме записей, а исправления делаются новыми записями.
+type Entry struct {
+	ID        string    `json:"id"`
+	GrantID   string    `json:"grant_id"`
+	BookingID string    `json:"booking_id,omitempty"`
+	Kind      EntryKind `json:"kind"`
+	Amount    int       `json:"amount"`
+	ActorID   string    `json:"actor_id,omitempty"`
+	Note      string    `json:"note,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Balance — кредиты клиента у тренера: доступные для новых занятий
+// и зарезервированные под подтвержденные занятия
+type Balance struct {
+	ClientID  string  `json:"client_id"`
+	TrainerID string  `json:"trainer_id"`
+	Available int     `json:"available"`
+	Held      int     `json:"held"`
+	Grants    []Grant `json:"grants"`
+}
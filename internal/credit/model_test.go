@@ -0,0 +1,40 @@
+package credit_test
+
+import (
+	"TrainerConnect/internal/credit"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestProductValidate(t *testing.T) {
+	single := &credit.Product{Kind: credit.KindSingle, Name: " Персональная тренировка ", Price: 300000, Currency: "rub"}
+	assert.NoError(t, single.Validate())
+	assert.Equal(t, 1, single.Sessions)
+	assert.Equal(t, "RUB", single.Currency)
+	assert.Equal(t, "Персональная тренировка", single.Name)
+
+	pack := &credit.Product{Kind: credit.KindPack, Name: "10 по цене 9", Sessions: 10, Price: 2700000, Currency: "RUB", ValidityDays: 90}
+	assert.NoError(t, pack.Validate())
+
+	// Пакет из одного занятия — это разовое занятие
+	assert.Error(t, (&credit.Product{Kind: credit.KindPack, Name: "Пакет", Sessions: 1, Currency: "RUB"}).Validate())
+	// Абонементу нужен период
+	assert.Error(t, (&credit.Product{Kind: credit.KindSubscription, Name: "Месяц", Sessions: 8, Currency: "RUB"}).Validate())
+	assert.Error(t, (&credit.Product{Kind: credit.KindSingle, Name: "Занятие", Sessions: 2, Currency: "RUB"}).Validate())
+	assert.Error(t, (&credit.Product{Kind: "gift", Name: "Сертификат", Sessions: 1, Currency: "RUB"}).Validate())
+	assert.Error(t, (&credit.Product{Kind: credit.KindSingle, Name: "", Currency: "RUB"}).Validate())
+	assert.Error(t, (&credit.Product{Kind: credit.KindSingle, Name: "Занятие", Price: -1, Currency: "RUB"}).Validate())
+	assert.Error(t, (&credit.Product{Kind: credit.KindSingle, Name: "Занятие", Currency: "RU"}).Validate())
+}
+
+func TestProductExpiresAt(t *testing.T) {
+	at := time.Date(2024, 1, 31, 10, 0, 0, 0, time.UTC)
+
+	assert.Nil(t, (&credit.Product{ValidityDays: 0}).ExpiresAt(at))
+
+	expires := (&credit.Product{ValidityDays: 30}).ExpiresAt(at)
+	if assert.NotNil(t, expires) {
+		assert.Equal(t, time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC), *expires)
+	}
+}
@@ -0,0 +1,252 @@
+package credit
+
+import (
+	"TrainerConnect/internal/apperr"
+	postgres "TrainerConnect/pkg/postgresql"
+	"context"
+	"database/sql"
+	"time"
+)
+
+var (
+	ErrProductNotFound = apperr.NotFound("product_not_found", "product not found")
+	ErrProductInactive = apperr.Conflict("product_inactive", "product is no longer sold")
+	ErrTrainerNotFound = apperr.NotFound("trainer_not_found", "trainer profile not found")
+	ErrUserNotFound    = apperr.NotFound("user_not_found", "user not found")
+	ErrOwnProduct      = apperr.Conflict("own_product", "trainer cannot buy their own product")
+)
+
+type Storage struct {
+	*sql.DB
+}
+
+func NewStorage(db *sql.DB) *Storage {
+	return &Storage{DB: db}
+}
+
+const productColumns = `id, trainer_id, kind, name, description, sessions, price, currency,
+	validity_days, active, created_at, updated_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanProduct(row rowScanner) (*Product, error) {
+	p := &Product{}
+	err := row.Scan(&p.ID, &p.TrainerID, &p.Kind, &p.Name, &p.Description, &p.Sessions, &p.Price, &p.Currency,
+		&p.ValidityDays, &p.Active, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrProductNotFound
+		}
+		return nil, err
+	}
+	return p, nil
+}
+
+// CreateProduct добавляет продукт в каталог тренера
+func (s *Storage) CreateProduct(ctx context.Context, p *Product) (err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	err = s.DB.QueryRowContext(ctx, `INSERT INTO products (trainer_id, kind, name, description, sessions, price, currency, validity_days, active)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id, created_at, updated_at`,
+		p.TrainerID, p.Kind, p.Name, p.Description, p.Sessions, p.Price, p.Currency, p.ValidityDays, p.Active).
+		Scan(&p.ID, &p.CreatedAt, &p.UpdatedAt)
+	if apperr.IsForeignKeyViolation(err) {
+		return ErrTrainerNotFound
+	}
+	return err
+}
+
+// GetProduct возвращает продукт по ID
+func (s *Storage) GetProduct(ctx context.Context, id string) (_ *Product, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	return scanProduct(s.DB.QueryRowContext(ctx, "SELECT "+productColumns+" FROM products WHERE id = $1", id))
+}
+
+// ListProducts возвращает каталог тренера. Снятые с продажи продукты включаются, если includeInactive.
+func (s *Storage) ListProducts(ctx context.Context, trainerID string, includeInactive bool) (_ []Product, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	rows, err := s.DB.QueryContext(ctx, "SELECT "+productColumns+` FROM products
+		WHERE trainer_id = $1 AND (active OR $2)
+		ORDER BY price, id`, trainerID, includeInactive)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	products := []Product{}
+	for rows.Next() {
+		p, err := scanProduct(rows)
+		if err != nil {
+			return nil, err
+		}
+		products = append(products, *p)
+	}
+	return products, rows.Err()
+}
+
+// UpdateProduct сохраняет изменения продукта. Уже начисленные кредиты не меняются:
+// количество и срок действия фиксируются в момент покупки.
+func (s *Storage) UpdateProduct(ctx context.Context, p *Product) (err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	err = s.DB.QueryRowContext(ctx, `UPDATE products SET kind = $1, name = $2, description = $3, sessions = $4, price = $5,
+			currency = $6, validity_days = $7, active = $8, updated_at = now()
+		WHERE id = $9 RETURNING trainer_id, created_at, updated_at`,
+		p.Kind, p.Name, p.Description, p.Sessions, p.Price, p.Currency, p.ValidityDays, p.Active, p.ID).
+		Scan(&p.TrainerID, &p.CreatedAt, &p.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return ErrProductNotFound
+	}
+	return err
+}
+
+// Grant начисляет клиенту кредиты за покупку продукта. actorID — кто зафиксировал покупку.
+func (s *Storage) Grant(ctx context.Context, clientID, productID, actorID, note string) (_ *Grant, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var active bool
+	if err := tx.QueryRowContext(ctx, "SELECT active FROM products WHERE id = $1", productID).Scan(&active); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrProductNotFound
+		}
+		return nil, err
+	}
+	if !active {
+		return nil, ErrProductInactive
+	}
+
+	g, err := s.GrantTx(ctx, tx, clientID, productID, actorID, note)
+	if err != nil {
+		return nil, err
+	}
+	return g, tx.Commit()
+}
+
+// GrantTx начисляет кредиты в транзакции вызывающего, например вместе с подтверждением оплаты.
+// Пустой actorID означает, что покупка зафиксирована системой. Снятый с продажи продукт
+// не проверяется: оплата, начатая до снятия, все равно должна принести кредиты.
+func (s *Storage) GrantTx(ctx context.Context, tx *sql.Tx, clientID, productID, actorID, note string) (_ *Grant, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	p, err := scanProduct(tx.QueryRowContext(ctx, "SELECT "+productColumns+" FROM products WHERE id = $1 FOR SHARE", productID))
+	if err != nil {
+		return nil, err
+	}
+	if p.TrainerID == clientID {
+		return nil, ErrOwnProduct
+	}
+
+	g := &Grant{ClientID: clientID, TrainerID: p.TrainerID, ProductID: p.ID, Quantity: p.Sessions, ExpiresAt: p.ExpiresAt(time.Now())}
+	err = tx.QueryRowContext(ctx, `INSERT INTO credit_grants (client_id, trainer_id, product_id, quantity, expires_at)
+		VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at`,
+		g.ClientID, g.TrainerID, g.ProductID, g.Quantity, g.ExpiresAt).Scan(&g.ID, &g.CreatedAt)
+	if err != nil {
+		if apperr.IsForeignKeyViolation(err) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	g.Remaining = g.Quantity
+
+	if err := insertEntry(ctx, tx, Entry{GrantID: g.ID, Kind: EntryPurchase, Amount: g.Quantity, ActorID: actorID, Note: note}); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+func insertEntry(ctx context.Context, tx *sql.Tx, e Entry) error {
+	_, err := tx.ExecContext(ctx, `INSERT INTO credit_entries (grant_id, booking_id, kind, amount, actor_id, note)
+		VALUES ($1, NULLIF($2, '')::integer, $3, $4, NULLIF($5, '')::integer, $6)`,
+		e.GrantID, e.BookingID, e.Kind, e.Amount, e.ActorID, e.Note)
+	return err
+}
+
+// Balance возвращает кредиты клиента у тренера на момент now. Просроченные начисления
+// в доступный баланс не входят, даже если списание по сроку еще не записано в журнал.
+func (s *Storage) Balance(ctx context.Context, clientID, trainerID string, now time.Time) (_ *Balance, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	rows, err := s.DB.QueryContext(ctx, `SELECT g.id, g.client_id, g.trainer_id, g.product_id, g.quantity, SUM(e.amount),
+			g.expires_at, g.created_at
+		FROM credit_grants g
+		JOIN credit_entries e ON e.grant_id = g.id
+		WHERE g.client_id = $1 AND g.trainer_id = $2 AND (g.expires_at IS NULL OR g.expires_at > $3)
+		GROUP BY g.id
+		HAVING SUM(e.amount) > 0
+		ORDER BY g.expires_at NULLS LAST, g.id`, clientID, trainerID, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	b := &Balance{ClientID: clientID, TrainerID: trainerID, Grants: []Grant{}}
+	for rows.Next() {
+		var g Grant
+		var expiresAt sql.NullTime
+		err := rows.Scan(&g.ID, &g.ClientID, &g.TrainerID, &g.ProductID, &g.Quantity, &g.Remaining, &expiresAt, &g.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		if expiresAt.Valid {
+			g.ExpiresAt = &expiresAt.Time
+		}
+		b.Available += g.Remaining
+		b.Grants = append(b.Grants, g)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Резерв — удержания, которые еще не сняты и не возвращены
+	err = s.DB.QueryRowContext(ctx, `SELECT COALESCE(-SUM(e.amount), 0)
+		FROM credit_entries e JOIN credit_grants g ON g.id = e.grant_id
+		WHERE g.client_id = $1 AND g.trainer_id = $2 AND e.kind IN ('hold', 'release', 'refund')`,
+		clientID, trainerID).Scan(&b.Held)
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Entries возвращает журнал кредитов клиента у тренера в хронологическом порядке
+func (s *Storage) Entries(ctx context.Context, clientID, trainerID string) (_ []Entry, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	rows, err := s.DB.QueryContext(ctx, `SELECT e.id, e.grant_id, COALESCE(e.booking_id::text, ''), e.kind, e.amount,
+			COALESCE(e.actor_id::text, ''), e.note, e.created_at
+		FROM credit_entries e JOIN credit_grants g ON g.id = e.grant_id
+		WHERE g.client_id = $1 AND g.trainer_id = $2
+		ORDER BY e.id`, clientID, trainerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []Entry{}
+	for rows.Next() {
+		var e Entry
+		if err := rows.Scan(&e.ID, &e.GrantID, &e.BookingID, &e.Kind, &e.Amount, &e.ActorID, &e.Note, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
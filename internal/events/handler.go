@@ -0,0 +1,90 @@
+package events
+
+import (
+	"TrainerConnect/internal/auth"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"log"
+	"net/http"
+	"time"
+)
+
+// writeTimeout — сколько ждать записи в соединение, прежде чем считать его мертвым
+const writeTimeout = 10 * time.Second
+
+type Handler struct {
+	Hub       *Hub
+	Heartbeat time.Duration
+}
+
+// NewHandler создает обработчик потока событий. Раз в heartbeat в поток пишется комментарий,
+// чтобы прокси не закрывали соединение, а оборванные соединения обнаруживались по ошибке записи.
+func NewHandler(hub *Hub, heartbeat time.Duration) *Handler {
+	return &Handler{Hub: hub, Heartbeat: heartbeat}
+}
+
+func (h *Handler) Register(router *chi.Mux) {
+	router.With(auth.RequireAuth).Get("/events", h.Stream)
+}
+
+// Stream отдает события текущего пользователя в формате Server-Sent Events.
+// Аутентификация — тем же bearer-токеном, что и REST API. Если клиент не успевает
+// читать события, хаб отключает подписку; в этом случае клиенту отправляется событие
+// overflow, после которого ему нужно переподключиться и перечитать состояние через REST.
+func (h *Handler) Stream(w http.ResponseWriter, r *http.Request) {
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	rc := http.NewResponseController(w)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+
+	sub := h.Hub.Subscribe(principal.UserID)
+	defer h.Hub.Unsubscribe(sub)
+
+	if err := write(rc, w, ": connected\n\n"); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(h.Heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e, ok := <-sub.Events():
+			if !ok {
+				write(rc, w, "event: overflow\ndata: {}\n\n")
+				return
+			}
+			data, err := json.Marshal(e.Data)
+			if err != nil {
+				log.Printf("Error encoding %s event: %v", e.Type, err)
+				continue
+			}
+			if err := write(rc, w, fmt.Sprintf("id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if err := write(rc, w, ": ping\n\n"); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// write пишет кадр в поток и сразу отправляет его клиенту. Перед каждой записью
+// продлевается дедлайн: таймаут записи сервера иначе оборвал бы долгий поток.
+func write(rc *http.ResponseController, w http.ResponseWriter, frame string) error {
+	if err := rc.SetWriteDeadline(time.Now().Add(writeTimeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	if _, err := fmt.Fprint(w, frame); err != nil {
+		return err
+	}
+	return rc.Flush()
+}
@@ -0,0 +1,111 @@
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// Event — событие, доставляемое открытым соединениям пользователя
+type Event struct {
+	ID        uint64      `json:"id"`
+	Type      string      `json:"type"`
+	Data      interface{} `json:"data"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+// Publisher принимает события от подсистем. Событие получают все открытые
+// соединения перечисленных пользователей.
+type Publisher interface {
+	Publish(eventType string, data interface{}, userIDs ...string)
+}
+
+// Subscription — подписка одного соединения на события пользователя
+type Subscription struct {
+	UserID string
+	events chan Event
+}
+
+// Events возвращает канал событий. Канал закрывается, если соединение не успевало
+// забирать события и было отключено хабом.
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Hub — внутрипроцессная шина событий. Публикация никогда не блокируется: у каждого
+// соединения свой буфер, и соединение, переполнившее буфер, отключается, чтобы один
+// медленный клиент не задерживал остальных.
+type Hub struct {
+	mu          sync.Mutex
+	subscribers map[string]map[*Subscription]struct{}
+	nextID      uint64
+	bufferSize  int
+}
+
+// NewHub создает шину с буфером bufferSize событий на соединение
+func NewHub(bufferSize int) *Hub {
+	return &Hub{subscribers: make(map[string]map[*Subscription]struct{}), bufferSize: bufferSize}
+}
+
+// Subscribe подписывает новое соединение пользователя
+func (h *Hub) Subscribe(userID string) *Subscription {
+	s := &Subscription{UserID: userID, events: make(chan Event, h.bufferSize)}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.subscribers[userID] == nil {
+		h.subscribers[userID] = make(map[*Subscription]struct{})
+	}
+	h.subscribers[userID][s] = struct{}{}
+	return s
+}
+
+// Unsubscribe отписывает соединение. Повторный вызов ничего не делает.
+func (h *Hub) Unsubscribe(s *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.remove(s)
+}
+
+// remove удаляет подписку и закрывает ее канал, вызывается под mu
+func (h *Hub) remove(s *Subscription) {
+	subs := h.subscribers[s.UserID]
+	if _, ok := subs[s]; !ok {
+		return
+	}
+	delete(subs, s)
+	if len(subs) == 0 {
+		delete(h.subscribers, s.UserID)
+	}
+	close(s.events)
+}
+
+// Publish отправляет событие всем соединениям пользователей userIDs
+func (h *Hub) Publish(eventType string, data interface{}, userIDs ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.nextID++
+	e := Event{ID: h.nextID, Type: eventType, Data: data, CreatedAt: time.Now().UTC()}
+
+	seen := make(map[string]bool, len(userIDs))
+	for _, userID := range userIDs {
+		if seen[userID] {
+			continue
+		}
+		seen[userID] = true
+		for s := range h.subscribers[userID] {
+			select {
+			case s.events <- e:
+			default:
+				h.remove(s)
+			}
+		}
+	}
+}
+
+// Connections возвращает число открытых соединений пользователя
+func (h *Hub) Connections(userID string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subscribers[userID])
+}
@@ -0,0 +1,99 @@
+package events_test
+
+import (
+	"TrainerConnect/internal/auth"
+	"TrainerConnect/internal/events"
+	"bufio"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestHubPublish(t *testing.T) {
+	hub := events.NewHub(4)
+	phone := hub.Subscribe("2")
+	laptop := hub.Subscribe("2")
+	other := hub.Subscribe("3")
+
+	// Событие получают все соединения адресата и только они
+	hub.Publish("message.created", map[string]string{"body": "Привет"}, "2", "2")
+	e := <-phone.Events()
+	assert.Equal(t, "message.created", e.Type)
+	assert.Equal(t, e.ID, (<-laptop.Events()).ID)
+	assert.Len(t, phone.Events(), 0, "дубликаты адресатов не дают повторной доставки")
+	assert.Len(t, other.Events(), 0)
+
+	hub.Unsubscribe(laptop)
+	hub.Unsubscribe(laptop)
+	assert.Equal(t, 1, hub.Connections("2"))
+}
+
+func TestHubDropsSlowSubscriber(t *testing.T) {
+	hub := events.NewHub(2)
+	slow := hub.Subscribe("2")
+
+	for i := 0; i < 3; i++ {
+		hub.Publish("booking.updated", i, "2")
+	}
+
+	// Буфер переполнен: подписка отключена, уже буферизованные события дочитываются
+	assert.Equal(t, 0, hub.Connections("2"))
+	count := 0
+	for range slow.Events() {
+		count++
+	}
+	assert.Equal(t, 2, count)
+	hub.Unsubscribe(slow)
+}
+
+func TestStream(t *testing.T) {
+	tokens := auth.NewTokenManager("secret", time.Minute, time.Hour)
+	hub := events.NewHub(8)
+	router := chi.NewRouter()
+	router.Use(auth.Middleware(tokens))
+	events.NewHandler(hub, 20*time.Millisecond).Register(router)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	// Без токена поток недоступен
+	resp, err := http.Get(server.URL + "/events")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	token, _ := tokens.IssueAccessToken("2", auth.RoleClient)
+	req, _ := http.NewRequest("GET", server.URL+"/events", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err = http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	reader := bufio.NewReader(resp.Body)
+	line, _ := reader.ReadString('\n')
+	assert.Equal(t, ": connected\n", line)
+
+	hub.Publish("booking.updated", map[string]string{"status": "confirmed"}, "2")
+
+	var frame []string
+	for {
+		line, err := reader.ReadString('\n')
+		if !assert.NoError(t, err) {
+			return
+		}
+		// Пропускаем пустые строки и heartbeat-комментарии
+		if line == "\n" || strings.HasPrefix(line, ":") {
+			if len(frame) > 0 {
+				break
+			}
+			continue
+		}
+		frame = append(frame, strings.TrimSpace(line))
+	}
+	assert.Equal(t, []string{"id: 1", "event: booking.updated", `data: {"status":"confirmed"}`}, frame)
+}
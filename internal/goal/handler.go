@@ -0,0 +1,315 @@
+package goal
+
+import (
+	"TrainerConnect/internal/apperr"
+	"TrainerConnect/internal/auth"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const goalURL = "/goals/"
+
+type Handler struct {
+	Storage *Storage
+	Tracker *Tracker
+	Policy  *auth.Policy
+}
+
+// NewHandler создает обработчик целей. Цели клиента видят и меняют сам клиент и его тренеры
+// из roster — по тем же правилам, что и показатели тела, на которых они основаны.
+func NewHandler(storage *Storage, tracker *Tracker, policy *auth.Policy) *Handler {
+	return &Handler{Storage: storage, Tracker: tracker, Policy: policy}
+}
+
+func (h *Handler) Register(router *chi.Mux) {
+	router.Group(func(r chi.Router) {
+		r.Use(auth.RequireAuth)
+		r.Get("/goals", h.ListGoals)
+		r.Post("/goals", h.CreateGoal)
+		r.Get(goalURL+"{id}", h.GetGoal)
+		r.Put(goalURL+"{id}", h.UpdateGoal)
+		r.Post(goalURL+"{id}/abandon", h.AbandonGoal)
+	})
+}
+
+// canAccess проверяет доступ текущего пользователя к целям клиента
+func (h *Handler) canAccess(w http.ResponseWriter, r *http.Request, clientID string) bool {
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	allowed, err := h.Policy.CanReadHealthData(r.Context(), principal, clientID)
+	if err != nil {
+		log.Printf("Error checking access to goals of user %s: %v", clientID, err)
+		apperr.Write(w, err)
+		return false
+	}
+	if !allowed {
+		apperr.Respond(w, http.StatusForbidden, "Forbidden")
+		return false
+	}
+	return true
+}
+
+// evaluate пересчитывает прогресс цели и сохраняет изменившийся статус
+func (h *Handler) evaluate(ctx context.Context, g *Goal, now time.Time) error {
+	current, err := h.Tracker.Current(ctx, g)
+	if err != nil {
+		return err
+	}
+	if g.Evaluate(current, now) {
+		return h.Storage.SaveEvaluation(ctx, g)
+	}
+	return nil
+}
+
+// load загружает цель по ID из URL, проверяет доступ и пересчитывает прогресс
+func (h *Handler) load(w http.ResponseWriter, r *http.Request) (*Goal, bool) {
+	id := chi.URLParam(r, "id")
+	if _, err := strconv.Atoi(id); err != nil {
+		apperr.Respond(w, http.StatusBadRequest, "Invalid goal ID")
+		return nil, false
+	}
+
+	g, err := h.Storage.Get(r.Context(), id)
+	if err != nil {
+		if !apperr.Expected(err) {
+			log.Printf("Error getting goal %s: %v", id, err)
+		}
+		apperr.Write(w, err)
+		return nil, false
+	}
+	if !h.canAccess(w, r, g.ClientID) {
+		return nil, false
+	}
+
+	if err := h.evaluate(r.Context(), g, time.Now().UTC()); err != nil {
+		log.Printf("Error evaluating goal %s: %v", id, err)
+		apperr.Write(w, err)
+		return nil, false
+	}
+	return g, true
+}
+
+// CreateGoal создает цель. Клиент ставит цель себе, тренер — клиенту из roster (client_id).
+// Если исходное значение не указано, берется текущее из журнала или показателей.
+func (h *Handler) CreateGoal(w http.ResponseWriter, r *http.Request) {
+	principal, _ := auth.PrincipalFromContext(r.Context())
+
+	var g Goal
+	if err := json.NewDecoder(r.Body).Decode(&g); err != nil {
+		apperr.Respond(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if g.ClientID == "" {
+		g.ClientID = principal.UserID
+	} else if _, err := strconv.Atoi(g.ClientID); err != nil {
+		apperr.Respond(w, http.StatusBadRequest, "Invalid client ID")
+		return
+	}
+	if !h.canAccess(w, r, g.ClientID) {
+		return
+	}
+
+	now := time.Now().UTC()
+	if g.StartDate.IsZero() {
+		g.StartDate = now
+	}
+	if err := g.Validate(); err != nil {
+		apperr.Write(w, err)
+		return
+	}
+	g.ID = ""
+	g.CreatedBy = principal.UserID
+	g.AbandonedAt = nil
+	g.Status = ""
+	for i := range g.Milestones {
+		g.Milestones[i].ReachedAt = nil
+	}
+
+	current, err := h.Tracker.Current(r.Context(), &g)
+	if err != nil {
+		log.Printf("Error getting current value for goal of user %s: %v", g.ClientID, err)
+		apperr.Write(w, err)
+		return
+	}
+	if g.Baseline == nil {
+		if current == nil && g.Source == SourceMetric {
+			apperr.Write(w, apperr.InvalidField("baseline", "required", "baseline is required until the first measurement is recorded"))
+			return
+		}
+		g.Baseline = current
+	}
+	g.Evaluate(current, now)
+
+	if err := h.Storage.Create(r.Context(), &g); err != nil {
+		if !apperr.Expected(err) {
+			log.Printf("Error creating goal: %v", err)
+		}
+		apperr.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(g)
+}
+
+// ListGoals возвращает цели с пересчитанным статусом. Без client_id клиент получает свои цели,
+// а тренер — цели всех своих клиентов. Параметр changed_since оставляет только цели,
+// статус которых изменился позже указанного момента, — его использует дашборд тренера.
+func (h *Handler) ListGoals(w http.ResponseWriter, r *http.Request) {
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	q := r.URL.Query()
+
+	clientID, trainerID := q.Get("client_id"), ""
+	if clientID != "" {
+		if _, err := strconv.Atoi(clientID); err != nil {
+			apperr.Respond(w, http.StatusBadRequest, "Invalid client ID")
+			return
+		}
+		if !h.canAccess(w, r, clientID) {
+			return
+		}
+	} else if principal.Role == auth.RoleTrainer {
+		trainerID = principal.UserID
+	} else {
+		clientID = principal.UserID
+	}
+
+	var since time.Time
+	if v := q.Get("changed_since"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			apperr.Respond(w, http.StatusBadRequest, "Invalid changed_since parameter, expected RFC 3339 time")
+			return
+		}
+		since = t
+	}
+
+	goals, err := h.Storage.List(r.Context(), clientID, trainerID)
+	if err != nil {
+		log.Printf("Error listing goals: %v", err)
+		apperr.Write(w, err)
+		return
+	}
+
+	now := time.Now().UTC()
+	result := []*Goal{}
+	for _, g := range goals {
+		if err := h.evaluate(r.Context(), g, now); err != nil {
+			log.Printf("Error evaluating goal %s: %v", g.ID, err)
+			apperr.Write(w, err)
+			return
+		}
+		if g.StatusChangedAt.After(since) {
+			result = append(result, g)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// GetGoal возвращает цель с текущим значением, прогрессом и статусом
+func (h *Handler) GetGoal(w http.ResponseWriter, r *http.Request) {
+	g, ok := h.load(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(g)
+}
+
+// UpdateGoal меняет название, цель, дедлайн и вехи. Источник цели и клиента изменить нельзя,
+// статус пересчитывается по новым условиям.
+func (h *Handler) UpdateGoal(w http.ResponseWriter, r *http.Request) {
+	current, ok := h.load(w, r)
+	if !ok {
+		return
+	}
+	if current.AbandonedAt != nil {
+		apperr.Write(w, ErrAbandoned)
+		return
+	}
+
+	var g Goal
+	if err := json.NewDecoder(r.Body).Decode(&g); err != nil {
+		apperr.Respond(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	g.ID, g.ClientID, g.CreatedBy = current.ID, current.ClientID, current.CreatedBy
+	g.Source, g.MetricKind, g.ExerciseID = current.Source, current.MetricKind, current.ExerciseID
+	g.StartDate, g.StatusChangedAt = current.StartDate, current.StatusChangedAt
+	if g.Baseline == nil {
+		g.Baseline = current.Baseline
+	}
+	if err := g.Validate(); err != nil {
+		apperr.Write(w, err)
+		return
+	}
+
+	// Уже достигнутые вехи сохраняют дату достижения, если их цель не изменилась
+	reached := make(map[string]Milestone, len(current.Milestones))
+	for _, m := range current.Milestones {
+		reached[m.ID] = m
+	}
+	for i := range g.Milestones {
+		m := &g.Milestones[i]
+		if old, ok := reached[m.ID]; ok && old.Target == m.Target {
+			m.ReachedAt = old.ReachedAt
+		} else {
+			m.ReachedAt = nil
+		}
+	}
+
+	g.Status = current.Status
+	if g.Target != current.Target {
+		g.Status = ""
+	}
+	g.Evaluate(current.Current, time.Now().UTC())
+
+	if err := h.Storage.Update(r.Context(), &g); err != nil {
+		if !apperr.Expected(err) {
+			log.Printf("Error updating goal %s: %v", g.ID, err)
+		}
+		apperr.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(g)
+}
+
+// AbandonGoal отмечает цель брошенной. Брошенная цель больше не пересчитывается.
+func (h *Handler) AbandonGoal(w http.ResponseWriter, r *http.Request) {
+	g, ok := h.load(w, r)
+	if !ok {
+		return
+	}
+	if g.AbandonedAt != nil {
+		apperr.Write(w, ErrAbandoned)
+		return
+	}
+
+	now := time.Now().UTC()
+	if err := h.Storage.Abandon(r.Context(), g.ID, now); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			apperr.Write(w, ErrAbandoned)
+			return
+		}
+		log.Printf("Error abandoning goal %s: %v", g.ID, err)
+		apperr.Write(w, err)
+		return
+	}
+	g.AbandonedAt = &now
+	g.Status = StatusAbandoned
+	g.StatusChangedAt = now
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(g)
+}
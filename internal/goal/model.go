@@ -0,0 +1,199 @@
+package goal
+
+import (
+	"TrainerConnect/internal/apperr"
+	"TrainerConnect/internal/metrics"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Source — откуда берется текущее значение цели
+type Source string
+
+const (
+	// SourceMetric — последнее измерение показателя тела
+	SourceMetric Source = "metric"
+	// SourceExercise — максимальный вес в упражнении по журналу тренировок
+	SourceExercise Source = "exercise"
+)
+
+// Status — вычисляемое состояние цели
+type Status string
+
+const (
+	StatusOnTrack   Status = "on_track"
+	StatusAtRisk    Status = "at_risk"
+	StatusAchieved  Status = "achieved"
+	StatusAbandoned Status = "abandoned"
+)
+
+// riskTolerance — на сколько фактический прогресс может отставать от равномерного графика,
+// прежде чем цель считается под угрозой
+const riskTolerance = 0.1
+
+// Milestone — промежуточная отметка на пути к цели
+type Milestone struct {
+	ID        string     `json:"id"`
+	Title     string     `json:"title"`
+	Target    float64    `json:"target"`
+	DueDate   *time.Time `json:"due_date,omitempty"`
+	ReachedAt *time.Time `json:"reached_at,omitempty"`
+}
+
+// Goal — цель клиента, согласованная с тренером. Прогресс вычисляется по журналу
+// тренировок или показателям тела, в единице Unit.
+type Goal struct {
+	ID              string       `json:"id"`
+	ClientID        string       `json:"client_id"`
+	CreatedBy       string       `json:"created_by"`
+	Title           string       `json:"title"`
+	Source          Source       `json:"source"`
+	MetricKind      metrics.Kind `json:"metric_kind,omitempty"`
+	ExerciseID      string       `json:"exercise_id,omitempty"`
+	Unit            metrics.Unit `json:"unit"`
+	Baseline        *float64     `json:"baseline"`
+	Target          float64      `json:"target"`
+	StartDate       time.Time    `json:"start_date"`
+	Deadline        time.Time    `json:"deadline"`
+	Milestones      []Milestone  `json:"milestones"`
+	Status          Status       `json:"status"`
+	StatusChangedAt time.Time    `json:"status_changed_at"`
+	AbandonedAt     *time.Time   `json:"abandoned_at,omitempty"`
+	Current         *float64     `json:"current"`
+	Progress        float64      `json:"progress"`
+}
+
+// kind возвращает показатель, по правилам которого проверяются единицы цели.
+// Вес в упражнениях измеряется как вес тела — в kg или lb.
+func (g *Goal) kind() metrics.Kind {
+	if g.Source == SourceExercise {
+		return metrics.KindWeight
+	}
+	return g.MetricKind
+}
+
+// Validate проверяет цель и вехи
+func (g *Goal) Validate() error {
+	g.Title = strings.TrimSpace(g.Title)
+	if g.Title == "" {
+		return apperr.InvalidField("title", "required", "title is required")
+	}
+	switch g.Source {
+	case SourceMetric:
+		if _, err := g.MetricKind.BaseUnit(); err != nil {
+			return err
+		}
+		g.ExerciseID = ""
+	case SourceExercise:
+		if _, err := strconv.Atoi(g.ExerciseID); err != nil {
+			return apperr.InvalidField("exercise_id", "required", "exercise_id is required for exercise goals")
+		}
+		g.MetricKind = ""
+	default:
+		return apperr.InvalidField("source", "oneof", "source must be metric or exercise")
+	}
+	if g.Unit == "" {
+		g.Unit, _ = g.kind().BaseUnit()
+	}
+	if err := g.kind().CheckUnit(g.Unit); err != nil {
+		return err
+	}
+	if g.Target <= 0 {
+		return apperr.InvalidField("target", "not_positive", "target must be positive")
+	}
+	if g.Baseline != nil && *g.Baseline == g.Target {
+		return apperr.InvalidField("target", "equals_baseline", "target must differ from baseline")
+	}
+	if g.Deadline.IsZero() || !g.Deadline.After(g.StartDate) {
+		return apperr.InvalidField("deadline", "before_start", "deadline must be after start date")
+	}
+	for i := range g.Milestones {
+		m := &g.Milestones[i]
+		m.Title = strings.TrimSpace(m.Title)
+		if m.Title == "" {
+			return apperr.InvalidField("milestones", "required", "milestone title is required")
+		}
+		if m.Target <= 0 {
+			return apperr.InvalidField("milestones", "not_positive", "milestone target must be positive")
+		}
+		if m.DueDate != nil && (m.DueDate.Before(g.StartDate) || m.DueDate.After(g.Deadline)) {
+			return apperr.InvalidField("milestones", "out_of_range", "milestone due date must be between start date and deadline")
+		}
+	}
+	return nil
+}
+
+// decreasing сообщает, что цель — снизить значение (например, вес тела)
+func (g *Goal) decreasing() bool {
+	return g.Baseline != nil && g.Target < *g.Baseline
+}
+
+// reached сообщает, достигнуто ли значение target с учетом направления цели
+func (g *Goal) reached(current, target float64) bool {
+	if g.decreasing() {
+		return current <= target
+	}
+	return current >= target
+}
+
+// Evaluate пересчитывает прогресс, статус и достигнутые вехи по текущему значению на момент now.
+// Возвращает true, если статус или вехи изменились и их нужно сохранить.
+//
+// Цель под угрозой, если дедлайн прошел, а цель не достигнута, или если доля пройденного пути
+// отстает от доли прошедшего времени больше чем на riskTolerance. Достигнутая или брошенная
+// цель свой статус больше не меняет.
+func (g *Goal) Evaluate(current *float64, now time.Time) bool {
+	g.Current = current
+	g.Progress = g.progress()
+
+	changed := false
+	if current != nil && g.AbandonedAt == nil {
+		for i := range g.Milestones {
+			m := &g.Milestones[i]
+			if m.ReachedAt == nil && g.reached(*current, m.Target) {
+				reachedAt := now
+				m.ReachedAt = &reachedAt
+				changed = true
+			}
+		}
+	}
+
+	status := g.Status
+	switch {
+	case g.AbandonedAt != nil:
+		status = StatusAbandoned
+	case g.Status == StatusAchieved:
+	case current != nil && g.reached(*current, g.Target):
+		status = StatusAchieved
+	case !now.Before(g.Deadline):
+		status = StatusAtRisk
+	default:
+		elapsed := float64(now.Sub(g.StartDate)) / float64(g.Deadline.Sub(g.StartDate))
+		if g.Progress < elapsed-riskTolerance {
+			status = StatusAtRisk
+		} else {
+			status = StatusOnTrack
+		}
+	}
+	if status != g.Status {
+		g.Status = status
+		g.StatusChangedAt = now
+		changed = true
+	}
+	return changed
+}
+
+// progress возвращает долю пройденного от исходного значения до цели, от 0 до 1
+func (g *Goal) progress() float64 {
+	if g.Current == nil {
+		return 0
+	}
+	baseline := 0.0
+	if g.Baseline != nil {
+		baseline = *g.Baseline
+	}
+	p := (*g.Current - baseline) / (g.Target - baseline)
+	return math.Round(math.Max(0, math.Min(1, p))*1000) / 1000
+}
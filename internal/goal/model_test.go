@@ -0,0 +1,85 @@
+package goal_test
+
+import (
+	"TrainerConnect/internal/goal"
+	"TrainerConnect/internal/metrics"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+var start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func value(v float64) *float64 {
+	return &v
+}
+
+// weightLoss — сбросить 5 кг за 10 недель
+func weightLoss() *goal.Goal {
+	return &goal.Goal{
+		Title:      "Минус 5 кг",
+		Source:     goal.SourceMetric,
+		MetricKind: metrics.KindWeight,
+		Baseline:   value(85),
+		Target:     80,
+		StartDate:  start,
+		Deadline:   start.Add(10 * 7 * 24 * time.Hour),
+		Milestones: []goal.Milestone{{Title: "Первые 2 кг", Target: 83}},
+	}
+}
+
+func TestGoalValidate(t *testing.T) {
+	g := weightLoss()
+	assert.NoError(t, g.Validate())
+	assert.Equal(t, metrics.UnitKg, g.Unit)
+
+	g = weightLoss()
+	g.Unit = metrics.UnitCm
+	assert.Error(t, g.Validate())
+
+	g = weightLoss()
+	g.Deadline = start
+	assert.Error(t, g.Validate())
+
+	g = &goal.Goal{Title: "Тяга 140", Source: goal.SourceExercise, Target: 140, StartDate: start, Deadline: start.AddDate(0, 3, 0)}
+	assert.Error(t, g.Validate(), "не указано упражнение")
+	g.ExerciseID = "3"
+	assert.NoError(t, g.Validate())
+}
+
+func TestEvaluate(t *testing.T) {
+	// Через 5 недель из 10 сброшено 3 кг из 5 — цель идет по плану, первая веха достигнута
+	g := weightLoss()
+	now := start.Add(5 * 7 * 24 * time.Hour)
+	assert.True(t, g.Evaluate(value(82), now))
+	assert.Equal(t, goal.StatusOnTrack, g.Status)
+	assert.Equal(t, 0.6, g.Progress)
+	assert.NotNil(t, g.Milestones[0].ReachedAt)
+
+	// Повторный расчет с теми же данными ничего не меняет
+	assert.False(t, g.Evaluate(value(82), now))
+
+	// Через 8 недель сброшен только 1 кг — цель под угрозой
+	g = weightLoss()
+	g.Evaluate(value(84), start.Add(8*7*24*time.Hour))
+	assert.Equal(t, goal.StatusAtRisk, g.Status)
+	assert.Nil(t, g.Milestones[0].ReachedAt)
+
+	// Достигнутая цель остается достигнутой, даже если вес вернулся
+	g = weightLoss()
+	g.Evaluate(value(79.5), now)
+	assert.Equal(t, goal.StatusAchieved, g.Status)
+	g.Evaluate(value(81), now.Add(time.Hour))
+	assert.Equal(t, goal.StatusAchieved, g.Status)
+
+	// Дедлайн прошел без результата
+	g = weightLoss()
+	g.Evaluate(nil, g.Deadline)
+	assert.Equal(t, goal.StatusAtRisk, g.Status)
+
+	// Брошенная цель не пересчитывается
+	g = weightLoss()
+	g.AbandonedAt = &now
+	g.Evaluate(value(79), now)
+	assert.Equal(t, goal.StatusAbandoned, g.Status)
+}
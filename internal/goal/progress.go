@@ -0,0 +1,59 @@
+package goal
+
+import (
+	"TrainerConnect/internal/metrics"
+	"context"
+	"errors"
+)
+
+// MetricReader возвращает последнее измерение показателя тела, реализуется metrics.Storage
+type MetricReader interface {
+	Latest(ctx context.Context, userID string, kind metrics.Kind) (*metrics.Entry, error)
+}
+
+// RecordReader возвращает лучший вес клиента в упражнении в килограммах, реализуется workout.Storage
+type RecordReader interface {
+	MaxWeight(ctx context.Context, clientID, exerciseID string) (*float64, error)
+}
+
+// Tracker получает текущее значение цели из журнала тренировок или показателей тела
+type Tracker struct {
+	Metrics MetricReader
+	Records RecordReader
+}
+
+func NewTracker(metrics MetricReader, records RecordReader) *Tracker {
+	return &Tracker{Metrics: metrics, Records: records}
+}
+
+// Current возвращает текущее значение цели в ее единице или nil, если данных еще нет
+func (t *Tracker) Current(ctx context.Context, g *Goal) (*float64, error) {
+	var base float64
+	switch g.Source {
+	case SourceMetric:
+		e, err := t.Metrics.Latest(ctx, g.ClientID, g.MetricKind)
+		if err != nil {
+			if errors.Is(err, metrics.ErrNotFound) {
+				return nil, nil
+			}
+			return nil, err
+		}
+		if base, err = e.Kind.ToBase(e.Value, e.Unit); err != nil {
+			return nil, err
+		}
+	case SourceExercise:
+		max, err := t.Records.MaxWeight(ctx, g.ClientID, g.ExerciseID)
+		if err != nil || max == nil {
+			return nil, err
+		}
+		base = *max
+	default:
+		return nil, errors.New("unknown goal source")
+	}
+
+	value, err := g.kind().FromBase(base, g.Unit)
+	if err != nil {
+		return nil, err
+	}
+	return &value, nil
+}
@@ -0,0 +1,255 @@
+package goal
+
+import (
+	"TrainerConnect/internal/apperr"
+	postgres "TrainerConnect/pkg/postgresql"
+	"context"
+	"database/sql"
+	"github.com/lib/pq"
+	"time"
+)
+
+var (
+	ErrNotFound         = apperr.NotFound("goal_not_found", "goal not found")
+	ErrExerciseNotFound = apperr.Invalid("exercise_not_found", "exercise not found")
+	// ErrAbandoned возвращается при попытке изменить или повторно бросить брошенную цель
+	ErrAbandoned = apperr.Conflict("goal_abandoned", "goal is abandoned")
+)
+
+type Storage struct {
+	*sql.DB
+}
+
+func NewStorage(db *sql.DB) *Storage {
+	return &Storage{DB: db}
+}
+
+const goalColumns = `id, client_id, created_by, title, source, COALESCE(metric_kind, ''), COALESCE(exercise_id::text, ''),
+	unit, baseline, target, start_date, deadline, status, status_changed_at, abandoned_at`
+
+func scanGoal(row interface{ Scan(...interface{}) error }) (*Goal, error) {
+	g := &Goal{}
+	err := row.Scan(&g.ID, &g.ClientID, &g.CreatedBy, &g.Title, &g.Source, &g.MetricKind, &g.ExerciseID,
+		&g.Unit, &g.Baseline, &g.Target, &g.StartDate, &g.Deadline, &g.Status, &g.StatusChangedAt, &g.AbandonedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return g, nil
+}
+
+// nullString превращает пустую строку в NULL
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// Create сохраняет цель вместе с вехами
+func (s *Storage) Create(ctx context.Context, g *Goal) (err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, `INSERT INTO goals (client_id, created_by, title, source, metric_kind, exercise_id, unit,
+			baseline, target, start_date, deadline, status, status_changed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13) RETURNING id`,
+		g.ClientID, g.CreatedBy, g.Title, g.Source, nullString(string(g.MetricKind)), nullString(g.ExerciseID), g.Unit,
+		g.Baseline, g.Target, g.StartDate, g.Deadline, g.Status, g.StatusChangedAt).Scan(&g.ID)
+	if err != nil {
+		if apperr.IsForeignKeyViolation(err) {
+			return ErrExerciseNotFound
+		}
+		return err
+	}
+	if err := insertMilestones(ctx, tx, g); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func insertMilestones(ctx context.Context, tx *sql.Tx, g *Goal) error {
+	for i := range g.Milestones {
+		m := &g.Milestones[i]
+		err := tx.QueryRowContext(ctx, `INSERT INTO goal_milestones (goal_id, title, target, due_date, reached_at)
+			VALUES ($1, $2, $3, $4, $5) RETURNING id`, g.ID, m.Title, m.Target, m.DueDate, m.ReachedAt).Scan(&m.ID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Get возвращает цель вместе с вехами
+func (s *Storage) Get(ctx context.Context, id string) (_ *Goal, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	g, err := scanGoal(s.DB.QueryRowContext(ctx, "SELECT "+goalColumns+" FROM goals WHERE id = $1", id))
+	if err != nil {
+		return nil, err
+	}
+	if err := s.loadMilestones(ctx, []*Goal{g}); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// loadMilestones загружает вехи для набора целей одним запросом
+func (s *Storage) loadMilestones(ctx context.Context, goals []*Goal) error {
+	byID := make(map[string]*Goal, len(goals))
+	ids := make([]string, 0, len(goals))
+	for _, g := range goals {
+		g.Milestones = []Milestone{}
+		byID[g.ID] = g
+		ids = append(ids, g.ID)
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	rows, err := s.DB.QueryContext(ctx, `SELECT id, goal_id, title, target, due_date, reached_at FROM goal_milestones
+		WHERE goal_id = ANY($1::int[]) ORDER BY goal_id, target, id`, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var m Milestone
+		var goalID string
+		if err := rows.Scan(&m.ID, &goalID, &m.Title, &m.Target, &m.DueDate, &m.ReachedAt); err != nil {
+			return err
+		}
+		g := byID[goalID]
+		g.Milestones = append(g.Milestones, m)
+	}
+	return rows.Err()
+}
+
+// List возвращает цели клиента clientID. Если clientID пуст, возвращаются цели всех клиентов
+// тренера trainerID, с которыми у него действующие отношения.
+func (s *Storage) List(ctx context.Context, clientID, trainerID string) (_ []*Goal, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	var rows *sql.Rows
+	if clientID != "" {
+		rows, err = s.DB.QueryContext(ctx, "SELECT "+goalColumns+" FROM goals WHERE client_id = $1 ORDER BY deadline, id", clientID)
+	} else {
+		rows, err = s.DB.QueryContext(ctx, "SELECT "+goalColumns+` FROM goals
+			WHERE client_id IN (SELECT client_id FROM trainer_clients
+				WHERE trainer_id = $1 AND status IN ('active', 'paused'))
+			ORDER BY deadline, id`, trainerID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	goals := []*Goal{}
+	for rows.Next() {
+		g, err := scanGoal(rows)
+		if err != nil {
+			return nil, err
+		}
+		goals = append(goals, g)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	if err := s.loadMilestones(ctx, goals); err != nil {
+		return nil, err
+	}
+	return goals, nil
+}
+
+// Update меняет условия цели и заменяет вехи. Источник цели изменить нельзя.
+func (s *Storage) Update(ctx context.Context, g *Goal) (err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `UPDATE goals SET title = $1, unit = $2, baseline = $3, target = $4, deadline = $5,
+			status = $6, status_changed_at = $7
+		WHERE id = $8`, g.Title, g.Unit, g.Baseline, g.Target, g.Deadline, g.Status, g.StatusChangedAt, g.ID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM goal_milestones WHERE goal_id = $1", g.ID); err != nil {
+		return err
+	}
+	if err := insertMilestones(ctx, tx, g); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SaveEvaluation сохраняет вычисленный статус и отметки о достижении вех
+func (s *Storage) SaveEvaluation(ctx context.Context, g *Goal) (err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, "UPDATE goals SET status = $1, status_changed_at = $2 WHERE id = $3",
+		g.Status, g.StatusChangedAt, g.ID)
+	if err != nil {
+		return err
+	}
+	for _, m := range g.Milestones {
+		if m.ReachedAt == nil {
+			continue
+		}
+		_, err := tx.ExecContext(ctx, "UPDATE goal_milestones SET reached_at = $1 WHERE id = $2 AND reached_at IS NULL",
+			m.ReachedAt, m.ID)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Abandon отмечает цель брошенной
+func (s *Storage) Abandon(ctx context.Context, id string, at time.Time) (err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	res, err := s.DB.ExecContext(ctx, `UPDATE goals SET abandoned_at = $1, status = $2, status_changed_at = $1
+		WHERE id = $3 AND abandoned_at IS NULL`, at, StatusAbandoned, id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -1,9 +1,9 @@
 package handlers
 
 import (
-	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/v5"
 )
 
 type Handler interface {
-	Register(router *chi.Router)
+	Register(router *chi.Mux)
 }
@@ -0,0 +1,211 @@
+package invoice
+
+import (
+	"TrainerConnect/internal/apperr"
+	"TrainerConnect/internal/auth"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	invoiceURL  = "/invoices/"
+	settingsURL = "/trainers/{id}/invoice-settings"
+)
+
+type Handler struct {
+	Storage *Storage
+}
+
+func NewHandler(storage *Storage) *Handler {
+	return &Handler{Storage: storage}
+}
+
+func (h *Handler) Register(router *chi.Mux) {
+	router.Group(func(r chi.Router) {
+		r.Use(auth.RequireAuth)
+		r.Get("/invoices", h.List)
+		r.Get(invoiceURL+"{id}", h.Get)
+		r.Get("/trainers/{id}/invoices/export", h.Export)
+		r.Get(settingsURL, h.GetSettings)
+		r.Put(settingsURL, h.UpdateSettings)
+	})
+}
+
+// monthRange возвращает месяц из параметра month или текущий месяц
+func monthRange(r *http.Request) (time.Time, time.Time, string, error) {
+	month := r.URL.Query().Get("month")
+	if month == "" {
+		month = time.Now().UTC().Format("2006-01")
+	}
+	from, to, err := ParseMonth(month)
+	return from, to, month, err
+}
+
+// List возвращает счета текущего пользователя за месяц month (YYYY-MM, по умолчанию текущий)
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	from, to, _, err := monthRange(r)
+	if err != nil {
+		apperr.Write(w, err)
+		return
+	}
+	principal, _ := auth.PrincipalFromContext(r.Context())
+
+	invoices, err := h.Storage.ListForUser(r.Context(), principal.UserID, from, to)
+	if err != nil {
+		log.Printf("Error listing invoices of user %s: %v", principal.UserID, err)
+		apperr.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(invoices)
+}
+
+// wantsHTML сообщает, что клиент запросил документ, а не JSON: параметром format=html
+// или заголовком Accept, как при открытии ссылки в браузере
+func wantsHTML(r *http.Request) bool {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return format == "html"
+	}
+	return strings.Contains(r.Header.Get("Accept"), "text/html")
+}
+
+// Get возвращает счет покупателю, тренеру или администратору в JSON или HTML
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if _, err := strconv.Atoi(id); err != nil {
+		apperr.Respond(w, http.StatusBadRequest, "Invalid invoice ID")
+		return
+	}
+
+	inv, err := h.Storage.Get(r.Context(), id)
+	if err != nil {
+		if !apperr.Expected(err) {
+			log.Printf("Error getting invoice %s: %v", id, err)
+		}
+		apperr.Write(w, err)
+		return
+	}
+
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	if principal.UserID != inv.ClientID && principal.UserID != inv.TrainerID && !principal.IsAdmin() {
+		apperr.Write(w, ErrNotFound)
+		return
+	}
+
+	if !wantsHTML(r) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(inv)
+		return
+	}
+
+	// Документ собирается в буфер, чтобы ошибка шаблона не оставила ответ обрезанным
+	var buf bytes.Buffer
+	if err := RenderHTML(&buf, inv); err != nil {
+		log.Printf("Error rendering invoice %s: %v", id, err)
+		apperr.Write(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	buf.WriteTo(w)
+}
+
+// canManage — счета и реквизиты тренера доступны ему самому и администратору
+func canManage(w http.ResponseWriter, r *http.Request) (string, bool) {
+	trainerID := chi.URLParam(r, "id")
+	if _, err := strconv.Atoi(trainerID); err != nil {
+		apperr.Respond(w, http.StatusBadRequest, "Invalid trainer ID")
+		return "", false
+	}
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	if principal.UserID != trainerID && !principal.IsAdmin() {
+		apperr.Respond(w, http.StatusForbidden, "Forbidden")
+		return "", false
+	}
+	return trainerID, true
+}
+
+// Export выгружает счета тренера за месяц month (YYYY-MM, по умолчанию текущий) в CSV
+func (h *Handler) Export(w http.ResponseWriter, r *http.Request) {
+	trainerID, ok := canManage(w, r)
+	if !ok {
+		return
+	}
+	from, to, month, err := monthRange(r)
+	if err != nil {
+		apperr.Write(w, err)
+		return
+	}
+
+	invoices, err := h.Storage.ListForTrainer(r.Context(), trainerID, from, to)
+	if err != nil {
+		log.Printf("Error listing invoices of trainer %s: %v", trainerID, err)
+		apperr.Write(w, err)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, invoices); err != nil {
+		log.Printf("Error writing invoices of trainer %s: %v", trainerID, err)
+		apperr.Write(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="invoices-%s-%s.csv"`, trainerID, month))
+	buf.WriteTo(w)
+}
+
+// GetSettings возвращает реквизиты тренера для счетов
+func (h *Handler) GetSettings(w http.ResponseWriter, r *http.Request) {
+	trainerID, ok := canManage(w, r)
+	if !ok {
+		return
+	}
+
+	settings, err := h.Storage.GetSettings(r.Context(), trainerID)
+	if err != nil {
+		log.Printf("Error getting invoice settings of trainer %s: %v", trainerID, err)
+		apperr.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(settings)
+}
+
+// UpdateSettings сохраняет реквизиты тренера; они применяются к следующим счетам
+func (h *Handler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	trainerID, ok := canManage(w, r)
+	if !ok {
+		return
+	}
+
+	var settings Settings
+	if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+		apperr.Respond(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	settings.TrainerID = trainerID
+	if err := settings.Validate(); err != nil {
+		apperr.Write(w, err)
+		return
+	}
+
+	if err := h.Storage.SaveSettings(r.Context(), settings); err != nil {
+		if !apperr.Expected(err) {
+			log.Printf("Error saving invoice settings of trainer %s: %v", trainerID, err)
+		}
+		apperr.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(settings)
+}
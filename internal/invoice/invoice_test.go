@@ -0,0 +1,95 @@
+package invoice_test
+
+import (
+	"TrainerConnect/internal/invoice"
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSetTotals(t *testing.T) {
+	// Налог 20% выделяется из цены: 2700.00 = 2250.00 + 450.00
+	inv := &invoice.Invoice{TaxRate: 2000, Lines: []invoice.Line{{Description: "10 занятий", Quantity: 1, UnitPrice: 270000}}}
+	inv.SetTotals()
+	assert.Equal(t, int64(270000), inv.Total)
+	assert.Equal(t, int64(45000), inv.Tax)
+	assert.Equal(t, int64(225000), inv.Subtotal)
+	assert.Equal(t, int64(270000), inv.Lines[0].Amount)
+
+	// Налог округляется до минимальной единицы: 10.00 при 7% — 0.654 -> 0.65
+	inv = &invoice.Invoice{TaxRate: 700, Lines: []invoice.Line{{Quantity: 2, UnitPrice: 500}}}
+	inv.SetTotals()
+	assert.Equal(t, int64(1000), inv.Total)
+	assert.Equal(t, int64(65), inv.Tax)
+	assert.Equal(t, int64(935), inv.Subtotal)
+
+	// Без налога
+	inv = &invoice.Invoice{Lines: []invoice.Line{{Quantity: 1, UnitPrice: 300000}}}
+	inv.SetTotals()
+	assert.Equal(t, int64(0), inv.Tax)
+	assert.Equal(t, inv.Total, inv.Subtotal)
+}
+
+func TestFormatting(t *testing.T) {
+	assert.Equal(t, "15-000042", invoice.FormatNumber("15", 42))
+	assert.Equal(t, "15-1234567", invoice.FormatNumber("15", 1234567))
+
+	assert.Equal(t, "2700.05 RUB", invoice.FormatAmount(270005, "RUB"))
+	assert.Equal(t, "0.07 EUR", invoice.FormatAmount(7, "EUR"))
+	assert.Equal(t, "-1.50 USD", invoice.FormatAmount(-150, "USD"))
+	assert.Equal(t, "5000 JPY", invoice.FormatAmount(5000, "JPY"))
+
+	assert.Equal(t, "20%", invoice.FormatRate(2000))
+	assert.Equal(t, "6.5%", invoice.FormatRate(650))
+	assert.Equal(t, "0.05%", invoice.FormatRate(5))
+}
+
+func TestParseMonth(t *testing.T) {
+	from, to, err := invoice.ParseMonth("2024-12")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC), from)
+	assert.Equal(t, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), to)
+
+	_, _, err = invoice.ParseMonth("12.2024")
+	assert.Error(t, err)
+}
+
+func sampleInvoice() *invoice.Invoice {
+	inv := &invoice.Invoice{
+		ID: "1", Number: "15-000001", Currency: "RUB",
+		Seller:   invoice.Party{Name: "ИП Иванов", TaxID: "7701234567"},
+		Buyer:    invoice.Party{Name: "Anna <Smith>", Email: "anna@example.com"},
+		TaxName:  "VAT",
+		TaxRate:  2000,
+		Lines:    []invoice.Line{{Description: "10 занятий, по цене 9", Quantity: 1, UnitPrice: 270000}},
+		IssuedAt: time.Date(2024, 5, 3, 10, 0, 0, 0, time.UTC),
+	}
+	inv.SetTotals()
+	return inv
+}
+
+func TestRenderHTML(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, invoice.RenderHTML(&buf, sampleInvoice()))
+	html := buf.String()
+
+	assert.Contains(t, html, "Invoice 15-000001")
+	assert.Contains(t, html, "2700.00 RUB")
+	assert.Contains(t, html, "VAT 20% (included)")
+	assert.Contains(t, html, "450.00 RUB")
+	// Данные пользователей экранируются
+	assert.Contains(t, html, "Anna &lt;Smith&gt;")
+}
+
+func TestWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, invoice.WriteCSV(&buf, []*invoice.Invoice{sampleInvoice()}))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.Equal(t, "number,issued_at,buyer_name,buyer_email,description,currency,subtotal,tax_name,tax_rate,tax,total", lines[0])
+	assert.Equal(t, `15-000001,2024-05-03T10:00:00Z,Anna <Smith>,anna@example.com,"10 занятий, по цене 9",RUB,2250.00,VAT,20,450.00,2700.00`, lines[1])
+}
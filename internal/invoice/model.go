@@ -0,0 +1,147 @@
+package invoice
+
+import (
+	"TrainerConnect/internal/apperr"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Party — реквизиты продавца или покупателя на момент выставления счета
+type Party struct {
+	Name    string `json:"name"`
+	Address string `json:"address,omitempty"`
+	TaxID   string `json:"tax_id,omitempty"`
+	Email   string `json:"email,omitempty"`
+}
+
+// Line — позиция счета. Суммы в минимальных единицах валюты.
+type Line struct {
+	Description string `json:"description"`
+	Quantity    int    `json:"quantity"`
+	UnitPrice   int64  `json:"unit_price"`
+	Amount      int64  `json:"amount"`
+}
+
+// Invoice — счет-квитанция за оплату. После выставления не изменяется.
+type Invoice struct {
+	ID        string `json:"id"`
+	Number    string `json:"number"`
+	TrainerID string `json:"trainer_id"`
+	ClientID  string `json:"client_id"`
+	PaymentID string `json:"payment_id"`
+	Currency  string `json:"currency"`
+	Seller    Party  `json:"seller"`
+	Buyer     Party  `json:"buyer"`
+	Lines     []Line `json:"lines"`
+
+	// Цены включают налог: Total равен оплаченной сумме, Tax выделен из нее по ставке TaxRate
+	Subtotal int64  `json:"subtotal"`
+	TaxName  string `json:"tax_name"`
+	// Ставка налога в сотых долях процента: 2000 — 20%
+	TaxRate int   `json:"tax_rate"`
+	Tax     int64 `json:"tax"`
+	Total   int64 `json:"total"`
+
+	IssuedAt time.Time `json:"issued_at"`
+}
+
+// Settings — реквизиты тренера и налог, которые указываются в его счетах
+type Settings struct {
+	TrainerID string `json:"trainer_id"`
+	LegalName string `json:"legal_name"`
+	Address   string `json:"address"`
+	TaxID     string `json:"tax_id"`
+	TaxName   string `json:"tax_name"`
+	TaxRate   int    `json:"tax_rate"`
+}
+
+// DefaultSettings применяется к тренерам, которые не указали реквизиты: счета без налога
+func DefaultSettings(trainerID string) Settings {
+	return Settings{TrainerID: trainerID, TaxName: "VAT"}
+}
+
+// Validate проверяет реквизиты и ставку налога
+func (s *Settings) Validate() error {
+	s.LegalName = strings.TrimSpace(s.LegalName)
+	s.TaxName = strings.TrimSpace(s.TaxName)
+	if utf8.RuneCountInString(s.LegalName) > 200 || utf8.RuneCountInString(s.Address) > 500 ||
+		utf8.RuneCountInString(s.TaxID) > 50 || utf8.RuneCountInString(s.TaxName) > 20 {
+		return apperr.InvalidField("legal_name", "too_long", "legal details are too long")
+	}
+	if s.TaxRate < 0 || s.TaxRate > 10000 {
+		return apperr.InvalidField("tax_rate", "out_of_range", "tax_rate must be between 0 and 10000 hundredths of a percent")
+	}
+	if s.TaxRate > 0 && s.TaxName == "" {
+		return apperr.InvalidField("tax_name", "required", "tax_name is required when tax_rate is set")
+	}
+	return nil
+}
+
+// SetTotals вычисляет суммы счета по позициям. Налог выделяется из цены с округлением
+// до минимальной единицы валюты, половина округляется вверх.
+func (inv *Invoice) SetTotals() {
+	inv.Total = 0
+	for i := range inv.Lines {
+		l := &inv.Lines[i]
+		l.Amount = l.UnitPrice * int64(l.Quantity)
+		inv.Total += l.Amount
+	}
+	rate := int64(inv.TaxRate)
+	inv.Tax = (2*inv.Total*rate + 10000 + rate) / (2 * (10000 + rate))
+	inv.Subtotal = inv.Total - inv.Tax
+}
+
+// FormatNumber возвращает номер счета: ID тренера и порядковый номер его счета
+func FormatNumber(trainerID string, seq int) string {
+	n := strconv.Itoa(seq)
+	if len(n) < 6 {
+		n = strings.Repeat("0", 6-len(n)) + n
+	}
+	return trainerID + "-" + n
+}
+
+// Валюты без дробных единиц по ISO 4217
+var zeroDecimal = map[string]bool{
+	"BIF": true, "CLP": true, "DJF": true, "GNF": true, "ISK": true, "JPY": true, "KMF": true, "KRW": true,
+	"PYG": true, "RWF": true, "UGX": true, "VND": true, "VUV": true, "XAF": true, "XOF": true, "XPF": true,
+}
+
+// FormatAmount форматирует сумму в минимальных единицах валюты, например "2700.00 RUB"
+func FormatAmount(amount int64, currency string) string {
+	sign := ""
+	if amount < 0 {
+		sign, amount = "-", -amount
+	}
+	if zeroDecimal[currency] {
+		return sign + strconv.FormatInt(amount, 10) + " " + currency
+	}
+	cents := strconv.FormatInt(amount%100, 10)
+	if len(cents) < 2 {
+		cents = "0" + cents
+	}
+	return sign + strconv.FormatInt(amount/100, 10) + "." + cents + " " + currency
+}
+
+// FormatRate форматирует ставку налога в сотых долях процента, например "20%" или "6.5%"
+func FormatRate(rate int) string {
+	s := strconv.Itoa(rate / 100)
+	if frac := rate % 100; frac != 0 {
+		f := strconv.Itoa(frac)
+		if frac < 10 {
+			f = "0" + f
+		}
+		s += "." + strings.TrimRight(f, "0")
+	}
+	return s + "%"
+}
+
+// ParseMonth разбирает месяц в формате 2006-01 и возвращает его границы [from, to) в UTC
+func ParseMonth(s string) (time.Time, time.Time, error) {
+	from, err := time.Parse("2006-01", s)
+	if err != nil {
+		return time.Time{}, time.Time{}, apperr.InvalidField("month", "invalid_month", "month must be in YYYY-MM format")
+	}
+	return from, from.AddDate(0, 1, 0), nil
+}
@@ -0,0 +1,64 @@
+package invoice
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"html/template"
+	"io"
+	"strconv"
+	"strings"
+)
+
+//go:embed invoice.html
+var invoiceHTML string
+
+var invoiceTemplate = template.Must(template.New("invoice").Funcs(template.FuncMap{
+	"amount": FormatAmount,
+	"rate":   FormatRate,
+}).Parse(invoiceHTML))
+
+// RenderHTML выводит счет в виде HTML-документа, пригодного для печати в PDF из браузера
+func RenderHTML(w io.Writer, inv *Invoice) error {
+	return invoiceTemplate.Execute(w, inv)
+}
+
+// csvHeader — столбцы ежемесячной выгрузки счетов
+var csvHeader = []string{"number", "issued_at", "buyer_name", "buyer_email", "description", "currency",
+	"subtotal", "tax_name", "tax_rate", "tax", "total"}
+
+// decimal переводит сумму в минимальных единицах в десятичную запись без кода валюты
+func decimal(amount int64, currency string) string {
+	return strings.TrimSuffix(FormatAmount(amount, currency), " "+currency)
+}
+
+// WriteCSV выгружает счета в CSV: одна строка на счет, суммы в основных единицах валюты
+func WriteCSV(w io.Writer, invoices []*Invoice) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, inv := range invoices {
+		descriptions := make([]string, 0, len(inv.Lines))
+		for _, l := range inv.Lines {
+			descriptions = append(descriptions, l.Description)
+		}
+		record := []string{
+			inv.Number,
+			inv.IssuedAt.UTC().Format("2006-01-02T15:04:05Z"),
+			inv.Buyer.Name,
+			inv.Buyer.Email,
+			strings.Join(descriptions, "; "),
+			inv.Currency,
+			decimal(inv.Subtotal, inv.Currency),
+			inv.TaxName,
+			strconv.FormatFloat(float64(inv.TaxRate)/100, 'f', -1, 64),
+			decimal(inv.Tax, inv.Currency),
+			decimal(inv.Total, inv.Currency),
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,237 @@
+package invoice
+
+import (
+	"TrainerConnect/internal/apperr"
+	"TrainerConnect/internal/payment"
+	postgres "TrainerConnect/pkg/postgresql"
+	"context"
+	"database/sql"
+	"github.com/lib/pq"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotFound        = apperr.NotFound("invoice_not_found", "invoice not found")
+	ErrTrainerNotFound = apperr.NotFound("trainer_not_found", "trainer profile not found")
+)
+
+type Storage struct {
+	*sql.DB
+}
+
+func NewStorage(db *sql.DB) *Storage {
+	return &Storage{DB: db}
+}
+
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+func getSettings(ctx context.Context, q queryRower, trainerID string) (Settings, error) {
+	s := Settings{TrainerID: trainerID}
+	err := q.QueryRowContext(ctx, `SELECT legal_name, address, tax_id, tax_name, tax_rate FROM invoice_settings WHERE trainer_id = $1`,
+		trainerID).Scan(&s.LegalName, &s.Address, &s.TaxID, &s.TaxName, &s.TaxRate)
+	if err == sql.ErrNoRows {
+		return DefaultSettings(trainerID), nil
+	}
+	return s, err
+}
+
+// GetSettings возвращает реквизиты тренера для счетов или настройки по умолчанию
+func (s *Storage) GetSettings(ctx context.Context, trainerID string) (_ Settings, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	return getSettings(ctx, s.DB, trainerID)
+}
+
+// SaveSettings сохраняет реквизиты тренера. Уже выставленные счета не меняются.
+func (s *Storage) SaveSettings(ctx context.Context, settings Settings) (err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	_, err = s.DB.ExecContext(ctx, `INSERT INTO invoice_settings (trainer_id, legal_name, address, tax_id, tax_name, tax_rate)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (trainer_id) DO UPDATE SET legal_name = EXCLUDED.legal_name, address = EXCLUDED.address,
+			tax_id = EXCLUDED.tax_id, tax_name = EXCLUDED.tax_name, tax_rate = EXCLUDED.tax_rate, updated_at = now()`,
+		settings.TrainerID, settings.LegalName, settings.Address, settings.TaxID, settings.TaxName, settings.TaxRate)
+	if apperr.IsForeignKeyViolation(err) {
+		return ErrTrainerNotFound
+	}
+	return err
+}
+
+// Issue выставляет счет за успешную оплату в транзакции, в которой оплата подтверждается.
+// Реквизиты сторон и налог копируются в счет, чтобы их последующее изменение его не затронуло.
+// Реализует payment.Invoicer.
+func (s *Storage) Issue(ctx context.Context, tx *sql.Tx, p *payment.Payment) (err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	settings, err := getSettings(ctx, tx, p.TrainerID)
+	if err != nil {
+		return err
+	}
+
+	inv := &Invoice{
+		TrainerID: p.TrainerID,
+		ClientID:  p.ClientID,
+		PaymentID: p.ID,
+		Currency:  p.Currency,
+		Seller:    Party{Name: settings.LegalName, Address: settings.Address, TaxID: settings.TaxID},
+		TaxName:   settings.TaxName,
+		TaxRate:   settings.TaxRate,
+	}
+	var trainerName string
+	err = tx.QueryRowContext(ctx, `SELECT t.first_name || ' ' || t.last_name, c.first_name || ' ' || c.last_name, c.email
+		FROM users t, users c WHERE t.user_id = $1 AND c.user_id = $2`, p.TrainerID, p.ClientID).
+		Scan(&trainerName, &inv.Buyer.Name, &inv.Buyer.Email)
+	if err != nil {
+		return err
+	}
+	if inv.Seller.Name == "" {
+		inv.Seller.Name = trainerName
+	}
+
+	var description string
+	if err := tx.QueryRowContext(ctx, "SELECT name FROM products WHERE id = $1", p.ProductID).Scan(&description); err != nil {
+		return err
+	}
+	inv.Lines = []Line{{Description: description, Quantity: 1, UnitPrice: p.Amount}}
+	inv.SetTotals()
+
+	var seq int
+	err = tx.QueryRowContext(ctx, `INSERT INTO invoice_counters (trainer_id, last_number) VALUES ($1, 1)
+		ON CONFLICT (trainer_id) DO UPDATE SET last_number = invoice_counters.last_number + 1
+		RETURNING last_number`, p.TrainerID).Scan(&seq)
+	if err != nil {
+		return err
+	}
+	inv.Number = FormatNumber(p.TrainerID, seq)
+
+	err = tx.QueryRowContext(ctx, `INSERT INTO invoices (trainer_id, client_id, payment_id, seq, number, currency,
+			seller_name, seller_address, seller_tax_id, buyer_name, buyer_email, subtotal, tax_name, tax_rate, tax, total)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16) RETURNING id`,
+		inv.TrainerID, inv.ClientID, inv.PaymentID, seq, inv.Number, inv.Currency,
+		inv.Seller.Name, inv.Seller.Address, inv.Seller.TaxID, inv.Buyer.Name, inv.Buyer.Email,
+		inv.Subtotal, inv.TaxName, inv.TaxRate, inv.Tax, inv.Total).Scan(&inv.ID)
+	if err != nil {
+		return err
+	}
+
+	for i, l := range inv.Lines {
+		_, err := tx.ExecContext(ctx, `INSERT INTO invoice_lines (invoice_id, position, description, quantity, unit_price, amount)
+			VALUES ($1, $2, $3, $4, $5, $6)`, inv.ID, i+1, l.Description, l.Quantity, l.UnitPrice, l.Amount)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const invoiceColumns = `id, number, trainer_id, client_id, payment_id, currency, seller_name, seller_address, seller_tax_id,
+	buyer_name, buyer_email, subtotal, tax_name, tax_rate, tax, total, issued_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanInvoice(row rowScanner) (*Invoice, error) {
+	inv := &Invoice{}
+	err := row.Scan(&inv.ID, &inv.Number, &inv.TrainerID, &inv.ClientID, &inv.PaymentID, &inv.Currency,
+		&inv.Seller.Name, &inv.Seller.Address, &inv.Seller.TaxID, &inv.Buyer.Name, &inv.Buyer.Email,
+		&inv.Subtotal, &inv.TaxName, &inv.TaxRate, &inv.Tax, &inv.Total, &inv.IssuedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	inv.Currency = strings.TrimSpace(inv.Currency)
+	return inv, nil
+}
+
+// Get возвращает счет с позициями
+func (s *Storage) Get(ctx context.Context, id string) (_ *Invoice, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	inv, err := scanInvoice(s.DB.QueryRowContext(ctx, "SELECT "+invoiceColumns+" FROM invoices WHERE id = $1", id))
+	if err != nil {
+		return nil, err
+	}
+	if err := s.loadLines(ctx, []*Invoice{inv}); err != nil {
+		return nil, err
+	}
+	return inv, nil
+}
+
+// ListForTrainer возвращает счета тренера, выставленные в интервале [from, to), по порядку номеров
+func (s *Storage) ListForTrainer(ctx context.Context, trainerID string, from, to time.Time) (_ []*Invoice, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	return s.list(ctx, "trainer_id = $1", trainerID, from, to)
+}
+
+// ListForUser возвращает счета, в которых пользователь — покупатель или продавец
+func (s *Storage) ListForUser(ctx context.Context, userID string, from, to time.Time) (_ []*Invoice, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	return s.list(ctx, "(trainer_id = $1 OR client_id = $1)", userID, from, to)
+}
+
+func (s *Storage) list(ctx context.Context, where, userID string, from, to time.Time) ([]*Invoice, error) {
+	rows, err := s.DB.QueryContext(ctx, "SELECT "+invoiceColumns+" FROM invoices WHERE "+where+`
+		AND issued_at >= $2 AND issued_at < $3 ORDER BY issued_at, id`, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invoices := []*Invoice{}
+	for rows.Next() {
+		inv, err := scanInvoice(rows)
+		if err != nil {
+			return nil, err
+		}
+		invoices = append(invoices, inv)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return invoices, s.loadLines(ctx, invoices)
+}
+
+// loadLines загружает позиции счетов одним запросом
+func (s *Storage) loadLines(ctx context.Context, invoices []*Invoice) error {
+	if len(invoices) == 0 {
+		return nil
+	}
+	byID := make(map[string]*Invoice, len(invoices))
+	ids := make([]string, 0, len(invoices))
+	for _, inv := range invoices {
+		inv.Lines = []Line{}
+		byID[inv.ID] = inv
+		ids = append(ids, inv.ID)
+	}
+
+	rows, err := s.DB.QueryContext(ctx, `SELECT invoice_id, description, quantity, unit_price, amount FROM invoice_lines
+		WHERE invoice_id = ANY($1::integer[]) ORDER BY invoice_id, position`, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id string
+		var l Line
+		if err := rows.Scan(&id, &l.Description, &l.Quantity, &l.UnitPrice, &l.Amount); err != nil {
+			return err
+		}
+		byID[id].Lines = append(byID[id].Lines, l)
+	}
+	return rows.Err()
+}
@@ -0,0 +1,91 @@
+package jobs
+
+import (
+	"TrainerConnect/internal/apperr"
+	"TrainerConnect/internal/auth"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+const listLimit = 100
+
+type Handler struct {
+	Storage *Storage
+}
+
+// NewHandler создает обработчик для разбора очереди. Доступен только администраторам.
+func NewHandler(storage *Storage) *Handler {
+	return &Handler{Storage: storage}
+}
+
+func (h *Handler) Register(router *chi.Mux) {
+	router.Group(func(r chi.Router) {
+		r.Use(auth.RequireAuth)
+		r.Get("/jobs", h.List)
+		r.Post("/jobs/{id}/retry", h.Retry)
+	})
+}
+
+// admin проверяет, что запрос выполняет администратор
+func admin(w http.ResponseWriter, r *http.Request) bool {
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	if !principal.IsAdmin() {
+		apperr.Respond(w, http.StatusForbidden, "Forbidden")
+		return false
+	}
+	return true
+}
+
+// List возвращает последние задачи в состоянии status, по умолчанию — dead
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	if !admin(w, r) {
+		return
+	}
+
+	status := Status(r.URL.Query().Get("status"))
+	switch status {
+	case "":
+		status = StatusDead
+	case StatusPending, StatusRunning, StatusDone, StatusDead:
+	default:
+		apperr.Respond(w, http.StatusBadRequest, "Invalid status parameter")
+		return
+	}
+
+	jobs, err := h.Storage.List(r.Context(), status, listLimit)
+	if err != nil {
+		log.Printf("Error listing jobs: %v", err)
+		apperr.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(jobs)
+}
+
+// Retry возвращает задачу из dead в очередь
+func (h *Handler) Retry(w http.ResponseWriter, r *http.Request) {
+	if !admin(w, r) {
+		return
+	}
+	id := chi.URLParam(r, "id")
+	if _, err := strconv.Atoi(id); err != nil {
+		apperr.Respond(w, http.StatusBadRequest, "Invalid job ID")
+		return
+	}
+
+	j, err := h.Storage.Retry(r.Context(), id)
+	if err != nil {
+		if !apperr.Expected(err) {
+			log.Printf("Error retrying job %s: %v", id, err)
+		}
+		apperr.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(j)
+}
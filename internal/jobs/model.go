@@ -0,0 +1,50 @@
+package jobs
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Status — состояние фоновой задачи
+type Status string
+
+const (
+	StatusPending Status = "pending"
+	StatusRunning Status = "running"
+	StatusDone    Status = "done"
+	// StatusDead — задача исчерпала попытки и ждет ручного разбора
+	StatusDead Status = "dead"
+)
+
+// Job — фоновая задача в очереди
+type Job struct {
+	ID          string          `json:"id"`
+	Kind        string          `json:"kind"`
+	Payload     json.RawMessage `json:"payload"`
+	Status      Status          `json:"status"`
+	RunAt       time.Time       `json:"run_at"`
+	Attempts    int             `json:"attempts"`
+	MaxAttempts int             `json:"max_attempts"`
+	LastError   string          `json:"last_error,omitempty"`
+	DedupeKey   *string         `json:"dedupe_key,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+const (
+	minBackoff = 30 * time.Second
+	maxBackoff = time.Hour
+)
+
+// Backoff возвращает паузу перед повтором после attempts неудачных попыток:
+// 30 секунд, затем вдвое больше после каждой попытки, но не больше часа
+func Backoff(attempts int) time.Duration {
+	d := minBackoff
+	for i := 1; i < attempts && d < maxBackoff; i++ {
+		d *= 2
+	}
+	if d > maxBackoff {
+		d = maxBackoff
+	}
+	return d
+}
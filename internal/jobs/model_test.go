@@ -0,0 +1,15 @@
+package jobs_test
+
+import (
+	"TrainerConnect/internal/jobs"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, 30*time.Second, jobs.Backoff(1))
+	assert.Equal(t, time.Minute, jobs.Backoff(2))
+	assert.Equal(t, 4*time.Minute, jobs.Backoff(4))
+	assert.Equal(t, time.Hour, jobs.Backoff(20))
+}
@@ -0,0 +1,136 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// HandlerFunc выполняет задачу. Ошибка означает, что задачу нужно повторить позже.
+type HandlerFunc func(ctx context.Context, payload json.RawMessage) error
+
+type periodicTask struct {
+	name     string
+	interval time.Duration
+	fn       func(ctx context.Context) error
+}
+
+// Runner забирает задачи из очереди и выполняет их обработчиками по виду задачи.
+// Несколько экземпляров сервера могут работать с одной очередью одновременно.
+type Runner struct {
+	Storage      *Storage
+	PollInterval time.Duration
+	BatchSize    int
+	// Lease — сколько времени дается на выполнение задачи, прежде чем ее заберет другой экземпляр
+	Lease time.Duration
+
+	handlers map[string]HandlerFunc
+	periodic []periodicTask
+}
+
+// NewRunner создает обработчик очереди с опросом раз в секунду
+func NewRunner(storage *Storage) *Runner {
+	return &Runner{
+		Storage:      storage,
+		PollInterval: time.Second,
+		BatchSize:    10,
+		Lease:        5 * time.Minute,
+		handlers:     make(map[string]HandlerFunc),
+	}
+}
+
+// Handle регистрирует обработчик задач вида kind. Вызывается до Run.
+func (r *Runner) Handle(kind string, fn HandlerFunc) {
+	r.handlers[kind] = fn
+}
+
+// Every регистрирует периодическое действие, которое выполняется в каждом экземпляре
+// сервера. Оно должно быть идемпотентным, например планировать задачи с dedupe-ключом.
+func (r *Runner) Every(interval time.Duration, name string, fn func(ctx context.Context) error) {
+	r.periodic = append(r.periodic, periodicTask{name: name, interval: interval, fn: fn})
+}
+
+// Run обрабатывает очередь и периодические действия, пока не отменен ctx
+func (r *Runner) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, task := range r.periodic {
+		wg.Add(1)
+		go func(task periodicTask) {
+			defer wg.Done()
+			r.runPeriodic(ctx, task)
+		}(task)
+	}
+
+	ticker := time.NewTicker(r.PollInterval)
+	defer ticker.Stop()
+	for ctx.Err() == nil {
+		// Пока очередь не пуста, забираем следующую пачку без паузы
+		if r.poll(ctx) == r.BatchSize {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+		case <-ticker.C:
+		}
+	}
+	wg.Wait()
+}
+
+func (r *Runner) runPeriodic(ctx context.Context, task periodicTask) {
+	ticker := time.NewTicker(task.interval)
+	defer ticker.Stop()
+	for {
+		if err := task.fn(ctx); err != nil {
+			log.Printf("Error running periodic task %s: %v", task.name, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll забирает и выполняет одну пачку задач, возвращает их число
+func (r *Runner) poll(ctx context.Context) int {
+	jobs, err := r.Storage.Claim(ctx, r.BatchSize, r.Lease)
+	if err != nil {
+		log.Printf("Error claiming jobs: %v", err)
+		return 0
+	}
+
+	for _, j := range jobs {
+		if err := r.execute(ctx, j); err != nil {
+			log.Printf("Job %s (%s) failed on attempt %d/%d: %v", j.ID, j.Kind, j.Attempts, j.MaxAttempts, err)
+			if err := r.Storage.Fail(ctx, j, err); err != nil {
+				log.Printf("Error recording failure of job %s: %v", j.ID, err)
+			}
+			continue
+		}
+		if err := r.Storage.Complete(ctx, j.ID); err != nil {
+			log.Printf("Error completing job %s: %v", j.ID, err)
+		}
+	}
+	return len(jobs)
+}
+
+// execute выполняет задачу в пределах аренды. Паника обработчика считается ошибкой попытки.
+func (r *Runner) execute(ctx context.Context, j *Job) (err error) {
+	fn, ok := r.handlers[j.Kind]
+	if !ok {
+		return fmt.Errorf("no handler for job kind %q", j.Kind)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, r.Lease)
+	defer cancel()
+	return fn(ctx, j.Payload)
+}
@@ -0,0 +1,155 @@
+package jobs
+
+import (
+	"TrainerConnect/internal/apperr"
+	postgres "TrainerConnect/pkg/postgresql"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+var ErrNotFound = apperr.NotFound("job_not_found", "job not found")
+
+type Storage struct {
+	*sql.DB
+}
+
+func NewStorage(db *sql.DB) *Storage {
+	return &Storage{DB: db}
+}
+
+const jobColumns = `id, kind, payload, status, run_at, attempts, max_attempts, COALESCE(last_error, ''),
+	dedupe_key, created_at, updated_at`
+
+func scanJob(row interface{ Scan(...interface{}) error }) (*Job, error) {
+	j := &Job{}
+	var payload []byte
+	err := row.Scan(&j.ID, &j.Kind, &payload, &j.Status, &j.RunAt, &j.Attempts, &j.MaxAttempts, &j.LastError,
+		&j.DedupeKey, &j.CreatedAt, &j.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	j.Payload = payload
+	return j, nil
+}
+
+// Enqueue ставит задачу в очередь на момент runAt. Если dedupeKey не пуст и задача с таким
+// ключом уже есть, новая не создается и возвращается false — так несколько экземпляров
+// сервера могут планировать одну и ту же задачу без дубликатов.
+func (s *Storage) Enqueue(ctx context.Context, kind string, payload interface{}, runAt time.Time, maxAttempts int, dedupeKey string) (_ bool, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return false, err
+	}
+	var key sql.NullString
+	if dedupeKey != "" {
+		key = sql.NullString{String: dedupeKey, Valid: true}
+	}
+
+	res, err := s.DB.ExecContext(ctx, `INSERT INTO jobs (kind, payload, run_at, max_attempts, dedupe_key)
+		VALUES ($1, $2, $3, $4, $5) ON CONFLICT (dedupe_key) DO NOTHING`, kind, data, runAt, maxAttempts, key)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// Claim забирает до limit готовых к запуску задач и продлевает их аренду на lease.
+// Строки, заблокированные другими экземплярами, пропускаются (SKIP LOCKED), поэтому
+// одну задачу не выполнят дважды. Задачи в состоянии running с истекшей арендой
+// считаются брошенными упавшим экземпляром и забираются повторно.
+func (s *Storage) Claim(ctx context.Context, limit int, lease time.Duration) (_ []*Job, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	rows, err := s.DB.QueryContext(ctx, `UPDATE jobs SET status = 'running', attempts = attempts + 1,
+			locked_until = now() + $2 * interval '1 second', updated_at = now()
+		WHERE id IN (
+			SELECT id FROM jobs
+			WHERE (status = 'pending' AND run_at <= now()) OR (status = 'running' AND locked_until < now())
+			ORDER BY run_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+jobColumns, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := []*Job{}
+	for rows.Next() {
+		j, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, j)
+	}
+	return jobs, rows.Err()
+}
+
+// Complete отмечает задачу выполненной
+func (s *Storage) Complete(ctx context.Context, id string) (err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	_, err = s.DB.ExecContext(ctx, `UPDATE jobs SET status = 'done', locked_until = NULL, last_error = NULL, updated_at = now()
+		WHERE id = $1`, id)
+	return err
+}
+
+// Fail записывает ошибку попытки. Задача возвращается в очередь через Backoff
+// или, если попытки исчерпаны, переводится в dead.
+func (s *Storage) Fail(ctx context.Context, j *Job, cause error) (err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	status, runAt := StatusPending, time.Now().Add(Backoff(j.Attempts))
+	if j.Attempts >= j.MaxAttempts {
+		status, runAt = StatusDead, j.RunAt
+	}
+	_, err = s.DB.ExecContext(ctx, `UPDATE jobs SET status = $1, run_at = $2, last_error = $3, locked_until = NULL, updated_at = now()
+		WHERE id = $4`, status, runAt, cause.Error(), j.ID)
+	return err
+}
+
+// List возвращает последние задачи в состоянии status
+func (s *Storage) List(ctx context.Context, status Status, limit int) (_ []*Job, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	rows, err := s.DB.QueryContext(ctx, "SELECT "+jobColumns+` FROM jobs WHERE status = $1
+		ORDER BY updated_at DESC LIMIT $2`, status, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := []*Job{}
+	for rows.Next() {
+		j, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, j)
+	}
+	return jobs, rows.Err()
+}
+
+// Retry возвращает задачу из dead в очередь с обнуленным счетчиком попыток
+func (s *Storage) Retry(ctx context.Context, id string) (_ *Job, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	return scanJob(s.DB.QueryRowContext(ctx, `UPDATE jobs SET status = 'pending', attempts = 0, run_at = now(), updated_at = now()
+		WHERE id = $1 AND status = 'dead'
+		RETURNING `+jobColumns, id))
+}
@@ -0,0 +1,317 @@
+package metrics
+
+import (
+	"TrainerConnect/internal/apperr"
+	"TrainerConnect/internal/auth"
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	metricsURL = "/users/{id}/metrics"
+	goalsURL   = metricsURL + "/goals"
+
+	// defaultRange — период выборки по умолчанию, если from и to не указаны
+	defaultRange = 90 * 24 * time.Hour
+	maxRange     = 2 * 366 * 24 * time.Hour
+)
+
+type Handler struct {
+	Storage *Storage
+	Policy  *auth.Policy
+}
+
+// NewHandler создает обработчик показателей тела. Показатели видят только сам пользователь
+// и его тренеры из roster.
+func NewHandler(storage *Storage, policy *auth.Policy) *Handler {
+	return &Handler{Storage: storage, Policy: policy}
+}
+
+func (h *Handler) Register(router *chi.Mux) {
+	router.Group(func(r chi.Router) {
+		r.Use(auth.RequireAuth)
+		r.Get(metricsURL, h.ListEntries)
+		r.Post(metricsURL, h.AddEntry)
+		r.Delete(metricsURL+"/{entryID}", h.DeleteEntry)
+		r.Get(metricsURL+"/{kind}/series", h.GetSeries)
+
+		r.Get(goalsURL, h.ListGoals)
+		r.Put(goalsURL+"/{kind}", h.SaveGoal)
+		r.Delete(goalsURL+"/{kind}", h.DeleteGoal)
+	})
+}
+
+// access читает ID пользователя из URL и проверяет доступ к его показателям
+func (h *Handler) access(w http.ResponseWriter, r *http.Request) (string, bool) {
+	userID := chi.URLParam(r, "id")
+	if _, err := strconv.Atoi(userID); err != nil {
+		apperr.Respond(w, http.StatusBadRequest, "Invalid user ID")
+		return "", false
+	}
+
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	allowed, err := h.Policy.CanReadHealthData(r.Context(), principal, userID)
+	if err != nil {
+		log.Printf("Error checking access to metrics of user %s: %v", userID, err)
+		apperr.Write(w, err)
+		return "", false
+	}
+	if !allowed {
+		apperr.Respond(w, http.StatusForbidden, "Forbidden")
+		return "", false
+	}
+	return userID, true
+}
+
+// owner проверяет, что изменять измерения пытается сам пользователь
+func owner(w http.ResponseWriter, r *http.Request, userID string) bool {
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	if principal.UserID != userID {
+		apperr.Respond(w, http.StatusForbidden, "Forbidden")
+		return false
+	}
+	return true
+}
+
+// parseRange читает интервал from/to из запроса. По умолчанию — последние 90 дней.
+func parseRange(r *http.Request) (time.Time, time.Time, error) {
+	q := r.URL.Query()
+	to := time.Now().UTC()
+	if v := q.Get("to"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return time.Time{}, time.Time{}, apperr.InvalidField("to", "invalid_time", "must be an RFC 3339 time")
+		}
+		to = t
+	}
+	from := to.Add(-defaultRange)
+	if v := q.Get("from"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return time.Time{}, time.Time{}, apperr.InvalidField("from", "invalid_time", "must be an RFC 3339 time")
+		}
+		from = t
+	}
+	if !to.After(from) || to.Sub(from) > maxRange {
+		return time.Time{}, time.Time{}, apperr.InvalidField("to", "invalid_range", "range must be positive and not longer than two years")
+	}
+	return from, to, nil
+}
+
+// ListEntries возвращает измерения пользователя за период, параметр kind фильтрует по показателю
+func (h *Handler) ListEntries(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.access(w, r)
+	if !ok {
+		return
+	}
+
+	kind := Kind(r.URL.Query().Get("kind"))
+	if kind != "" {
+		if _, err := kind.BaseUnit(); err != nil {
+			apperr.Write(w, err)
+			return
+		}
+	}
+	from, to, err := parseRange(r)
+	if err != nil {
+		apperr.Write(w, err)
+		return
+	}
+
+	entries, err := h.Storage.List(r.Context(), userID, kind, from, to)
+	if err != nil {
+		log.Printf("Error listing metrics of user %s: %v", userID, err)
+		apperr.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
+
+// AddEntry сохраняет измерение. Записывать показатели может только сам пользователь.
+func (h *Handler) AddEntry(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.access(w, r)
+	if !ok || !owner(w, r, userID) {
+		return
+	}
+
+	var e Entry
+	if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+		apperr.Respond(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := e.Validate(); err != nil {
+		apperr.Write(w, err)
+		return
+	}
+	if e.MeasuredAt.IsZero() {
+		e.MeasuredAt = time.Now().UTC()
+	}
+	e.ID = ""
+	e.UserID = userID
+
+	if err := h.Storage.Add(r.Context(), &e); err != nil {
+		log.Printf("Error adding metric of user %s: %v", userID, err)
+		apperr.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(e)
+}
+
+// DeleteEntry удаляет ошибочное измерение
+func (h *Handler) DeleteEntry(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.access(w, r)
+	if !ok || !owner(w, r, userID) {
+		return
+	}
+	entryID := chi.URLParam(r, "entryID")
+	if _, err := strconv.Atoi(entryID); err != nil {
+		apperr.Respond(w, http.StatusBadRequest, "Invalid entry ID")
+		return
+	}
+
+	if err := h.Storage.Delete(r.Context(), userID, entryID); err != nil {
+		if !apperr.Expected(err) {
+			log.Printf("Error deleting metric %s: %v", entryID, err)
+		}
+		apperr.Write(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetSeries возвращает ряд показателя для графика. Параметры: resolution (day, week, month),
+// unit (по умолчанию базовая единица показателя), from и to. Если у пользователя есть цель
+// по показателю, она возвращается в той же единице.
+func (h *Handler) GetSeries(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.access(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	kind := Kind(chi.URLParam(r, "kind"))
+	unit, err := kind.BaseUnit()
+	if err != nil {
+		apperr.Write(w, err)
+		return
+	}
+	if u := q.Get("unit"); u != "" {
+		unit = Unit(u)
+		if err := kind.CheckUnit(unit); err != nil {
+			apperr.Write(w, err)
+			return
+		}
+	}
+	resolution, err := ParseResolution(q.Get("resolution"))
+	if err != nil {
+		apperr.Write(w, err)
+		return
+	}
+	from, to, err := parseRange(r)
+	if err != nil {
+		apperr.Write(w, err)
+		return
+	}
+
+	series, err := h.Storage.Series(r.Context(), userID, kind, unit, resolution, from, to)
+	if err != nil {
+		log.Printf("Error building %s series of user %s: %v", kind, userID, err)
+		apperr.Write(w, err)
+		return
+	}
+
+	goal, err := h.Storage.GetGoal(r.Context(), userID, kind)
+	switch {
+	case err == nil:
+		base, _ := kind.ToBase(goal.Target, goal.Unit)
+		goal.Target, _ = kind.FromBase(base, unit)
+		goal.Unit = unit
+		series.Goal = goal
+	case !errors.Is(err, ErrGoalNotFound):
+		log.Printf("Error getting %s goal of user %s: %v", kind, userID, err)
+		apperr.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(series)
+}
+
+// ListGoals возвращает цели пользователя по показателям
+func (h *Handler) ListGoals(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.access(w, r)
+	if !ok {
+		return
+	}
+
+	goals, err := h.Storage.ListGoals(r.Context(), userID)
+	if err != nil {
+		log.Printf("Error listing metric goals of user %s: %v", userID, err)
+		apperr.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(goals)
+}
+
+// SaveGoal задает цель по показателю. Цель может поставить сам пользователь или его тренер.
+func (h *Handler) SaveGoal(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.access(w, r)
+	if !ok {
+		return
+	}
+
+	var g Goal
+	if err := json.NewDecoder(r.Body).Decode(&g); err != nil {
+		apperr.Respond(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	g.Kind = Kind(chi.URLParam(r, "kind"))
+	if err := g.Validate(); err != nil {
+		apperr.Write(w, err)
+		return
+	}
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	g.UserID = userID
+	g.SetBy = principal.UserID
+
+	if err := h.Storage.SaveGoal(r.Context(), &g); err != nil {
+		log.Printf("Error saving %s goal of user %s: %v", g.Kind, userID, err)
+		apperr.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(g)
+}
+
+// DeleteGoal удаляет цель по показателю
+func (h *Handler) DeleteGoal(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.access(w, r)
+	if !ok {
+		return
+	}
+
+	kind := Kind(chi.URLParam(r, "kind"))
+	if err := h.Storage.DeleteGoal(r.Context(), userID, kind); err != nil {
+		if !apperr.Expected(err) {
+			log.Printf("Error deleting %s goal of user %s: %v", kind, userID, err)
+		}
+		apperr.Write(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,204 @@
+package metrics
+
+import (
+	"TrainerConnect/internal/apperr"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Kind — измеряемый показатель
+type Kind string
+
+const (
+	KindWeight    Kind = "weight"
+	KindBodyFat   Kind = "body_fat"
+	KindWaist     Kind = "waist"
+	KindChest     Kind = "chest"
+	KindHips      Kind = "hips"
+	KindArm       Kind = "arm"
+	KindThigh     Kind = "thigh"
+	KindNeck      Kind = "neck"
+	KindRestingHR Kind = "resting_hr"
+)
+
+// Unit — единица измерения
+type Unit string
+
+const (
+	UnitKg      Unit = "kg"
+	UnitLb      Unit = "lb"
+	UnitCm      Unit = "cm"
+	UnitIn      Unit = "in"
+	UnitPercent Unit = "percent"
+	UnitBPM     Unit = "bpm"
+)
+
+// Коэффициенты перевода в базовую единицу
+var toBase = map[Unit]float64{
+	UnitKg:      1,
+	UnitLb:      0.45359237,
+	UnitCm:      1,
+	UnitIn:      2.54,
+	UnitPercent: 1,
+	UnitBPM:     1,
+}
+
+// baseUnits — базовая единица каждого показателя, в ней значения хранятся в БД
+var baseUnits = map[Kind]Unit{
+	KindWeight:    UnitKg,
+	KindBodyFat:   UnitPercent,
+	KindWaist:     UnitCm,
+	KindChest:     UnitCm,
+	KindHips:      UnitCm,
+	KindArm:       UnitCm,
+	KindThigh:     UnitCm,
+	KindNeck:      UnitCm,
+	KindRestingHR: UnitBPM,
+}
+
+// compatible перечисляет единицы, в которых можно вводить значения в базовой единице
+var compatible = map[Unit][]Unit{
+	UnitKg:      {UnitKg, UnitLb},
+	UnitCm:      {UnitCm, UnitIn},
+	UnitPercent: {UnitPercent},
+	UnitBPM:     {UnitBPM},
+}
+
+var ErrUnknownKind = apperr.Invalid("unknown_metric_kind", "unknown metric kind")
+
+// BaseUnit возвращает базовую единицу показателя
+func (k Kind) BaseUnit() (Unit, error) {
+	unit, ok := baseUnits[k]
+	if !ok {
+		return "", ErrUnknownKind
+	}
+	return unit, nil
+}
+
+// CheckUnit проверяет, что показатель можно измерять в единице unit
+func (k Kind) CheckUnit(unit Unit) error {
+	base, err := k.BaseUnit()
+	if err != nil {
+		return err
+	}
+	for _, u := range compatible[base] {
+		if u == unit {
+			return nil
+		}
+	}
+	return apperr.InvalidField("unit", "invalid_unit", fmt.Sprintf("unit %q is not valid for %s", unit, k))
+}
+
+// ToBase переводит значение из единицы unit в базовую единицу показателя
+func (k Kind) ToBase(value float64, unit Unit) (float64, error) {
+	if err := k.CheckUnit(unit); err != nil {
+		return 0, err
+	}
+	return value * toBase[unit], nil
+}
+
+// FromBase переводит значение из базовой единицы показателя в единицу unit
+func (k Kind) FromBase(value float64, unit Unit) (float64, error) {
+	if err := k.CheckUnit(unit); err != nil {
+		return 0, err
+	}
+	return round(value / toBase[unit]), nil
+}
+
+// round округляет значение до сотых, чтобы при переводе не появлялся шум вида 80.00000001
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// Entry — одно измерение показателя. Value хранится в единице Unit, в которой его ввели.
+type Entry struct {
+	ID         string    `json:"id"`
+	UserID     string    `json:"user_id"`
+	Kind       Kind      `json:"kind"`
+	Value      float64   `json:"value"`
+	Unit       Unit      `json:"unit"`
+	MeasuredAt time.Time `json:"measured_at"`
+	Notes      string    `json:"notes"`
+}
+
+// Validate проверяет показатель, единицу и значение измерения. Если единица не указана,
+// используется базовая единица показателя.
+func (e *Entry) Validate() error {
+	base, err := e.Kind.BaseUnit()
+	if err != nil {
+		return err
+	}
+	if e.Unit == "" {
+		e.Unit = base
+	}
+	if err := e.Kind.CheckUnit(e.Unit); err != nil {
+		return err
+	}
+	if e.Value <= 0 || math.IsInf(e.Value, 0) || math.IsNaN(e.Value) {
+		return apperr.InvalidField("value", "not_positive", "must be positive")
+	}
+	if e.Kind == KindBodyFat && e.Value >= 100 {
+		return apperr.InvalidField("value", "out_of_range", "body_fat must be below 100 percent")
+	}
+	return nil
+}
+
+// Resolution — шаг прореживания временного ряда
+type Resolution string
+
+const (
+	Daily   Resolution = "day"
+	Weekly  Resolution = "week"
+	Monthly Resolution = "month"
+)
+
+// ParseResolution разбирает шаг ряда, по умолчанию — день
+func ParseResolution(s string) (Resolution, error) {
+	switch Resolution(s) {
+	case "":
+		return Daily, nil
+	case Daily, Weekly, Monthly:
+		return Resolution(s), nil
+	}
+	return "", apperr.InvalidField("resolution", "oneof", "must be one of: day, week, month")
+}
+
+// Point — точка временного ряда: среднее, минимум и максимум за период
+type Point struct {
+	Bucket time.Time `json:"bucket"`
+	Avg    float64   `json:"avg"`
+	Min    float64   `json:"min"`
+	Max    float64   `json:"max"`
+	Count  int       `json:"count"`
+}
+
+// Series — временной ряд показателя, готовый для построения графика
+type Series struct {
+	Kind       Kind       `json:"kind"`
+	Unit       Unit       `json:"unit"`
+	Resolution Resolution `json:"resolution"`
+	Points     []Point    `json:"points"`
+	Goal       *Goal      `json:"goal,omitempty"`
+}
+
+// Goal — целевое значение показателя пользователя. На каждый показатель одна цель.
+type Goal struct {
+	UserID    string     `json:"user_id"`
+	Kind      Kind       `json:"kind"`
+	Target    float64    `json:"target"`
+	Unit      Unit       `json:"unit"`
+	Deadline  *time.Time `json:"deadline,omitempty"`
+	SetBy     string     `json:"set_by"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// Validate проверяет цель по тем же правилам, что и измерение
+func (g *Goal) Validate() error {
+	e := Entry{Kind: g.Kind, Value: g.Target, Unit: g.Unit}
+	if err := e.Validate(); err != nil {
+		return err
+	}
+	g.Unit = e.Unit
+	return nil
+}
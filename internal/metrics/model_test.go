@@ -0,0 +1,51 @@
+package metrics_test
+
+import (
+	"TrainerConnect/internal/metrics"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestConversion(t *testing.T) {
+	base, err := metrics.KindWeight.ToBase(176.37, metrics.UnitLb)
+	assert.NoError(t, err)
+	assert.InDelta(t, 80, base, 0.01)
+
+	lb, err := metrics.KindWeight.FromBase(80, metrics.UnitLb)
+	assert.NoError(t, err)
+	assert.Equal(t, 176.37, lb)
+
+	in, err := metrics.KindWaist.FromBase(81.28, metrics.UnitIn)
+	assert.NoError(t, err)
+	assert.Equal(t, 32.0, in)
+
+	// Вес нельзя измерять в сантиметрах, а пульс — в килограммах
+	_, err = metrics.KindWeight.ToBase(80, metrics.UnitCm)
+	assert.Error(t, err)
+	_, err = metrics.KindRestingHR.FromBase(60, metrics.UnitKg)
+	assert.Error(t, err)
+}
+
+func TestEntryValidate(t *testing.T) {
+	e := &metrics.Entry{Kind: metrics.KindWeight, Value: 80}
+	assert.NoError(t, e.Validate())
+	assert.Equal(t, metrics.UnitKg, e.Unit, "по умолчанию базовая единица")
+
+	assert.Error(t, (&metrics.Entry{Kind: "height", Value: 180}).Validate())
+	assert.Error(t, (&metrics.Entry{Kind: metrics.KindWeight, Value: 0}).Validate())
+	assert.Error(t, (&metrics.Entry{Kind: metrics.KindBodyFat, Value: 120, Unit: metrics.UnitPercent}).Validate())
+	assert.Error(t, (&metrics.Entry{Kind: metrics.KindChest, Value: 100, Unit: metrics.UnitLb}).Validate())
+}
+
+func TestParseResolution(t *testing.T) {
+	r, err := metrics.ParseResolution("")
+	assert.NoError(t, err)
+	assert.Equal(t, metrics.Daily, r)
+
+	r, err = metrics.ParseResolution("month")
+	assert.NoError(t, err)
+	assert.Equal(t, metrics.Monthly, r)
+
+	_, err = metrics.ParseResolution("year")
+	assert.Error(t, err)
+}
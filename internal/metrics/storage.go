@@ -0,0 +1,227 @@
+package metrics
+
+import (
+	"TrainerConnect/internal/apperr"
+	postgres "TrainerConnect/pkg/postgresql"
+	"context"
+	"database/sql"
+	"time"
+)
+
+var (
+	ErrNotFound     = apperr.NotFound("metric_entry_not_found", "metric entry not found")
+	ErrGoalNotFound = apperr.NotFound("metric_goal_not_found", "metric goal not found")
+)
+
+type Storage struct {
+	*sql.DB
+}
+
+func NewStorage(db *sql.DB) *Storage {
+	return &Storage{DB: db}
+}
+
+// Add сохраняет измерение. Кроме введенного значения хранится значение в базовой единице,
+// по которому строятся ряды.
+func (s *Storage) Add(ctx context.Context, e *Entry) (err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	base, err := e.Kind.ToBase(e.Value, e.Unit)
+	if err != nil {
+		return err
+	}
+	return s.DB.QueryRowContext(ctx, `INSERT INTO metric_entries (user_id, kind, value, unit, value_base, measured_at, notes)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`,
+		e.UserID, e.Kind, e.Value, e.Unit, base, e.MeasuredAt, e.Notes).Scan(&e.ID)
+}
+
+// Get возвращает измерение по ID
+func (s *Storage) Get(ctx context.Context, id string) (_ *Entry, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	e := &Entry{}
+	err = s.DB.QueryRowContext(ctx, `SELECT id, user_id, kind, value, unit, measured_at, notes FROM metric_entries WHERE id = $1`, id).
+		Scan(&e.ID, &e.UserID, &e.Kind, &e.Value, &e.Unit, &e.MeasuredAt, &e.Notes)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return e, nil
+}
+
+// Delete удаляет измерение пользователя
+func (s *Storage) Delete(ctx context.Context, userID, id string) (err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	res, err := s.DB.ExecContext(ctx, "DELETE FROM metric_entries WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// List возвращает измерения пользователя за интервал [from, to). Пустой kind — все показатели.
+func (s *Storage) List(ctx context.Context, userID string, kind Kind, from, to time.Time) (_ []Entry, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	rows, err := s.DB.QueryContext(ctx, `SELECT id, user_id, kind, value, unit, measured_at, notes FROM metric_entries
+		WHERE user_id = $1 AND ($2 = '' OR kind = $2) AND measured_at >= $3 AND measured_at < $4
+		ORDER BY measured_at DESC, id DESC`, userID, kind, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []Entry{}
+	for rows.Next() {
+		var e Entry
+		if err := rows.Scan(&e.ID, &e.UserID, &e.Kind, &e.Value, &e.Unit, &e.MeasuredAt, &e.Notes); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// Series возвращает ряд показателя за интервал [from, to), усредненный по дням, неделям
+// или месяцам (границы периодов — по UTC). Значения переводятся в единицу unit.
+func (s *Storage) Series(ctx context.Context, userID string, kind Kind, unit Unit, resolution Resolution, from, to time.Time) (_ *Series, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	if err := kind.CheckUnit(unit); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.DB.QueryContext(ctx, `SELECT date_trunc($3, measured_at AT TIME ZONE 'UTC') AS bucket,
+			AVG(value_base), MIN(value_base), MAX(value_base), COUNT(*)
+		FROM metric_entries
+		WHERE user_id = $1 AND kind = $2 AND measured_at >= $4 AND measured_at < $5
+		GROUP BY bucket
+		ORDER BY bucket`, userID, kind, string(resolution), from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	series := &Series{Kind: kind, Unit: unit, Resolution: resolution, Points: []Point{}}
+	for rows.Next() {
+		var p Point
+		if err := rows.Scan(&p.Bucket, &p.Avg, &p.Min, &p.Max, &p.Count); err != nil {
+			return nil, err
+		}
+		p.Bucket = time.Date(p.Bucket.Year(), p.Bucket.Month(), p.Bucket.Day(), 0, 0, 0, 0, time.UTC)
+		p.Avg, _ = kind.FromBase(p.Avg, unit)
+		p.Min, _ = kind.FromBase(p.Min, unit)
+		p.Max, _ = kind.FromBase(p.Max, unit)
+		series.Points = append(series.Points, p)
+	}
+	return series, rows.Err()
+}
+
+// Latest возвращает последнее измерение показателя пользователя
+func (s *Storage) Latest(ctx context.Context, userID string, kind Kind) (_ *Entry, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	e := &Entry{}
+	err = s.DB.QueryRowContext(ctx, `SELECT id, user_id, kind, value, unit, measured_at, notes FROM metric_entries
+		WHERE user_id = $1 AND kind = $2 ORDER BY measured_at DESC, id DESC LIMIT 1`, userID, kind).
+		Scan(&e.ID, &e.UserID, &e.Kind, &e.Value, &e.Unit, &e.MeasuredAt, &e.Notes)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return e, nil
+}
+
+const goalColumns = "user_id, kind, target, unit, deadline, set_by, updated_at"
+
+func scanGoal(row interface{ Scan(...interface{}) error }) (*Goal, error) {
+	g := &Goal{}
+	err := row.Scan(&g.UserID, &g.Kind, &g.Target, &g.Unit, &g.Deadline, &g.SetBy, &g.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrGoalNotFound
+		}
+		return nil, err
+	}
+	return g, nil
+}
+
+// SaveGoal создает или заменяет цель пользователя по показателю
+func (s *Storage) SaveGoal(ctx context.Context, g *Goal) (err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	return s.DB.QueryRowContext(ctx, `INSERT INTO metric_goals (user_id, kind, target, unit, deadline, set_by, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, now())
+		ON CONFLICT (user_id, kind) DO UPDATE
+		SET target = EXCLUDED.target, unit = EXCLUDED.unit, deadline = EXCLUDED.deadline,
+			set_by = EXCLUDED.set_by, updated_at = EXCLUDED.updated_at
+		RETURNING updated_at`, g.UserID, g.Kind, g.Target, g.Unit, g.Deadline, g.SetBy).Scan(&g.UpdatedAt)
+}
+
+// GetGoal возвращает цель пользователя по показателю
+func (s *Storage) GetGoal(ctx context.Context, userID string, kind Kind) (_ *Goal, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	return scanGoal(s.DB.QueryRowContext(ctx, "SELECT "+goalColumns+" FROM metric_goals WHERE user_id = $1 AND kind = $2", userID, kind))
+}
+
+// ListGoals возвращает все цели пользователя
+func (s *Storage) ListGoals(ctx context.Context, userID string) (_ []Goal, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	rows, err := s.DB.QueryContext(ctx, "SELECT "+goalColumns+" FROM metric_goals WHERE user_id = $1 ORDER BY kind", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	goals := []Goal{}
+	for rows.Next() {
+		g, err := scanGoal(rows)
+		if err != nil {
+			return nil, err
+		}
+		goals = append(goals, *g)
+	}
+	return goals, rows.Err()
+}
+
+// DeleteGoal удаляет цель пользователя по показателю
+func (s *Storage) DeleteGoal(ctx context.Context, userID string, kind Kind) (err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	res, err := s.DB.ExecContext(ctx, "DELETE FROM metric_goals WHERE user_id = $1 AND kind = $2", userID, kind)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrGoalNotFound
+	}
+	return nil
+}
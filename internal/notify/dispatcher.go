@@ -0,0 +1,219 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// PreferenceSource возвращает настройки уведомлений пользователя, реализуется Storage
+type PreferenceSource interface {
+	Preferences(ctx context.Context, userID string) (*Preferences, error)
+}
+
+// AddressBook возвращает почтовый адрес пользователя, реализуется Storage
+type AddressBook interface {
+	Email(ctx context.Context, userID string) (string, error)
+}
+
+// delivery — попытка доставить уведомление по одному каналу
+type delivery struct {
+	n       Notification
+	channel Channel
+	attempt int
+}
+
+// Dispatcher асинхронно доставляет уведомления по каналам из настроек пользователя.
+// Notify никогда не блокирует вызывающего: уведомление ставится в очередь, а неудачные
+// попытки и отложенные из-за тихих часов отправки повторяются по таймеру.
+type Dispatcher struct {
+	Channels    map[Channel]Notifier
+	Preferences PreferenceSource
+	Addresses   AddressBook
+
+	// MaxAttempts — сколько раз пытаться доставить уведомление по каналу,
+	// Backoff — пауза перед второй попыткой, дальше она удваивается
+	MaxAttempts int
+	Backoff     time.Duration
+	Now         func() time.Time
+
+	queue  chan delivery
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu      sync.Mutex
+	stopped bool
+	timers  map[*time.Timer]struct{}
+}
+
+// NewDispatcher создает диспетчер с очередью на queueSize уведомлений
+func NewDispatcher(channels map[Channel]Notifier, preferences PreferenceSource, addresses AddressBook, queueSize int) *Dispatcher {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Dispatcher{
+		Channels:    channels,
+		Preferences: preferences,
+		Addresses:   addresses,
+		MaxAttempts: 5,
+		Backoff:     30 * time.Second,
+		Now:         time.Now,
+		queue:       make(chan delivery, queueSize),
+		ctx:         ctx,
+		cancel:      cancel,
+		timers:      make(map[*time.Timer]struct{}),
+	}
+}
+
+// Start запускает workers обработчиков очереди
+func (d *Dispatcher) Start(workers int) {
+	for i := 0; i < workers; i++ {
+		d.wg.Add(1)
+		go d.work()
+	}
+}
+
+// Stop прекращает прием уведомлений и ждет, пока обработчики доставят уже стоящие
+// в очереди. Отложенные отправки и повторы отменяются.
+func (d *Dispatcher) Stop() {
+	d.mu.Lock()
+	d.stopped = true
+	for t := range d.timers {
+		t.Stop()
+	}
+	close(d.queue)
+	d.mu.Unlock()
+
+	d.wg.Wait()
+	d.cancel()
+}
+
+// Notify ставит уведомление в очередь. Каналы определяются позже, в обработчике очереди.
+func (d *Dispatcher) Notify(n Notification) {
+	d.enqueue(delivery{n: n})
+}
+
+// enqueue добавляет доставку в очередь без блокировки. Если очередь переполнена,
+// доставка отбрасывается: потерять уведомление лучше, чем задержать HTTP-запрос.
+func (d *Dispatcher) enqueue(dl delivery) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stopped {
+		return
+	}
+	select {
+	case d.queue <- dl:
+	default:
+		log.Printf("Notification queue is full, dropping %s for user %s", dl.n.Kind, dl.n.UserID)
+	}
+}
+
+// later ставит доставку в очередь через delay
+func (d *Dispatcher) later(delay time.Duration, dl delivery) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stopped {
+		return
+	}
+	var t *time.Timer
+	t = time.AfterFunc(delay, func() {
+		d.mu.Lock()
+		delete(d.timers, t)
+		d.mu.Unlock()
+		d.enqueue(dl)
+	})
+	d.timers[t] = struct{}{}
+}
+
+func (d *Dispatcher) work() {
+	defer d.wg.Done()
+	for dl := range d.queue {
+		if dl.channel == "" {
+			d.fanOut(dl.n)
+		} else {
+			d.deliver(dl)
+		}
+	}
+}
+
+// fanOut раскладывает уведомление по каналам из настроек пользователя
+func (d *Dispatcher) fanOut(n Notification) {
+	prefs, err := d.Preferences.Preferences(context.Background(), n.UserID)
+	if err != nil {
+		log.Printf("Error loading notification preferences of user %s, using defaults: %v", n.UserID, err)
+		prefs = DefaultPreferences(n.UserID)
+	}
+
+	for _, channel := range prefs.ChannelsFor(n.Kind) {
+		dl := delivery{n: n, channel: channel}
+		// Тихие часы касаются только почты: событие в приложении не будит пользователя
+		if channel == ChannelEmail && prefs.QuietHours != nil {
+			if until, quiet := prefs.QuietHours.Until(d.Now()); quiet {
+				d.later(until.Sub(d.Now()), dl)
+				continue
+			}
+		}
+		d.deliver(dl)
+	}
+}
+
+// Deliver доставляет уведомление сразу, минуя очередь, и возвращает ошибки неудачных отправок.
+// Повторы остаются за вызывающим, например за очередью задач; при повторе уведомление может
+// еще раз прийти по каналам, где оно уже было доставлено. Письма в тихие часы не откладываются,
+// а пропускаются: синхронно отправляются уведомления, которые к концу тихих часов устареют.
+func (d *Dispatcher) Deliver(ctx context.Context, n Notification) error {
+	prefs, err := d.Preferences.Preferences(ctx, n.UserID)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, channel := range prefs.ChannelsFor(n.Kind) {
+		if channel == ChannelEmail && prefs.QuietHours != nil {
+			if _, quiet := prefs.QuietHours.Until(d.Now()); quiet {
+				continue
+			}
+		}
+		if err := d.send(ctx, delivery{n: n, channel: channel}); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", channel, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// send отправляет уведомление по каналу, для почты предварительно находя адрес пользователя
+func (d *Dispatcher) send(ctx context.Context, dl delivery) error {
+	notifier, ok := d.Channels[dl.channel]
+	if !ok {
+		return nil
+	}
+
+	if dl.channel == ChannelEmail && dl.n.Email == "" {
+		email, err := d.Addresses.Email(ctx, dl.n.UserID)
+		if err != nil {
+			return fmt.Errorf("getting email of user %s: %w", dl.n.UserID, err)
+		}
+		dl.n.Email = email
+	}
+	return notifier.Send(ctx, dl.n)
+}
+
+// deliver отправляет уведомление по каналу и при ошибке планирует повтор с удвоением паузы
+func (d *Dispatcher) deliver(dl delivery) {
+	if err := d.send(d.ctx, dl); err != nil {
+		log.Printf("Error sending %s notification to user %s via %s (attempt %d): %v",
+			dl.n.Kind, dl.n.UserID, dl.channel, dl.attempt+1, err)
+		d.retry(dl)
+	}
+}
+
+func (d *Dispatcher) retry(dl delivery) {
+	dl.attempt++
+	if dl.attempt >= d.MaxAttempts {
+		log.Printf("Giving up %s notification to user %s via %s", dl.n.Kind, dl.n.UserID, dl.channel)
+		return
+	}
+	d.later(d.Backoff<<(dl.attempt-1), dl)
+}
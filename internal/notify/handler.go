@@ -0,0 +1,93 @@
+package notify
+
+import (
+	"TrainerConnect/internal/apperr"
+	"TrainerConnect/internal/auth"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+const preferencesURL = "/users/{id}/notification-preferences"
+
+type Handler struct {
+	Storage *Storage
+	Policy  *auth.Policy
+}
+
+// NewHandler создает обработчик настроек уведомлений
+func NewHandler(storage *Storage, policy *auth.Policy) *Handler {
+	return &Handler{Storage: storage, Policy: policy}
+}
+
+func (h *Handler) Register(router *chi.Mux) {
+	router.Group(func(r chi.Router) {
+		r.Use(auth.RequireAuth)
+		r.Get(preferencesURL, h.GetPreferences)
+		r.Put(preferencesURL, h.UpdatePreferences)
+	})
+}
+
+// userID читает ID пользователя из URL и проверяет право менять его настройки
+func (h *Handler) userID(w http.ResponseWriter, r *http.Request) (string, bool) {
+	id := chi.URLParam(r, "id")
+	if _, err := strconv.Atoi(id); err != nil {
+		apperr.Respond(w, http.StatusBadRequest, "Invalid user ID")
+		return "", false
+	}
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	if !h.Policy.CanEditUser(principal, id) {
+		apperr.Respond(w, http.StatusForbidden, "Forbidden")
+		return "", false
+	}
+	return id, true
+}
+
+// GetPreferences возвращает настройки уведомлений пользователя
+func (h *Handler) GetPreferences(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+
+	prefs, err := h.Storage.Preferences(r.Context(), id)
+	if err != nil {
+		log.Printf("Error getting notification preferences of user %s: %v", id, err)
+		apperr.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(prefs)
+}
+
+// UpdatePreferences заменяет настройки уведомлений. Виды, не указанные в channels,
+// доставляются по каналам по умолчанию; пустой список отключает вид.
+func (h *Handler) UpdatePreferences(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+
+	var prefs Preferences
+	if err := json.NewDecoder(r.Body).Decode(&prefs); err != nil {
+		apperr.Respond(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := prefs.Validate(); err != nil {
+		apperr.Write(w, err)
+		return
+	}
+	prefs.UserID = id
+
+	if err := h.Storage.SavePreferences(r.Context(), &prefs); err != nil {
+		log.Printf("Error saving notification preferences of user %s: %v", id, err)
+		apperr.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(prefs)
+}
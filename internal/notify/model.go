@@ -0,0 +1,169 @@
+package notify
+
+import (
+	"TrainerConnect/internal/apperr"
+	"context"
+	"fmt"
+	"time"
+)
+
+// Kind — вид уведомления
+type Kind string
+
+const (
+	KindBookingReminder  Kind = "booking_reminder"
+	KindBookingCancelled Kind = "booking_cancelled"
+	KindNewMessage       Kind = "new_message"
+)
+
+// Channel — канал доставки уведомлений
+type Channel string
+
+const (
+	ChannelEmail Channel = "email"
+	// ChannelInApp — событие notification в потоке /events
+	ChannelInApp Channel = "in_app"
+)
+
+// Notification — уведомление пользователю. Email заполняется диспетчером перед отправкой по почте.
+type Notification struct {
+	UserID  string      `json:"user_id"`
+	Kind    Kind        `json:"kind"`
+	Subject string      `json:"subject"`
+	Body    string      `json:"body"`
+	Data    interface{} `json:"data,omitempty"`
+	Email   string      `json:"-"`
+}
+
+// Notifier доставляет уведомление по одному каналу
+type Notifier interface {
+	Send(ctx context.Context, n Notification) error
+}
+
+// Sender ставит уведомление в очередь доставки и сразу возвращает управление, реализуется Dispatcher
+type Sender interface {
+	Notify(n Notification)
+}
+
+// Deliverer доставляет уведомление синхронно и сообщает об ошибке, реализуется Dispatcher
+type Deliverer interface {
+	Deliver(ctx context.Context, n Notification) error
+}
+
+// defaultChannels — каналы по умолчанию для пользователей, не менявших настройки
+var defaultChannels = map[Kind][]Channel{
+	KindBookingReminder:  {ChannelEmail, ChannelInApp},
+	KindBookingCancelled: {ChannelEmail, ChannelInApp},
+	KindNewMessage:       {ChannelInApp},
+}
+
+// Preferences — настройки уведомлений пользователя: каналы для каждого вида и тихие часы
+type Preferences struct {
+	UserID     string             `json:"user_id"`
+	Channels   map[Kind][]Channel `json:"channels"`
+	QuietHours *QuietHours        `json:"quiet_hours,omitempty"`
+}
+
+// DefaultPreferences возвращает настройки по умолчанию
+func DefaultPreferences(userID string) *Preferences {
+	channels := make(map[Kind][]Channel, len(defaultChannels))
+	for kind, list := range defaultChannels {
+		channels[kind] = append([]Channel(nil), list...)
+	}
+	return &Preferences{UserID: userID, Channels: channels}
+}
+
+// ChannelsFor возвращает каналы для вида уведомлений. Вид, не упомянутый в настройках,
+// доставляется по каналам по умолчанию; пустой список отключает вид полностью.
+func (p *Preferences) ChannelsFor(kind Kind) []Channel {
+	if list, ok := p.Channels[kind]; ok {
+		return list
+	}
+	return defaultChannels[kind]
+}
+
+// Validate проверяет виды, каналы и тихие часы
+func (p *Preferences) Validate() error {
+	if p.Channels == nil {
+		p.Channels = map[Kind][]Channel{}
+	}
+	for kind, list := range p.Channels {
+		if _, ok := defaultChannels[kind]; !ok {
+			return apperr.InvalidField("channels", "unknown_kind", fmt.Sprintf("unknown notification kind %q", kind))
+		}
+		for _, c := range list {
+			if c != ChannelEmail && c != ChannelInApp {
+				return apperr.InvalidField("channels", "unknown_channel", fmt.Sprintf("unknown channel %q", c))
+			}
+		}
+	}
+	if p.QuietHours != nil {
+		return p.QuietHours.Validate()
+	}
+	return nil
+}
+
+// QuietHours — период, когда уведомления не отправляются по почте, в часовом поясе пользователя.
+// Start позже End означает период через полночь, например 22:00–07:00.
+type QuietHours struct {
+	Start    string `json:"start"`
+	End      string `json:"end"`
+	TimeZone string `json:"time_zone"`
+}
+
+// Validate проверяет формат времени и часовой пояс
+func (q *QuietHours) Validate() error {
+	start, err := parseClock(q.Start)
+	if err != nil {
+		return apperr.InvalidField("quiet_hours.start", "invalid_time", err.Error())
+	}
+	end, err := parseClock(q.End)
+	if err != nil {
+		return apperr.InvalidField("quiet_hours.end", "invalid_time", err.Error())
+	}
+	if start == end {
+		return apperr.InvalidField("quiet_hours.end", "equals_start", "quiet hours start and end must differ")
+	}
+	if _, err := time.LoadLocation(q.TimeZone); err != nil {
+		return apperr.InvalidField("quiet_hours.time_zone", "unknown_time_zone", fmt.Sprintf("unknown time zone %q", q.TimeZone))
+	}
+	return nil
+}
+
+// parseClock разбирает время суток вида 22:00 и возвращает смещение от полуночи
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// Until сообщает, попадает ли момент t в тихие часы, и если да — когда они закончатся
+func (q *QuietHours) Until(t time.Time) (time.Time, bool) {
+	start, err1 := parseClock(q.Start)
+	end, err2 := parseClock(q.End)
+	loc, err3 := time.LoadLocation(q.TimeZone)
+	if err1 != nil || err2 != nil || err3 != nil {
+		return time.Time{}, false
+	}
+
+	local := t.In(loc)
+	offset := time.Duration(local.Hour())*time.Hour + time.Duration(local.Minute())*time.Minute +
+		time.Duration(local.Second())*time.Second
+	// Конец тишины считается по часам пользователя, чтобы переход на летнее время его не сдвигал
+	endOn := func(day time.Time) time.Time {
+		return time.Date(day.Year(), day.Month(), day.Day(), int(end/time.Hour), int(end%time.Hour/time.Minute), 0, 0, loc)
+	}
+
+	switch {
+	case start < end && offset >= start && offset < end:
+		return endOn(local), true
+	case start > end && offset >= start:
+		// Период через полночь, сейчас вечер: тишина до утра следующего дня
+		return endOn(local.AddDate(0, 0, 1)), true
+	case start > end && offset < end:
+		return endOn(local), true
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,156 @@
+package notify
+
+import (
+	"TrainerConnect/internal/events"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SMTPNotifier отправляет уведомления по почте
+type SMTPNotifier struct {
+	Addr     string
+	From     string
+	Username string
+	Password string
+	Timeout  time.Duration
+}
+
+// NewSMTPNotifier создает отправку через SMTP-сервер addr (host:port). Если username пуст,
+// сервер используется без аутентификации.
+func NewSMTPNotifier(addr, from, username, password string) *SMTPNotifier {
+	return &SMTPNotifier{Addr: addr, From: from, Username: username, Password: password, Timeout: 30 * time.Second}
+}
+
+// Send отправляет письмо. Все соединение ограничено Timeout, чтобы зависший сервер
+// не занимал обработчик очереди бесконечно.
+func (s *SMTPNotifier) Send(ctx context.Context, n Notification) error {
+	if n.Email == "" {
+		return errors.New("recipient has no email address")
+	}
+	host, _, err := net.SplitHostPort(s.Addr)
+	if err != nil {
+		return err
+	}
+
+	dialer := net.Dialer{Timeout: s.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", s.Addr)
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(s.Timeout))
+
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if s.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", s.Username, s.Password, host)); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(s.From); err != nil {
+		return err
+	}
+	if err := c.Rcpt(n.Email); err != nil {
+		return err
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(buildMessage(s.From, n.Email, n.Subject, n.Body)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// buildMessage формирует текстовое письмо в UTF-8
+func buildMessage(from, to, subject, body string) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", to)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+// LogNotifier записывает уведомления строками JSON. Используется вместо почты при разработке
+// и в тестах.
+type LogNotifier struct {
+	mu      sync.Mutex
+	w       io.Writer
+	Channel Channel
+}
+
+// NewLogNotifier создает запись уведомлений канала channel в w
+func NewLogNotifier(w io.Writer, channel Channel) *LogNotifier {
+	return &LogNotifier{w: w, Channel: channel}
+}
+
+// NewFileNotifier создает запись уведомлений в конец файла path
+func NewFileNotifier(path string, channel Channel) (*LogNotifier, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return NewLogNotifier(f, channel), nil
+}
+
+type logRecord struct {
+	Time    time.Time `json:"time"`
+	Channel Channel   `json:"channel"`
+	Email   string    `json:"email,omitempty"`
+	Notification
+}
+
+func (l *LogNotifier) Send(ctx context.Context, n Notification) error {
+	line, err := json.Marshal(logRecord{Time: time.Now().UTC(), Channel: l.Channel, Email: n.Email, Notification: n})
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, err = l.w.Write(append(line, '\n'))
+	return err
+}
+
+// InAppNotifier доставляет уведомления в открытые потоки /events пользователя
+type InAppNotifier struct {
+	Events events.Publisher
+}
+
+func NewInAppNotifier(publisher events.Publisher) *InAppNotifier {
+	return &InAppNotifier{Events: publisher}
+}
+
+func (a *InAppNotifier) Send(ctx context.Context, n Notification) error {
+	a.Events.Publish("notification", n, n.UserID)
+	return nil
+}
@@ -0,0 +1,171 @@
+package notify_test
+
+import (
+	"TrainerConnect/internal/notify"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestQuietHours(t *testing.T) {
+	q := &notify.QuietHours{Start: "22:00", End: "07:00", TimeZone: "Europe/Moscow"}
+	assert.NoError(t, q.Validate())
+
+	// 23:30 по Москве — тишина до 07:00 следующего дня
+	until, quiet := q.Until(time.Date(2024, 3, 4, 20, 30, 0, 0, time.UTC))
+	assert.True(t, quiet)
+	assert.Equal(t, time.Date(2024, 3, 5, 4, 0, 0, 0, time.UTC), until.UTC())
+
+	// 05:00 по Москве — тишина до 07:00 того же дня
+	until, quiet = q.Until(time.Date(2024, 3, 4, 2, 0, 0, 0, time.UTC))
+	assert.True(t, quiet)
+	assert.Equal(t, time.Date(2024, 3, 4, 4, 0, 0, 0, time.UTC), until.UTC())
+
+	_, quiet = q.Until(time.Date(2024, 3, 4, 12, 0, 0, 0, time.UTC))
+	assert.False(t, quiet)
+
+	// Дневной период без перехода через полночь
+	q = &notify.QuietHours{Start: "13:00", End: "14:00", TimeZone: "UTC"}
+	_, quiet = q.Until(time.Date(2024, 3, 4, 13, 59, 0, 0, time.UTC))
+	assert.True(t, quiet)
+	_, quiet = q.Until(time.Date(2024, 3, 4, 14, 0, 0, 0, time.UTC))
+	assert.False(t, quiet)
+
+	assert.Error(t, (&notify.QuietHours{Start: "25:00", End: "07:00", TimeZone: "UTC"}).Validate())
+	assert.Error(t, (&notify.QuietHours{Start: "22:00", End: "07:00", TimeZone: "Mars/Olympus"}).Validate())
+}
+
+func TestPreferences(t *testing.T) {
+	p := notify.DefaultPreferences("2")
+	assert.Equal(t, []notify.Channel{notify.ChannelInApp}, p.ChannelsFor(notify.KindNewMessage))
+
+	// Пустой список отключает вид, не упомянутый вид берет каналы по умолчанию
+	p = &notify.Preferences{Channels: map[notify.Kind][]notify.Channel{notify.KindNewMessage: {}}}
+	assert.NoError(t, p.Validate())
+	assert.Empty(t, p.ChannelsFor(notify.KindNewMessage))
+	assert.Len(t, p.ChannelsFor(notify.KindBookingReminder), 2)
+
+	p = &notify.Preferences{Channels: map[notify.Kind][]notify.Channel{notify.KindNewMessage: {"sms"}}}
+	assert.Error(t, p.Validate())
+}
+
+// stubSource отдает одинаковые настройки и адрес всем пользователям
+type stubSource struct {
+	prefs *notify.Preferences
+}
+
+func (s stubSource) Preferences(ctx context.Context, userID string) (*notify.Preferences, error) {
+	return s.prefs, nil
+}
+
+func (s stubSource) Email(ctx context.Context, userID string) (string, error) {
+	return "user" + userID + "@example.com", nil
+}
+
+// flakyNotifier отказывает первые failures раз
+type flakyNotifier struct {
+	mu       sync.Mutex
+	failures int
+	sent     []notify.Notification
+	done     chan struct{}
+}
+
+func (f *flakyNotifier) Send(ctx context.Context, n notify.Notification) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.failures > 0 {
+		f.failures--
+		return errors.New("smtp: connection refused")
+	}
+	f.sent = append(f.sent, n)
+	close(f.done)
+	return nil
+}
+
+func TestDispatcherRetries(t *testing.T) {
+	email := &flakyNotifier{failures: 2, done: make(chan struct{})}
+	var inApp bytes.Buffer
+	channels := map[notify.Channel]notify.Notifier{
+		notify.ChannelEmail: email,
+		notify.ChannelInApp: notify.NewLogNotifier(&inApp, notify.ChannelInApp),
+	}
+	d := notify.NewDispatcher(channels, stubSource{notify.DefaultPreferences("2")}, stubSource{}, 8)
+	d.Backoff = time.Millisecond
+	d.Start(2)
+
+	d.Notify(notify.Notification{UserID: "2", Kind: notify.KindBookingCancelled, Subject: "Занятие отменено"})
+
+	select {
+	case <-email.done:
+	case <-time.After(time.Second):
+		t.Fatal("notification was not delivered after retries")
+	}
+	d.Stop()
+
+	assert.Equal(t, "user2@example.com", email.sent[0].Email)
+
+	var record map[string]interface{}
+	assert.NoError(t, json.Unmarshal(inApp.Bytes(), &record))
+	assert.Equal(t, "in_app", record["channel"])
+	assert.Equal(t, "Занятие отменено", record["subject"])
+}
+
+func TestDispatcherQuietHours(t *testing.T) {
+	email := &flakyNotifier{done: make(chan struct{})}
+	prefs := notify.DefaultPreferences("2")
+	prefs.QuietHours = &notify.QuietHours{Start: "22:00", End: "07:00", TimeZone: "UTC"}
+	d := notify.NewDispatcher(map[notify.Channel]notify.Notifier{notify.ChannelEmail: email},
+		stubSource{prefs}, stubSource{}, 8)
+
+	// Часы диспетчера показывают 06:59:59.95 — письмо уйдет через 50 мс, когда кончатся тихие часы
+	d.Now = func() time.Time { return time.Date(2024, 3, 4, 6, 59, 59, 950e6, time.UTC) }
+	d.Start(1)
+	defer d.Stop()
+
+	d.Notify(notify.Notification{UserID: "2", Kind: notify.KindBookingReminder})
+	select {
+	case <-email.done:
+		t.Fatal("email was sent during quiet hours")
+	case <-time.After(20 * time.Millisecond):
+	}
+	select {
+	case <-email.done:
+	case <-time.After(time.Second):
+		t.Fatal("email was not sent after quiet hours")
+	}
+}
+
+func TestDispatcherDeliver(t *testing.T) {
+	email := &flakyNotifier{failures: 1, done: make(chan struct{})}
+	var inApp bytes.Buffer
+	channels := map[notify.Channel]notify.Notifier{
+		notify.ChannelEmail: email,
+		notify.ChannelInApp: notify.NewLogNotifier(&inApp, notify.ChannelInApp),
+	}
+	prefs := notify.DefaultPreferences("2")
+	d := notify.NewDispatcher(channels, stubSource{prefs}, stubSource{}, 8)
+	d.Now = func() time.Time { return time.Date(2024, 3, 4, 12, 0, 0, 0, time.UTC) }
+
+	// Диспетчер не запущен: Deliver отправляет сам и возвращает ошибку отказавшего канала
+	n := notify.Notification{UserID: "2", Kind: notify.KindBookingReminder, Subject: "Скоро занятие"}
+	err := d.Deliver(context.Background(), n)
+	assert.ErrorContains(t, err, "connection refused")
+	assert.Contains(t, inApp.String(), "Скоро занятие")
+
+	assert.NoError(t, d.Deliver(context.Background(), n))
+	if assert.Len(t, email.sent, 1) {
+		assert.Equal(t, "user2@example.com", email.sent[0].Email)
+	}
+
+	// В тихие часы письмо пропускается, а не откладывается
+	prefs.QuietHours = &notify.QuietHours{Start: "11:00", End: "13:00", TimeZone: "UTC"}
+	inApp.Reset()
+	assert.NoError(t, d.Deliver(context.Background(), n))
+	assert.Len(t, email.sent, 1)
+	assert.Contains(t, inApp.String(), "Скоро занятие")
+}
@@ -0,0 +1,83 @@
+package notify
+
+import (
+	"TrainerConnect/internal/apperr"
+	postgres "TrainerConnect/pkg/postgresql"
+	"context"
+	"database/sql"
+	"encoding/json"
+)
+
+var ErrUserNotFound = apperr.NotFound("user_not_found", "user not found")
+
+type Storage struct {
+	*sql.DB
+}
+
+func NewStorage(db *sql.DB) *Storage {
+	return &Storage{DB: db}
+}
+
+// Preferences возвращает настройки пользователя или настройки по умолчанию, если он их не менял
+func (s *Storage) Preferences(ctx context.Context, userID string) (_ *Preferences, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	var channels []byte
+	var start, end, tz sql.NullString
+	err = s.DB.QueryRowContext(ctx, `SELECT channels, quiet_start, quiet_end, time_zone FROM notification_preferences
+		WHERE user_id = $1`, userID).Scan(&channels, &start, &end, &tz)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return DefaultPreferences(userID), nil
+		}
+		return nil, err
+	}
+
+	p := &Preferences{UserID: userID}
+	if err := json.Unmarshal(channels, &p.Channels); err != nil {
+		return nil, err
+	}
+	if start.Valid {
+		p.QuietHours = &QuietHours{Start: start.String, End: end.String, TimeZone: tz.String}
+	}
+	return p, nil
+}
+
+// SavePreferences сохраняет настройки пользователя
+func (s *Storage) SavePreferences(ctx context.Context, p *Preferences) (err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	channels, err := json.Marshal(p.Channels)
+	if err != nil {
+		return err
+	}
+	var start, end, tz sql.NullString
+	if q := p.QuietHours; q != nil {
+		start = sql.NullString{String: q.Start, Valid: true}
+		end = sql.NullString{String: q.End, Valid: true}
+		tz = sql.NullString{String: q.TimeZone, Valid: true}
+	}
+
+	_, err = s.DB.ExecContext(ctx, `INSERT INTO notification_preferences (user_id, channels, quiet_start, quiet_end, time_zone)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id) DO UPDATE
+		SET channels = EXCLUDED.channels, quiet_start = EXCLUDED.quiet_start,
+			quiet_end = EXCLUDED.quiet_end, time_zone = EXCLUDED.time_zone`,
+		p.UserID, channels, start, end, tz)
+	return err
+}
+
+// Email возвращает почтовый адрес пользователя
+func (s *Storage) Email(ctx context.Context, userID string) (_ string, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	var email string
+	err = s.DB.QueryRowContext(ctx, "SELECT email FROM users WHERE user_id = $1", userID).Scan(&email)
+	if err == sql.ErrNoRows {
+		return "", ErrUserNotFound
+	}
+	return email, err
+}
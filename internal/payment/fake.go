@@ -0,0 +1,182 @@
+package payment
+
+import (
+	"TrainerConnect/internal/apperr"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var (
+	ErrUnknownCheckout = apperr.NotFound("checkout_not_found", "unknown checkout")
+	ErrCheckoutClosed  = apperr.Conflict("checkout_closed", "checkout is already paid")
+)
+
+// Состояния оплаты у фиктивного провайдера
+const (
+	fakeOpen       = "open"
+	fakeAuthorized = "authorized"
+	fakeCaptured   = "captured"
+	fakeRefunded   = "refunded"
+)
+
+type fakeCheckout struct {
+	Checkout
+	request   CheckoutRequest
+	reference string
+	state     string
+}
+
+// FakeProvider хранит оплаты в памяти и подписывает события тем же способом, что и Stripe.
+// Используется в тестах и при локальной разработке; в сервере включается только явно.
+type FakeProvider struct {
+	WebhookSecret string
+	// CheckoutURL — адрес страницы оплаты, к которому добавляется ID оплаты у провайдера
+	CheckoutURL string
+	// Deliver получает подписанные события, как если бы провайдер отправил их на webhook
+	Deliver func(payload []byte, header http.Header)
+	Now     func() time.Time
+
+	mu        sync.Mutex
+	seq       int
+	checkouts map[string]*fakeCheckout
+	refs      map[string]*fakeCheckout
+}
+
+// NewFakeProvider создает фиктивного провайдера с секретом подписи событий
+func NewFakeProvider(webhookSecret string) *FakeProvider {
+	return &FakeProvider{
+		WebhookSecret: webhookSecret,
+		CheckoutURL:   "/payments/fake-checkout/",
+		Now:           time.Now,
+		checkouts:     make(map[string]*fakeCheckout),
+		refs:          make(map[string]*fakeCheckout),
+	}
+}
+
+func (p *FakeProvider) Name() string {
+	return "fake"
+}
+
+func (p *FakeProvider) CreateCheckout(ctx context.Context, req CheckoutRequest) (*Checkout, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.seq++
+	id := fmt.Sprintf("cs_fake_%d", p.seq)
+	c := &fakeCheckout{
+		Checkout:  Checkout{ID: id, URL: p.CheckoutURL + id},
+		request:   req,
+		reference: fmt.Sprintf("pi_fake_%d", p.seq),
+		state:     fakeOpen,
+	}
+	p.checkouts[id] = c
+	p.refs[c.reference] = c
+	return &c.Checkout, nil
+}
+
+func (p *FakeProvider) Capture(ctx context.Context, reference string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	c, ok := p.refs[reference]
+	if !ok {
+		return ErrUnknownCheckout
+	}
+	switch c.state {
+	case fakeAuthorized:
+		c.state = fakeCaptured
+		return nil
+	case fakeCaptured:
+		return nil
+	}
+	return fmt.Errorf("fake: cannot capture payment in state %s", c.state)
+}
+
+func (p *FakeProvider) Refund(ctx context.Context, reference string, amount int64) error {
+	p.mu.Lock()
+	c, ok := p.refs[reference]
+	if !ok {
+		p.mu.Unlock()
+		return ErrUnknownCheckout
+	}
+	if c.state != fakeCaptured {
+		state := c.state
+		p.mu.Unlock()
+		return fmt.Errorf("fake: cannot refund payment in state %s", state)
+	}
+	c.state = fakeRefunded
+	p.seq++
+	ev := WebhookEvent{ID: fmt.Sprintf("evt_fake_%d", p.seq), Type: EventRefunded,
+		PaymentID: c.request.PaymentID, Reference: reference, Amount: amount}
+	p.mu.Unlock()
+
+	p.deliver(ev)
+	return nil
+}
+
+// PaymentID возвращает ID оплаты, для которой создана страница checkoutID
+func (p *FakeProvider) PaymentID(checkoutID string) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	c, ok := p.checkouts[checkoutID]
+	if !ok {
+		return "", ErrUnknownCheckout
+	}
+	return c.request.PaymentID, nil
+}
+
+// Pay имитирует оплату клиентом на странице провайдера: средства блокируются
+// и отправляется событие EventCheckoutCompleted
+func (p *FakeProvider) Pay(checkoutID string) (*WebhookEvent, error) {
+	p.mu.Lock()
+	c, ok := p.checkouts[checkoutID]
+	if !ok {
+		p.mu.Unlock()
+		return nil, ErrUnknownCheckout
+	}
+	if c.state != fakeOpen {
+		p.mu.Unlock()
+		return nil, ErrCheckoutClosed
+	}
+	c.state = fakeAuthorized
+	p.seq++
+	ev := WebhookEvent{ID: fmt.Sprintf("evt_fake_%d", p.seq), Type: EventCheckoutCompleted,
+		PaymentID: c.request.PaymentID, Reference: c.reference, Amount: c.request.Amount}
+	p.mu.Unlock()
+
+	p.deliver(ev)
+	return &ev, nil
+}
+
+// SignEvent сериализует событие и возвращает его вместе с заголовком подписи
+func (p *FakeProvider) SignEvent(ev WebhookEvent) ([]byte, http.Header) {
+	payload, _ := json.Marshal(ev)
+	header := http.Header{}
+	header.Set("Fake-Signature", Sign(p.WebhookSecret, payload, p.Now()))
+	return payload, header
+}
+
+func (p *FakeProvider) deliver(ev WebhookEvent) {
+	if p.Deliver == nil {
+		return
+	}
+	payload, header := p.SignEvent(ev)
+	p.Deliver(payload, header)
+}
+
+// ParseWebhook проверяет заголовок Fake-Signature. Тело события — сериализованный WebhookEvent.
+func (p *FakeProvider) ParseWebhook(payload []byte, header http.Header) (*WebhookEvent, error) {
+	if err := VerifySignature(p.WebhookSecret, payload, header.Get("Fake-Signature"), p.Now()); err != nil {
+		return nil, err
+	}
+	var ev WebhookEvent
+	if err := json.Unmarshal(payload, &ev); err != nil || ev.ID == "" {
+		return nil, ErrInvalidEvent
+	}
+	return &ev, nil
+}
@@ -0,0 +1,214 @@
+package payment
+
+import (
+	"TrainerConnect/internal/apperr"
+	"TrainerConnect/internal/auth"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+const (
+	paymentURL = "/payments/"
+	// Максимальный размер тела уведомления провайдера
+	maxWebhookSize = 64 << 10
+)
+
+type Handler struct {
+	Service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{Service: service}
+}
+
+func (h *Handler) Register(router *chi.Mux) {
+	// Уведомления провайдера подписаны и не требуют токена
+	router.Post(paymentURL+"webhook", h.Webhook)
+
+	router.Group(func(r chi.Router) {
+		r.Use(auth.RequireAuth)
+		// Страница оплаты фиктивного провайдера для локальной разработки
+		if _, ok := h.Service.Provider.(*FakeProvider); ok {
+			r.Post(paymentURL+"fake-checkout/{checkoutID}", h.FakePay)
+		}
+		r.Get("/payments", h.List)
+		r.Post(paymentURL+"checkout", h.Checkout)
+		r.Get(paymentURL+"{id}", h.Get)
+		r.Post(paymentURL+"{id}/refund", h.Refund)
+	})
+}
+
+// Webhook принимает события провайдера. Ошибка обработки возвращает 500,
+// чтобы провайдер доставил событие повторно.
+func (h *Handler) Webhook(w http.ResponseWriter, r *http.Request) {
+	payload, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookSize))
+	if err != nil {
+		apperr.Respond(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.Service.HandleWebhook(r.Context(), payload, r.Header); err != nil {
+		if !apperr.Expected(err) {
+			log.Printf("Error handling payment webhook: %v", err)
+		}
+		apperr.Write(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// FakePay имитирует оплату на странице фиктивного провайдера. Оплатить можно
+// только свою покупку; чужая страница оплаты выглядит несуществующей.
+func (h *Handler) FakePay(w http.ResponseWriter, r *http.Request) {
+	fake := h.Service.Provider.(*FakeProvider)
+	checkoutID := chi.URLParam(r, "checkoutID")
+
+	paymentID, err := fake.PaymentID(checkoutID)
+	if err != nil {
+		apperr.Write(w, err)
+		return
+	}
+	p, err := h.Service.Storage.Get(r.Context(), paymentID)
+	if err != nil {
+		log.Printf("Error getting payment %s: %v", paymentID, err)
+		apperr.Write(w, err)
+		return
+	}
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	if principal.UserID != p.ClientID {
+		apperr.Write(w, ErrUnknownCheckout)
+		return
+	}
+
+	ev, err := fake.Pay(checkoutID)
+	if err != nil {
+		apperr.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ev)
+}
+
+type checkoutRequest struct {
+	ProductID  string `json:"product_id"`
+	SuccessURL string `json:"success_url"`
+	CancelURL  string `json:"cancel_url"`
+}
+
+// Checkout начинает оплату продукта текущим пользователем и возвращает адрес страницы оплаты
+func (h *Handler) Checkout(w http.ResponseWriter, r *http.Request) {
+	var req checkoutRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apperr.Respond(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if _, err := strconv.Atoi(req.ProductID); err != nil {
+		apperr.Respond(w, http.StatusBadRequest, "Invalid product ID")
+		return
+	}
+	if req.SuccessURL == "" {
+		apperr.Write(w, apperr.InvalidField("success_url", "required", "success_url is required"))
+		return
+	}
+	if req.CancelURL == "" {
+		apperr.Write(w, apperr.InvalidField("cancel_url", "required", "cancel_url is required"))
+		return
+	}
+
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	p, err := h.Service.Checkout(r.Context(), principal.UserID, req.ProductID, req.SuccessURL, req.CancelURL)
+	if err != nil {
+		if apperr.Expected(err) {
+			apperr.Write(w, err)
+			return
+		}
+		log.Printf("Error creating checkout for product %s: %v", req.ProductID, err)
+		apperr.Respond(w, http.StatusBadGateway, "Error creating checkout")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(p)
+}
+
+// List возвращает покупки и продажи текущего пользователя
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	principal, _ := auth.PrincipalFromContext(r.Context())
+
+	payments, err := h.Service.Storage.ListForUser(r.Context(), principal.UserID)
+	if err != nil {
+		log.Printf("Error listing payments of user %s: %v", principal.UserID, err)
+		apperr.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(payments)
+}
+
+// load загружает оплату, доступную покупателю, тренеру и администратору
+func (h *Handler) load(w http.ResponseWriter, r *http.Request) (*Payment, bool) {
+	id := chi.URLParam(r, "id")
+	if _, err := strconv.Atoi(id); err != nil {
+		apperr.Respond(w, http.StatusBadRequest, "Invalid payment ID")
+		return nil, false
+	}
+
+	p, err := h.Service.Storage.Get(r.Context(), id)
+	if err != nil {
+		if !apperr.Expected(err) {
+			log.Printf("Error getting payment %s: %v", id, err)
+		}
+		apperr.Write(w, err)
+		return nil, false
+	}
+
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	if principal.UserID != p.ClientID && principal.UserID != p.TrainerID && !principal.IsAdmin() {
+		apperr.Write(w, ErrNotFound)
+		return nil, false
+	}
+	return p, true
+}
+
+// Get возвращает оплату
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
+	p, ok := h.load(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(p)
+}
+
+// Refund запрашивает возврат оплаты. Доступно тренеру-продавцу и администратору;
+// оплата изменится, когда провайдер подтвердит возврат.
+func (h *Handler) Refund(w http.ResponseWriter, r *http.Request) {
+	p, ok := h.load(w, r)
+	if !ok {
+		return
+	}
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	if principal.UserID != p.TrainerID && !principal.IsAdmin() {
+		apperr.Respond(w, http.StatusForbidden, "Forbidden")
+		return
+	}
+
+	if err := h.Service.Refund(r.Context(), p); err != nil {
+		if apperr.Expected(err) {
+			apperr.Write(w, err)
+			return
+		}
+		log.Printf("Error refunding payment %s: %v", p.ID, err)
+		apperr.Respond(w, http.StatusBadGateway, "Error refunding payment")
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
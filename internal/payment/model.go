@@ -0,0 +1,78 @@
+package payment
+
+import (
+	"time"
+)
+
+// Status — состояние оплаты продукта
+type Status string
+
+const (
+	StatusPending  Status = "pending"
+	StatusPaid     Status = "paid"
+	StatusFailed   Status = "failed"
+	StatusRefunded Status = "refunded"
+)
+
+// Payment — покупка клиентом продукта тренера через платежного провайдера
+type Payment struct {
+	ID        string `json:"id"`
+	ClientID  string `json:"client_id"`
+	TrainerID string `json:"trainer_id"`
+	ProductID string `json:"product_id"`
+	// Сумма в минимальных единицах валюты
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+	Status   Status `json:"status"`
+
+	Provider    string `json:"provider"`
+	CheckoutID  string `json:"checkout_id,omitempty"`
+	CheckoutURL string `json:"checkout_url,omitempty"`
+	// Reference — ID платежа у провайдера, по нему выполняются списание и возврат
+	Reference string `json:"reference,omitempty"`
+	// GrantID — начисление кредитов, созданное после оплаты
+	GrantID string `json:"grant_id,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CheckoutRequest — параметры страницы оплаты у провайдера
+type CheckoutRequest struct {
+	PaymentID   string
+	Amount      int64
+	Currency    string
+	Description string
+	SuccessURL  string
+	CancelURL   string
+}
+
+// Checkout — созданная у провайдера страница оплаты
+type Checkout struct {
+	ID  string
+	URL string
+}
+
+// EventType — вид события провайдера, приведенный к общему для всех провайдеров виду
+type EventType string
+
+const (
+	// EventCheckoutCompleted — клиент оплатил на странице провайдера, средства заблокированы
+	// и должны быть списаны вызовом Capture
+	EventCheckoutCompleted EventType = "checkout.completed"
+	// EventPaymentFailed — оплата не прошла или страница оплаты истекла
+	EventPaymentFailed EventType = "payment.failed"
+	// EventRefunded — провайдер вернул средства клиенту
+	EventRefunded EventType = "payment.refunded"
+)
+
+// WebhookEvent — событие провайдера с проверенной подписью
+type WebhookEvent struct {
+	// ID события у провайдера; повторная доставка события с тем же ID игнорируется
+	ID   string    `json:"id"`
+	Type EventType `json:"type"`
+	// PaymentID — наш ID оплаты из метаданных, если провайдер его передал
+	PaymentID string `json:"payment_id,omitempty"`
+	Reference string `json:"reference"`
+	Amount    int64  `json:"amount"`
+}
@@ -0,0 +1,143 @@
+package payment_test
+
+import (
+	"TrainerConnect/internal/payment"
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestVerifySignature(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	payload := []byte(`{"id":"evt_1"}`)
+	header := payment.Sign("secret", payload, now)
+
+	assert.NoError(t, payment.VerifySignature("secret", payload, header, now.Add(time.Minute)))
+
+	// Чужой секрет, измененное тело и устаревшая подпись отклоняются
+	assert.ErrorIs(t, payment.VerifySignature("other", payload, header, now), payment.ErrInvalidSignature)
+	assert.ErrorIs(t, payment.VerifySignature("secret", []byte(`{"id":"evt_2"}`), header, now), payment.ErrInvalidSignature)
+	assert.ErrorIs(t, payment.VerifySignature("secret", payload, header, now.Add(time.Hour)), payment.ErrInvalidSignature)
+	assert.ErrorIs(t, payment.VerifySignature("secret", payload, "", now), payment.ErrInvalidSignature)
+	assert.ErrorIs(t, payment.VerifySignature("secret", payload, "t=abc,v1=00", now), payment.ErrInvalidSignature)
+
+	// При смене секрета заголовок содержит несколько подписей
+	rotated := payment.Sign("old", payload, now) + ",v1=" + header[len(fmt.Sprintf("t=%d,v1=", now.Unix())):]
+	assert.NoError(t, payment.VerifySignature("secret", payload, rotated, now))
+}
+
+func TestStripeParseWebhook(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	p := payment.NewStripeProvider("sk_test", "whsec")
+	p.Now = func() time.Time { return now }
+
+	payload := []byte(`{"id":"evt_1","type":"checkout.session.completed","data":{"object":{
+		"id":"cs_1","payment_intent":"pi_1","amount_total":270000,"metadata":{"payment_id":"42"}}}}`)
+	header := http.Header{}
+	header.Set("Stripe-Signature", payment.Sign("whsec", payload, now))
+
+	ev, err := p.ParseWebhook(payload, header)
+	require.NoError(t, err)
+	assert.Equal(t, &payment.WebhookEvent{ID: "evt_1", Type: payment.EventCheckoutCompleted,
+		PaymentID: "42", Reference: "pi_1", Amount: 270000}, ev)
+
+	// Неизвестные события разбираются без типа, чтобы их можно было подтвердить
+	other := []byte(`{"id":"evt_2","type":"customer.created","data":{"object":{"id":"cus_1"}}}`)
+	header.Set("Stripe-Signature", payment.Sign("whsec", other, now))
+	ev, err = p.ParseWebhook(other, header)
+	require.NoError(t, err)
+	assert.Equal(t, payment.EventType(""), ev.Type)
+
+	header.Set("Stripe-Signature", payment.Sign("wrong", payload, now))
+	_, err = p.ParseWebhook(payload, header)
+	assert.ErrorIs(t, err, payment.ErrInvalidSignature)
+}
+
+func TestStripeCreateCheckout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/checkout/sessions", r.URL.Path)
+		assert.Equal(t, "Bearer sk_test", r.Header.Get("Authorization"))
+		assert.Equal(t, "checkout-42", r.Header.Get("Idempotency-Key"))
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "manual", r.PostForm.Get("payment_intent_data[capture_method]"))
+		assert.Equal(t, "42", r.PostForm.Get("metadata[payment_id]"))
+		assert.Equal(t, "rub", r.PostForm.Get("line_items[0][price_data][currency]"))
+		assert.Equal(t, "270000", r.PostForm.Get("line_items[0][price_data][unit_amount]"))
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":"cs_1","url":"https://checkout.stripe.com/c/pay/cs_1"}`)
+	}))
+	defer server.Close()
+
+	p := payment.NewStripeProvider("sk_test", "whsec")
+	p.BaseURL = server.URL
+
+	checkout, err := p.CreateCheckout(context.Background(), payment.CheckoutRequest{
+		PaymentID: "42", Amount: 270000, Currency: "RUB", Description: "10 занятий",
+		SuccessURL: "https://example.com/ok", CancelURL: "https://example.com/cancel",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, &payment.Checkout{ID: "cs_1", URL: "https://checkout.stripe.com/c/pay/cs_1"}, checkout)
+}
+
+func TestStripeError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"error":{"type":"invalid_request_error","code":"payment_intent_unexpected_state","message":"already captured"}}`)
+	}))
+	defer server.Close()
+
+	p := payment.NewStripeProvider("sk_test", "whsec")
+	p.BaseURL = server.URL
+
+	err := p.Capture(context.Background(), "pi_1")
+	assert.ErrorContains(t, err, "already captured")
+}
+
+func TestFakeProvider(t *testing.T) {
+	p := payment.NewFakeProvider("secret")
+	var delivered []*payment.WebhookEvent
+	p.Deliver = func(payload []byte, header http.Header) {
+		// Доставленные события проходят ту же проверку подписи, что и в обработчике webhook
+		ev, err := p.ParseWebhook(payload, header)
+		require.NoError(t, err)
+		delivered = append(delivered, ev)
+	}
+	ctx := context.Background()
+
+	checkout, err := p.CreateCheckout(ctx, payment.CheckoutRequest{PaymentID: "7", Amount: 300000, Currency: "RUB"})
+	require.NoError(t, err)
+
+	paymentID, err := p.PaymentID(checkout.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "7", paymentID)
+
+	ev, err := p.Pay(checkout.ID)
+	require.NoError(t, err)
+	assert.Equal(t, payment.EventCheckoutCompleted, ev.Type)
+	assert.Equal(t, "7", ev.PaymentID)
+	assert.Equal(t, int64(300000), ev.Amount)
+
+	// Повторная оплата той же страницы невозможна
+	_, err = p.Pay(checkout.ID)
+	assert.ErrorIs(t, err, payment.ErrCheckoutClosed)
+
+	// Возврат до списания невозможен, списание идемпотентно
+	assert.Error(t, p.Refund(ctx, ev.Reference, ev.Amount))
+	assert.NoError(t, p.Capture(ctx, ev.Reference))
+	assert.NoError(t, p.Capture(ctx, ev.Reference))
+
+	require.NoError(t, p.Refund(ctx, ev.Reference, ev.Amount))
+	require.Len(t, delivered, 2)
+	assert.Equal(t, payment.EventRefunded, delivered[1].Type)
+	assert.Equal(t, ev.Reference, delivered[1].Reference)
+	assert.NotEqual(t, delivered[0].ID, delivered[1].ID)
+
+	_, err = p.Pay("cs_unknown")
+	assert.ErrorIs(t, err, payment.ErrUnknownCheckout)
+}
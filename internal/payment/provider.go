@@ -0,0 +1,92 @@
+package payment
+
+import (
+	"TrainerConnect/internal/apperr"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidSignature = apperr.Unauthorized("invalid_signature", "invalid webhook signature")
+	ErrInvalidEvent     = apperr.Invalid("invalid_event", "invalid webhook event")
+)
+
+// SignatureTolerance — насколько подписанное событие может быть старше текущего времени.
+// Ограничивает повторную отправку перехваченных событий.
+const SignatureTolerance = 5 * time.Minute
+
+// PaymentProvider — платежный провайдер. Списание и возврат выполняются по Reference
+// из события EventCheckoutCompleted и должны быть идемпотентны.
+type PaymentProvider interface {
+	// Name — имя провайдера, под которым сохраняются оплаты и события
+	Name() string
+	// CreateCheckout создает страницу оплаты, на которую перенаправляется клиент
+	CreateCheckout(ctx context.Context, req CheckoutRequest) (*Checkout, error)
+	// Capture списывает заблокированные при оплате средства
+	Capture(ctx context.Context, reference string) error
+	// Refund возвращает клиенту amount из списанных средств
+	Refund(ctx context.Context, reference string, amount int64) error
+	// ParseWebhook проверяет подпись уведомления и разбирает событие.
+	// При неверной подписи возвращается ErrInvalidSignature.
+	ParseWebhook(payload []byte, header http.Header) (*WebhookEvent, error)
+}
+
+// Sign подписывает payload в формате заголовка "t=<unix time>,v1=<hex HMAC-SHA256>".
+// Подписывается строка "<t>.<payload>", как у Stripe.
+func Sign(secret string, payload []byte, at time.Time) string {
+	t := strconv.FormatInt(at.Unix(), 10)
+	return "t=" + t + ",v1=" + computeSignature(secret, t, payload)
+}
+
+func computeSignature(secret, t string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(t))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature проверяет заголовок подписи, созданный Sign. Заголовок может содержать
+// несколько подписей v1, например во время смены секрета; достаточно совпадения одной.
+func VerifySignature(secret string, payload []byte, header string, now time.Time) error {
+	var t string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			t = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	if t == "" || len(signatures) == 0 {
+		return fmt.Errorf("%w: malformed header", ErrInvalidSignature)
+	}
+
+	unix, err := strconv.ParseInt(t, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: malformed timestamp", ErrInvalidSignature)
+	}
+	if age := now.Sub(time.Unix(unix, 0)); age > SignatureTolerance || age < -SignatureTolerance {
+		return fmt.Errorf("%w: timestamp outside of tolerance", ErrInvalidSignature)
+	}
+
+	expected := []byte(computeSignature(secret, t, payload))
+	for _, s := range signatures {
+		if hmac.Equal(expected, []byte(s)) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
@@ -0,0 +1,181 @@
+package payment
+
+import (
+	"TrainerConnect/internal/apperr"
+	"TrainerConnect/internal/credit"
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+)
+
+var (
+	ErrFreeProduct   = apperr.Conflict("free_product", "free products are granted by the trainer directly")
+	ErrNotRefundable = apperr.Conflict("not_refundable", "only paid payments can be refunded")
+)
+
+// Invoicer выставляет счет за оплату в той же транзакции, в которой она подтверждается
+type Invoicer interface {
+	Issue(ctx context.Context, tx *sql.Tx, p *Payment) error
+}
+
+// Service проводит оплату продуктов через провайдера и начисляет кредиты по его событиям
+type Service struct {
+	Storage  *Storage
+	Provider PaymentProvider
+	Credits  *credit.Storage
+	// Invoices необязателен: без него счета за оплаты не выставляются
+	Invoices Invoicer
+}
+
+// NewService создает сервис оплат. Фиктивный провайдер без получателя событий
+// доставляет их сразу в HandleWebhook, как это сделал бы настоящий провайдер.
+func NewService(storage *Storage, provider PaymentProvider, credits *credit.Storage, invoices Invoicer) *Service {
+	s := &Service{Storage: storage, Provider: provider, Credits: credits, Invoices: invoices}
+	if fake, ok := provider.(*FakeProvider); ok && fake.Deliver == nil {
+		fake.Deliver = func(payload []byte, header http.Header) {
+			if err := s.HandleWebhook(context.Background(), payload, header); err != nil {
+				log.Printf("Error handling fake payment event: %v", err)
+			}
+		}
+	}
+	return s
+}
+
+// Checkout создает оплату продукта клиентом и страницу оплаты у провайдера.
+// Цена фиксируется в момент создания оплаты.
+func (s *Service) Checkout(ctx context.Context, clientID, productID, successURL, cancelURL string) (*Payment, error) {
+	product, err := s.Credits.GetProduct(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case !product.Active:
+		return nil, credit.ErrProductInactive
+	case product.TrainerID == clientID:
+		return nil, credit.ErrOwnProduct
+	case product.Price == 0:
+		return nil, ErrFreeProduct
+	}
+
+	p := &Payment{
+		ClientID:  clientID,
+		TrainerID: product.TrainerID,
+		ProductID: product.ID,
+		Amount:    product.Price,
+		Currency:  product.Currency,
+		Provider:  s.Provider.Name(),
+	}
+	if err := s.Storage.Create(ctx, p); err != nil {
+		return nil, err
+	}
+
+	checkout, err := s.Provider.CreateCheckout(ctx, CheckoutRequest{
+		PaymentID:   p.ID,
+		Amount:      p.Amount,
+		Currency:    p.Currency,
+		Description: product.Name,
+		SuccessURL:  successURL,
+		CancelURL:   cancelURL,
+	})
+	if err != nil {
+		if ferr := s.Storage.SetFailed(ctx, p.ID); ferr != nil {
+			log.Printf("Error marking payment %s as failed: %v", p.ID, ferr)
+		}
+		return nil, err
+	}
+	if err := s.Storage.SetCheckout(ctx, p, checkout); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Refund запрашивает у провайдера возврат всей суммы. Оплата отмечается возвращенной,
+// а неиспользованные кредиты списываются, когда провайдер пришлет событие о возврате.
+func (s *Service) Refund(ctx context.Context, p *Payment) error {
+	if p.Status != StatusPaid {
+		return ErrNotRefundable
+	}
+	return s.Provider.Refund(ctx, p.Reference, p.Amount)
+}
+
+// HandleWebhook проверяет подпись события провайдера и применяет его к оплате и кредитам.
+// Событие обрабатывается не более одного раза: его ID сохраняется в той же транзакции,
+// что и изменения. Если возвращается ошибка, событие не сохранено и провайдер повторит его.
+func (s *Service) HandleWebhook(ctx context.Context, payload []byte, header http.Header) error {
+	ev, err := s.Provider.ParseWebhook(payload, header)
+	if err != nil {
+		return err
+	}
+	provider := s.Provider.Name()
+
+	tx, err := s.Storage.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	fresh, err := recordEvent(ctx, tx, provider, ev)
+	if err != nil || !fresh {
+		return err
+	}
+	if ev.Type == "" {
+		return tx.Commit()
+	}
+
+	p, err := lockPayment(ctx, tx, provider, ev)
+	if errors.Is(err, ErrNotFound) {
+		log.Printf("Payment event %s %s does not match any payment", provider, ev.ID)
+		return tx.Commit()
+	}
+	if err != nil {
+		return err
+	}
+	if err := linkEvent(ctx, tx, provider, ev.ID, p.ID); err != nil {
+		return err
+	}
+
+	switch {
+	case ev.Type == EventCheckoutCompleted && p.Status == StatusPending:
+		if ev.Amount != p.Amount {
+			// Заблокированные средства не списываются и вернутся клиенту по истечении блокировки
+			log.Printf("Payment %s: provider amount %d does not match %d", p.ID, ev.Amount, p.Amount)
+			p.Status = StatusFailed
+			break
+		}
+		// Списание идемпотентно у провайдера, поэтому повтор после отката транзакции безопасен
+		if err := s.Provider.Capture(ctx, ev.Reference); err != nil {
+			return err
+		}
+		grant, err := s.Credits.GrantTx(ctx, tx, p.ClientID, p.ProductID, "", "payment "+p.ID)
+		if err != nil {
+			return err
+		}
+		p.Status, p.Reference, p.GrantID = StatusPaid, ev.Reference, grant.ID
+		if err := updatePayment(ctx, tx, p); err != nil {
+			return err
+		}
+		if s.Invoices != nil {
+			if err := s.Invoices.Issue(ctx, tx, p); err != nil {
+				return err
+			}
+		}
+		return tx.Commit()
+	case ev.Type == EventPaymentFailed && p.Status == StatusPending:
+		p.Status = StatusFailed
+	case ev.Type == EventRefunded && p.Status == StatusPaid:
+		if err := s.Credits.Revoke(ctx, tx, p.GrantID, "refund of payment "+p.ID); err != nil {
+			return err
+		}
+		p.Status = StatusRefunded
+	default:
+		// Событие не меняет оплату, например повторное завершение уже оплаченной
+		return tx.Commit()
+	}
+
+	if err := updatePayment(ctx, tx, p); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
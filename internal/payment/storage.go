@@ -0,0 +1,138 @@
+package payment
+
+import (
+	"TrainerConnect/internal/apperr"
+	postgres "TrainerConnect/pkg/postgresql"
+	"context"
+	"database/sql"
+)
+
+var ErrNotFound = apperr.NotFound("payment_not_found", "payment not found")
+
+type Storage struct {
+	*sql.DB
+}
+
+func NewStorage(db *sql.DB) *Storage {
+	return &Storage{DB: db}
+}
+
+const paymentColumns = `id, client_id, trainer_id, product_id, amount, currency, status, provider,
+	COALESCE(checkout_id, ''), COALESCE(checkout_url, ''), COALESCE(reference, ''), COALESCE(grant_id::text, ''),
+	created_at, updated_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanPayment(row rowScanner) (*Payment, error) {
+	p := &Payment{}
+	err := row.Scan(&p.ID, &p.ClientID, &p.TrainerID, &p.ProductID, &p.Amount, &p.Currency, &p.Status, &p.Provider,
+		&p.CheckoutID, &p.CheckoutURL, &p.Reference, &p.GrantID, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return p, nil
+}
+
+// Create сохраняет новую оплату в состоянии pending
+func (s *Storage) Create(ctx context.Context, p *Payment) (err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	p.Status = StatusPending
+	return s.DB.QueryRowContext(ctx, `INSERT INTO payments (client_id, trainer_id, product_id, amount, currency, status, provider)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, created_at, updated_at`,
+		p.ClientID, p.TrainerID, p.ProductID, p.Amount, p.Currency, p.Status, p.Provider).
+		Scan(&p.ID, &p.CreatedAt, &p.UpdatedAt)
+}
+
+// SetCheckout сохраняет страницу оплаты, созданную у провайдера
+func (s *Storage) SetCheckout(ctx context.Context, p *Payment, checkout *Checkout) (err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	p.CheckoutID, p.CheckoutURL = checkout.ID, checkout.URL
+	return s.DB.QueryRowContext(ctx, `UPDATE payments SET checkout_id = $1, checkout_url = $2, updated_at = now()
+		WHERE id = $3 RETURNING updated_at`, p.CheckoutID, p.CheckoutURL, p.ID).Scan(&p.UpdatedAt)
+}
+
+// SetFailed отмечает оплату неуспешной, если она еще не завершена
+func (s *Storage) SetFailed(ctx context.Context, id string) (err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	_, err = s.DB.ExecContext(ctx, `UPDATE payments SET status = $1, updated_at = now() WHERE id = $2 AND status = $3`,
+		StatusFailed, id, StatusPending)
+	return err
+}
+
+// Get возвращает оплату по ID
+func (s *Storage) Get(ctx context.Context, id string) (_ *Payment, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	return scanPayment(s.DB.QueryRowContext(ctx, "SELECT "+paymentColumns+" FROM payments WHERE id = $1", id))
+}
+
+// ListForUser возвращает оплаты, в которых пользователь — покупатель или продавец
+func (s *Storage) ListForUser(ctx context.Context, userID string) (_ []Payment, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	rows, err := s.DB.QueryContext(ctx, "SELECT "+paymentColumns+` FROM payments
+		WHERE client_id = $1 OR trainer_id = $1
+		ORDER BY created_at DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	payments := []Payment{}
+	for rows.Next() {
+		p, err := scanPayment(rows)
+		if err != nil {
+			return nil, err
+		}
+		payments = append(payments, *p)
+	}
+	return payments, rows.Err()
+}
+
+// recordEvent запоминает событие провайдера и возвращает false, если оно уже обработано.
+// Параллельная доставка того же события ждет завершения первой транзакции.
+func recordEvent(ctx context.Context, tx *sql.Tx, provider string, ev *WebhookEvent) (bool, error) {
+	res, err := tx.ExecContext(ctx, `INSERT INTO payment_events (provider, event_id, type)
+		VALUES ($1, $2, $3) ON CONFLICT (provider, event_id) DO NOTHING`, provider, ev.ID, ev.Type)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// lockPayment блокирует оплату, к которой относится событие: по нашему ID из метаданных,
+// а если провайдер его не передал — по ID платежа у провайдера
+func lockPayment(ctx context.Context, tx *sql.Tx, provider string, ev *WebhookEvent) (*Payment, error) {
+	if ev.PaymentID != "" {
+		return scanPayment(tx.QueryRowContext(ctx, "SELECT "+paymentColumns+" FROM payments WHERE id::text = $1 AND provider = $2 FOR UPDATE",
+			ev.PaymentID, provider))
+	}
+	return scanPayment(tx.QueryRowContext(ctx, "SELECT "+paymentColumns+" FROM payments WHERE reference = $1 AND provider = $2 FOR UPDATE",
+		ev.Reference, provider))
+}
+
+func linkEvent(ctx context.Context, tx *sql.Tx, provider, eventID, paymentID string) error {
+	_, err := tx.ExecContext(ctx, "UPDATE payment_events SET payment_id = $1 WHERE provider = $2 AND event_id = $3",
+		paymentID, provider, eventID)
+	return err
+}
+
+func updatePayment(ctx context.Context, tx *sql.Tx, p *Payment) error {
+	return tx.QueryRowContext(ctx, `UPDATE payments SET status = $1, reference = NULLIF($2, ''), grant_id = NULLIF($3, '')::bigint,
+			updated_at = now()
+		WHERE id = $4 RETURNING updated_at`, p.Status, p.Reference, p.GrantID, p.ID).Scan(&p.UpdatedAt)
+}
@@ -0,0 +1,162 @@
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const stripeAPI = "https://api.stripe.com"
+
+// StripeProvider работает с Stripe Checkout. Оплата создается с ручным списанием:
+// после события checkout.session.completed средства списываются вызовом Capture.
+type StripeProvider struct {
+	SecretKey     string
+	WebhookSecret string
+	BaseURL       string
+	Client        *http.Client
+	Now           func() time.Time
+}
+
+// NewStripeProvider создает провайдера с ключом API и секретом подписи уведомлений
+func NewStripeProvider(secretKey, webhookSecret string) *StripeProvider {
+	return &StripeProvider{
+		SecretKey:     secretKey,
+		WebhookSecret: webhookSecret,
+		BaseURL:       stripeAPI,
+		Client:        &http.Client{Timeout: 30 * time.Second},
+		Now:           time.Now,
+	}
+}
+
+func (p *StripeProvider) Name() string {
+	return "stripe"
+}
+
+type stripeError struct {
+	Error struct {
+		Type    string `json:"type"`
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// post выполняет запрос к API. idempotencyKey защищает от повторного выполнения
+// операции при повторе запроса после сетевой ошибки.
+func (p *StripeProvider) post(ctx context.Context, path, idempotencyKey string, form url.Values, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.BaseURL+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+p.SecretKey)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Idempotency-Key", idempotencyKey)
+
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		var e stripeError
+		if json.Unmarshal(body, &e) == nil && e.Error.Message != "" {
+			return fmt.Errorf("stripe: %s (%s %s)", e.Error.Message, e.Error.Type, e.Error.Code)
+		}
+		return fmt.Errorf("stripe: unexpected status %d", resp.StatusCode)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(body, out)
+}
+
+func (p *StripeProvider) CreateCheckout(ctx context.Context, req CheckoutRequest) (*Checkout, error) {
+	form := url.Values{
+		"mode":                                {"payment"},
+		"success_url":                         {req.SuccessURL},
+		"cancel_url":                          {req.CancelURL},
+		"client_reference_id":                 {req.PaymentID},
+		"metadata[payment_id]":                {req.PaymentID},
+		"payment_intent_data[capture_method]": {"manual"},
+		"payment_intent_data[metadata][payment_id]":     {req.PaymentID},
+		"line_items[0][quantity]":                       {"1"},
+		"line_items[0][price_data][currency]":           {strings.ToLower(req.Currency)},
+		"line_items[0][price_data][unit_amount]":        {strconv.FormatInt(req.Amount, 10)},
+		"line_items[0][price_data][product_data][name]": {req.Description},
+	}
+
+	var session struct {
+		ID  string `json:"id"`
+		URL string `json:"url"`
+	}
+	if err := p.post(ctx, "/v1/checkout/sessions", "checkout-"+req.PaymentID, form, &session); err != nil {
+		return nil, err
+	}
+	return &Checkout{ID: session.ID, URL: session.URL}, nil
+}
+
+func (p *StripeProvider) Capture(ctx context.Context, reference string) error {
+	return p.post(ctx, "/v1/payment_intents/"+url.PathEscape(reference)+"/capture", "capture-"+reference, url.Values{}, nil)
+}
+
+func (p *StripeProvider) Refund(ctx context.Context, reference string, amount int64) error {
+	form := url.Values{
+		"payment_intent": {reference},
+		"amount":         {strconv.FormatInt(amount, 10)},
+	}
+	return p.post(ctx, "/v1/refunds", "refund-"+reference, form, nil)
+}
+
+// stripeEvent — поля событий Stripe, которые нужны для обработки оплат
+type stripeEvent struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	Data struct {
+		Object struct {
+			ID             string            `json:"id"`
+			PaymentIntent  string            `json:"payment_intent"`
+			Amount         int64             `json:"amount"`
+			AmountTotal    int64             `json:"amount_total"`
+			AmountRefunded int64             `json:"amount_refunded"`
+			Metadata       map[string]string `json:"metadata"`
+		} `json:"object"`
+	} `json:"data"`
+}
+
+// ParseWebhook проверяет заголовок Stripe-Signature и приводит событие к WebhookEvent.
+// События, не влияющие на оплаты, возвращаются с пустым Type.
+func (p *StripeProvider) ParseWebhook(payload []byte, header http.Header) (*WebhookEvent, error) {
+	if err := VerifySignature(p.WebhookSecret, payload, header.Get("Stripe-Signature"), p.Now()); err != nil {
+		return nil, err
+	}
+
+	var e stripeEvent
+	if err := json.Unmarshal(payload, &e); err != nil || e.ID == "" {
+		return nil, ErrInvalidEvent
+	}
+	obj := e.Data.Object
+	ev := &WebhookEvent{ID: e.ID, PaymentID: obj.Metadata["payment_id"]}
+
+	switch e.Type {
+	case "checkout.session.completed":
+		ev.Type, ev.Reference, ev.Amount = EventCheckoutCompleted, obj.PaymentIntent, obj.AmountTotal
+	case "checkout.session.expired":
+		ev.Type, ev.Reference = EventPaymentFailed, obj.PaymentIntent
+	case "payment_intent.payment_failed", "payment_intent.canceled":
+		ev.Type, ev.Reference, ev.Amount = EventPaymentFailed, obj.ID, obj.Amount
+	case "charge.refunded":
+		ev.Type, ev.Reference, ev.Amount = EventRefunded, obj.PaymentIntent, obj.AmountRefunded
+	}
+	return ev, nil
+}
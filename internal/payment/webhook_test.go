@@ -0,0 +1,153 @@
+package payment_test
+
+import (
+	"TrainerConnect/internal/auth"
+	"TrainerConnect/internal/credit"
+	"TrainerConnect/internal/invoice"
+	"TrainerConnect/internal/payment"
+	postgres "TrainerConnect/pkg/postgresql"
+	"TrainerConnect/pkg/postgresql/config"
+	"context"
+	"database/sql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"log"
+	"math/rand"
+	"net/http"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+)
+
+var db *sql.DB
+
+func TestMain(m *testing.M) {
+	// Тесты с БД пропускаются, если она недоступна; остальные тесты пакета выполняются всегда
+	cfg, err := config.ReadConfig("../../pkg/postgresql/config/database_test.json")
+	if err == nil {
+		db, err = postgres.NewDB(cfg)
+	}
+	if err == nil {
+		var migrator *postgres.Migrator
+		if migrator, err = postgres.NewMigrator(db); err == nil {
+			_, err = migrator.Up(context.Background())
+		}
+	}
+	if err != nil {
+		log.Printf("Test database is unavailable, skipping database tests: %v", err)
+		db = nil
+	}
+
+	exitCode := m.Run()
+	if db != nil {
+		db.Close()
+	}
+	os.Exit(exitCode)
+}
+
+func requireDB(t *testing.T) {
+	if db == nil {
+		t.Skip("test database is unavailable")
+	}
+}
+
+// ID пользователей берутся из диапазона, которого не касаются другие тесты с той же БД
+var nextUserID = 930000000 + rand.New(rand.NewSource(time.Now().UnixNano())).Intn(1000000)*100
+
+// newUser создает пользователя с ролью role. Журнал кредитов нельзя изменять, поэтому
+// пользователи, их оплаты и начисления остаются в тестовой БД.
+func newUser(t *testing.T, role string) string {
+	nextUserID++
+	id := strconv.Itoa(nextUserID)
+	_, err := db.Exec(`INSERT INTO users (user_id, first_name, last_name, username, password, salt, role, email)
+		VALUES ($1, 'Test', 'User', $2, 'hash', 'salt', $3, $4)`, id, "payment"+id, role, "payment"+id+"@example.com")
+	require.NoError(t, err)
+	return id
+}
+
+// webhookEnv — сервис оплат с фиктивным провайдером, события которого перехватываются тестом
+type webhookEnv struct {
+	service  *payment.Service
+	provider *payment.FakeProvider
+	client   string
+	product  string
+
+	payload []byte
+	header  http.Header
+}
+
+func newWebhookEnv(t *testing.T) *webhookEnv {
+	credits := credit.NewStorage(db)
+	env := &webhookEnv{provider: payment.NewFakeProvider("whsec")}
+	env.provider.Deliver = func(payload []byte, header http.Header) {
+		env.payload, env.header = payload, header
+	}
+	env.service = payment.NewService(payment.NewStorage(db), env.provider, credits, invoice.NewStorage(db))
+
+	trainer := newUser(t, auth.RoleTrainer)
+	env.client = newUser(t, auth.RoleClient)
+	_, err := db.Exec("INSERT INTO trainer_profiles (user_id, hourly_rate, currency) VALUES ($1, 6000, 'EUR')", trainer)
+	require.NoError(t, err)
+	p := &credit.Product{TrainerID: trainer, Kind: credit.KindPack, Name: "Pack", Sessions: 10, Price: 50000,
+		Currency: "EUR", Active: true}
+	require.NoError(t, credits.CreateProduct(context.Background(), p))
+	env.product = p.ID
+	return env
+}
+
+// pay создает оплату и имитирует ее завершение клиентом. Событие провайдера
+// сохраняется в env и не обрабатывается.
+func (env *webhookEnv) pay(t *testing.T) (*payment.Payment, *payment.WebhookEvent) {
+	p, err := env.service.Checkout(context.Background(), env.client, env.product, "https://example.com/ok", "https://example.com/cancel")
+	require.NoError(t, err)
+	ev, err := env.provider.Pay(p.CheckoutID)
+	require.NoError(t, err)
+	return p, ev
+}
+
+// count возвращает число строк query с параметром arg
+func count(t *testing.T, query string, arg interface{}) int {
+	var n int
+	require.NoError(t, db.QueryRow(query, arg).Scan(&n))
+	return n
+}
+
+func TestWebhookDuplicateEvent(t *testing.T) {
+	requireDB(t)
+	ctx := context.Background()
+	env := newWebhookEnv(t)
+	p, _ := env.pay(t)
+
+	// Провайдер может доставить одно событие несколько раз
+	require.NoError(t, env.service.HandleWebhook(ctx, env.payload, env.header))
+	require.NoError(t, env.service.HandleWebhook(ctx, env.payload, env.header))
+
+	paid, err := env.service.Storage.Get(ctx, p.ID)
+	require.NoError(t, err)
+	assert.Equal(t, payment.StatusPaid, paid.Status)
+	assert.NotEmpty(t, paid.GrantID)
+
+	assert.Equal(t, 1, count(t, "SELECT COUNT(*) FROM credit_grants WHERE client_id = $1", env.client))
+	assert.Equal(t, 1, count(t, "SELECT COUNT(*) FROM credit_entries e JOIN credit_grants g ON g.id = e.grant_id WHERE g.client_id = $1", env.client))
+	assert.Equal(t, 1, count(t, "SELECT COUNT(*) FROM invoices WHERE payment_id = $1", p.ID))
+}
+
+func TestWebhookInvalidSignature(t *testing.T) {
+	requireDB(t)
+	ctx := context.Background()
+	env := newWebhookEnv(t)
+	p, ev := env.pay(t)
+
+	header := http.Header{}
+	header.Set("Fake-Signature", payment.Sign("wrong", env.payload, time.Now()))
+	err := env.service.HandleWebhook(ctx, env.payload, header)
+	assert.ErrorIs(t, err, payment.ErrInvalidSignature)
+
+	pending, err := env.service.Storage.Get(ctx, p.ID)
+	require.NoError(t, err)
+	assert.Equal(t, payment.StatusPending, pending.Status)
+	assert.Equal(t, 0, count(t, "SELECT COUNT(*) FROM payment_events WHERE provider = 'fake' AND event_id = $1", ev.ID))
+	assert.Equal(t, 0, count(t, "SELECT COUNT(*) FROM credit_grants WHERE client_id = $1", env.client))
+	assert.Equal(t, 0, count(t, "SELECT COUNT(*) FROM invoices WHERE payment_id = $1", p.ID))
+}
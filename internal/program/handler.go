@@ -0,0 +1,337 @@
+package program
+
+import (
+	"TrainerConnect/internal/apperr"
+	"TrainerConnect/internal/auth"
+	"TrainerConnect/internal/events"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+const (
+	exerciseURL = "/exercises/"
+	programURL  = "/programs/"
+)
+
+type Handler struct {
+	Storage *Storage
+	Roster  auth.Roster
+	Events  events.Publisher
+}
+
+// NewHandler создает обработчик программ. Назначить программу можно только клиенту из roster тренера.
+// О назначении и новых версиях программы клиент узнает через publisher.
+func NewHandler(storage *Storage, roster auth.Roster, publisher events.Publisher) *Handler {
+	return &Handler{Storage: storage, Roster: roster, Events: publisher}
+}
+
+// publish сообщает назначенному клиенту, что его программа изменилась
+func (h *Handler) publish(p *Program, clientID *string) {
+	if clientID != nil {
+		h.Events.Publish("program.updated", p, *clientID)
+	}
+}
+
+func (h *Handler) Register(router *chi.Mux) {
+	router.Group(func(r chi.Router) {
+		r.Use(auth.RequireAuth)
+		r.Get("/exercises", h.ListExercises)
+		r.Post("/exercises", h.CreateExercise)
+		r.Get(exerciseURL+"{id}", h.GetExercise)
+
+		r.Get("/programs", h.ListPrograms)
+		r.Post("/programs", h.CreateProgram)
+		r.Get(programURL+"{id}", h.GetProgram)
+		r.Put(programURL+"{id}", h.UpdateProgram)
+		r.Get(programURL+"{id}/versions", h.ListVersions)
+		r.Post(programURL+"{id}/assign", h.AssignProgram)
+	})
+}
+
+// isTrainer проверяет, что пользователь может составлять программы
+func isTrainer(p *auth.Principal) bool {
+	return p.Role == auth.RoleTrainer || p.IsAdmin()
+}
+
+// ListExercises ищет упражнения в библиотеке по группе мышц (muscle) и названию (q)
+func (h *Handler) ListExercises(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	exercises, err := h.Storage.ListExercises(r.Context(), q.Get("muscle"), q.Get("q"))
+	if err != nil {
+		log.Printf("Error listing exercises: %v", err)
+		apperr.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(exercises)
+}
+
+// CreateExercise добавляет упражнение в библиотеку. Доступно тренерам и администраторам.
+func (h *Handler) CreateExercise(w http.ResponseWriter, r *http.Request) {
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	if !isTrainer(principal) {
+		apperr.Respond(w, http.StatusForbidden, "Forbidden")
+		return
+	}
+
+	var e Exercise
+	if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+		apperr.Respond(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := e.Validate(); err != nil {
+		apperr.Write(w, err)
+		return
+	}
+	e.CreatedBy = principal.UserID
+
+	if err := h.Storage.CreateExercise(r.Context(), &e); err != nil {
+		log.Printf("Error creating exercise: %v", err)
+		apperr.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(e)
+}
+
+// GetExercise возвращает упражнение из библиотеки
+func (h *Handler) GetExercise(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if _, err := strconv.Atoi(id); err != nil {
+		apperr.Respond(w, http.StatusBadRequest, "Invalid exercise ID")
+		return
+	}
+
+	e, err := h.Storage.GetExercise(r.Context(), id)
+	if err != nil {
+		if !apperr.Expected(err) {
+			log.Printf("Error getting exercise %s: %v", id, err)
+		}
+		apperr.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(e)
+}
+
+// checkRoster проверяет, что клиент тренируется у тренера, и при отказе сам отправляет ответ
+func (h *Handler) checkRoster(w http.ResponseWriter, r *http.Request, trainerID, clientID string) bool {
+	ok, err := h.Roster.HasClient(r.Context(), trainerID, clientID)
+	if err != nil {
+		log.Printf("Error checking roster of trainer %s: %v", trainerID, err)
+		apperr.Write(w, err)
+		return false
+	}
+	if !ok {
+		apperr.Write(w, ErrNotOnRoster)
+		return false
+	}
+	return true
+}
+
+// writeSaveError отправляет ответ на ошибку сохранения программы
+func writeSaveError(w http.ResponseWriter, err error) {
+	if !apperr.Expected(err) {
+		log.Printf("Error saving program: %v", err)
+	}
+	apperr.Write(w, err)
+}
+
+// CreateProgram создает программу. Если указан client_id, программа сразу назначается клиенту.
+func (h *Handler) CreateProgram(w http.ResponseWriter, r *http.Request) {
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	if principal.Role != auth.RoleTrainer {
+		apperr.Respond(w, http.StatusForbidden, "Only trainers can create programs")
+		return
+	}
+
+	var p Program
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		apperr.Respond(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := p.Validate(); err != nil {
+		apperr.Write(w, err)
+		return
+	}
+	p.TrainerID = principal.UserID
+	if p.ClientID != nil {
+		if _, err := strconv.Atoi(*p.ClientID); err != nil {
+			apperr.Respond(w, http.StatusBadRequest, "Invalid client ID")
+			return
+		}
+		if !h.checkRoster(w, r, p.TrainerID, *p.ClientID) {
+			return
+		}
+	}
+
+	if err := h.Storage.Create(r.Context(), &p); err != nil {
+		writeSaveError(w, err)
+		return
+	}
+	h.publish(&p, p.ClientID)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(p)
+}
+
+// ListPrograms возвращает программы текущего пользователя без содержимого
+func (h *Handler) ListPrograms(w http.ResponseWriter, r *http.Request) {
+	principal, _ := auth.PrincipalFromContext(r.Context())
+
+	programs, err := h.Storage.ListForUser(r.Context(), principal.UserID)
+	if err != nil {
+		log.Printf("Error listing programs of user %s: %v", principal.UserID, err)
+		apperr.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(programs)
+}
+
+// load загружает программу нужной версии и проверяет доступ: автор, назначенный клиент или администратор
+func (h *Handler) load(w http.ResponseWriter, r *http.Request, version int) (*Program, bool) {
+	id := chi.URLParam(r, "id")
+	if _, err := strconv.Atoi(id); err != nil {
+		apperr.Respond(w, http.StatusBadRequest, "Invalid program ID")
+		return nil, false
+	}
+
+	p, err := h.Storage.Get(r.Context(), id, version)
+	if err != nil {
+		if !apperr.Expected(err) {
+			log.Printf("Error getting program %s: %v", id, err)
+		}
+		apperr.Write(w, err)
+		return nil, false
+	}
+
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	isClient := p.ClientID != nil && *p.ClientID == principal.UserID
+	if principal.UserID != p.TrainerID && !isClient && !principal.IsAdmin() {
+		apperr.Respond(w, http.StatusForbidden, "Forbidden")
+		return nil, false
+	}
+	return p, true
+}
+
+// GetProgram возвращает программу. Параметр version позволяет получить прошлую версию.
+func (h *Handler) GetProgram(w http.ResponseWriter, r *http.Request) {
+	version := 0
+	if v := r.URL.Query().Get("version"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			apperr.Respond(w, http.StatusBadRequest, "Invalid version parameter")
+			return
+		}
+		version = n
+	}
+
+	p, ok := h.load(w, r, version)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(p)
+}
+
+// UpdateProgram сохраняет новое содержимое программы как новую версию. Доступно только автору.
+func (h *Handler) UpdateProgram(w http.ResponseWriter, r *http.Request) {
+	current, ok := h.load(w, r, 0)
+	if !ok {
+		return
+	}
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	if principal.UserID != current.TrainerID {
+		apperr.Respond(w, http.StatusForbidden, "Forbidden")
+		return
+	}
+
+	var p Program
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		apperr.Respond(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := p.Validate(); err != nil {
+		apperr.Write(w, err)
+		return
+	}
+	p.ID = current.ID
+
+	if err := h.Storage.SaveVersion(r.Context(), &p, principal.UserID); err != nil {
+		writeSaveError(w, err)
+		return
+	}
+	h.publish(&p, current.ClientID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(p)
+}
+
+// ListVersions возвращает историю версий программы
+func (h *Handler) ListVersions(w http.ResponseWriter, r *http.Request) {
+	p, ok := h.load(w, r, 0)
+	if !ok {
+		return
+	}
+
+	versions, err := h.Storage.Versions(r.Context(), p.ID)
+	if err != nil {
+		log.Printf("Error listing versions of program %s: %v", p.ID, err)
+		apperr.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(versions)
+}
+
+type assignRequest struct {
+	ClientID string `json:"client_id"`
+}
+
+// AssignProgram назначает программу клиенту из roster тренера
+func (h *Handler) AssignProgram(w http.ResponseWriter, r *http.Request) {
+	p, ok := h.load(w, r, 0)
+	if !ok {
+		return
+	}
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	if principal.UserID != p.TrainerID {
+		apperr.Respond(w, http.StatusForbidden, "Forbidden")
+		return
+	}
+
+	var req assignRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apperr.Respond(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if _, err := strconv.Atoi(req.ClientID); err != nil {
+		apperr.Respond(w, http.StatusBadRequest, "Invalid client ID")
+		return
+	}
+	if !h.checkRoster(w, r, p.TrainerID, req.ClientID) {
+		return
+	}
+
+	if err := h.Storage.Assign(r.Context(), p.ID, req.ClientID); err != nil {
+		writeSaveError(w, err)
+		return
+	}
+	p.ClientID = &req.ClientID
+	h.publish(p, p.ClientID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(p)
+}
@@ -0,0 +1,135 @@
+package program
+
+import (
+	"TrainerConnect/internal/apperr"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Exercise — упражнение из общей библиотеки
+type Exercise struct {
+	ID           string   `json:"id"`
+	Name         string   `json:"name"`
+	MuscleGroups []string `json:"muscle_groups"`
+	Equipment    []string `json:"equipment"`
+	Instructions string   `json:"instructions"`
+	MediaURL     string   `json:"media_url"`
+	CreatedBy    string   `json:"created_by"`
+}
+
+// Validate проверяет обязательные поля упражнения и приводит группы мышц и инвентарь к нижнему регистру
+func (e *Exercise) Validate() error {
+	e.Name = strings.TrimSpace(e.Name)
+	if e.Name == "" {
+		return apperr.InvalidField("name", "required", "exercise name is required")
+	}
+	if len(e.MuscleGroups) == 0 {
+		return apperr.InvalidField("muscle_groups", "required", "at least one muscle group is required")
+	}
+	for i, m := range e.MuscleGroups {
+		e.MuscleGroups[i] = strings.ToLower(strings.TrimSpace(m))
+	}
+	if e.Equipment == nil {
+		e.Equipment = []string{}
+	}
+	for i, eq := range e.Equipment {
+		e.Equipment[i] = strings.ToLower(strings.TrimSpace(eq))
+	}
+	if e.MediaURL != "" && !strings.HasPrefix(e.MediaURL, "https://") && !strings.HasPrefix(e.MediaURL, "http://") {
+		return apperr.InvalidField("media_url", "invalid_url", "media_url must be an http(s) URL")
+	}
+	return nil
+}
+
+// Prescription — назначение упражнения в тренировочном дне
+type Prescription struct {
+	ExerciseID   string `json:"exercise_id"`
+	ExerciseName string `json:"exercise_name,omitempty"`
+	Sets         int    `json:"sets"`
+	Reps         string `json:"reps"`
+	Load         string `json:"load"`
+	Tempo        string `json:"tempo"`
+	RestSeconds  int    `json:"rest_seconds"`
+	Notes        string `json:"notes"`
+}
+
+// Day — тренировочный день недели программы
+type Day struct {
+	Number    int            `json:"number"`
+	Title     string         `json:"title"`
+	Exercises []Prescription `json:"exercises"`
+}
+
+// Week — неделя программы
+type Week struct {
+	Number int   `json:"number"`
+	Days   []Day `json:"days"`
+}
+
+// Program — программа тренировок тренера. Содержимое программы версионируется:
+// каждое изменение создает новую версию, а старые остаются неизменными.
+type Program struct {
+	ID          string    `json:"id"`
+	TrainerID   string    `json:"trainer_id"`
+	ClientID    *string   `json:"client_id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Version     int       `json:"version"`
+	Weeks       []Week    `json:"weeks"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Version — запись о версии программы
+type Version struct {
+	ProgramID string    `json:"program_id"`
+	Version   int       `json:"version"`
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Validate проверяет структуру программы: недели и дни пронумерованы без повторов,
+// в каждом дне есть упражнения с положительным числом подходов
+func (p *Program) Validate() error {
+	p.Title = strings.TrimSpace(p.Title)
+	if p.Title == "" {
+		return apperr.InvalidField("title", "required", "program title is required")
+	}
+	if len(p.Weeks) == 0 {
+		return apperr.InvalidField("weeks", "required", "program must contain at least one week")
+	}
+
+	weeks := map[int]bool{}
+	for _, w := range p.Weeks {
+		if w.Number < 1 || weeks[w.Number] {
+			return apperr.InvalidField("weeks", "invalid_number", fmt.Sprintf("week numbers must be positive and unique, got %d", w.Number))
+		}
+		weeks[w.Number] = true
+
+		days := map[int]bool{}
+		for _, d := range w.Days {
+			if d.Number < 1 || d.Number > 7 || days[d.Number] {
+				return apperr.InvalidField("days", "invalid_number", fmt.Sprintf("week %d: day numbers must be unique and between 1 and 7, got %d", w.Number, d.Number))
+			}
+			days[d.Number] = true
+
+			if len(d.Exercises) == 0 {
+				return apperr.InvalidField("exercises", "required", fmt.Sprintf("week %d day %d: at least one exercise is required", w.Number, d.Number))
+			}
+			for _, e := range d.Exercises {
+				if _, err := strconv.Atoi(e.ExerciseID); err != nil {
+					return apperr.InvalidField("exercise_id", "numeric", fmt.Sprintf("week %d day %d: invalid exercise_id %q", w.Number, d.Number, e.ExerciseID))
+				}
+				if e.Sets < 1 {
+					return apperr.InvalidField("sets", "not_positive", fmt.Sprintf("week %d day %d: sets must be positive", w.Number, d.Number))
+				}
+				if e.RestSeconds < 0 {
+					return apperr.InvalidField("rest_seconds", "negative", fmt.Sprintf("week %d day %d: rest_seconds must not be negative", w.Number, d.Number))
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package program_test
+
+import (
+	"TrainerConnect/internal/program"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func validProgram() *program.Program {
+	return &program.Program{
+		Title: "Базовая сила",
+		Weeks: []program.Week{
+			{Number: 1, Days: []program.Day{
+				{Number: 1, Title: "Ноги", Exercises: []program.Prescription{
+					{ExerciseID: "1", Sets: 5, Reps: "5", Load: "75% 1RM", Tempo: "3-1-1-0", RestSeconds: 180},
+				}},
+				{Number: 3, Title: "Спина", Exercises: []program.Prescription{
+					{ExerciseID: "2", Sets: 3, Reps: "8-10", RestSeconds: 120},
+				}},
+			}},
+		},
+	}
+}
+
+func TestProgramValidate(t *testing.T) {
+	assert.NoError(t, validProgram().Validate())
+
+	p := validProgram()
+	p.Title = "  "
+	assert.Error(t, p.Validate())
+
+	p = validProgram()
+	p.Weeks = append(p.Weeks, program.Week{Number: 1})
+	assert.Error(t, p.Validate(), "повторяющийся номер недели")
+
+	p = validProgram()
+	p.Weeks[0].Days[1].Number = 8
+	assert.Error(t, p.Validate(), "номер дня вне недели")
+
+	p = validProgram()
+	p.Weeks[0].Days[0].Exercises[0].Sets = 0
+	assert.Error(t, p.Validate())
+
+	p = validProgram()
+	p.Weeks[0].Days[0].Exercises[0].ExerciseID = "squat"
+	assert.Error(t, p.Validate())
+}
+
+func TestExerciseValidate(t *testing.T) {
+	e := &program.Exercise{Name: " Присед ", MuscleGroups: []string{"Quads", " Glutes"}}
+	assert.NoError(t, e.Validate())
+	assert.Equal(t, "Присед", e.Name)
+	assert.Equal(t, []string{"quads", "glutes"}, e.MuscleGroups)
+	assert.Equal(t, []string{}, e.Equipment)
+
+	e = &program.Exercise{Name: "Тяга", MuscleGroups: []string{"back"}, MediaURL: "ftp://video"}
+	assert.Error(t, e.Validate())
+}
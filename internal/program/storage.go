@@ -0,0 +1,299 @@
+package program
+
+import (
+	"TrainerConnect/internal/apperr"
+	postgres "TrainerConnect/pkg/postgresql"
+	"context"
+	"database/sql"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrNotFound         = apperr.NotFound("program_not_found", "program not found")
+	ErrExerciseNotFound = apperr.NotFound("exercise_not_found", "exercise not found")
+	// ErrUnknownExercise возвращается, если программа ссылается на несуществующее упражнение
+	ErrUnknownExercise = apperr.Invalid("exercise_not_found", "exercise not found")
+	// ErrNotOnRoster возвращается, если программу назначают клиенту, который не тренируется у тренера
+	ErrNotOnRoster = apperr.Invalid("not_on_roster", "client is not on the trainer's roster")
+)
+
+type Storage struct {
+	*sql.DB
+}
+
+func NewStorage(db *sql.DB) *Storage {
+	return &Storage{DB: db}
+}
+
+// CreateExercise добавляет упражнение в библиотеку
+func (s *Storage) CreateExercise(ctx context.Context, e *Exercise) (err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	return s.DB.QueryRowContext(ctx, `INSERT INTO exercises (name, muscle_groups, equipment, instructions, media_url, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`,
+		e.Name, pq.Array(e.MuscleGroups), pq.Array(e.Equipment), e.Instructions, e.MediaURL, e.CreatedBy).Scan(&e.ID)
+}
+
+// GetExercise возвращает упражнение по ID
+func (s *Storage) GetExercise(ctx context.Context, id string) (_ *Exercise, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	e := &Exercise{}
+	err = s.DB.QueryRowContext(ctx, `SELECT id, name, muscle_groups, equipment, instructions, media_url, created_by
+		FROM exercises WHERE id = $1`, id).
+		Scan(&e.ID, &e.Name, pq.Array(&e.MuscleGroups), pq.Array(&e.Equipment), &e.Instructions, &e.MediaURL, &e.CreatedBy)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrExerciseNotFound
+		}
+		return nil, err
+	}
+	return e, nil
+}
+
+// ListExercises ищет упражнения по группе мышц и части названия. Пустые параметры не фильтруют.
+func (s *Storage) ListExercises(ctx context.Context, muscleGroup, query string) (_ []Exercise, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	rows, err := s.DB.QueryContext(ctx, `SELECT id, name, muscle_groups, equipment, instructions, media_url, created_by
+		FROM exercises
+		WHERE ($1 = '' OR muscle_groups @> ARRAY[$1]::text[]) AND ($2 = '' OR name ILIKE '%' || $2 || '%')
+		ORDER BY name LIMIT 200`, muscleGroup, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	exercises := []Exercise{}
+	for rows.Next() {
+		var e Exercise
+		err := rows.Scan(&e.ID, &e.Name, pq.Array(&e.MuscleGroups), pq.Array(&e.Equipment), &e.Instructions, &e.MediaURL, &e.CreatedBy)
+		if err != nil {
+			return nil, err
+		}
+		exercises = append(exercises, e)
+	}
+	return exercises, rows.Err()
+}
+
+// Create сохраняет новую программу с первой версией содержимого
+func (s *Storage) Create(ctx context.Context, p *Program) (err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	p.Version = 1
+	err = tx.QueryRowContext(ctx, `INSERT INTO programs (trainer_id, client_id, title, description, current_version)
+		VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at, updated_at`,
+		p.TrainerID, p.ClientID, p.Title, p.Description, p.Version).Scan(&p.ID, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	if err := insertVersion(ctx, tx, p, p.TrainerID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SaveVersion сохраняет измененное содержимое программы как новую версию.
+// Предыдущие версии, по которым клиент уже тренировался, не меняются.
+func (s *Storage) SaveVersion(ctx context.Context, p *Program, createdBy string) (err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, `UPDATE programs SET title = $1, description = $2, current_version = current_version + 1, updated_at = now()
+		WHERE id = $3 RETURNING current_version, trainer_id, client_id, created_at, updated_at`,
+		p.Title, p.Description, p.ID).Scan(&p.Version, &p.TrainerID, &p.ClientID, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrNotFound
+		}
+		return err
+	}
+
+	if err := insertVersion(ctx, tx, p, createdBy); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func insertVersion(ctx context.Context, tx *sql.Tx, p *Program, createdBy string) error {
+	_, err := tx.ExecContext(ctx, "INSERT INTO program_versions (program_id, version, created_by) VALUES ($1, $2, $3)",
+		p.ID, p.Version, createdBy)
+	if err != nil {
+		return err
+	}
+
+	for _, w := range p.Weeks {
+		for _, d := range w.Days {
+			_, err := tx.ExecContext(ctx, `INSERT INTO program_days (program_id, version, week_number, day_number, title)
+				VALUES ($1, $2, $3, $4, $5)`, p.ID, p.Version, w.Number, d.Number, d.Title)
+			if err != nil {
+				return err
+			}
+			for i, e := range d.Exercises {
+				_, err := tx.ExecContext(ctx, `INSERT INTO program_items (program_id, version, week_number, day_number, position,
+						exercise_id, sets, reps, load, tempo, rest_seconds, notes)
+					VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`,
+					p.ID, p.Version, w.Number, d.Number, i+1, e.ExerciseID, e.Sets, e.Reps, e.Load, e.Tempo, e.RestSeconds, e.Notes)
+				if err != nil {
+					if apperr.IsForeignKeyViolation(err) {
+						return ErrUnknownExercise
+					}
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Get возвращает программу с содержимым указанной версии; version 0 означает текущую версию
+func (s *Storage) Get(ctx context.Context, id string, version int) (_ *Program, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	p := &Program{}
+	err = s.DB.QueryRowContext(ctx, `SELECT id, trainer_id, client_id, title, description, current_version, created_at, updated_at
+		FROM programs WHERE id = $1`, id).
+		Scan(&p.ID, &p.TrainerID, &p.ClientID, &p.Title, &p.Description, &p.Version, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	if version != 0 {
+		if version < 1 || version > p.Version {
+			return nil, ErrNotFound
+		}
+		p.Version = version
+	}
+
+	p.Weeks, err = s.loadWeeks(ctx, p.ID, p.Version)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (s *Storage) loadWeeks(ctx context.Context, programID string, version int) ([]Week, error) {
+	rows, err := s.DB.QueryContext(ctx, `SELECT d.week_number, d.day_number, d.title, i.exercise_id, e.name,
+			i.sets, i.reps, i.load, i.tempo, i.rest_seconds, i.notes
+		FROM program_days d
+		JOIN program_items i ON i.program_id = d.program_id AND i.version = d.version
+			AND i.week_number = d.week_number AND i.day_number = d.day_number
+		JOIN exercises e ON e.id = i.exercise_id
+		WHERE d.program_id = $1 AND d.version = $2
+		ORDER BY d.week_number, d.day_number, i.position`, programID, version)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	weeks := []Week{}
+	for rows.Next() {
+		var weekNumber, dayNumber int
+		var title string
+		var e Prescription
+		err := rows.Scan(&weekNumber, &dayNumber, &title, &e.ExerciseID, &e.ExerciseName,
+			&e.Sets, &e.Reps, &e.Load, &e.Tempo, &e.RestSeconds, &e.Notes)
+		if err != nil {
+			return nil, err
+		}
+
+		// Строки упорядочены, поэтому новая неделя или день начинаются при смене номера
+		if n := len(weeks); n == 0 || weeks[n-1].Number != weekNumber {
+			weeks = append(weeks, Week{Number: weekNumber})
+		}
+		week := &weeks[len(weeks)-1]
+		if n := len(week.Days); n == 0 || week.Days[n-1].Number != dayNumber {
+			week.Days = append(week.Days, Day{Number: dayNumber, Title: title})
+		}
+		day := &week.Days[len(week.Days)-1]
+		day.Exercises = append(day.Exercises, e)
+	}
+	return weeks, rows.Err()
+}
+
+// Versions возвращает список версий программы
+func (s *Storage) Versions(ctx context.Context, programID string) (_ []Version, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	rows, err := s.DB.QueryContext(ctx, `SELECT program_id, version, created_by, created_at
+		FROM program_versions WHERE program_id = $1 ORDER BY version`, programID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := []Version{}
+	for rows.Next() {
+		var v Version
+		if err := rows.Scan(&v.ProgramID, &v.Version, &v.CreatedBy, &v.CreatedAt); err != nil {
+			return nil, err
+		}
+		versions = append(versions, v)
+	}
+	return versions, rows.Err()
+}
+
+// ListForUser возвращает программы, созданные тренером или назначенные клиенту, без содержимого
+func (s *Storage) ListForUser(ctx context.Context, userID string) (_ []Program, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	rows, err := s.DB.QueryContext(ctx, `SELECT id, trainer_id, client_id, title, description, current_version, created_at, updated_at
+		FROM programs WHERE trainer_id = $1 OR client_id = $1 ORDER BY updated_at DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	programs := []Program{}
+	for rows.Next() {
+		var p Program
+		err := rows.Scan(&p.ID, &p.TrainerID, &p.ClientID, &p.Title, &p.Description, &p.Version, &p.CreatedAt, &p.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		programs = append(programs, p)
+	}
+	return programs, rows.Err()
+}
+
+// Assign назначает программу клиенту. Содержимое версий при этом не меняется.
+func (s *Storage) Assign(ctx context.Context, programID, clientID string) (err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	res, err := s.DB.ExecContext(ctx, "UPDATE programs SET client_id = $1, updated_at = now() WHERE id = $2", clientID, programID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package reminder
+
+import (
+	"TrainerConnect/internal/booking"
+	"TrainerConnect/internal/jobs"
+	"TrainerConnect/internal/notify"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// JobKind — вид задачи отправки напоминания
+const JobKind = "booking.reminder"
+
+// Offsets — за сколько до начала занятия напоминать
+var Offsets = []time.Duration{24 * time.Hour, time.Hour}
+
+const (
+	// ScanInterval — как часто искать занятия, о которых пора напомнить
+	ScanInterval = time.Minute
+	// grace — насколько можно опоздать с напоминанием, например после перезапуска сервера.
+	// Занятие, подтвержденное за 5 часов до начала, не получит напоминание «за 24 часа».
+	grace = 10 * time.Minute
+	// lookahead — на сколько вперед планировать напоминания при каждом проходе
+	lookahead = 2 * ScanInterval
+
+	maxAttempts = 5
+)
+
+// Payload — данные задачи напоминания. StartsAt сверяется с бронированием перед отправкой,
+// чтобы не напоминать о перенесенном занятии по старому времени.
+type Payload struct {
+	BookingID string        `json:"booking_id"`
+	StartsAt  time.Time     `json:"starts_at"`
+	Offset    time.Duration `json:"offset"`
+}
+
+// Plan возвращает напоминания о занятии, начинающемся в startsAt, которые пора
+// запланировать в момент now
+func Plan(bookingID string, startsAt, now time.Time) []Payload {
+	var due []Payload
+	for _, offset := range Offsets {
+		at := startsAt.Add(-offset)
+		if !at.Before(now.Add(-grace)) && !at.After(now.Add(lookahead)) {
+			due = append(due, Payload{BookingID: bookingID, StartsAt: startsAt, Offset: offset})
+		}
+	}
+	return due
+}
+
+// dedupeKey однозначно определяет напоминание: после переноса занятия ключ меняется
+func (p Payload) dedupeKey() string {
+	return fmt.Sprintf("reminder:%s:%d:%s", p.BookingID, p.StartsAt.Unix(), p.Offset)
+}
+
+// Reminders планирует и отправляет напоминания о подтвержденных занятиях
+type Reminders struct {
+	Bookings *booking.Storage
+	Jobs     *jobs.Storage
+	Notify   notify.Deliverer
+	Now      func() time.Time
+}
+
+func New(bookings *booking.Storage, jobStorage *jobs.Storage, deliverer notify.Deliverer) *Reminders {
+	return &Reminders{Bookings: bookings, Jobs: jobStorage, Notify: deliverer, Now: time.Now}
+}
+
+// Register подключает напоминания к обработчику очереди
+func (r *Reminders) Register(runner *jobs.Runner) {
+	runner.Handle(JobKind, r.Send)
+	runner.Every(ScanInterval, "reminder scan", r.Schedule)
+}
+
+// Schedule ставит в очередь напоминания о подтвержденных занятиях, до которых осталось
+// 24 часа или час. Выполняется в каждом экземпляре сервера: повторная постановка
+// отсекается dedupe-ключом задачи.
+func (r *Reminders) Schedule(ctx context.Context) error {
+	now := r.Now()
+	maxOffset := Offsets[0]
+	bookings, err := r.Bookings.StartingBetween(ctx, booking.StatusConfirmed, now, now.Add(maxOffset+lookahead))
+	if err != nil {
+		return err
+	}
+
+	for _, b := range bookings {
+		for _, p := range Plan(b.ID, b.StartsAt, now) {
+			if _, err := r.Jobs.Enqueue(ctx, JobKind, p, p.StartsAt.Add(-p.Offset), maxAttempts, p.dedupeKey()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Send отправляет напоминание обоим участникам, если занятие все еще подтверждено
+// и не перенесено. Доставка синхронная: при ошибке задача повторяется очередью
+// и после исчерпания попыток переходит в dead.
+func (r *Reminders) Send(ctx context.Context, data json.RawMessage) error {
+	var p Payload
+	if err := json.Unmarshal(data, &p); err != nil {
+		return err
+	}
+
+	b, err := r.Bookings.Get(ctx, p.BookingID)
+	if err != nil {
+		if errors.Is(err, booking.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+	if b.Status != booking.StatusConfirmed || !b.StartsAt.Equal(p.StartsAt) {
+		return nil
+	}
+
+	when := "in 24 hours"
+	if p.Offset == time.Hour {
+		when = "in 1 hour"
+	}
+	var errs []error
+	for _, userID := range []string{b.ClientID, b.TrainerID} {
+		err := r.Notify.Deliver(ctx, notify.Notification{
+			UserID:  userID,
+			Kind:    notify.KindBookingReminder,
+			Subject: "Upcoming session " + when,
+			Body:    fmt.Sprintf("Your session starts %s, at %s UTC.", when, b.StartsAt.UTC().Format("2006-01-02 15:04")),
+			Data:    b,
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("reminder to user %s: %w", userID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,31 @@
+package reminder_test
+
+import (
+	"TrainerConnect/internal/reminder"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestPlan(t *testing.T) {
+	startsAt := time.Date(2024, 3, 5, 18, 0, 0, 0, time.UTC)
+
+	// Ровно за сутки — пора планировать напоминание за 24 часа
+	due := reminder.Plan("7", startsAt, startsAt.Add(-24*time.Hour-time.Minute))
+	if assert.Len(t, due, 1) {
+		assert.Equal(t, 24*time.Hour, due[0].Offset)
+		assert.Equal(t, "7", due[0].BookingID)
+	}
+
+	// За 12 часов напоминать еще не о чем
+	assert.Empty(t, reminder.Plan("7", startsAt, startsAt.Add(-12*time.Hour)))
+
+	// Небольшое опоздание допустимо
+	due = reminder.Plan("7", startsAt, startsAt.Add(-55*time.Minute))
+	if assert.Len(t, due, 1) {
+		assert.Equal(t, time.Hour, due[0].Offset)
+	}
+
+	// Занятие подтвердили за 20 часов — напоминание за сутки уже не отправляется
+	assert.Empty(t, reminder.Plan("7", startsAt, startsAt.Add(-20*time.Hour)))
+}
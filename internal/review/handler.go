@@ -0,0 +1,239 @@
+package review
+
+import (
+	"TrainerConnect/internal/apperr"
+	"TrainerConnect/internal/auth"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+const (
+	reviewURL       = "/reviews/"
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+type Handler struct {
+	Storage *Storage
+}
+
+// NewHandler создает обработчик отзывов
+func NewHandler(storage *Storage) *Handler {
+	return &Handler{Storage: storage}
+}
+
+func (h *Handler) Register(router *chi.Mux) {
+	// Отзывы о тренере публичны, как и его профиль
+	router.Get("/trainers/{id}/reviews", h.ListForTrainer)
+	router.Get(reviewURL+"{id}", h.Get)
+
+	router.Group(func(r chi.Router) {
+		r.Use(auth.RequireAuth)
+		r.Post("/bookings/{id}/review", h.Create)
+		r.Put(reviewURL+"{id}/reply", h.Reply)
+		r.Post(reviewURL+"{id}/hide", h.moderate(true))
+		r.Post(reviewURL+"{id}/unhide", h.moderate(false))
+	})
+}
+
+// Create сохраняет отзыв клиента о завершенном занятии
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	bookingID := chi.URLParam(r, "id")
+	if _, err := strconv.Atoi(bookingID); err != nil {
+		apperr.Respond(w, http.StatusBadRequest, "Invalid booking ID")
+		return
+	}
+
+	var rv Review
+	if err := json.NewDecoder(r.Body).Decode(&rv); err != nil {
+		apperr.Respond(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := rv.Validate(); err != nil {
+		apperr.Write(w, err)
+		return
+	}
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	rv = Review{BookingID: bookingID, ClientID: principal.UserID, Rating: rv.Rating, Body: rv.Body}
+
+	if err := h.Storage.Create(r.Context(), &rv); err != nil {
+		if !apperr.Expected(err) {
+			log.Printf("Error creating review for booking %s: %v", bookingID, err)
+		}
+		apperr.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(rv)
+}
+
+// canSeeHidden — скрытые отзывы видят администраторы
+func canSeeHidden(r *http.Request) bool {
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	return ok && principal.IsAdmin()
+}
+
+// ListForTrainer возвращает отзывы о тренере постранично. Параметры: before — ID отзыва
+// из предыдущей страницы, limit — размер страницы. Администратор может запросить
+// скрытые отзывы параметром include_hidden=true.
+func (h *Handler) ListForTrainer(w http.ResponseWriter, r *http.Request) {
+	trainerID := chi.URLParam(r, "id")
+	if _, err := strconv.Atoi(trainerID); err != nil {
+		apperr.Respond(w, http.StatusBadRequest, "Invalid trainer ID")
+		return
+	}
+
+	q := r.URL.Query()
+	before := q.Get("before")
+	if before != "" {
+		if _, err := strconv.Atoi(before); err != nil {
+			apperr.Respond(w, http.StatusBadRequest, "Invalid before parameter")
+			return
+		}
+	}
+	limit := defaultPageSize
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			apperr.Respond(w, http.StatusBadRequest, "Invalid limit parameter")
+			return
+		}
+		if n < maxPageSize {
+			limit = n
+		} else {
+			limit = maxPageSize
+		}
+	}
+	includeHidden := q.Get("include_hidden") == "true" && canSeeHidden(r)
+
+	page, err := h.Storage.ListForTrainer(r.Context(), trainerID, includeHidden, before, limit)
+	if err != nil {
+		log.Printf("Error listing reviews of trainer %s: %v", trainerID, err)
+		apperr.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+// load загружает отзыв по ID из URL. Скрытый отзыв видят только его автор, тренер и администраторы.
+func (h *Handler) load(w http.ResponseWriter, r *http.Request) (*Review, bool) {
+	id := chi.URLParam(r, "id")
+	if _, err := strconv.Atoi(id); err != nil {
+		apperr.Respond(w, http.StatusBadRequest, "Invalid review ID")
+		return nil, false
+	}
+
+	rv, err := h.Storage.Get(r.Context(), id)
+	if err != nil {
+		if !apperr.Expected(err) {
+			log.Printf("Error getting review %s: %v", id, err)
+		}
+		apperr.Write(w, err)
+		return nil, false
+	}
+
+	if rv.Hidden {
+		principal, ok := auth.PrincipalFromContext(r.Context())
+		if !ok || (principal.UserID != rv.ClientID && principal.UserID != rv.TrainerID && !principal.IsAdmin()) {
+			apperr.Write(w, ErrNotFound)
+			return nil, false
+		}
+	}
+	return rv, true
+}
+
+// Get возвращает отзыв
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
+	rv, ok := h.load(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rv)
+}
+
+type replyRequest struct {
+	Reply string `json:"reply"`
+}
+
+// Reply сохраняет ответ тренера на отзыв о его занятии
+func (h *Handler) Reply(w http.ResponseWriter, r *http.Request) {
+	rv, ok := h.load(w, r)
+	if !ok {
+		return
+	}
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	if principal.UserID != rv.TrainerID {
+		apperr.Respond(w, http.StatusForbidden, "Forbidden")
+		return
+	}
+
+	var req replyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apperr.Respond(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	reply, err := ValidateReply(req.Reply)
+	if err != nil {
+		apperr.Write(w, err)
+		return
+	}
+
+	updated, err := h.Storage.Reply(r.Context(), rv.ID, reply)
+	if err != nil {
+		log.Printf("Error replying to review %s: %v", rv.ID, err)
+		apperr.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+type moderateRequest struct {
+	Reason string `json:"reason"`
+}
+
+// moderate возвращает обработчик, скрывающий отзыв или возвращающий его. Доступно администраторам.
+func (h *Handler) moderate(hidden bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal, _ := auth.PrincipalFromContext(r.Context())
+		if !principal.IsAdmin() {
+			apperr.Respond(w, http.StatusForbidden, "Forbidden")
+			return
+		}
+		id := chi.URLParam(r, "id")
+		if _, err := strconv.Atoi(id); err != nil {
+			apperr.Respond(w, http.StatusBadRequest, "Invalid review ID")
+			return
+		}
+
+		var req moderateRequest
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				apperr.Respond(w, http.StatusBadRequest, "Invalid request body")
+				return
+			}
+		}
+
+		rv, err := h.Storage.SetHidden(r.Context(), id, hidden, req.Reason, principal.UserID)
+		if err != nil {
+			if !apperr.Expected(err) {
+				log.Printf("Error moderating review %s: %v", id, err)
+			}
+			apperr.Write(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(rv)
+	}
+}
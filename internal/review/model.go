@@ -0,0 +1,59 @@
+package review
+
+import (
+	"TrainerConnect/internal/apperr"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	maxBodyLength  = 2000
+	maxReplyLength = 2000
+)
+
+// Review — отзыв клиента о занятии. Скрытые модератором отзывы не показываются публично
+// и не учитываются в рейтинге тренера.
+type Review struct {
+	ID           string     `json:"id"`
+	BookingID    string     `json:"booking_id"`
+	TrainerID    string     `json:"trainer_id"`
+	ClientID     string     `json:"client_id"`
+	Rating       int        `json:"rating"`
+	Body         string     `json:"body"`
+	Reply        string     `json:"reply,omitempty"`
+	RepliedAt    *time.Time `json:"replied_at,omitempty"`
+	Hidden       bool       `json:"hidden,omitempty"`
+	HiddenReason string     `json:"hidden_reason,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// Validate проверяет оценку и текст отзыва
+func (r *Review) Validate() error {
+	if r.Rating < 1 || r.Rating > 5 {
+		return apperr.InvalidField("rating", "out_of_range", "rating must be between 1 and 5")
+	}
+	r.Body = strings.TrimSpace(r.Body)
+	if utf8.RuneCountInString(r.Body) > maxBodyLength {
+		return apperr.InvalidField("body", "too_long", "review is too long")
+	}
+	return nil
+}
+
+// ValidateReply проверяет ответ тренера
+func ValidateReply(reply string) (string, error) {
+	reply = strings.TrimSpace(reply)
+	if reply == "" {
+		return "", apperr.InvalidField("reply", "required", "reply is required")
+	}
+	if utf8.RuneCountInString(reply) > maxReplyLength {
+		return "", apperr.InvalidField("reply", "too_long", "reply is too long")
+	}
+	return reply, nil
+}
+
+// Page — страница отзывов от новых к старым
+type Page struct {
+	Reviews []Review `json:"reviews"`
+	Before  string   `json:"before,omitempty"`
+}
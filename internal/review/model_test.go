@@ -0,0 +1,30 @@
+package review_test
+
+import (
+	"TrainerConnect/internal/review"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestReviewValidate(t *testing.T) {
+	r := &review.Review{Rating: 5, Body: "  Отличный тренер  "}
+	assert.NoError(t, r.Validate())
+	assert.Equal(t, "Отличный тренер", r.Body)
+
+	// Оценка без текста допустима
+	assert.NoError(t, (&review.Review{Rating: 3}).Validate())
+
+	assert.Error(t, (&review.Review{Rating: 0}).Validate())
+	assert.Error(t, (&review.Review{Rating: 6}).Validate())
+	assert.Error(t, (&review.Review{Rating: 4, Body: strings.Repeat("а", 2001)}).Validate())
+}
+
+func TestValidateReply(t *testing.T) {
+	reply, err := review.ValidateReply(" Спасибо! ")
+	assert.NoError(t, err)
+	assert.Equal(t, "Спасибо!", reply)
+
+	_, err = review.ValidateReply("   ")
+	assert.Error(t, err)
+}
@@ -0,0 +1,202 @@
+package review
+
+import (
+	"TrainerConnect/internal/apperr"
+	"TrainerConnect/internal/booking"
+	postgres "TrainerConnect/pkg/postgresql"
+	"context"
+	"database/sql"
+)
+
+var (
+	ErrNotFound         = apperr.NotFound("review_not_found", "review not found")
+	ErrBookingNotFound  = apperr.NotFound("booking_not_found", "booking not found")
+	ErrNotClient        = apperr.Forbidden("not_booking_client", "only the client of the booking can review it")
+	ErrNotCompleted     = apperr.Conflict("session_not_completed", "only completed sessions can be reviewed")
+	ErrAlreadyReviewed  = apperr.Conflict("already_reviewed", "booking is already reviewed")
+	ErrAlreadyModerated = apperr.Conflict("already_moderated", "review is already in this state")
+)
+
+type Storage struct {
+	*sql.DB
+}
+
+func NewStorage(db *sql.DB) *Storage {
+	return &Storage{DB: db}
+}
+
+const reviewColumns = `id, booking_id, trainer_id, client_id, rating, body, COALESCE(reply, ''), replied_at,
+	hidden, COALESCE(hidden_reason, ''), created_at`
+
+func scanReview(row interface{ Scan(...interface{}) error }) (*Review, error) {
+	r := &Review{}
+	err := row.Scan(&r.ID, &r.BookingID, &r.TrainerID, &r.ClientID, &r.Rating, &r.Body, &r.Reply, &r.RepliedAt,
+		&r.Hidden, &r.HiddenReason, &r.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return r, nil
+}
+
+// lockTrainer блокирует профиль тренера до конца транзакции, чтобы параллельные
+// изменения отзывов пересчитывали рейтинг по очереди и не теряли друг друга
+func lockTrainer(ctx context.Context, tx *sql.Tx, trainerID string) error {
+	_, err := tx.ExecContext(ctx, "SELECT 1 FROM trainer_profiles WHERE user_id = $1 FOR UPDATE", trainerID)
+	return err
+}
+
+// refreshRating пересчитывает средний рейтинг и число видимых отзывов в профиле тренера
+func refreshRating(ctx context.Context, tx *sql.Tx, trainerID string) error {
+	_, err := tx.ExecContext(ctx, `UPDATE trainer_profiles p SET rating_avg = r.avg, rating_count = r.count
+		FROM (SELECT COALESCE(ROUND(AVG(rating), 2), 0)::double precision AS avg, COUNT(*) AS count
+			FROM reviews WHERE trainer_id = $1 AND NOT hidden) r
+		WHERE p.user_id = $1`, trainerID)
+	return err
+}
+
+// Create сохраняет отзыв о завершенном занятии. Отзыв оставляет клиент, один на занятие.
+func (s *Storage) Create(ctx context.Context, r *Review) (err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var trainerID, clientID string
+	var status booking.Status
+	err = tx.QueryRowContext(ctx, "SELECT trainer_id, client_id, status FROM bookings WHERE id = $1 FOR SHARE", r.BookingID).
+		Scan(&trainerID, &clientID, &status)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrBookingNotFound
+		}
+		return err
+	}
+	if clientID != r.ClientID {
+		return ErrNotClient
+	}
+	if status != booking.StatusCompleted {
+		return ErrNotCompleted
+	}
+	r.TrainerID = trainerID
+
+	if err := lockTrainer(ctx, tx, trainerID); err != nil {
+		return err
+	}
+	err = tx.QueryRowContext(ctx, `INSERT INTO reviews (booking_id, trainer_id, client_id, rating, body)
+		VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at`,
+		r.BookingID, r.TrainerID, r.ClientID, r.Rating, r.Body).Scan(&r.ID, &r.CreatedAt)
+	if err != nil {
+		if apperr.IsUniqueViolation(err) {
+			return ErrAlreadyReviewed
+		}
+		return err
+	}
+	if err := refreshRating(ctx, tx, trainerID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Get возвращает отзыв по ID
+func (s *Storage) Get(ctx context.Context, id string) (_ *Review, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	return scanReview(s.DB.QueryRowContext(ctx, "SELECT "+reviewColumns+" FROM reviews WHERE id = $1", id))
+}
+
+// ListForTrainer возвращает страницу отзывов о тренере от новых к старым.
+// Скрытые отзывы возвращаются только при includeHidden.
+func (s *Storage) ListForTrainer(ctx context.Context, trainerID string, includeHidden bool, before string, limit int) (_ *Page, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	rows, err := s.DB.QueryContext(ctx, "SELECT "+reviewColumns+` FROM reviews
+		WHERE trainer_id = $1 AND ($2 OR NOT hidden) AND ($3 = '' OR id < $3::int)
+		ORDER BY id DESC LIMIT $4`, trainerID, includeHidden, before, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	page := &Page{Reviews: []Review{}}
+	for rows.Next() {
+		r, err := scanReview(rows)
+		if err != nil {
+			return nil, err
+		}
+		page.Reviews = append(page.Reviews, *r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(page.Reviews) > limit {
+		page.Reviews = page.Reviews[:limit]
+		page.Before = page.Reviews[limit-1].ID
+	}
+	return page, nil
+}
+
+// Reply сохраняет ответ тренера на отзыв. Повторный ответ заменяет предыдущий.
+func (s *Storage) Reply(ctx context.Context, id, reply string) (_ *Review, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	return scanReview(s.DB.QueryRowContext(ctx, `UPDATE reviews SET reply = $1, replied_at = now() WHERE id = $2
+		RETURNING `+reviewColumns, reply, id))
+}
+
+// SetHidden скрывает отзыв или возвращает его и пересчитывает рейтинг тренера
+func (s *Storage) SetHidden(ctx context.Context, id string, hidden bool, reason, moderatorID string) (_ *Review, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var trainerID string
+	err = tx.QueryRowContext(ctx, "SELECT trainer_id FROM reviews WHERE id = $1", id).Scan(&trainerID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if err := lockTrainer(ctx, tx, trainerID); err != nil {
+		return nil, err
+	}
+	// Состояние читается после блокировки тренера, иначе его мог изменить другой модератор
+	var current bool
+	if err := tx.QueryRowContext(ctx, "SELECT hidden FROM reviews WHERE id = $1 FOR UPDATE", id).Scan(&current); err != nil {
+		return nil, err
+	}
+	if current == hidden {
+		return nil, ErrAlreadyModerated
+	}
+
+	var reasonValue, moderator sql.NullString
+	if hidden {
+		reasonValue = sql.NullString{String: reason, Valid: true}
+		moderator = sql.NullString{String: moderatorID, Valid: true}
+	}
+	r, err := scanReview(tx.QueryRowContext(ctx, `UPDATE reviews SET hidden = $1, hidden_reason = $2, hidden_by = $3
+		WHERE id = $4 RETURNING `+reviewColumns, hidden, reasonValue, moderator, id))
+	if err != nil {
+		return nil, err
+	}
+	if err := refreshRating(ctx, tx, trainerID); err != nil {
+		return nil, err
+	}
+	return r, tx.Commit()
+}
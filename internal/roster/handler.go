@@ -0,0 +1,221 @@
+package roster
+
+import (
+	"TrainerConnect/internal/apperr"
+	"TrainerConnect/internal/auth"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+const clientsURL = "/trainers/{id}/clients"
+
+type Handler struct {
+	Storage *Storage
+}
+
+func NewHandler(storage *Storage) *Handler {
+	return &Handler{Storage: storage}
+}
+
+func (h *Handler) Register(router *chi.Mux) {
+	router.Group(func(r chi.Router) {
+		r.Use(auth.RequireAuth)
+		r.Get(clientsURL, h.ListClients)
+		r.Post(clientsURL+"/invite", h.Invite)
+		r.Post(clientsURL+"/{clientID}/accept", h.Accept)
+		r.Post(clientsURL+"/{clientID}/decline", h.Decline)
+		r.Post(clientsURL+"/{clientID}/pause", h.Pause)
+		r.Post(clientsURL+"/{clientID}/resume", h.Resume)
+		r.Delete(clientsURL+"/{clientID}", h.End)
+	})
+}
+
+// urlIDs извлекает ID тренера и клиента из URL и при ошибке сам отправляет ответ
+func urlIDs(w http.ResponseWriter, r *http.Request) (string, string, bool) {
+	trainerID, clientID := chi.URLParam(r, "id"), chi.URLParam(r, "clientID")
+	if _, err := strconv.Atoi(trainerID); err != nil {
+		apperr.Respond(w, http.StatusBadRequest, "Invalid trainer ID")
+		return "", "", false
+	}
+	if _, err := strconv.Atoi(clientID); err != nil {
+		apperr.Respond(w, http.StatusBadRequest, "Invalid client ID")
+		return "", "", false
+	}
+	return trainerID, clientID, true
+}
+
+func writeRelationship(w http.ResponseWriter, rel *Relationship) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rel)
+}
+
+func writeStorageError(w http.ResponseWriter, err error) {
+	if !apperr.Expected(err) {
+		log.Printf("Error updating trainer-client relationship: %v", err)
+	}
+	apperr.Write(w, err)
+}
+
+// ListClients возвращает действующих, приостановленных и бывших клиентов тренера
+func (h *Handler) ListClients(w http.ResponseWriter, r *http.Request) {
+	trainerID := chi.URLParam(r, "id")
+	if _, err := strconv.Atoi(trainerID); err != nil {
+		apperr.Respond(w, http.StatusBadRequest, "Invalid trainer ID")
+		return
+	}
+
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	if principal.UserID != trainerID && !principal.IsAdmin() {
+		apperr.Respond(w, http.StatusForbidden, "Forbidden")
+		return
+	}
+
+	status := Status(r.URL.Query().Get("status"))
+	switch status {
+	case "", StatusInvited, StatusActive, StatusPaused, StatusEnded, StatusDeclined:
+	default:
+		apperr.Respond(w, http.StatusBadRequest, "Invalid status parameter")
+		return
+	}
+
+	members, err := h.Storage.ListClients(r.Context(), trainerID, status)
+	if err != nil {
+		log.Printf("Error listing clients of trainer %s: %v", trainerID, err)
+		apperr.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(members)
+}
+
+type inviteRequest struct {
+	ClientID string `json:"client_id"`
+}
+
+// Invite создает приглашение. Тренер приглашает клиента, указанного в теле запроса,
+// а клиент, обращаясь к чужому тренеру, просит взять его в подопечные.
+func (h *Handler) Invite(w http.ResponseWriter, r *http.Request) {
+	trainerID := chi.URLParam(r, "id")
+	if _, err := strconv.Atoi(trainerID); err != nil {
+		apperr.Respond(w, http.StatusBadRequest, "Invalid trainer ID")
+		return
+	}
+
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	clientID := principal.UserID
+	if principal.UserID == trainerID {
+		var req inviteRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apperr.Respond(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		if _, err := strconv.Atoi(req.ClientID); err != nil || req.ClientID == trainerID {
+			apperr.Respond(w, http.StatusBadRequest, "Invalid client ID")
+			return
+		}
+		clientID = req.ClientID
+	}
+
+	rel, err := h.Storage.Invite(r.Context(), trainerID, clientID, principal.UserID)
+	if err != nil {
+		writeStorageError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(rel)
+}
+
+// respond обрабатывает ответ на приглашение: принять или отклонить его может только приглашенная сторона
+func (h *Handler) respond(w http.ResponseWriter, r *http.Request, to Status) {
+	trainerID, clientID, ok := urlIDs(w, r)
+	if !ok {
+		return
+	}
+
+	rel, err := h.Storage.Get(r.Context(), trainerID, clientID)
+	if err != nil {
+		writeStorageError(w, err)
+		return
+	}
+
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	if !rel.IsParticipant(principal.UserID) || principal.UserID == rel.InvitedBy {
+		apperr.Respond(w, http.StatusForbidden, "Forbidden")
+		return
+	}
+
+	rel, err = h.Storage.SetStatus(r.Context(), trainerID, clientID, []Status{StatusInvited}, to)
+	if err != nil {
+		writeStorageError(w, err)
+		return
+	}
+	writeRelationship(w, rel)
+}
+
+// Accept принимает приглашение
+func (h *Handler) Accept(w http.ResponseWriter, r *http.Request) {
+	h.respond(w, r, StatusActive)
+}
+
+// Decline отклоняет приглашение
+func (h *Handler) Decline(w http.ResponseWriter, r *http.Request) {
+	h.respond(w, r, StatusDeclined)
+}
+
+// setByTrainer меняет состояние отношений от имени тренера (или администратора)
+func (h *Handler) setByTrainer(w http.ResponseWriter, r *http.Request, from Status, to Status) {
+	trainerID, clientID, ok := urlIDs(w, r)
+	if !ok {
+		return
+	}
+
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	if principal.UserID != trainerID && !principal.IsAdmin() {
+		apperr.Respond(w, http.StatusForbidden, "Forbidden")
+		return
+	}
+
+	rel, err := h.Storage.SetStatus(r.Context(), trainerID, clientID, []Status{from}, to)
+	if err != nil {
+		writeStorageError(w, err)
+		return
+	}
+	writeRelationship(w, rel)
+}
+
+// Pause приостанавливает работу с клиентом
+func (h *Handler) Pause(w http.ResponseWriter, r *http.Request) {
+	h.setByTrainer(w, r, StatusActive, StatusPaused)
+}
+
+// Resume возобновляет работу с клиентом
+func (h *Handler) Resume(w http.ResponseWriter, r *http.Request) {
+	h.setByTrainer(w, r, StatusPaused, StatusActive)
+}
+
+// End завершает отношения или отзывает приглашение. Это может сделать любая из сторон.
+func (h *Handler) End(w http.ResponseWriter, r *http.Request) {
+	trainerID, clientID, ok := urlIDs(w, r)
+	if !ok {
+		return
+	}
+
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	if principal.UserID != trainerID && principal.UserID != clientID && !principal.IsAdmin() {
+		apperr.Respond(w, http.StatusForbidden, "Forbidden")
+		return
+	}
+
+	rel, err := h.Storage.SetStatus(r.Context(), trainerID, clientID, []Status{StatusInvited, StatusActive, StatusPaused}, StatusEnded)
+	if err != nil {
+		writeStorageError(w, err)
+		return
+	}
+	writeRelationship(w, rel)
+}
@@ -0,0 +1,42 @@
+package roster
+
+import (
+	"time"
+)
+
+// Status — состояние отношений тренера и клиента
+type Status string
+
+const (
+	StatusInvited  Status = "invited"
+	StatusActive   Status = "active"
+	StatusPaused   Status = "paused"
+	StatusEnded    Status = "ended"
+	StatusDeclined Status = "declined"
+)
+
+// Relationship — отношения тренера и клиента. Приглашение может отправить любая сторона,
+// принять или отклонить его должна другая.
+type Relationship struct {
+	TrainerID string     `json:"trainer_id"`
+	ClientID  string     `json:"client_id"`
+	Status    Status     `json:"status"`
+	InvitedBy string     `json:"invited_by"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	EndedAt   *time.Time `json:"ended_at,omitempty"`
+}
+
+// IsParticipant сообщает, является ли пользователь одной из сторон отношений
+func (r *Relationship) IsParticipant(userID string) bool {
+	return userID == r.TrainerID || userID == r.ClientID
+}
+
+// Member — клиент в списке тренера
+type Member struct {
+	ClientID  string    `json:"client_id"`
+	FirstName string    `json:"firstname"`
+	LastName  string    `json:"lastname"`
+	Status    Status    `json:"status"`
+	Since     time.Time `json:"since"`
+}
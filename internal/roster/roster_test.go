@@ -0,0 +1,254 @@
+package roster_test
+
+import (
+	"TrainerConnect/internal/auth"
+	"TrainerConnect/internal/roster"
+	postgres "TrainerConnect/pkg/postgresql"
+	"TrainerConnect/pkg/postgresql/config"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"log"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	db     *sql.DB
+	tokens = auth.NewTokenManager("test-secret", time.Minute, time.Hour)
+)
+
+func TestMain(m *testing.M) {
+	// Тесты работают с тестовой БД и пропускаются, если она недоступна
+	cfg, err := config.ReadConfig("../../pkg/postgresql/config/database_test.json")
+	if err == nil {
+		db, err = postgres.NewDB(cfg)
+	}
+	if err == nil {
+		var migrator *postgres.Migrator
+		if migrator, err = postgres.NewMigrator(db); err == nil {
+			_, err = migrator.Up(context.Background())
+		}
+	}
+	if err != nil {
+		log.Printf("Test database is unavailable, skipping database tests: %v", err)
+		db = nil
+	}
+
+	exitCode := m.Run()
+	if db != nil {
+		db.Close()
+	}
+	os.Exit(exitCode)
+}
+
+func requireDB(t *testing.T) {
+	if db == nil {
+		t.Skip("test database is unavailable")
+	}
+}
+
+// ID пользователей берутся из диапазона, которого не касаются другие тесты с той же БД
+var nextUserID = 900000000 + rand.New(rand.NewSource(time.Now().UnixNano())).Intn(1000000)*100
+
+// newUser создает пользователя с ролью role и удаляет его вместе с отношениями после теста
+func newUser(t *testing.T, role string) string {
+	nextUserID++
+	id := strconv.Itoa(nextUserID)
+	_, err := db.Exec(`INSERT INTO users (user_id, username, password, salt, role, email)
+		VALUES ($1, $2, 'hash', 'salt', $3, $4)`, id, "roster"+id, role, "roster"+id+"@example.com")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		db.Exec("DELETE FROM users WHERE user_id = $1", id)
+	})
+	return id
+}
+
+func TestStorageTransitions(t *testing.T) {
+	requireDB(t)
+	ctx := context.Background()
+	storage := roster.NewStorage(db)
+	trainer, client := newUser(t, auth.RoleTrainer), newUser(t, auth.RoleClient)
+
+	rel, err := storage.Invite(ctx, trainer, client, trainer)
+	require.NoError(t, err)
+	assert.Equal(t, roster.StatusInvited, rel.Status)
+	assert.Equal(t, trainer, rel.InvitedBy)
+
+	// Пока приглашение ожидает ответа, повторное невозможно
+	_, err = storage.Invite(ctx, trainer, client, client)
+	assert.ErrorIs(t, err, roster.ErrAlreadyExists)
+
+	// invited → active → paused → active → ended
+	steps := []struct{ from, to roster.Status }{
+		{roster.StatusInvited, roster.StatusActive},
+		{roster.StatusActive, roster.StatusPaused},
+		{roster.StatusPaused, roster.StatusActive},
+		{roster.StatusActive, roster.StatusEnded},
+	}
+	for _, step := range steps {
+		rel, err = storage.SetStatus(ctx, trainer, client, []roster.Status{step.from}, step.to)
+		require.NoError(t, err, "%s → %s", step.from, step.to)
+		assert.Equal(t, step.to, rel.Status)
+
+		active, err := storage.HasClient(ctx, trainer, client)
+		require.NoError(t, err)
+		assert.Equal(t, step.to != roster.StatusEnded, active)
+	}
+	assert.NotNil(t, rel.EndedAt)
+
+	// Из завершенных отношений перейти можно только новым приглашением
+	_, err = storage.SetStatus(ctx, trainer, client, []roster.Status{roster.StatusActive}, roster.StatusPaused)
+	assert.ErrorIs(t, err, roster.ErrInvalidState)
+
+	rel, err = storage.Invite(ctx, trainer, client, client)
+	require.NoError(t, err)
+	assert.Equal(t, roster.StatusInvited, rel.Status)
+	assert.Equal(t, client, rel.InvitedBy)
+	assert.Nil(t, rel.EndedAt)
+
+	// После отказа тоже можно пригласить заново
+	_, err = storage.SetStatus(ctx, trainer, client, []roster.Status{roster.StatusInvited}, roster.StatusDeclined)
+	require.NoError(t, err)
+	rel, err = storage.Invite(ctx, trainer, client, trainer)
+	require.NoError(t, err)
+	assert.Equal(t, roster.StatusInvited, rel.Status)
+
+	members, err := storage.ListClients(ctx, trainer, roster.StatusInvited)
+	require.NoError(t, err)
+	require.Len(t, members, 1)
+	assert.Equal(t, client, members[0].ClientID)
+}
+
+func TestStorageInviteErrors(t *testing.T) {
+	requireDB(t)
+	ctx := context.Background()
+	storage := roster.NewStorage(db)
+	trainer, client := newUser(t, auth.RoleTrainer), newUser(t, auth.RoleClient)
+
+	_, err := storage.Invite(ctx, client, trainer, client)
+	assert.ErrorIs(t, err, roster.ErrNotTrainer)
+	_, err = storage.Invite(ctx, "1999999999", client, client)
+	assert.ErrorIs(t, err, roster.ErrUserNotFound)
+	_, err = storage.Invite(ctx, trainer, "1999999999", trainer)
+	assert.ErrorIs(t, err, roster.ErrUserNotFound)
+
+	_, err = storage.SetStatus(ctx, trainer, client, []roster.Status{roster.StatusInvited}, roster.StatusActive)
+	assert.ErrorIs(t, err, roster.ErrNotFound)
+}
+
+// serve выполняет запрос к обработчику от имени пользователя userID
+func serve(t *testing.T, router *chi.Mux, method, target, body, userID string) *httptest.ResponseRecorder {
+	token, err := tokens.IssueAccessToken(userID, auth.RoleClient)
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	return rr
+}
+
+func decodeStatus(t *testing.T, rr *httptest.ResponseRecorder) roster.Status {
+	var rel roster.Relationship
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&rel))
+	return rel.Status
+}
+
+func TestHandlerRejectsBeforeStorage(t *testing.T) {
+	// Эти проверки выполняются до обращения к хранилищу, поэтому БД не нужна
+	router := chi.NewRouter()
+	router.Use(auth.Middleware(tokens))
+	roster.NewHandler(roster.NewStorage(nil)).Register(router)
+
+	req := httptest.NewRequest("GET", "/trainers/1/clients", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+
+	rr = serve(t, router, "POST", "/trainers/x/clients/2/accept", "", "2")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	rr = serve(t, router, "POST", "/trainers/1/clients/2/pause", "", "2")
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	rr = serve(t, router, "DELETE", "/trainers/1/clients/2", "", "3")
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	rr = serve(t, router, "GET", "/trainers/1/clients?status=unknown", "", "1")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	rr = serve(t, router, "POST", "/trainers/1/clients/invite", `{"client_id": "1"}`, "1")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestHandlerInvitations(t *testing.T) {
+	requireDB(t)
+	router := chi.NewRouter()
+	router.Use(auth.Middleware(tokens))
+	roster.NewHandler(roster.NewStorage(db)).Register(router)
+
+	trainer, client, other := newUser(t, auth.RoleTrainer), newUser(t, auth.RoleClient), newUser(t, auth.RoleClient)
+	base := "/trainers/" + trainer + "/clients"
+	pair := base + "/" + client
+
+	rr := serve(t, router, "POST", base+"/invite", fmt.Sprintf(`{"client_id": %q}`, client), trainer)
+	require.Equal(t, http.StatusCreated, rr.Code)
+
+	// Принять приглашение может только приглашенная сторона
+	rr = serve(t, router, "POST", pair+"/accept", "", trainer)
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	rr = serve(t, router, "POST", pair+"/accept", "", other)
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	rr = serve(t, router, "POST", pair+"/accept", "", client)
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, roster.StatusActive, decodeStatus(t, rr))
+	rr = serve(t, router, "POST", pair+"/accept", "", client)
+	assert.Equal(t, http.StatusConflict, rr.Code)
+
+	// Приостановить и возобновить работу может только тренер
+	rr = serve(t, router, "POST", pair+"/pause", "", client)
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	rr = serve(t, router, "POST", pair+"/pause", "", trainer)
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, roster.StatusPaused, decodeStatus(t, rr))
+	rr = serve(t, router, "POST", pair+"/resume", "", trainer)
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, roster.StatusActive, decodeStatus(t, rr))
+
+	// Завершить отношения может любая сторона, после чего клиент просит взять его снова
+	rr = serve(t, router, "DELETE", pair, "", client)
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, roster.StatusEnded, decodeStatus(t, rr))
+	rr = serve(t, router, "POST", base+"/invite", "", client)
+	require.Equal(t, http.StatusCreated, rr.Code)
+
+	// Теперь отвечает тренер: клиент свою заявку принять не может
+	rr = serve(t, router, "POST", pair+"/accept", "", client)
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	rr = serve(t, router, "POST", pair+"/decline", "", trainer)
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, roster.StatusDeclined, decodeStatus(t, rr))
+
+	rr = serve(t, router, "POST", base+"/invite", fmt.Sprintf(`{"client_id": %q}`, client), trainer)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+
+	// Список клиентов видит только сам тренер
+	rr = serve(t, router, "GET", base+"?status=invited", "", client)
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	rr = serve(t, router, "GET", base+"?status=invited", "", trainer)
+	require.Equal(t, http.StatusOK, rr.Code)
+	var members []roster.Member
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&members))
+	require.Len(t, members, 1)
+	assert.Equal(t, client, members[0].ClientID)
+}
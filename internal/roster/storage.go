@@ -0,0 +1,152 @@
+package roster
+
+import (
+	"TrainerConnect/internal/apperr"
+	"TrainerConnect/internal/auth"
+	postgres "TrainerConnect/pkg/postgresql"
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrNotFound      = apperr.NotFound("relationship_not_found", "relationship not found")
+	ErrAlreadyExists = apperr.Conflict("relationship_exists", "relationship already exists")
+	ErrInvalidState  = apperr.Conflict("invalid_relationship_state", "relationship is not in a suitable state")
+	ErrNotTrainer    = apperr.Invalid("not_trainer", "user is not a trainer")
+	ErrUserNotFound  = apperr.NotFound("user_not_found", "user not found")
+)
+
+type Storage struct {
+	*sql.DB
+}
+
+func NewStorage(db *sql.DB) *Storage {
+	return &Storage{DB: db}
+}
+
+const relationshipColumns = "trainer_id, client_id, status, invited_by, created_at, updated_at, ended_at"
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanRelationship(row rowScanner) (*Relationship, error) {
+	r := &Relationship{}
+	err := row.Scan(&r.TrainerID, &r.ClientID, &r.Status, &r.InvitedBy, &r.CreatedAt, &r.UpdatedAt, &r.EndedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return r, nil
+}
+
+// Invite создает приглашение от invitedBy. Завершенные и отклоненные отношения можно начать заново,
+// для действующих и ожидающих ответа возвращается ErrAlreadyExists.
+func (s *Storage) Invite(ctx context.Context, trainerID, clientID, invitedBy string) (_ *Relationship, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	var trainerRole string
+	err = s.DB.QueryRowContext(ctx, "SELECT role FROM users WHERE user_id = $1", trainerID).Scan(&trainerRole)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	if trainerRole != auth.RoleTrainer {
+		return nil, ErrNotTrainer
+	}
+
+	r, err := scanRelationship(s.DB.QueryRowContext(ctx, `INSERT INTO trainer_clients (trainer_id, client_id, status, invited_by)
+		VALUES ($1, $2, 'invited', $3)
+		ON CONFLICT (trainer_id, client_id) DO UPDATE SET status = 'invited', invited_by = EXCLUDED.invited_by,
+			updated_at = now(), ended_at = NULL
+		WHERE trainer_clients.status IN ('ended', 'declined')
+		RETURNING `+relationshipColumns, trainerID, clientID, invitedBy))
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrAlreadyExists
+		}
+		if apperr.IsForeignKeyViolation(err) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return r, nil
+}
+
+// Get возвращает отношения тренера и клиента
+func (s *Storage) Get(ctx context.Context, trainerID, clientID string) (_ *Relationship, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	return scanRelationship(s.DB.QueryRowContext(ctx, "SELECT "+relationshipColumns+
+		" FROM trainer_clients WHERE trainer_id = $1 AND client_id = $2", trainerID, clientID))
+}
+
+// SetStatus переводит отношения в состояние to, если текущее состояние входит в from.
+// Иначе возвращает ErrInvalidState.
+func (s *Storage) SetStatus(ctx context.Context, trainerID, clientID string, from []Status, to Status) (_ *Relationship, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	allowed := make([]string, len(from))
+	for i, st := range from {
+		allowed[i] = string(st)
+	}
+
+	r, err := scanRelationship(s.DB.QueryRowContext(ctx, `UPDATE trainer_clients SET status = $3, updated_at = now(),
+			ended_at = CASE WHEN $3 IN ('ended', 'declined') THEN now() ELSE NULL END
+		WHERE trainer_id = $1 AND client_id = $2 AND status = ANY($4)
+		RETURNING `+relationshipColumns, trainerID, clientID, to, pq.Array(allowed)))
+	if errors.Is(err, ErrNotFound) {
+		if _, getErr := s.Get(ctx, trainerID, clientID); getErr != nil {
+			return nil, getErr
+		}
+		return nil, ErrInvalidState
+	}
+	return r, err
+}
+
+// ListClients возвращает клиентов тренера. Пустой status означает действующих,
+// приостановленных и бывших клиентов одновременно.
+func (s *Storage) ListClients(ctx context.Context, trainerID string, status Status) (_ []Member, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	rows, err := s.DB.QueryContext(ctx, `SELECT u.user_id, u.first_name, u.last_name, tc.status, tc.updated_at
+		FROM trainer_clients tc JOIN users u ON u.user_id = tc.client_id
+		WHERE tc.trainer_id = $1 AND (($2 = '' AND tc.status IN ('active', 'paused', 'ended')) OR tc.status = $2)
+		ORDER BY tc.status, u.last_name, u.first_name`, trainerID, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []Member{}
+	for rows.Next() {
+		var m Member
+		if err := rows.Scan(&m.ClientID, &m.FirstName, &m.LastName, &m.Status, &m.Since); err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+	return members, rows.Err()
+}
+
+// HasClient сообщает, тренируется ли клиент у тренера сейчас (отношения действуют или приостановлены).
+// Реализует auth.Roster.
+func (s *Storage) HasClient(ctx context.Context, trainerID, clientID string) (_ bool, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	var exists bool
+	err = s.DB.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM trainer_clients
+		WHERE trainer_id = $1 AND client_id = $2 AND status IN ('active', 'paused'))`, trainerID, clientID).Scan(&exists)
+	return exists, err
+}
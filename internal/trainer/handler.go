@@ -0,0 +1,266 @@
+package trainer
+
+import (
+	"TrainerConnect/internal/apperr"
+	"TrainerConnect/internal/auth"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Handler struct {
+	Storage *Storage
+}
+
+const trainerURL = "/trainers/"
+
+func NewHandler(storage *Storage) *Handler {
+	return &Handler{Storage: storage}
+}
+
+func (h *Handler) Register(router *chi.Mux) {
+	router.Get("/trainers", h.Search)
+	router.Get(trainerURL+"{id}", h.GetProfile)
+	router.With(auth.RequireAuth).Put(trainerURL+"{id}", h.UpdateProfile)
+}
+
+// Search ищет тренеров по фильтрам из строки запроса и возвращает страницу результатов
+func (h *Handler) Search(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseSearchFilter(r.URL.Query())
+	if err != nil {
+		apperr.Write(w, err)
+		return
+	}
+
+	page, err := h.Storage.Search(r.Context(), *filter)
+	if err != nil {
+		log.Printf("Error searching trainers: %v", err)
+		apperr.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+// parseSearchFilter разбирает параметры поиска тренеров
+func parseSearchFilter(q url.Values) (*SearchFilter, error) {
+	f := &SearchFilter{
+		Specialty: q.Get("specialty"),
+		Language:  q.Get("language"),
+		City:      q.Get("city"),
+		Currency:  q.Get("currency"),
+		Sort:      q.Get("sort"),
+	}
+
+	var err error
+	if f.MinPrice, err = parseInt64Param(q, "min_price"); err != nil {
+		return nil, err
+	}
+	if f.MaxPrice, err = parseInt64Param(q, "max_price"); err != nil {
+		return nil, err
+	}
+	if f.MinRating, err = parseFloatParam(q, "min_rating"); err != nil {
+		return nil, err
+	}
+	if f.Latitude, err = parseFloatParam(q, "lat"); err != nil {
+		return nil, err
+	}
+	if f.Longitude, err = parseFloatParam(q, "lng"); err != nil {
+		return nil, err
+	}
+	if f.AvailableFrom, err = parseTimeParam(q, "available_from"); err != nil {
+		return nil, err
+	}
+	if f.AvailableTo, err = parseTimeParam(q, "available_to"); err != nil {
+		return nil, err
+	}
+	if (f.AvailableFrom == nil) != (f.AvailableTo == nil) {
+		return nil, apperr.InvalidField("available_to", "required", "available_from and available_to must be given together")
+	}
+	if f.AvailableFrom != nil && (!f.AvailableTo.After(*f.AvailableFrom) || f.AvailableTo.Sub(*f.AvailableFrom) > 31*24*time.Hour) {
+		return nil, apperr.InvalidField("available_to", "invalid_range", "availability window must be positive and not longer than 31 days")
+	}
+	if limit := q.Get("limit"); limit != "" {
+		if f.Limit, err = strconv.Atoi(limit); err != nil || f.Limit <= 0 {
+			return nil, apperr.InvalidField("limit", "invalid_number", "must be a positive integer")
+		}
+	}
+	if cursor := q.Get("cursor"); cursor != "" {
+		if f.Cursor, err = DecodeCursor(cursor); err != nil {
+			return nil, err
+		}
+	}
+
+	switch f.Sort {
+	case "":
+		f.Sort = SortRating
+	case SortRating, SortPrice:
+	case SortDistance:
+		if f.Latitude == nil || f.Longitude == nil {
+			return nil, apperr.InvalidField("lat", "required", "sort by distance requires lat and lng parameters")
+		}
+	default:
+		return nil, apperr.InvalidField("sort", "oneof", "must be one of: rating, price, distance")
+	}
+	if (f.Latitude == nil) != (f.Longitude == nil) {
+		return nil, apperr.InvalidField("lng", "required", "lat and lng must be given together")
+	}
+	if f.MinPrice != nil && f.MaxPrice != nil && *f.MinPrice > *f.MaxPrice {
+		return nil, apperr.InvalidField("max_price", "invalid_range", "min_price must not exceed max_price")
+	}
+	if f.Cursor != nil && !f.Cursor.matches(f) {
+		return nil, ErrInvalidCursor
+	}
+
+	return f, nil
+}
+
+func parseInt64Param(q url.Values, name string) (*int64, error) {
+	value := q.Get(name)
+	if value == "" {
+		return nil, nil
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return nil, apperr.InvalidField(name, "invalid_number", "must be an integer")
+	}
+	return &n, nil
+}
+
+func parseFloatParam(q url.Values, name string) (*float64, error) {
+	value := q.Get(name)
+	if value == "" {
+		return nil, nil
+	}
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, apperr.InvalidField(name, "invalid_number", "must be a number")
+	}
+	return &n, nil
+}
+
+func parseTimeParam(q url.Values, name string) (*time.Time, error) {
+	value := q.Get(name)
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, apperr.InvalidField(name, "invalid_time", "must be an RFC 3339 time")
+	}
+	return &t, nil
+}
+
+// GetProfile возвращает публичный профиль тренера
+func (h *Handler) GetProfile(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if _, err := strconv.Atoi(id); err != nil {
+		apperr.Respond(w, http.StatusBadRequest, "Invalid trainer ID")
+		return
+	}
+
+	profile, err := h.Storage.GetPublicProfile(r.Context(), id)
+	if err != nil {
+		log.Printf("Error getting trainer profile %s: %v", id, err)
+		apperr.Write(w, err)
+		return
+	}
+	if profile == nil {
+		apperr.Write(w, ErrNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(profile)
+}
+
+// UpdateProfile создает или заменяет профиль тренера. Менять профиль может сам тренер или администратор.
+func (h *Handler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if _, err := strconv.Atoi(id); err != nil {
+		apperr.Respond(w, http.StatusBadRequest, "Invalid trainer ID")
+		return
+	}
+
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	if principal.UserID != id && !principal.IsAdmin() {
+		apperr.Respond(w, http.StatusForbidden, "Forbidden")
+		return
+	}
+
+	var profile Profile
+	if err := json.NewDecoder(r.Body).Decode(&profile); err != nil {
+		apperr.Respond(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	profile.UserID = id
+	profile.Currency = strings.ToUpper(profile.Currency)
+
+	if err := validateProfile(&profile); err != nil {
+		apperr.Write(w, err)
+		return
+	}
+
+	if err := h.Storage.SaveProfile(r.Context(), &profile); err != nil {
+		if !apperr.Expected(err) {
+			log.Printf("Error saving trainer profile %s: %v", id, err)
+		}
+		apperr.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(profile)
+}
+
+// validateProfile нормализует поля профиля и возвращает ошибку первого неверного поля
+func validateProfile(p *Profile) error {
+	if p.YearsExperience < 0 {
+		return apperr.InvalidField("years_experience", "negative", "must not be negative")
+	}
+	if p.HourlyRate < 0 {
+		return apperr.InvalidField("hourly_rate", "negative", "must not be negative")
+	}
+	if len(p.Currency) != 3 {
+		return apperr.InvalidField("currency", "invalid_currency", "must be a three-letter ISO 4217 code")
+	}
+	if p.Specialties == nil {
+		p.Specialties = []string{}
+	}
+	for i, s := range p.Specialties {
+		p.Specialties[i] = strings.ToLower(strings.TrimSpace(s))
+		if p.Specialties[i] == "" {
+			return apperr.InvalidField("specialties", "empty_value", "must not contain empty values")
+		}
+	}
+	if p.Languages == nil {
+		p.Languages = []string{}
+	}
+	for i, l := range p.Languages {
+		p.Languages[i] = strings.ToLower(strings.TrimSpace(l))
+		if p.Languages[i] == "" {
+			return apperr.InvalidField("languages", "empty_value", "must not contain empty values")
+		}
+	}
+	if (p.Latitude == nil) != (p.Longitude == nil) {
+		return apperr.InvalidField("longitude", "required", "latitude and longitude must be given together")
+	}
+	if p.Latitude != nil && (*p.Latitude < -90 || *p.Latitude > 90 || *p.Longitude < -180 || *p.Longitude > 180) {
+		return apperr.InvalidField("latitude", "out_of_range", "coordinates are out of range")
+	}
+	for _, c := range p.Certifications {
+		if strings.TrimSpace(c.Name) == "" {
+			return apperr.InvalidField("certifications", "required", "certification name is required")
+		}
+		if c.IssuedAt != nil && c.ExpiresAt != nil && c.ExpiresAt.Before(*c.IssuedAt) {
+			return apperr.InvalidField("certifications", "invalid_range", "certification expires before it was issued")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package trainer
+
+import (
+	"time"
+)
+
+// Certification — сертификат тренера. Сертификаты без даты окончания бессрочны.
+type Certification struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Issuer    string     `json:"issuer"`
+	IssuedAt  *time.Time `json:"issued_at,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// Expired сообщает, истек ли срок действия сертификата на момент now
+func (c Certification) Expired(now time.Time) bool {
+	return c.ExpiresAt != nil && c.ExpiresAt.Before(now)
+}
+
+// Profile — профиль тренера, привязанный к пользователю с ролью "trainer".
+// Ставка хранится в минимальных единицах валюты (копейках, центах).
+type Profile struct {
+	UserID          string          `json:"user_id"`
+	Bio             string          `json:"bio"`
+	Specialties     []string        `json:"specialties"`
+	Languages       []string        `json:"languages"`
+	Certifications  []Certification `json:"certifications"`
+	YearsExperience int             `json:"years_experience"`
+	HourlyRate      int64           `json:"hourly_rate"`
+	Currency        string          `json:"currency"`
+	City            string          `json:"city"`
+	Latitude        *float64        `json:"latitude,omitempty"`
+	Longitude       *float64        `json:"longitude,omitempty"`
+}
+
+// PublicProfile — профиль тренера, который видят клиенты.
+// Не содержит приватных полей пользователя: email, логина, роли.
+type PublicProfile struct {
+	UserID          string          `json:"user_id"`
+	FirstName       string          `json:"firstname"`
+	LastName        string          `json:"lastname"`
+	Bio             string          `json:"bio"`
+	Specialties     []string        `json:"specialties"`
+	Languages       []string        `json:"languages"`
+	Certifications  []Certification `json:"certifications"`
+	YearsExperience int             `json:"years_experience"`
+	HourlyRate      int64           `json:"hourly_rate"`
+	Currency        string          `json:"currency"`
+	City            string          `json:"city"`
+	Rating          float64         `json:"rating"`
+	RatingCount     int             `json:"rating_count"`
+}
+
+// Summary — краткая карточка тренера в результатах поиска
+type Summary struct {
+	UserID          string   `json:"user_id"`
+	FirstName       string   `json:"firstname"`
+	LastName        string   `json:"lastname"`
+	Specialties     []string `json:"specialties"`
+	Languages       []string `json:"languages"`
+	YearsExperience int      `json:"years_experience"`
+	HourlyRate      int64    `json:"hourly_rate"`
+	Currency        string   `json:"currency"`
+	City            string   `json:"city"`
+	Rating          float64  `json:"rating"`
+	RatingCount     int      `json:"rating_count"`
+	DistanceKm      *float64 `json:"distance_km,omitempty"`
+}
+
+// SearchPage — страница результатов поиска. Пустой NextCursor означает последнюю страницу.
+type SearchPage struct {
+	Trainers   []Summary `json:"trainers"`
+	NextCursor string    `json:"next_cursor,omitempty"`
+}
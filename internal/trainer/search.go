@@ -0,0 +1,222 @@
+package trainer
+
+import (
+	"TrainerConnect/internal/apperr"
+	"TrainerConnect/internal/auth"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Порядок сортировки результатов поиска
+const (
+	SortRating   = "rating"
+	SortPrice    = "price"
+	SortDistance = "distance"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 50
+)
+
+var ErrInvalidCursor = apperr.Invalid("invalid_cursor", "invalid cursor")
+
+// SearchFilter — параметры поиска тренеров. Nil-поля не участвуют в фильтрации.
+type SearchFilter struct {
+	Specialty string
+	Language  string
+	City      string
+	Currency  string
+	MinPrice  *int64
+	MaxPrice  *int64
+	MinRating *float64
+	Latitude  *float64
+	Longitude *float64
+
+	// Тренер должен иметь хотя бы одно окно расписания, пересекающееся с [AvailableFrom, AvailableTo)
+	AvailableFrom *time.Time
+	AvailableTo   *time.Time
+
+	Sort   string
+	Cursor *Cursor
+	Limit  int
+}
+
+// Cursor указывает на последнюю запись предыдущей страницы: значение ключа сортировки и ID тренера.
+// Курсор действителен только для той сортировки (и точки отсчета расстояния), для которой выдан.
+type Cursor struct {
+	Sort      string   `json:"s"`
+	Key       string   `json:"k"`
+	ID        int64    `json:"id"`
+	Latitude  *float64 `json:"lat,omitempty"`
+	Longitude *float64 `json:"lng,omitempty"`
+}
+
+// Encode упаковывает курсор в непрозрачную строку для передачи клиенту
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor распаковывает курсор, полученный от клиента
+func DecodeCursor(s string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.Key == "" {
+		return nil, ErrInvalidCursor
+	}
+	switch c.Sort {
+	case SortPrice:
+		_, err = strconv.ParseInt(c.Key, 10, 64)
+	case SortRating, SortDistance:
+		_, err = strconv.ParseFloat(c.Key, 64)
+	default:
+		return nil, ErrInvalidCursor
+	}
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// matches сообщает, что курсор выдан для той же сортировки и точки отсчета, что и в фильтре f
+func (c *Cursor) matches(f *SearchFilter) bool {
+	if c.Sort != f.Sort {
+		return false
+	}
+	if c.Sort == SortDistance {
+		return sameCoordinate(c.Latitude, f.Latitude) && sameCoordinate(c.Longitude, f.Longitude)
+	}
+	return true
+}
+
+func sameCoordinate(a, b *float64) bool {
+	return a != nil && b != nil && *a == *b
+}
+
+// Расстояние по формуле гаверсинусов в километрах от точки (%[1]s, %[2]s) до тренера
+const distanceExpr = `(6371 * 2 * asin(sqrt(power(sin(radians(p.latitude - %[1]s) / 2), 2) +
+	cos(radians(%[1]s)) * cos(radians(p.latitude)) * power(sin(radians(p.longitude - %[2]s) / 2), 2))))`
+
+// Тренер доступен, если у него есть разовое окно или недельное окно в невыходной день,
+// пересекающееся с интервалом [%[1]s, %[2]s)
+const availabilityExpr = `(EXISTS (SELECT 1 FROM availability_extra_slots e
+		WHERE e.trainer_id = p.user_id AND e.starts_at < %[2]s AND e.ends_at > %[1]s)
+	OR EXISTS (SELECT 1 FROM availability_weekly w
+		CROSS JOIN LATERAL generate_series((%[1]s AT TIME ZONE w.time_zone)::date::timestamp,
+			(%[2]s AT TIME ZONE w.time_zone)::date::timestamp, interval '1 day') AS d(day)
+		WHERE w.trainer_id = p.user_id AND EXTRACT(DOW FROM d.day) = w.weekday
+			AND (d.day + w.start_time) AT TIME ZONE w.time_zone < %[2]s
+			AND (d.day + w.end_time) AT TIME ZONE w.time_zone > %[1]s
+			AND NOT EXISTS (SELECT 1 FROM availability_blackouts b
+				WHERE b.trainer_id = w.trainer_id AND b.day = d.day::date)))`
+
+// limit возвращает размер страницы с учетом значения по умолчанию и ограничения сверху
+func (f *SearchFilter) limit() int {
+	if f.Limit <= 0 {
+		return defaultSearchLimit
+	}
+	if f.Limit > maxSearchLimit {
+		return maxSearchLimit
+	}
+	return f.Limit
+}
+
+// query строит SQL-запрос поиска с постраничной выборкой по ключу (keyset pagination).
+// Запрос выбирает на одну запись больше размера страницы, чтобы понять, есть ли следующая.
+func (f *SearchFilter) query() (string, []interface{}) {
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	where := []string{"u.role = " + arg(auth.RoleTrainer)}
+	if f.Specialty != "" {
+		where = append(where, "p.specialties @> ARRAY["+arg(strings.ToLower(f.Specialty))+"]::text[]")
+	}
+	if f.Language != "" {
+		where = append(where, "p.languages @> ARRAY["+arg(strings.ToLower(f.Language))+"]::text[]")
+	}
+	if f.City != "" {
+		where = append(where, "lower(p.city) = lower("+arg(f.City)+")")
+	}
+	if f.Currency != "" {
+		where = append(where, "p.currency = "+arg(strings.ToUpper(f.Currency)))
+	}
+	if f.MinPrice != nil {
+		where = append(where, "p.hourly_rate >= "+arg(*f.MinPrice))
+	}
+	if f.MaxPrice != nil {
+		where = append(where, "p.hourly_rate <= "+arg(*f.MaxPrice))
+	}
+	if f.MinRating != nil {
+		where = append(where, "p.rating_avg >= "+arg(*f.MinRating))
+	}
+
+	if f.AvailableFrom != nil && f.AvailableTo != nil {
+		from, to := arg(*f.AvailableFrom)+"::timestamptz", arg(*f.AvailableTo)+"::timestamptz"
+		where = append(where, fmt.Sprintf(availabilityExpr, from, to))
+	}
+
+	distance := "NULL::double precision"
+	if f.Latitude != nil && f.Longitude != nil {
+		distance = fmt.Sprintf(distanceExpr, arg(*f.Latitude), arg(*f.Longitude))
+	}
+
+	var key, keyType, direction string
+	switch f.Sort {
+	case SortPrice:
+		key, keyType, direction = "p.hourly_rate", "bigint", "ASC"
+	case SortDistance:
+		key, keyType, direction = distance, "double precision", "ASC"
+		where = append(where, "p.latitude IS NOT NULL", "p.longitude IS NOT NULL")
+	default:
+		key, keyType, direction = "p.rating_avg", "double precision", "DESC"
+	}
+
+	if f.Cursor != nil {
+		op := ">"
+		if direction == "DESC" {
+			op = "<"
+		}
+		k := arg(f.Cursor.Key) + "::" + keyType
+		id := arg(f.Cursor.ID)
+		where = append(where, fmt.Sprintf("(%[1]s %[2]s %[3]s OR (%[1]s = %[3]s AND p.user_id > %[4]s))", key, op, k, id))
+	}
+
+	query := `SELECT u.user_id, u.first_name, u.last_name, p.specialties, p.languages, p.years_experience,
+			p.hourly_rate, p.currency, p.city, p.rating_avg, p.rating_count, ` + distance + ` AS distance_km
+		FROM trainer_profiles p JOIN users u ON u.user_id = p.user_id
+		WHERE ` + strings.Join(where, " AND ") + `
+		ORDER BY ` + key + ` ` + direction + `, p.user_id ASC
+		LIMIT ` + arg(f.limit()+1)
+
+	return query, args
+}
+
+// cursorAfter возвращает курсор, указывающий на запись s, для текущего порядка сортировки
+func (f *SearchFilter) cursorAfter(s Summary) Cursor {
+	id, _ := strconv.ParseInt(s.UserID, 10, 64)
+	switch f.Sort {
+	case SortPrice:
+		return Cursor{Sort: SortPrice, Key: strconv.FormatInt(s.HourlyRate, 10), ID: id}
+	case SortDistance:
+		var d float64
+		if s.DistanceKm != nil {
+			d = *s.DistanceKm
+		}
+		return Cursor{Sort: SortDistance, Key: strconv.FormatFloat(d, 'g', -1, 64), ID: id,
+			Latitude: f.Latitude, Longitude: f.Longitude}
+	default:
+		return Cursor{Sort: SortRating, Key: strconv.FormatFloat(s.Rating, 'g', -1, 64), ID: id}
+	}
+}
@@ -0,0 +1,92 @@
+package trainer
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	c := Cursor{Sort: SortRating, Key: "4.75", ID: 12}
+
+	decoded, err := DecodeCursor(c.Encode())
+	assert.NoError(t, err)
+	assert.Equal(t, &c, decoded)
+
+	_, err = DecodeCursor("not-a-cursor")
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+
+	// Ключ цены — целое число
+	_, err = DecodeCursor(Cursor{Sort: SortPrice, Key: "4.75", ID: 12}.Encode())
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+}
+
+func TestCursorMismatch(t *testing.T) {
+	rating := Cursor{Sort: SortRating, Key: "4.75", ID: 12}.Encode()
+	_, err := parseSearchFilter(url.Values{"cursor": {rating}})
+	assert.NoError(t, err)
+	_, err = parseSearchFilter(url.Values{"cursor": {rating}, "sort": {"price"}})
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+
+	// Курсор по расстоянию привязан к точке отсчета
+	lat, lng := 55.75, 37.61
+	filter := &SearchFilter{Sort: SortDistance, Latitude: &lat, Longitude: &lng}
+	distance := filter.cursorAfter(Summary{UserID: "3"}).Encode()
+	_, err = parseSearchFilter(url.Values{"cursor": {distance}, "sort": {"distance"}, "lat": {"55.75"}, "lng": {"37.61"}})
+	assert.NoError(t, err)
+	_, err = parseSearchFilter(url.Values{"cursor": {distance}, "sort": {"distance"}, "lat": {"59.93"}, "lng": {"30.31"}})
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+}
+
+func TestSearchQueryFilters(t *testing.T) {
+	filter, err := parseSearchFilter(url.Values{
+		"specialty": {"Yoga"},
+		"city":      {"Kazan"},
+		"min_price": {"100000"},
+		"sort":      {"price"},
+		"limit":     {"10"},
+	})
+	assert.NoError(t, err)
+
+	query, args := filter.query()
+	assert.Contains(t, query, "u.role = $1")
+	assert.Contains(t, query, "p.specialties @> ARRAY[$2]::text[]")
+	assert.Contains(t, query, "lower(p.city) = lower($3)")
+	assert.Contains(t, query, "p.hourly_rate >= $4")
+	assert.Contains(t, query, "ORDER BY p.hourly_rate ASC, p.user_id ASC")
+	assert.Equal(t, []interface{}{"trainer", "yoga", "Kazan", int64(100000), 11}, args)
+}
+
+func TestSearchQueryCursor(t *testing.T) {
+	// По рейтингу сортируем по убыванию, поэтому следующая страница — рейтинг меньше курсора
+	filter := &SearchFilter{Sort: SortRating, Cursor: &Cursor{Key: "4.5", ID: 3}}
+	query, args := filter.query()
+	assert.Contains(t, query, "(p.rating_avg < $2::double precision OR (p.rating_avg = $2::double precision AND p.user_id > $3))")
+	assert.Equal(t, []interface{}{"trainer", "4.5", int64(3), defaultSearchLimit + 1}, args)
+
+	// Сортировка по расстоянию исключает тренеров без координат
+	filter, err := parseSearchFilter(url.Values{"sort": {"distance"}, "lat": {"55.75"}, "lng": {"37.61"}})
+	assert.NoError(t, err)
+	query, _ = filter.query()
+	assert.True(t, strings.Contains(query, "p.latitude IS NOT NULL"))
+
+	_, err = parseSearchFilter(url.Values{"sort": {"distance"}})
+	assert.Error(t, err)
+}
+
+func TestSearchQueryAvailability(t *testing.T) {
+	filter, err := parseSearchFilter(url.Values{
+		"available_from": {"2026-10-19T09:00:00Z"},
+		"available_to":   {"2026-10-19T12:00:00Z"},
+	})
+	assert.NoError(t, err)
+
+	query, args := filter.query()
+	assert.Contains(t, query, "e.starts_at < $3::timestamptz AND e.ends_at > $2::timestamptz")
+	assert.Contains(t, query, "availability_blackouts")
+	assert.Len(t, args, 4)
+
+	_, err = parseSearchFilter(url.Values{"available_from": {"2026-10-19T09:00:00Z"}})
+	assert.Error(t, err)
+}
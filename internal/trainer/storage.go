@@ -0,0 +1,188 @@
+package trainer
+
+import (
+	"TrainerConnect/internal/apperr"
+	"TrainerConnect/internal/auth"
+	postgres "TrainerConnect/pkg/postgresql"
+	"context"
+	"database/sql"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrNotFound = apperr.NotFound("trainer_not_found", "trainer not found")
+	// ErrNotTrainer возвращается, если профиль пытаются привязать к пользователю без роли auth.RoleTrainer
+	ErrNotTrainer = apperr.Invalid("not_trainer", "user is not a trainer")
+)
+
+type Storage struct {
+	*sql.DB
+}
+
+func NewStorage(db *sql.DB) *Storage {
+	return &Storage{DB: db}
+}
+
+// GetProfile возвращает профиль тренера или nil, если профиль еще не заполнен
+func (s *Storage) GetProfile(ctx context.Context, userID string) (_ *Profile, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	row := s.DB.QueryRowContext(ctx, `SELECT user_id, bio, specialties, languages, years_experience, hourly_rate, currency,
+			city, latitude, longitude
+		FROM trainer_profiles WHERE user_id = $1`, userID)
+
+	p := &Profile{}
+	err = row.Scan(&p.UserID, &p.Bio, pq.Array(&p.Specialties), pq.Array(&p.Languages), &p.YearsExperience,
+		&p.HourlyRate, &p.Currency, &p.City, &p.Latitude, &p.Longitude)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	p.Certifications, err = s.getCertifications(ctx, userID, false)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// GetPublicProfile возвращает публичный профиль тренера вместе с именем из таблицы users.
+// Просроченные сертификаты в публичный профиль не попадают.
+func (s *Storage) GetPublicProfile(ctx context.Context, userID string) (_ *PublicProfile, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	row := s.DB.QueryRowContext(ctx, `SELECT u.user_id, u.first_name, u.last_name, p.bio, p.specialties, p.languages,
+			p.years_experience, p.hourly_rate, p.currency, p.city, p.rating_avg, p.rating_count
+		FROM trainer_profiles p JOIN users u ON u.user_id = p.user_id
+		WHERE p.user_id = $1 AND u.role = $2`, userID, auth.RoleTrainer)
+
+	p := &PublicProfile{}
+	err = row.Scan(&p.UserID, &p.FirstName, &p.LastName, &p.Bio, pq.Array(&p.Specialties), pq.Array(&p.Languages),
+		&p.YearsExperience, &p.HourlyRate, &p.Currency, &p.City, &p.Rating, &p.RatingCount)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	p.Certifications, err = s.getCertifications(ctx, userID, true)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// SaveProfile создает или полностью заменяет профиль тренера вместе с сертификатами
+func (s *Storage) SaveProfile(ctx context.Context, p *Profile) (err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var role string
+	err = tx.QueryRowContext(ctx, "SELECT role FROM users WHERE user_id = $1", p.UserID).Scan(&role)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrNotTrainer
+		}
+		return err
+	}
+	if role != auth.RoleTrainer {
+		return ErrNotTrainer
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO trainer_profiles (user_id, bio, specialties, languages, years_experience,
+			hourly_rate, currency, city, latitude, longitude)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (user_id) DO UPDATE SET bio = EXCLUDED.bio, specialties = EXCLUDED.specialties,
+			languages = EXCLUDED.languages, years_experience = EXCLUDED.years_experience,
+			hourly_rate = EXCLUDED.hourly_rate, currency = EXCLUDED.currency, city = EXCLUDED.city,
+			latitude = EXCLUDED.latitude, longitude = EXCLUDED.longitude, updated_at = now()`,
+		p.UserID, p.Bio, pq.Array(p.Specialties), pq.Array(p.Languages), p.YearsExperience,
+		p.HourlyRate, p.Currency, p.City, p.Latitude, p.Longitude)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM trainer_certifications WHERE trainer_id = $1", p.UserID); err != nil {
+		return err
+	}
+	for i := range p.Certifications {
+		c := &p.Certifications[i]
+		err := tx.QueryRowContext(ctx, `INSERT INTO trainer_certifications (trainer_id, name, issuer, issued_at, expires_at)
+			VALUES ($1, $2, $3, $4, $5) RETURNING id`,
+			p.UserID, c.Name, c.Issuer, c.IssuedAt, c.ExpiresAt).Scan(&c.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s *Storage) getCertifications(ctx context.Context, userID string, activeOnly bool) ([]Certification, error) {
+	query := "SELECT id, name, issuer, issued_at, expires_at FROM trainer_certifications WHERE trainer_id = $1"
+	if activeOnly {
+		query += " AND (expires_at IS NULL OR expires_at >= CURRENT_DATE)"
+	}
+	query += " ORDER BY id"
+
+	rows, err := s.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	certifications := []Certification{}
+	for rows.Next() {
+		var c Certification
+		if err := rows.Scan(&c.ID, &c.Name, &c.Issuer, &c.IssuedAt, &c.ExpiresAt); err != nil {
+			return nil, err
+		}
+		certifications = append(certifications, c)
+	}
+	return certifications, rows.Err()
+}
+
+// Search ищет тренеров по фильтру и возвращает одну страницу результатов
+func (s *Storage) Search(ctx context.Context, f SearchFilter) (_ *SearchPage, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	query, args := f.query()
+	rows, err := s.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	page := &SearchPage{Trainers: []Summary{}}
+	for rows.Next() {
+		var t Summary
+		err := rows.Scan(&t.UserID, &t.FirstName, &t.LastName, pq.Array(&t.Specialties), pq.Array(&t.Languages),
+			&t.YearsExperience, &t.HourlyRate, &t.Currency, &t.City, &t.Rating, &t.RatingCount, &t.DistanceKm)
+		if err != nil {
+			return nil, err
+		}
+		page.Trainers = append(page.Trainers, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Лишняя запись означает, что есть следующая страница
+	if limit := f.limit(); len(page.Trainers) > limit {
+		page.Trainers = page.Trainers[:limit]
+		page.NextCursor = f.cursorAfter(page.Trainers[limit-1]).Encode()
+	}
+	return page, nil
+}
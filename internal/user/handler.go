@@ -3,9 +3,15 @@
 package user
 
 import (
+	"TrainerConnect/internal/apperr"
+	"TrainerConnect/internal/auth"
+	"TrainerConnect/internal/validate"
 	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"github.com/go-chi/chi/v5"
 	"golang.org/x/crypto/bcrypt"
 	"log"
@@ -14,31 +20,74 @@ import (
 )
 
 type Handler struct {
-	Storage *Storage
+	Storage UserRepository
+	Auth    *auth.Service
+	Policy  *auth.Policy
 }
 
 const userURL = "/users/"
 
-func NewHandler(storage *Storage) *Handler {
-	return &Handler{Storage: storage}
+// ErrInvalidCredentials не уточняет, что именно неверно: имя пользователя или пароль
+var ErrInvalidCredentials = apperr.Unauthorized("invalid_credentials", "invalid credentials")
+
+func NewHandler(storage UserRepository, authService *auth.Service, policy *auth.Policy) *Handler {
+	return &Handler{Storage: storage, Auth: authService, Policy: policy}
 }
 
 func (h *Handler) Register(router *chi.Mux) {
 	router.Mount(userURL, router)
-	router.Get(userURL, h.GetList)
-	router.Get(userURL+"{id}", h.GetUserHandler)
-	router.Get("/users", h.GetUserByUsernameHandler)
 	router.Post(userURL, h.CreateNewUserHandler)
-	router.Put(userURL+"{id}", h.UpdateUser)
-	router.Patch(userURL+"{id}", h.PatchUser)
-	router.Delete(userURL+"{id}", h.DeleteUser)
 	router.Post("/auth", h.AuthenticateUser)
+
+	// Остальные маршруты доступны только после аутентификации
+	router.Group(func(r chi.Router) {
+		r.Use(auth.RequireAuth)
+		r.Get(userURL, h.GetList)
+		r.Get(userURL+"{id}", h.GetUserHandler)
+		r.Get("/users", h.GetUserByUsernameHandler)
+		r.Put(userURL+"{id}", h.UpdateUser)
+		r.Patch(userURL+"{id}", h.PatchUser)
+		r.Delete(userURL+"{id}", h.DeleteUser)
+	})
+}
+
+// canRead проверяет право текущего пользователя на чтение данных пользователя userID
+// и при отказе сам отправляет ответ
+func (h *Handler) canRead(w http.ResponseWriter, r *http.Request, userID string) bool {
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	allowed, err := h.Policy.CanReadUser(r.Context(), principal, userID)
+	if err != nil {
+		log.Printf("Error checking access to user %s: %v", userID, err)
+		apperr.Write(w, err)
+		return false
+	}
+	if !allowed {
+		apperr.Respond(w, http.StatusForbidden, "Forbidden")
+		return false
+	}
+	return true
+}
+
+// canEdit проверяет право текущего пользователя на изменение пользователя userID
+func (h *Handler) canEdit(w http.ResponseWriter, r *http.Request, userID string) bool {
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	if !h.Policy.CanEditUser(principal, userID) {
+		apperr.Respond(w, http.StatusForbidden, "Forbidden")
+		return false
+	}
+	return true
 }
 
 func (h *Handler) GetList(w http.ResponseWriter, r *http.Request) {
-	users, err := h.Storage.GetAllUsers()
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	if !h.Policy.CanListUsers(principal) {
+		apperr.Respond(w, http.StatusForbidden, "Forbidden")
+		return
+	}
+
+	users, err := h.Storage.GetAllUsers(r.Context())
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apperr.Write(w, err)
 		return
 	}
 
@@ -51,17 +100,16 @@ func (h *Handler) GetUserHandler(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	userID, err := strconv.Atoi(id)
 	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		apperr.Respond(w, http.StatusBadRequest, "Invalid user ID")
 		return
 	}
-
-	user, err := h.Storage.GetUserByID(userID)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if !h.canRead(w, r, id) {
 		return
 	}
-	if user == nil {
-		http.Error(w, "User not found", http.StatusNotFound)
+
+	user, err := h.Storage.GetUserByID(r.Context(), userID)
+	if err != nil {
+		apperr.Write(w, err)
 		return
 	}
 
@@ -72,20 +120,21 @@ func (h *Handler) CreateNewUserHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	// Декодирование данных запроса, включая пароль
-	var userData struct {
-		ID        string `json:"id"`
-		FirstName string `json:"firstname"`
-		LastName  string `json:"lastname"`
-		Username  string `json:"username"`
-		Role      string `json:"role"`
-		Email     string `json:"email"`
-		Password  string `json:"password"`
-	}
+	var userData CreateUserRequest
 	if err := json.NewDecoder(r.Body).Decode(&userData); err != nil {
 		log.Printf("Error decoding request body: %v", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		apperr.Respond(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	// Проверка всех полей сразу: клиент получает полный список ошибок
+	if err := validate.Struct(userData); err != nil {
+		apperr.Write(w, err)
 		return
 	}
+	if userData.Role == "" {
+		userData.Role = auth.RoleClient
+	}
 
 	// Преобразование в структуру User
 	user := User{
@@ -95,7 +144,6 @@ func (h *Handler) CreateNewUserHandler(w http.ResponseWriter, r *http.Request) {
 		Username:  userData.Username,
 		Role:      userData.Role,
 		Email:     userData.Email,
-		Password:  userData.Password,
 	}
 
 	// Логирование перед созданием пользователя
@@ -105,7 +153,7 @@ func (h *Handler) CreateNewUserHandler(w http.ResponseWriter, r *http.Request) {
 	salt, err := GenerateSalt()
 	if err != nil {
 		log.Printf("Error generating salt: %v", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apperr.Write(w, err)
 		return
 	}
 
@@ -113,14 +161,16 @@ func (h *Handler) CreateNewUserHandler(w http.ResponseWriter, r *http.Request) {
 	hashedPassword, err := HashPassword(userData.Password, salt)
 	if err != nil {
 		log.Printf("Error hashing password: %v", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apperr.Write(w, err)
 		return
 	}
 
 	// Создание нового пользователя с использованием метода CreateUser
-	if err := h.Storage.CreateUser(&user, hashedPassword, salt); err != nil {
-		log.Printf("Error creating user: %v", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := h.Storage.CreateUser(r.Context(), &user, hashedPassword, salt); err != nil {
+		if !apperr.Expected(err) {
+			log.Printf("Error creating user: %v", err)
+		}
+		apperr.Write(w, err)
 		return
 	}
 
@@ -136,30 +186,44 @@ func (h *Handler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	userID, err := strconv.Atoi(id)
 	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		apperr.Respond(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+	if !h.canEdit(w, r, id) {
 		return
 	}
 
 	// Получаем пользователя из базы данных по ID
-	updatedUser, err := h.Storage.GetUserByID(userID)
+	updatedUser, err := h.Storage.GetUserByID(r.Context(), userID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-	if updatedUser == nil {
-		http.Error(w, "User not found", http.StatusNotFound)
+		apperr.Write(w, err)
 		return
 	}
 
 	// Декодируем JSON-тело запроса и обновляем только те поля, которые присутствуют в запросе
+	currentRole := updatedUser.Role
 	if err := json.NewDecoder(r.Body).Decode(&updatedUser); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		apperr.Respond(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	// Идентификатор берется только из URL, а роль может менять только администратор
+	updatedUser.ID = id
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	if updatedUser.Role != currentRole && !h.Policy.CanAssignRole(principal) {
+		apperr.Respond(w, http.StatusForbidden, "Forbidden to change role")
+		return
+	}
+
+	if err := validate.Struct(profileOf(updatedUser)); err != nil {
+		apperr.Write(w, err)
 		return
 	}
 
 	// Обновляем пользователя в базе данных
-	if err := h.Storage.UpdateUser(updatedUser); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := h.Storage.UpdateUser(r.Context(), updatedUser); err != nil {
+		log.Printf("Error updating user %s: %v", id, err)
+		apperr.Write(w, err)
 		return
 	}
 
@@ -171,24 +235,23 @@ func (h *Handler) PatchUser(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	userID, err := strconv.Atoi(id)
 	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		apperr.Respond(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+	if !h.canEdit(w, r, id) {
 		return
 	}
 
 	var patchData map[string]string
 	if err := json.NewDecoder(r.Body).Decode(&patchData); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		apperr.Respond(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
 	// Получаем текущего пользователя
-	currentUser, err := h.Storage.GetUserByID(userID)
+	currentUser, err := h.Storage.GetUserByID(r.Context(), userID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-	if currentUser == nil {
-		http.Error(w, "User not found", http.StatusNotFound)
+		apperr.Write(w, err)
 		return
 	}
 
@@ -206,9 +269,16 @@ func (h *Handler) PatchUser(w http.ResponseWriter, r *http.Request) {
 		currentUser.Username = username
 	}
 
+	// Проверяем профиль целиком: частичное изменение не должно его испортить
+	if err := validate.Struct(profileOf(currentUser)); err != nil {
+		apperr.Write(w, err)
+		return
+	}
+
 	// Обновляем пользователя
-	if err := h.Storage.UpdateUser(currentUser); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := h.Storage.UpdateUser(r.Context(), currentUser); err != nil {
+		log.Printf("Error updating user %s: %v", id, err)
+		apperr.Write(w, err)
 		return
 	}
 
@@ -219,12 +289,21 @@ func (h *Handler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	userID, err := strconv.Atoi(id)
 	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		apperr.Respond(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	if !h.Policy.CanDeleteUser(principal) {
+		apperr.Respond(w, http.StatusForbidden, "Forbidden")
 		return
 	}
 
-	if err := h.Storage.DeleteUser(userID); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := h.Storage.DeleteUser(r.Context(), userID); err != nil {
+		if !apperr.Expected(err) {
+			log.Printf("Error deleting user %s: %v", id, err)
+		}
+		apperr.Write(w, err)
 		return
 	}
 
@@ -234,21 +313,36 @@ func (h *Handler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) GetUserByUsernameHandler(w http.ResponseWriter, r *http.Request) {
 	username := r.URL.Query().Get("username")
 	if username == "" {
-		http.Error(w, "Missing username parameter", http.StatusBadRequest)
+		apperr.Respond(w, http.StatusBadRequest, "Missing username parameter")
 		return
 	}
 
-	user, _, err := h.Storage.GetUserByUsername(username)
+	user, _, err := h.Storage.GetUserByUsername(r.Context(), username)
 	if err != nil {
-		http.Error(w, "Error getting user by username", http.StatusInternalServerError)
+		if !apperr.Expected(err) {
+			log.Printf("Error getting user %s: %v", username, err)
+		}
+		apperr.Write(w, err)
 		return
 	}
 
-	if user == nil {
-		http.Error(w, "User not found", http.StatusNotFound)
+	// Недоступный пользователь неотличим от несуществующего, иначе по ответу
+	// можно было бы проверить, занято ли имя
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	allowed, err := h.Policy.CanReadUser(r.Context(), principal, user.ID)
+	if err != nil {
+		log.Printf("Error checking access to user %s: %v", user.ID, err)
+		apperr.Write(w, err)
+		return
+	}
+	if !allowed {
+		apperr.Write(w, ErrUserNotFound)
 		return
 	}
 
+	// Пароль и соль не должны попадать в ответ
+	user.Password, user.Salt = "", ""
+
 	// Отправляем информацию о пользователе в формате JSON
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(user)
@@ -265,10 +359,10 @@ func GenerateSalt() (string, error) {
 	return hex.EncodeToString(saltBytes), nil
 }
 
-// HashPassword хэширует пароль с использованием соли
+// HashPassword хэширует пароль с использованием соли. bcrypt принимает не больше 72 байт,
+// а соль сама занимает 64, поэтому пароль с солью сначала сворачивается через SHA-256
 func HashPassword(password, salt string) (string, error) {
-	passwordBytes := []byte(password + salt)
-	hashedPassword, err := bcrypt.GenerateFromPassword(passwordBytes, bcrypt.DefaultCost)
+	hashedPassword, err := bcrypt.GenerateFromPassword(prehash(password, salt), bcrypt.DefaultCost)
 	if err != nil {
 		return "", err
 	}
@@ -286,15 +380,20 @@ func (h *Handler) AuthenticateUser(w http.ResponseWriter, r *http.Request) {
 	}
 	if err := json.NewDecoder(r.Body).Decode(&authData); err != nil {
 		log.Printf("Error decoding request body: %v", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		apperr.Respond(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
 	// Получение пользователя и соли по имени пользователя из базы данных
-	existingUser, salt, err := h.Storage.GetUserByUsername(authData.Username)
+	existingUser, salt, err := h.Storage.GetUserByUsername(r.Context(), authData.Username)
+	if errors.Is(err, ErrUserNotFound) {
+		log.Printf("User %s not found", authData.Username)
+		apperr.Write(w, ErrInvalidCredentials)
+		return
+	}
 	if err != nil {
 		log.Printf("Error getting user by username: %v", err)
-		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+		apperr.Write(w, err)
 		return
 	}
 
@@ -302,22 +401,34 @@ func (h *Handler) AuthenticateUser(w http.ResponseWriter, r *http.Request) {
 	err = ComparePasswords(existingUser.Password, authData.Password, salt)
 	if err != nil {
 		log.Printf("Invalid password for user %s", authData.Username)
-		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+		apperr.Write(w, ErrInvalidCredentials)
 		return
 	}
 
-	// TODO: Создание токена аутентификации (JWT, например) и отправка его в ответе
-	// Пример: отправка успешного ответа
-	w.Write([]byte("Authentication successful"))
+	// Выдача пары токенов: access-токен несет ID и роль пользователя,
+	// refresh-токен хранится на сервере и позволяет получить новый access-токен
+	tokens, err := h.Auth.Issue(r.Context(), existingUser.ID, existingUser.Role)
+	if err != nil {
+		log.Printf("Error issuing tokens for user %s: %v", authData.Username, err)
+		apperr.Write(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(tokens)
 }
 
 // ComparePasswords сравнивает хэш пароля с предоставленным паролем и солью
+// Хэши, созданные до перехода на SHA-256, проверяются по прежней схеме
 func ComparePasswords(hashedPassword, password, salt string) error {
-	// Проверка пароля
-	err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password+salt))
-	if err != nil {
-		return err
+	err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), prehash(password, salt))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) && len(password)+len(salt) <= 72 {
+		return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password+salt))
 	}
 
-	return nil
+	return err
+}
+
+func prehash(password, salt string) []byte {
+	sum := sha256.Sum256([]byte(password + salt))
+	return []byte(base64.StdEncoding.EncodeToString(sum[:]))
 }
@@ -0,0 +1,199 @@
+package user_test
+
+import (
+	"TrainerConnect/internal/apperr"
+	"TrainerConnect/internal/auth"
+	"TrainerConnect/internal/user"
+	"context"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newMemoryRouter создает роутер с обработчиком пользователей поверх хранилища в памяти
+func newMemoryRouter(repo user.UserRepository) *chi.Mux {
+	router := chi.NewRouter()
+	router.Use(auth.Middleware(tokens))
+	handler := user.NewHandler(repo, auth.NewService(tokens, nil), auth.NewPolicy(nil))
+	handler.Register(router)
+	return router
+}
+
+// serve выполняет запрос от имени пользователя userID с ролью role; пустой userID — без токена
+func serve(t *testing.T, router *chi.Mux, method, target, body, userID, role string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if userID != "" {
+		token, err := tokens.IssueAccessToken(userID, role)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	return rr
+}
+
+// problemCode возвращает код ошибки из ответа application/problem+json
+func problemCode(t *testing.T, rr *httptest.ResponseRecorder) string {
+	var p apperr.Problem
+	require.Equal(t, "application/problem+json", rr.Header().Get("Content-Type"))
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&p))
+	return p.Code
+}
+
+func TestMemoryHandlers(t *testing.T) {
+	repo := user.NewMemoryStorage()
+	router := newMemoryRouter(repo)
+
+	rr := serve(t, router, "POST", "/users/", `{"id": "1", "firstname": "John", "lastname": "Doe", "username": "johndoe",
+		"role": "client", "email": "john.doe@example.com", "password": "secret123"}`, "", "")
+	require.Equal(t, http.StatusOK, rr.Code)
+	rr = serve(t, router, "POST", "/users/", `{"id": "2", "firstname": "Jane", "lastname": "Doe", "username": "janedoe",
+		"role": "trainer", "email": "jane.doe@example.com", "password": "secret456"}`, "", "")
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	// Имя пользователя уже занято
+	rr = serve(t, router, "POST", "/users/", `{"id": "3", "firstname": "John", "lastname": "Roe", "username": "johndoe",
+		"email": "other@example.com", "password": "secret789"}`, "", "")
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.Equal(t, "user_exists", problemCode(t, rr))
+
+	// Пароль хранится в виде хэша и не возвращается при чтении по ID
+	stored, salt, err := repo.GetUserByUsername(context.Background(), "johndoe")
+	require.NoError(t, err)
+	require.NoError(t, user.ComparePasswords(stored.Password, "secret123", salt))
+
+	rr = serve(t, router, "GET", "/users/1", "", "1", auth.RoleClient)
+	require.Equal(t, http.StatusOK, rr.Code)
+	var got user.User
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&got))
+	assert.Equal(t, user.User{ID: "1", FirstName: "John", LastName: "Doe", Username: "johndoe", Role: "client", Email: "john.doe@example.com"}, got)
+
+	// Клиент не видит чужие данные, а администратор видит список всех пользователей
+	rr = serve(t, router, "GET", "/users/2", "", "1", auth.RoleClient)
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	rr = serve(t, router, "GET", "/users/", "", "0", auth.RoleAdmin)
+	require.Equal(t, http.StatusOK, rr.Code)
+	var all []user.User
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&all))
+	require.Len(t, all, 2)
+	assert.Equal(t, "1", all[0].ID)
+	assert.Equal(t, "2", all[1].ID)
+
+	rr = serve(t, router, "PATCH", "/users/1", `{"FirstName": "Johnny"}`, "1", auth.RoleClient)
+	require.Equal(t, http.StatusOK, rr.Code)
+	rr = serve(t, router, "PUT", "/users/1", `{"email": "jane.doe@example.com"}`, "1", auth.RoleClient)
+	assert.Equal(t, http.StatusConflict, rr.Code)
+
+	// Чужое и несуществующее имя пользователя дают одинаковый ответ
+	rr = serve(t, router, "GET", "/users?username=janedoe", "", "1", auth.RoleClient)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Equal(t, "user_not_found", problemCode(t, rr))
+	rr = serve(t, router, "GET", "/users?username=nobody", "", "1", auth.RoleClient)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Equal(t, "user_not_found", problemCode(t, rr))
+
+	rr = serve(t, router, "GET", "/users?username=johndoe", "", "1", auth.RoleClient)
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&got))
+	assert.Equal(t, "Johnny", got.FirstName)
+	assert.Empty(t, got.Password)
+
+	rr = serve(t, router, "DELETE", "/users/1", "", "0", auth.RoleAdmin)
+	require.Equal(t, http.StatusOK, rr.Code)
+	rr = serve(t, router, "GET", "/users/1", "", "0", auth.RoleAdmin)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Equal(t, "user_not_found", problemCode(t, rr))
+}
+
+func TestValidation(t *testing.T) {
+	repo := user.NewMemoryStorage()
+	router := newMemoryRouter(repo)
+
+	// Все ошибки полей возвращаются одним ответом
+	rr := serve(t, router, "POST", "/users/", `{"id": "a1", "firstname": "", "lastname": "Doe", "username": "jo",
+		"role": "admin", "email": "not-an-email", "password": "12345678"}`, "", "")
+	require.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	var p apperr.Problem
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&p))
+	assert.Equal(t, "validation_failed", p.Code)
+	assert.Equal(t, []apperr.FieldError{
+		{Field: "id", Code: "numeric", Message: "must be a positive integer"},
+		{Field: "firstname", Code: "required", Message: "is required"},
+		{Field: "username", Code: "too_short", Message: "must be at least 3 characters long"},
+		{Field: "role", Code: "oneof", Message: "must be one of: client, trainer"},
+		{Field: "email", Code: "email", Message: "must be a valid email address"},
+		{Field: "password", Code: "weak_password", Message: "must contain at least one letter and one digit"},
+	}, p.Errors)
+
+	// Без роли пользователь регистрируется как клиент; длинный пароль тоже допустим
+	rr = serve(t, router, "POST", "/users/", `{"id": "1", "firstname": "John", "lastname": "Doe", "username": "johndoe",
+		"email": "john.doe@example.com", "password": "a very long passphrase with digits 0123456789 and more"}`, "", "")
+	require.Equal(t, http.StatusOK, rr.Code)
+	var created user.User
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&created))
+	assert.Equal(t, auth.RoleClient, created.Role)
+	assert.Empty(t, created.Password)
+
+	stored, salt, err := repo.GetUserByUsername(context.Background(), "johndoe")
+	require.NoError(t, err)
+	assert.NoError(t, user.ComparePasswords(stored.Password, "a very long passphrase with digits 0123456789 and more", salt))
+
+	// PUT и PATCH проверяют профиль по тем же правилам
+	rr = serve(t, router, "PUT", "/users/1", `{"email": "john@", "username": ""}`, "1", auth.RoleClient)
+	require.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&p))
+	assert.Equal(t, []apperr.FieldError{
+		{Field: "username", Code: "required", Message: "is required"},
+		{Field: "email", Code: "email", Message: "must be a valid email address"},
+	}, p.Errors)
+
+	rr = serve(t, router, "PUT", "/users/1", `{"role": "superuser"}`, "0", auth.RoleAdmin)
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+
+	rr = serve(t, router, "PATCH", "/users/1", `{"Username": "john doe"}`, "1", auth.RoleClient)
+	require.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&p))
+	require.Len(t, p.Errors, 1)
+	assert.Equal(t, "username", p.Errors[0].Field)
+}
+
+func TestComparePasswordsLegacyHash(t *testing.T) {
+	// Хэши, созданные до перехода на SHA-256, остаются действительными
+	legacy, err := bcrypt.GenerateFromPassword([]byte("sec123"+"salt"), bcrypt.MinCost)
+	require.NoError(t, err)
+	assert.NoError(t, user.ComparePasswords(string(legacy), "sec123", "salt"))
+	assert.Error(t, user.ComparePasswords(string(legacy), "sec124", "salt"))
+}
+
+func TestMemoryStorage(t *testing.T) {
+	ctx := context.Background()
+	repo := user.NewMemoryStorage()
+
+	u := &user.User{ID: "7", Username: "alice", Email: "alice@example.com", Role: "client", Password: "plain"}
+	require.NoError(t, repo.CreateUser(ctx, u, "hash", "salt"))
+	assert.ErrorIs(t, repo.CreateUser(ctx, &user.User{ID: "7", Username: "bob", Email: "bob@example.com"}, "h", "s"), user.ErrUserExists)
+	assert.Error(t, repo.CreateUser(ctx, &user.User{ID: "x", Username: "bob"}, "h", "s"))
+
+	// Изменение профиля не затрагивает пароль
+	require.NoError(t, repo.UpdateUser(ctx, &user.User{ID: "7", Username: "alice2", Email: "alice@example.com", Role: "trainer"}))
+	got, salt, err := repo.GetUserByUsername(ctx, "alice2")
+	require.NoError(t, err)
+	assert.Equal(t, "hash", got.Password)
+	assert.Equal(t, "salt", salt)
+	assert.Equal(t, "trainer", got.Role)
+
+	_, err = repo.GetUserByID(ctx, 8)
+	assert.ErrorIs(t, err, user.ErrUserNotFound)
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = repo.GetAllUsers(cancelled)
+	assert.ErrorIs(t, err, context.Canceled)
+}
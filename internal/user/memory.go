@@ -0,0 +1,160 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// MemoryStorage — реализация UserRepository в памяти процесса. Повторяет поведение
+// PostgreSQL: ID должен быть числом, а ID, имя пользователя и email уникальны.
+// Подходит для тестов и разработки без базы данных.
+type MemoryStorage struct {
+	mu    sync.RWMutex
+	users map[int]storedUser
+}
+
+type storedUser struct {
+	User
+	password string
+	salt     string
+}
+
+var _ UserRepository = (*MemoryStorage)(nil)
+
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{users: make(map[int]storedUser)}
+}
+
+// public возвращает копию пользователя без пароля и соли, как ее читает Storage
+func (u storedUser) public() User {
+	user := u.User
+	user.Password, user.Salt = "", ""
+	return user
+}
+
+// conflicts проверяет, занято ли имя пользователя или email кем-то, кроме userID
+func (s *MemoryStorage) conflicts(userID int, user *User) bool {
+	for id, u := range s.users {
+		if id != userID && (u.Username == user.Username || u.Email == user.Email) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *MemoryStorage) CreateUser(ctx context.Context, user *User, password, salt string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	userID, err := strconv.Atoi(user.ID)
+	if err != nil {
+		return fmt.Errorf("invalid user ID %q", user.ID)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[userID]; ok || s.conflicts(userID, user) {
+		return ErrUserExists
+	}
+	stored := storedUser{User: *user, password: password, salt: salt}
+	stored.ID = strconv.Itoa(userID)
+	stored.User.Password, stored.User.Salt = "", ""
+	s.users[userID] = stored
+	return nil
+}
+
+func (s *MemoryStorage) GetUserByID(ctx context.Context, userID int) (*User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	u, ok := s.users[userID]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	user := u.public()
+	return &user, nil
+}
+
+func (s *MemoryStorage) UpdateUser(ctx context.Context, user *User) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	userID, err := strconv.Atoi(user.ID)
+	if err != nil {
+		return fmt.Errorf("invalid user ID %q", user.ID)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[userID]
+	if !ok {
+		return nil
+	}
+	if s.conflicts(userID, user) {
+		return ErrUserExists
+	}
+	u.FirstName, u.LastName, u.Role, u.Email, u.Username = user.FirstName, user.LastName, user.Role, user.Email, user.Username
+	s.users[userID] = u
+	return nil
+}
+
+func (s *MemoryStorage) DeleteUser(ctx context.Context, userID int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.users, userID)
+	return nil
+}
+
+// GetAllUsers возвращает пользователей в порядке возрастания ID
+func (s *MemoryStorage) GetAllUsers(ctx context.Context) ([]User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ids := make([]int, 0, len(s.users))
+	for id := range s.users {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	var users []User
+	for _, id := range ids {
+		users = append(users, s.users[id].public())
+	}
+	return users, nil
+}
+
+func (s *MemoryStorage) GetUserByUsername(ctx context.Context, username string) (*User, string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, "", err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, u := range s.users {
+		if u.Username == username {
+			user := u.User
+			user.Password, user.Salt = u.password, u.salt
+			return &user, u.salt, nil
+		}
+	}
+	return nil, "", ErrUserNotFound
+}
@@ -10,3 +10,35 @@ type User struct {
 	Role      string `json:"role"`
 	Email     string `json:"email"`
 }
+
+// CreateUserRequest — тело запроса на регистрацию. Роль admin при регистрации недоступна,
+// без роли пользователь регистрируется как клиент
+type CreateUserRequest struct {
+	ID        string `json:"id" validate:"required,numeric,max=9"`
+	FirstName string `json:"firstname" validate:"required,max=64"`
+	LastName  string `json:"lastname" validate:"required,max=64"`
+	Username  string `json:"username" validate:"required,min=3,max=32,username"`
+	Role      string `json:"role" validate:"oneof=client trainer"`
+	Email     string `json:"email" validate:"required,email,max=254"`
+	Password  string `json:"password" validate:"required,password"`
+}
+
+// ProfileRequest — правила для профиля после изменения через PUT или PATCH.
+// Смену роли дополнительно проверяет auth.Policy
+type ProfileRequest struct {
+	FirstName string `json:"firstname" validate:"required,max=64"`
+	LastName  string `json:"lastname" validate:"required,max=64"`
+	Username  string `json:"username" validate:"required,min=3,max=32,username"`
+	Role      string `json:"role" validate:"required,oneof=client trainer admin"`
+	Email     string `json:"email" validate:"required,email,max=254"`
+}
+
+func profileOf(u *User) ProfileRequest {
+	return ProfileRequest{
+		FirstName: u.FirstName,
+		LastName:  u.LastName,
+		Username:  u.Username,
+		Role:      u.Role,
+		Email:     u.Email,
+	}
+}
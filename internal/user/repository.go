@@ -0,0 +1,30 @@
+package user
+
+import (
+	"TrainerConnect/internal/apperr"
+	"context"
+)
+
+var (
+	// ErrUserExists возвращается, когда пользователь с таким ID, именем или email уже есть
+	ErrUserExists   = apperr.Conflict("user_exists", "user already exists")
+	ErrUserNotFound = apperr.NotFound("user_not_found", "user not found")
+	// ErrUserInUse возвращается при удалении пользователя, у которого есть занятия, платежи или счета
+	ErrUserInUse = apperr.Conflict("user_in_use", "user has bookings, payments or other records and cannot be deleted")
+)
+
+// UserRepository — хранилище пользователей. Методы чтения возвращают ErrUserNotFound,
+// если пользователь не найден; изменение и удаление отсутствующего пользователя не является ошибкой.
+type UserRepository interface {
+	// CreateUser сохраняет пользователя с уже захэшированным паролем и его солью
+	CreateUser(ctx context.Context, user *User, password, salt string) error
+	// GetUserByID возвращает пользователя без пароля и соли
+	GetUserByID(ctx context.Context, userID int) (*User, error)
+	// UpdateUser изменяет данные пользователя, кроме пароля и соли
+	UpdateUser(ctx context.Context, user *User) error
+	// DeleteUser удаляет пользователя или возвращает ErrUserInUse, если на него ссылаются другие записи
+	DeleteUser(ctx context.Context, userID int) error
+	GetAllUsers(ctx context.Context) ([]User, error)
+	// GetUserByUsername возвращает пользователя вместе с хэшем пароля и соль
+	GetUserByUsername(ctx context.Context, username string) (*User, string, error)
+}
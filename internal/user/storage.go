@@ -1,53 +1,80 @@
 package user
 
 import (
+	"TrainerConnect/internal/apperr"
+	postgres "TrainerConnect/pkg/postgresql"
+	"context"
 	"database/sql"
 	"log"
 )
 
+// Storage — реализация UserRepository поверх PostgreSQL
 type Storage struct {
 	*sql.DB
 }
 
+var _ UserRepository = (*Storage)(nil)
+
 func NewStorage(db *sql.DB) *Storage {
 	return &Storage{DB: db}
 }
 
 // CreateUser создает нового пользователя в базе данных
-func (s *Storage) CreateUser(user *User, password, salt string) error {
-	_, err := s.DB.Exec("INSERT INTO users (user_id, first_name, last_name, username, password, role, email, salt) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)",
+func (s *Storage) CreateUser(ctx context.Context, user *User, password, salt string) (err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	_, err = s.DB.ExecContext(ctx, "INSERT INTO users (user_id, first_name, last_name, username, password, role, email, salt) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)",
 		user.ID, user.FirstName, user.LastName, user.Username, password, user.Role, user.Email, salt)
 
-	return err
+	return uniqueViolation(err)
 }
 
-func (s *Storage) GetUserByID(userID int) (*User, error) {
+func (s *Storage) GetUserByID(ctx context.Context, userID int) (_ *User, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
 	// Реализация получения пользователя из базы данных по ID
-	row := s.DB.QueryRow("SELECT user_id, first_name, last_name, role, email, username FROM users WHERE user_id = $1", userID)
+	row := s.DB.QueryRowContext(ctx, "SELECT user_id, first_name, last_name, role, email, username FROM users WHERE user_id = $1", userID)
 	user := &User{}
-	err := row.Scan(&user.ID, &user.FirstName, &user.LastName, &user.Role, &user.Email, &user.Username)
+	err = row.Scan(&user.ID, &user.FirstName, &user.LastName, &user.Role, &user.Email, &user.Username)
 	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrUserNotFound
+		}
 		return nil, err
 	}
 	return user, nil
 }
 
-func (s *Storage) UpdateUser(user *User) error {
+func (s *Storage) UpdateUser(ctx context.Context, user *User) (err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
 	// Реализация обновления данных пользователя в базе данных
-	_, err := s.DB.Exec("UPDATE users SET first_name=$1, last_name=$2, role=$3, email=$4, username=$5 WHERE user_id=$6",
+	_, err = s.DB.ExecContext(ctx, "UPDATE users SET first_name=$1, last_name=$2, role=$3, email=$4, username=$5 WHERE user_id=$6",
 		user.FirstName, user.LastName, user.Role, user.Email, user.Username, user.ID)
-	return err
+	return uniqueViolation(err)
 }
 
-func (s *Storage) DeleteUser(userID int) error {
+func (s *Storage) DeleteUser(ctx context.Context, userID int) (err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
 	// Реализация удаления пользователя из базы данных
-	_, err := s.DB.Exec("DELETE FROM users WHERE user_id = $1", userID)
+	_, err = s.DB.ExecContext(ctx, "DELETE FROM users WHERE user_id = $1", userID)
+	if apperr.IsForeignKeyViolation(err) {
+		return ErrUserInUse.Wrap(err)
+	}
 	return err
 }
 
-func (s *Storage) GetAllUsers() ([]User, error) {
+func (s *Storage) GetAllUsers(ctx context.Context) (_ []User, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
 	// Реализация получения списка всех пользователей из базы данных
-	rows, err := s.DB.Query("SELECT user_id, first_name, last_name, role, email, username FROM users")
+	rows, err := s.DB.QueryContext(ctx, "SELECT user_id, first_name, last_name, role, email, username FROM users")
 	if err != nil {
 		return nil, err
 	}
@@ -62,21 +89,24 @@ func (s *Storage) GetAllUsers() ([]User, error) {
 		}
 		users = append(users, user)
 	}
-	return users, nil
+	return users, rows.Err()
 }
 
 // GetUserByUsername возвращает пользователя и соль по имени пользователя из базы данных
-func (s *Storage) GetUserByUsername(username string) (*User, string, error) {
+func (s *Storage) GetUserByUsername(ctx context.Context, username string) (_ *User, _ string, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
 	query := "SELECT user_id, username, password, salt, role, first_name, last_name, email FROM users WHERE username = $1"
-	row := s.DB.QueryRow(query, username)
+	row := s.DB.QueryRowContext(ctx, query, username)
 
 	var u User
 
-	err := row.Scan(&u.ID, &u.Username, &u.Password, &u.Salt, &u.Role, &u.FirstName, &u.LastName, &u.Email)
+	err = row.Scan(&u.ID, &u.Username, &u.Password, &u.Salt, &u.Role, &u.FirstName, &u.LastName, &u.Email)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, "", nil // Пользователь не найден, возвращаем nil и пустую соль
+			return nil, "", ErrUserNotFound
 		}
 		log.Printf("Error getting user by username: %v", err)
 		return nil, "", err
@@ -84,3 +114,11 @@ func (s *Storage) GetUserByUsername(username string) (*User, string, error) {
 
 	return &u, u.Salt, nil
 }
+
+// uniqueViolation заменяет нарушение уникальности ID, имени или email на ErrUserExists
+func uniqueViolation(err error) error {
+	if apperr.IsUniqueViolation(err) {
+		return ErrUserExists.Wrap(err)
+	}
+	return apperr.FromDB(err)
+}
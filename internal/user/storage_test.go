@@ -1,12 +1,16 @@
 package user_test
 
 import (
+	"TrainerConnect/internal/apperr"
+	"TrainerConnect/internal/auth"
 	"TrainerConnect/internal/user"
 	postgres "TrainerConnect/pkg/postgresql"
 	"TrainerConnect/pkg/postgresql/config"
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
 	"log"
 	"net/http"
@@ -14,6 +18,7 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 
@@ -24,26 +29,51 @@ var db *sql.DB
 var mock sqlmock.Sqlmock
 
 func TestMain(m *testing.M) {
-	// Инициализируем тестовую БД
+	// Инициализируем тестовую БД. Если она недоступна, тесты с БД пропускаются,
+	// а тесты поверх MemoryStorage выполняются как обычно.
 	cfgTest, err := config.ReadConfig("../../pkg/postgresql/config/database_test.json")
-	if err != nil {
-		log.Fatal(err)
+	if err == nil {
+		db, err = postgres.NewDB(cfgTest)
 	}
-
-	db, err = postgres.NewDB(cfgTest)
 	if err != nil {
-		log.Fatal(err)
+		log.Printf("Test database is unavailable, skipping database tests: %v", err)
+		db = nil
 	}
-	// Закрываем БД после выполнения тестов с БД
-	defer db.Close()
 
-	// Запускаем все тесты пока работает моковая БД
+	// Запускаем все тесты
 	exitCode := m.Run()
 
-	// Exit with the test result
+	// Закрываем БД после выполнения тестов с БД
+	if db != nil {
+		db.Close()
+	}
 	os.Exit(exitCode)
 }
 
+var tokens = auth.NewTokenManager("test-secret", time.Minute, time.Hour)
+
+// newRouter создает роутер с обработчиком пользователей поверх тестовой БД
+// и пропускает тест, если БД недоступна
+func newRouter(t *testing.T) *chi.Mux {
+	if db == nil {
+		t.Skip("test database is unavailable")
+	}
+	router := chi.NewRouter()
+	router.Use(auth.Middleware(tokens))
+	handler := user.NewHandler(user.NewStorage(db), auth.NewService(tokens, auth.NewStorage(db)), auth.NewPolicy(nil))
+	handler.Register(router)
+	return router
+}
+
+// authorize подписывает запрос токеном администратора
+func authorize(t *testing.T, req *http.Request) {
+	token, err := tokens.IssueAccessToken("0", auth.RoleAdmin)
+	if err != nil {
+		t.Fatalf("Error issuing token: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+}
+
 //func TestAllHandlers(t *testing.T) {
 //	TestCreateNewUserHandler(t)
 //	TestGetUserHandler(t)
@@ -53,9 +83,7 @@ func TestMain(m *testing.M) {
 
 func TestCreateNewUserHandler(t *testing.T) {
 	// Создаем роутер
-	router := chi.NewRouter()
-	handler := user.NewHandler(user.NewStorage(db))
-	handler.Register(router)
+	router := newRouter(t)
 
 	// Данные запроса в БД
 	requestData1 := `{
@@ -65,7 +93,7 @@ func TestCreateNewUserHandler(t *testing.T) {
 		"username": "johndoe",
 		"role": "client",
 		"email": "john.doe@example.com", 
-		"password": "sec123"
+		"password": "secret123"
 	}`
 
 	requestData2 := `{
@@ -75,7 +103,7 @@ func TestCreateNewUserHandler(t *testing.T) {
 		"username": "joahndoe",
 		"role": "client",
 		"email": "joahn.doe@example.com", 
-		"password": "set123"
+		"password": "secret456"
 	}`
 
 	// Формируем POST запрос в тестовую БД для первого пользователя
@@ -117,9 +145,7 @@ func TestCreateNewUserHandler(t *testing.T) {
 
 func TestGetUserHandler(t *testing.T) {
 	// Создаем роутер
-	router := chi.NewRouter()
-	handler := user.NewHandler(user.NewStorage(db))
-	handler.Register(router)
+	router := newRouter(t)
 
 	// Формируем GET запрос в тестовую БД
 	req, err := http.NewRequest("GET", "/users/1", nil)
@@ -127,6 +153,8 @@ func TestGetUserHandler(t *testing.T) {
 		t.Fatalf("Error creating request: %v", err)
 	}
 
+	authorize(t, req)
+
 	// Записываем ответ
 	rr := httptest.NewRecorder()
 
@@ -150,9 +178,7 @@ func TestGetUserHandler(t *testing.T) {
 
 func TestGetListHandler(t *testing.T) {
 	// Создаем роутер
-	router := chi.NewRouter()
-	handler := user.NewHandler(user.NewStorage(db))
-	handler.Register(router)
+	router := newRouter(t)
 
 	// Формируем GET запрос в тестовую БД
 	req, err := http.NewRequest("GET", "/users?username=johndoe", nil)
@@ -160,6 +186,8 @@ func TestGetListHandler(t *testing.T) {
 		t.Fatalf("Error creating request: %v", err)
 	}
 
+	authorize(t, req)
+
 	// Записываем ответ
 	rr := httptest.NewRecorder()
 	router.ServeHTTP(rr, req)
@@ -197,9 +225,7 @@ func TestGetListHandler(t *testing.T) {
 
 func TestUpdateUserHandler(t *testing.T) {
 	// Создаем роутер
-	router := chi.NewRouter()
-	handler := user.NewHandler(user.NewStorage(db))
-	handler.Register(router)
+	router := newRouter(t)
 
 	// Данные запроса в БД
 	//user, _, err := h.Storage.GetUserByUsername(username)
@@ -216,6 +242,8 @@ func TestUpdateUserHandler(t *testing.T) {
 		t.Fatalf("Error creating request: %v", err)
 	}
 
+	authorize(t, req)
+
 	// Set the content type header
 	req.Header.Set("Content-Type", "application/json")
 
@@ -231,9 +259,7 @@ func TestUpdateUserHandler(t *testing.T) {
 
 func TestGetAllUsersHandler(t *testing.T) {
 	// Создаем роутер
-	router := chi.NewRouter()
-	handler := user.NewHandler(user.NewStorage(db))
-	handler.Register(router)
+	router := newRouter(t)
 
 	// Формируем GET запрос в тестовую БД
 	req, err := http.NewRequest("GET", "/users/", nil)
@@ -241,6 +267,8 @@ func TestGetAllUsersHandler(t *testing.T) {
 		t.Fatalf("Error creating request: %v", err)
 	}
 
+	authorize(t, req)
+
 	// Записываем ответ
 	rr := httptest.NewRecorder()
 
@@ -266,9 +294,7 @@ func TestGetAllUsersHandler(t *testing.T) {
 
 func TestDeleteUserHandler(t *testing.T) {
 	// Create a chi router
-	router := chi.NewRouter()
-	handler := user.NewHandler(user.NewStorage(db))
-	handler.Register(router)
+	router := newRouter(t)
 
 	// Create a request for the DeleteUserHandler endpoint
 	req, err := http.NewRequest("DELETE", "/users/1", nil)
@@ -276,6 +302,8 @@ func TestDeleteUserHandler(t *testing.T) {
 		t.Fatalf("Error creating request: %v", err)
 	}
 
+	authorize(t, req)
+
 	// Create a response recorder to capture the response
 	rr := httptest.NewRecorder()
 
@@ -330,3 +358,25 @@ func TestDeleteUserHandler(t *testing.T) {
 //	}
 //}
 //
+
+func TestDeleteUserInUse(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer mockDB.Close()
+
+	// На пользователя ссылаются занятия: удаление нарушает внешний ключ
+	mock.ExpectExec("DELETE FROM users WHERE user_id = \\$1").
+		WithArgs(5).
+		WillReturnError(&pq.Error{Code: "23503"})
+
+	err = user.NewStorage(mockDB).DeleteUser(context.Background(), 5)
+	assert.ErrorIs(t, err, user.ErrUserInUse)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	rr := httptest.NewRecorder()
+	apperr.Write(rr, err)
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"code":"user_in_use"`)
+}
@@ -0,0 +1,187 @@
+// Package validate проверяет DTO запросов по декларативным правилам в тегах структуры:
+//
+//	type Request struct {
+//		Username string `json:"username" validate:"required,min=3,max=32,username"`
+//		Role     string `json:"role" validate:"oneof=client trainer"`
+//	}
+//
+// Правила перечисляются через запятую и проверяются по порядку; для поля сообщается
+// первое нарушенное правило. Незаполненное поле проверяется только правилом required.
+package validate
+
+import (
+	"TrainerConnect/internal/apperr"
+	"fmt"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// PasswordMinLength и PasswordMaxLength — политика длины пароля в символах
+const (
+	PasswordMinLength = 8
+	PasswordMaxLength = 128
+)
+
+// rule проверяет значение поля с аргументом правила и возвращает ошибку или nil
+type rule func(value, arg string) *apperr.FieldError
+
+var rules = map[string]rule{
+	"required": required,
+	"min":      minLength,
+	"max":      maxLength,
+	"email":    email,
+	"oneof":    oneOf,
+	"numeric":  numeric,
+	"username": username,
+	"password": password,
+}
+
+// Struct проверяет строковые поля структуры v (или указателя на нее) по тегам validate
+// и возвращает все нарушения сразу в виде apperr.Validation. Имя поля в ошибке
+// берется из тега json. Неизвестное правило в теге — ошибка программиста, поэтому паника.
+func Struct(v interface{}) error {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	rt := rv.Type()
+
+	var errs []apperr.FieldError
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		tag := field.Tag.Get("validate")
+		if tag == "" {
+			continue
+		}
+		if field.Type.Kind() != reflect.String {
+			panic(fmt.Sprintf("validate: field %s is not a string", field.Name))
+		}
+		if fe := check(rv.Field(i).String(), tag); fe != nil {
+			fe.Field = fieldName(field)
+			errs = append(errs, *fe)
+		}
+	}
+
+	if len(errs) > 0 {
+		return apperr.Validation(errs...)
+	}
+	return nil
+}
+
+// check применяет правила тега к значению и возвращает первое нарушение
+func check(value, tag string) *apperr.FieldError {
+	for _, spec := range strings.Split(tag, ",") {
+		name, arg, _ := strings.Cut(spec, "=")
+		fn, ok := rules[name]
+		if !ok {
+			panic(fmt.Sprintf("validate: unknown rule %q", name))
+		}
+		if value == "" && name != "required" {
+			continue
+		}
+		if fe := fn(value, arg); fe != nil {
+			return fe
+		}
+	}
+	return nil
+}
+
+func fieldName(f reflect.StructField) string {
+	if name, _, _ := strings.Cut(f.Tag.Get("json"), ","); name != "" && name != "-" {
+		return name
+	}
+	return f.Name
+}
+
+func fieldError(code, format string, args ...interface{}) *apperr.FieldError {
+	return &apperr.FieldError{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+func required(value, _ string) *apperr.FieldError {
+	if strings.TrimSpace(value) == "" {
+		return fieldError("required", "is required")
+	}
+	return nil
+}
+
+func intArg(arg string) int {
+	n, err := strconv.Atoi(arg)
+	if err != nil {
+		panic(fmt.Sprintf("validate: invalid rule argument %q", arg))
+	}
+	return n
+}
+
+func minLength(value, arg string) *apperr.FieldError {
+	if n := intArg(arg); utf8.RuneCountInString(value) < n {
+		return fieldError("too_short", "must be at least %d characters long", n)
+	}
+	return nil
+}
+
+func maxLength(value, arg string) *apperr.FieldError {
+	if n := intArg(arg); utf8.RuneCountInString(value) > n {
+		return fieldError("too_long", "must be at most %d characters long", n)
+	}
+	return nil
+}
+
+// email принимает только адрес без имени и угловых скобок, например user@example.com
+func email(value, _ string) *apperr.FieldError {
+	addr, err := mail.ParseAddress(value)
+	if err != nil || addr.Address != value || !strings.Contains(value[strings.LastIndex(value, "@")+1:], ".") {
+		return fieldError("email", "must be a valid email address")
+	}
+	return nil
+}
+
+func oneOf(value, arg string) *apperr.FieldError {
+	allowed := strings.Fields(arg)
+	for _, a := range allowed {
+		if value == a {
+			return nil
+		}
+	}
+	return fieldError("oneof", "must be one of: %s", strings.Join(allowed, ", "))
+}
+
+func numeric(value, _ string) *apperr.FieldError {
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return fieldError("numeric", "must be a positive integer")
+		}
+	}
+	return nil
+}
+
+func username(value, _ string) *apperr.FieldError {
+	for _, r := range value {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '.' && r != '_' && r != '-' {
+			return fieldError("username", "may contain only letters, digits, '.', '_' and '-'")
+		}
+	}
+	return nil
+}
+
+// password — политика надежности: от PasswordMinLength до PasswordMaxLength символов,
+// хотя бы одна буква и одна цифра
+func password(value, _ string) *apperr.FieldError {
+	n := utf8.RuneCountInString(value)
+	if n < PasswordMinLength {
+		return fieldError("too_short", "must be at least %d characters long", PasswordMinLength)
+	}
+	if n > PasswordMaxLength {
+		return fieldError("too_long", "must be at most %d characters long", PasswordMaxLength)
+	}
+
+	var letter, digit bool
+	for _, r := range value {
+		letter = letter || unicode.IsLetter(r)
+		digit = digit || unicode.IsDigit(r)
+	}
+	if !letter || !digit {
+		return fieldError("weak_password", "must contain at least one letter and one digit")
+	}
+	return nil
+}
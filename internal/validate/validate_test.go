@@ -0,0 +1,58 @@
+package validate_test
+
+import (
+	"TrainerConnect/internal/apperr"
+	"TrainerConnect/internal/validate"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+type request struct {
+	Name     string `json:"name" validate:"required,min=2,max=5"`
+	Email    string `json:"email,omitempty" validate:"email"`
+	Kind     string `validate:"oneof=a b"`
+	Password string `json:"password" validate:"password"`
+	Note     string `json:"note"`
+}
+
+func fields(t *testing.T, err error) []apperr.FieldError {
+	var appErr *apperr.Error
+	require.True(t, errors.As(err, &appErr))
+	assert.ErrorIs(t, err, apperr.Validation())
+	return appErr.Fields
+}
+
+func TestStruct(t *testing.T) {
+	assert.NoError(t, validate.Struct(request{Name: "Ann"}))
+	assert.NoError(t, validate.Struct(&request{Name: "Ёжик", Email: "ann@example.com", Kind: "b", Password: "пароль12"}))
+
+	err := validate.Struct(request{Name: " ", Email: "Ann <ann@example.com>", Kind: "c", Password: "abcdefgh"})
+	assert.Equal(t, []apperr.FieldError{
+		{Field: "name", Code: "required", Message: "is required"},
+		{Field: "email", Code: "email", Message: "must be a valid email address"},
+		{Field: "Kind", Code: "oneof", Message: "must be one of: a, b"},
+		{Field: "password", Code: "weak_password", Message: "must contain at least one letter and one digit"},
+	}, fields(t, err))
+
+	err = validate.Struct(request{Name: "A", Email: "ann@localhost", Password: "a1"})
+	assert.Equal(t, []string{"too_short", "email", "too_short"}, codes(fields(t, err)))
+	err = validate.Struct(request{Name: "Annabel"})
+	assert.Equal(t, []string{"too_long"}, codes(fields(t, err)))
+}
+
+func TestStructUnknownRule(t *testing.T) {
+	type bad struct {
+		Name string `validate:"uppercase"`
+	}
+	assert.Panics(t, func() { validate.Struct(bad{Name: "x"}) })
+}
+
+func codes(fes []apperr.FieldError) []string {
+	out := make([]string, len(fes))
+	for i, fe := range fes {
+		out[i] = fe.Code
+	}
+	return out
+}
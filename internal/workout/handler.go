@@ -0,0 +1,344 @@
+package workout
+
+import (
+	"TrainerConnect/internal/apperr"
+	"TrainerConnect/internal/auth"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	workoutURL  = "/workouts/"
+	progressURL = "/clients/{id}/progress/"
+
+	// defaultRange — период выборки по умолчанию, если from и to не указаны
+	defaultRange = 12 * 7 * 24 * time.Hour
+	maxRange     = 366 * 24 * time.Hour
+)
+
+type Handler struct {
+	Storage *Storage
+	Policy  *auth.Policy
+}
+
+// NewHandler создает обработчик журнала тренировок. Журнал и статистику клиента читают
+// сам клиент, его тренеры из roster и администраторы.
+func NewHandler(storage *Storage, policy *auth.Policy) *Handler {
+	return &Handler{Storage: storage, Policy: policy}
+}
+
+func (h *Handler) Register(router *chi.Mux) {
+	router.Group(func(r chi.Router) {
+		r.Use(auth.RequireAuth)
+		r.Get("/workouts", h.ListSessions)
+		r.Post("/workouts", h.CreateSession)
+		r.Get(workoutURL+"{id}", h.GetSession)
+		r.Post(workoutURL+"{id}/sets", h.AddSet)
+		r.Post(workoutURL+"{id}/complete", h.CompleteSession)
+
+		r.Get(progressURL+"volume", h.GetVolume)
+		r.Get(progressURL+"records", h.GetRecords)
+		r.Get(progressURL+"adherence", h.GetAdherence)
+	})
+}
+
+// canRead проверяет право текущего пользователя на чтение журнала клиента
+func (h *Handler) canRead(w http.ResponseWriter, r *http.Request, clientID string) bool {
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	allowed, err := h.Policy.CanReadUser(r.Context(), principal, clientID)
+	if err != nil {
+		log.Printf("Error checking access to user %s: %v", clientID, err)
+		apperr.Write(w, err)
+		return false
+	}
+	if !allowed {
+		apperr.Respond(w, http.StatusForbidden, "Forbidden")
+		return false
+	}
+	return true
+}
+
+// parseRange читает интервал from/to из запроса. По умолчанию — последние 12 недель.
+func parseRange(r *http.Request) (time.Time, time.Time, error) {
+	q := r.URL.Query()
+	to := time.Now().UTC()
+	if v := q.Get("to"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return time.Time{}, time.Time{}, apperr.InvalidField("to", "invalid_time", "must be an RFC 3339 time")
+		}
+		to = t
+	}
+	from := to.Add(-defaultRange)
+	if v := q.Get("from"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return time.Time{}, time.Time{}, apperr.InvalidField("from", "invalid_time", "must be an RFC 3339 time")
+		}
+		from = t
+	}
+	if !to.After(from) || to.Sub(from) > maxRange {
+		return time.Time{}, time.Time{}, apperr.InvalidField("to", "invalid_range", "range must be positive and not longer than a year")
+	}
+	return from, to, nil
+}
+
+// writeStorageError отправляет ответ на ошибку работы с журналом
+func writeStorageError(w http.ResponseWriter, err error) {
+	if !apperr.Expected(err) {
+		log.Printf("Error saving workout: %v", err)
+	}
+	apperr.Write(w, err)
+}
+
+// CreateSession записывает тренировку текущего клиента, при необходимости сразу с подходами
+func (h *Handler) CreateSession(w http.ResponseWriter, r *http.Request) {
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	if principal.Role != auth.RoleClient {
+		apperr.Respond(w, http.StatusForbidden, "Only clients can log workouts")
+		return
+	}
+
+	var s Session
+	if err := json.NewDecoder(r.Body).Decode(&s); err != nil {
+		apperr.Respond(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if s.StartedAt.IsZero() {
+		s.StartedAt = time.Now().UTC()
+	}
+	if err := s.Validate(); err != nil {
+		apperr.Write(w, err)
+		return
+	}
+	s.ID = ""
+	s.ClientID = principal.UserID
+	if s.Sets == nil {
+		s.Sets = []SetLog{}
+	}
+
+	if err := h.Storage.Create(r.Context(), &s); err != nil {
+		writeStorageError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(s)
+}
+
+// load загружает тренировку по ID из URL
+func (h *Handler) load(w http.ResponseWriter, r *http.Request) (*Session, bool) {
+	id := chi.URLParam(r, "id")
+	if _, err := strconv.Atoi(id); err != nil {
+		apperr.Respond(w, http.StatusBadRequest, "Invalid workout ID")
+		return nil, false
+	}
+
+	s, err := h.Storage.Get(r.Context(), id)
+	if err != nil {
+		if !apperr.Expected(err) {
+			log.Printf("Error getting workout %s: %v", id, err)
+		}
+		apperr.Write(w, err)
+		return nil, false
+	}
+	return s, true
+}
+
+// loadOwn загружает тренировку для изменения: менять журнал может только сам клиент
+func (h *Handler) loadOwn(w http.ResponseWriter, r *http.Request) (*Session, bool) {
+	s, ok := h.load(w, r)
+	if !ok {
+		return nil, false
+	}
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	if principal.UserID != s.ClientID {
+		apperr.Respond(w, http.StatusForbidden, "Forbidden")
+		return nil, false
+	}
+	return s, true
+}
+
+// GetSession возвращает тренировку с подходами
+func (h *Handler) GetSession(w http.ResponseWriter, r *http.Request) {
+	s, ok := h.load(w, r)
+	if !ok || !h.canRead(w, r, s.ClientID) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s)
+}
+
+// ListSessions возвращает тренировки клиента за период. По умолчанию — текущего пользователя,
+// параметр client_id позволяет тренеру посмотреть журнал своего клиента.
+func (h *Handler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	clientID := r.URL.Query().Get("client_id")
+	if clientID == "" {
+		clientID = principal.UserID
+	} else if _, err := strconv.Atoi(clientID); err != nil {
+		apperr.Respond(w, http.StatusBadRequest, "Invalid client ID")
+		return
+	}
+	if !h.canRead(w, r, clientID) {
+		return
+	}
+
+	from, to, err := parseRange(r)
+	if err != nil {
+		apperr.Write(w, err)
+		return
+	}
+
+	sessions, err := h.Storage.List(r.Context(), clientID, from, to)
+	if err != nil {
+		log.Printf("Error listing workouts of user %s: %v", clientID, err)
+		apperr.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sessions)
+}
+
+// AddSet добавляет подход к незавершенной тренировке
+func (h *Handler) AddSet(w http.ResponseWriter, r *http.Request) {
+	s, ok := h.loadOwn(w, r)
+	if !ok {
+		return
+	}
+
+	var set SetLog
+	if err := json.NewDecoder(r.Body).Decode(&set); err != nil {
+		apperr.Respond(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := set.Validate(); err != nil {
+		apperr.Write(w, err)
+		return
+	}
+	set.ID = ""
+	set.SessionID = s.ID
+
+	if err := h.Storage.AddSet(r.Context(), &set); err != nil {
+		writeStorageError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(set)
+}
+
+// CompleteSession отмечает тренировку завершенной. Только завершенные тренировки
+// учитываются в соблюдении программы.
+func (h *Handler) CompleteSession(w http.ResponseWriter, r *http.Request) {
+	s, ok := h.loadOwn(w, r)
+	if !ok {
+		return
+	}
+
+	now := time.Now().UTC()
+	if err := h.Storage.Complete(r.Context(), s.ID, now); err != nil {
+		writeStorageError(w, err)
+		return
+	}
+	s.CompletedAt = &now
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s)
+}
+
+// progressClient читает ID клиента из URL и проверяет доступ к его статистике
+func (h *Handler) progressClient(w http.ResponseWriter, r *http.Request) (string, bool) {
+	clientID := chi.URLParam(r, "id")
+	if _, err := strconv.Atoi(clientID); err != nil {
+		apperr.Respond(w, http.StatusBadRequest, "Invalid client ID")
+		return "", false
+	}
+	return clientID, h.canRead(w, r, clientID)
+}
+
+// GetVolume возвращает недельный тоннаж клиента по группам мышц
+func (h *Handler) GetVolume(w http.ResponseWriter, r *http.Request) {
+	clientID, ok := h.progressClient(w, r)
+	if !ok {
+		return
+	}
+	from, to, err := parseRange(r)
+	if err != nil {
+		apperr.Write(w, err)
+		return
+	}
+
+	volumes, err := h.Storage.WeeklyVolume(r.Context(), clientID, from, to)
+	if err != nil {
+		log.Printf("Error computing volume of user %s: %v", clientID, err)
+		apperr.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(volumes)
+}
+
+// GetRecords возвращает личные рекорды клиента по упражнениям
+func (h *Handler) GetRecords(w http.ResponseWriter, r *http.Request) {
+	clientID, ok := h.progressClient(w, r)
+	if !ok {
+		return
+	}
+
+	records, err := h.Storage.PersonalRecords(r.Context(), clientID)
+	if err != nil {
+		log.Printf("Error computing records of user %s: %v", clientID, err)
+		apperr.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(records)
+}
+
+// GetAdherence возвращает соблюдение назначенной программы (program_id) клиентом.
+// Параметр version выбирает версию программы, по умолчанию — текущая.
+func (h *Handler) GetAdherence(w http.ResponseWriter, r *http.Request) {
+	clientID, ok := h.progressClient(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	programID := q.Get("program_id")
+	if _, err := strconv.Atoi(programID); err != nil {
+		apperr.Respond(w, http.StatusBadRequest, "Invalid program ID")
+		return
+	}
+	version := 0
+	if v := q.Get("version"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			apperr.Respond(w, http.StatusBadRequest, "Invalid version parameter")
+			return
+		}
+		version = n
+	}
+
+	adherence, err := h.Storage.Adherence(r.Context(), clientID, programID, version)
+	if err != nil {
+		if !apperr.Expected(err) {
+			log.Printf("Error computing adherence of user %s: %v", clientID, err)
+		}
+		apperr.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(adherence)
+}
@@ -0,0 +1,122 @@
+package workout
+
+import (
+	"TrainerConnect/internal/apperr"
+	"math"
+	"time"
+)
+
+// SetLog — выполненный подход
+type SetLog struct {
+	ID         string    `json:"id"`
+	SessionID  string    `json:"session_id"`
+	ExerciseID string    `json:"exercise_id"`
+	SetNumber  int       `json:"set_number"`
+	Reps       int       `json:"reps"`
+	WeightKg   float64   `json:"weight_kg"`
+	RPE        *float64  `json:"rpe,omitempty"`
+	Notes      string    `json:"notes"`
+	LoggedAt   time.Time `json:"logged_at"`
+}
+
+// Validate проверяет значения подхода
+func (s *SetLog) Validate() error {
+	if s.ExerciseID == "" {
+		return apperr.InvalidField("exercise_id", "required", "exercise_id is required")
+	}
+	if s.SetNumber < 1 {
+		return apperr.InvalidField("set_number", "not_positive", "set_number must be positive")
+	}
+	if s.Reps < 0 || s.WeightKg < 0 {
+		return apperr.InvalidField("reps", "negative", "reps and weight_kg must not be negative")
+	}
+	if s.RPE != nil && (*s.RPE < 1 || *s.RPE > 10) {
+		return apperr.InvalidField("rpe", "out_of_range", "rpe must be between 1 and 10")
+	}
+	return nil
+}
+
+// Session — тренировка клиента. Может быть привязана к дню назначенной программы
+// конкретной версии, тогда учитывается в соблюдении программы.
+type Session struct {
+	ID             string     `json:"id"`
+	ClientID       string     `json:"client_id"`
+	ProgramID      *string    `json:"program_id,omitempty"`
+	ProgramVersion *int       `json:"program_version,omitempty"`
+	WeekNumber     *int       `json:"week_number,omitempty"`
+	DayNumber      *int       `json:"day_number,omitempty"`
+	StartedAt      time.Time  `json:"started_at"`
+	CompletedAt    *time.Time `json:"completed_at,omitempty"`
+	Notes          string     `json:"notes"`
+	Sets           []SetLog   `json:"sets"`
+}
+
+// Validate проверяет, что ссылка на программу указана полностью или не указана вовсе
+func (s *Session) Validate() error {
+	linked := 0
+	for _, set := range []bool{s.ProgramID != nil, s.ProgramVersion != nil, s.WeekNumber != nil, s.DayNumber != nil} {
+		if set {
+			linked++
+		}
+	}
+	if linked != 0 && linked != 4 {
+		return apperr.InvalidField("program_id", "required", "program_id, program_version, week_number and day_number must be given together")
+	}
+	if s.CompletedAt != nil && s.CompletedAt.Before(s.StartedAt) {
+		return apperr.InvalidField("completed_at", "before_start", "completed_at must not be before started_at")
+	}
+	for i := range s.Sets {
+		if err := s.Sets[i].Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WeeklyVolume — тоннаж (повторения × вес) по группе мышц за неделю
+type WeeklyVolume struct {
+	Week        time.Time `json:"week"`
+	MuscleGroup string    `json:"muscle_group"`
+	VolumeKg    float64   `json:"volume_kg"`
+	Sets        int       `json:"sets"`
+}
+
+// PersonalRecord — личный рекорд клиента в упражнении
+type PersonalRecord struct {
+	ExerciseID         string    `json:"exercise_id"`
+	ExerciseName       string    `json:"exercise_name"`
+	MaxWeightKg        float64   `json:"max_weight_kg"`
+	RepsAtMaxWeight    int       `json:"reps_at_max_weight"`
+	AchievedAt         time.Time `json:"achieved_at"`
+	EstimatedOneRepMax float64   `json:"estimated_one_rep_max"`
+}
+
+// Adherence — соблюдение назначенной программы: сколько предписанных дней версии выполнено
+type Adherence struct {
+	ProgramID      string  `json:"program_id"`
+	ProgramVersion int     `json:"program_version"`
+	PrescribedDays int     `json:"prescribed_days"`
+	CompletedDays  int     `json:"completed_days"`
+	Percent        float64 `json:"percent"`
+}
+
+// computePercent вычисляет процент выполнения, округленный до десятых
+func (a *Adherence) computePercent() {
+	if a.PrescribedDays == 0 {
+		a.Percent = 0
+		return
+	}
+	a.Percent = math.Round(float64(a.CompletedDays)*1000/float64(a.PrescribedDays)) / 10
+}
+
+// EstimatedOneRepMax оценивает максимум на одно повторение по формуле Эпли.
+// Та же формула используется в SQL-запросе личных рекордов.
+func EstimatedOneRepMax(weightKg float64, reps int) float64 {
+	if reps <= 0 {
+		return 0
+	}
+	if reps == 1 {
+		return weightKg
+	}
+	return weightKg * (1 + float64(reps)/30)
+}
@@ -0,0 +1,43 @@
+package workout_test
+
+import (
+	"TrainerConnect/internal/workout"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestSessionValidate(t *testing.T) {
+	programID, version, week, day := "1", 2, 1, 3
+	start := time.Date(2024, 3, 4, 18, 0, 0, 0, time.UTC)
+
+	s := &workout.Session{StartedAt: start}
+	assert.NoError(t, s.Validate(), "тренировка вне программы")
+
+	s = &workout.Session{StartedAt: start, ProgramID: &programID, ProgramVersion: &version, WeekNumber: &week, DayNumber: &day}
+	assert.NoError(t, s.Validate())
+
+	s = &workout.Session{StartedAt: start, ProgramID: &programID, DayNumber: &day}
+	assert.Error(t, s.Validate(), "неполная ссылка на программу")
+
+	before := start.Add(-time.Minute)
+	s = &workout.Session{StartedAt: start, CompletedAt: &before}
+	assert.Error(t, s.Validate())
+
+	rpe := 11.0
+	s = &workout.Session{StartedAt: start, Sets: []workout.SetLog{{ExerciseID: "1", SetNumber: 1, Reps: 5, RPE: &rpe}}}
+	assert.Error(t, s.Validate(), "RPE вне шкалы")
+}
+
+func TestSetLogValidate(t *testing.T) {
+	assert.NoError(t, (&workout.SetLog{ExerciseID: "1", SetNumber: 1, Reps: 8, WeightKg: 60}).Validate())
+	assert.Error(t, (&workout.SetLog{SetNumber: 1, Reps: 8}).Validate())
+	assert.Error(t, (&workout.SetLog{ExerciseID: "1", SetNumber: 0, Reps: 8}).Validate())
+	assert.Error(t, (&workout.SetLog{ExerciseID: "1", SetNumber: 1, Reps: 8, WeightKg: -5}).Validate())
+}
+
+func TestEstimatedOneRepMax(t *testing.T) {
+	assert.Equal(t, 100.0, workout.EstimatedOneRepMax(100, 1))
+	assert.InDelta(t, 116.67, workout.EstimatedOneRepMax(100, 5), 0.01)
+	assert.Equal(t, 0.0, workout.EstimatedOneRepMax(100, 0))
+}
@@ -0,0 +1,301 @@
+package workout
+
+import (
+	"TrainerConnect/internal/apperr"
+	postgres "TrainerConnect/pkg/postgresql"
+	"context"
+	"database/sql"
+	"time"
+)
+
+var (
+	ErrNotFound         = apperr.NotFound("workout_not_found", "workout session not found")
+	ErrProgramMismatch  = apperr.Invalid("program_mismatch", "program day is not assigned to the client")
+	ErrExerciseNotFound = apperr.Invalid("exercise_not_found", "exercise not found")
+	// ErrProgramNotAssigned возвращается при расчете выполнения программы, не назначенной клиенту
+	ErrProgramNotAssigned = apperr.NotFound("program_not_assigned", "program is not assigned to the client")
+	ErrAlreadyCompleted   = apperr.Conflict("workout_completed", "workout session is already completed")
+)
+
+// Оценка максимума на одно повторение по формуле Эпли, см. EstimatedOneRepMax
+const oneRepMaxExpr = "CASE WHEN s.reps = 1 THEN s.weight_kg ELSE s.weight_kg * (1 + s.reps / 30.0) END"
+
+type Storage struct {
+	*sql.DB
+}
+
+func NewStorage(db *sql.DB) *Storage {
+	return &Storage{DB: db}
+}
+
+// Create сохраняет тренировку вместе с подходами. Если тренировка привязана к программе,
+// программа должна быть назначена клиенту, а указанный день — существовать в этой версии.
+func (s *Storage) Create(ctx context.Context, session *Session) (err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if session.ProgramID != nil {
+		var exists bool
+		err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM programs p
+			JOIN program_days d ON d.program_id = p.id
+			WHERE p.id = $1 AND p.client_id = $2 AND d.version = $3 AND d.week_number = $4 AND d.day_number = $5)`,
+			*session.ProgramID, session.ClientID, *session.ProgramVersion, *session.WeekNumber, *session.DayNumber).Scan(&exists)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return ErrProgramMismatch
+		}
+	}
+
+	err = tx.QueryRowContext(ctx, `INSERT INTO workout_sessions (client_id, program_id, program_version, week_number, day_number,
+			started_at, completed_at, notes)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`,
+		session.ClientID, session.ProgramID, session.ProgramVersion, session.WeekNumber, session.DayNumber,
+		session.StartedAt, session.CompletedAt, session.Notes).Scan(&session.ID)
+	if err != nil {
+		return err
+	}
+
+	for i := range session.Sets {
+		session.Sets[i].SessionID = session.ID
+		if err := insertSet(ctx, tx, &session.Sets[i]); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+type execer interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+func insertSet(ctx context.Context, q execer, set *SetLog) error {
+	if set.LoggedAt.IsZero() {
+		set.LoggedAt = time.Now().UTC()
+	}
+	err := q.QueryRowContext(ctx, `INSERT INTO workout_sets (session_id, exercise_id, set_number, reps, weight_kg, rpe, notes, logged_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`,
+		set.SessionID, set.ExerciseID, set.SetNumber, set.Reps, set.WeightKg, set.RPE, set.Notes, set.LoggedAt).Scan(&set.ID)
+	if apperr.IsForeignKeyViolation(err) {
+		return ErrExerciseNotFound
+	}
+	return err
+}
+
+// AddSet добавляет подход к незавершенной тренировке
+func (s *Storage) AddSet(ctx context.Context, set *SetLog) (err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	var completed bool
+	err = s.DB.QueryRowContext(ctx, "SELECT completed_at IS NOT NULL FROM workout_sessions WHERE id = $1", set.SessionID).Scan(&completed)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrNotFound
+		}
+		return err
+	}
+	if completed {
+		return ErrAlreadyCompleted
+	}
+	return insertSet(ctx, s.DB, set)
+}
+
+// Complete отмечает тренировку завершенной
+func (s *Storage) Complete(ctx context.Context, id string, at time.Time) (err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	res, err := s.DB.ExecContext(ctx, "UPDATE workout_sessions SET completed_at = $1 WHERE id = $2 AND completed_at IS NULL", at, id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrAlreadyCompleted
+	}
+	return nil
+}
+
+const sessionColumns = "id, client_id, program_id, program_version, week_number, day_number, started_at, completed_at, notes"
+
+func scanSession(row interface{ Scan(...interface{}) error }) (*Session, error) {
+	ws := &Session{}
+	err := row.Scan(&ws.ID, &ws.ClientID, &ws.ProgramID, &ws.ProgramVersion, &ws.WeekNumber, &ws.DayNumber,
+		&ws.StartedAt, &ws.CompletedAt, &ws.Notes)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return ws, nil
+}
+
+// Get возвращает тренировку вместе с подходами
+func (s *Storage) Get(ctx context.Context, id string) (_ *Session, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	ws, err := scanSession(s.DB.QueryRowContext(ctx, "SELECT "+sessionColumns+" FROM workout_sessions WHERE id = $1", id))
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.DB.QueryContext(ctx, `SELECT id, session_id, exercise_id, set_number, reps, weight_kg, rpe, notes, logged_at
+		FROM workout_sets WHERE session_id = $1 ORDER BY logged_at, set_number`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ws.Sets = []SetLog{}
+	for rows.Next() {
+		var set SetLog
+		err := rows.Scan(&set.ID, &set.SessionID, &set.ExerciseID, &set.SetNumber, &set.Reps, &set.WeightKg,
+			&set.RPE, &set.Notes, &set.LoggedAt)
+		if err != nil {
+			return nil, err
+		}
+		ws.Sets = append(ws.Sets, set)
+	}
+	return ws, rows.Err()
+}
+
+// List возвращает тренировки клиента, начатые в интервале [from, to), без подходов
+func (s *Storage) List(ctx context.Context, clientID string, from, to time.Time) (_ []Session, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	rows, err := s.DB.QueryContext(ctx, "SELECT "+sessionColumns+` FROM workout_sessions
+		WHERE client_id = $1 AND started_at >= $2 AND started_at < $3 ORDER BY started_at DESC`, clientID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []Session{}
+	for rows.Next() {
+		ws, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, *ws)
+	}
+	return sessions, rows.Err()
+}
+
+// WeeklyVolume возвращает тоннаж клиента по группам мышц за каждую неделю интервала [from, to).
+// Подход учитывается во всех группах мышц упражнения.
+func (s *Storage) WeeklyVolume(ctx context.Context, clientID string, from, to time.Time) (_ []WeeklyVolume, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	rows, err := s.DB.QueryContext(ctx, `SELECT date_trunc('week', s.logged_at) AS week, mg.muscle_group,
+			SUM(s.reps * s.weight_kg), COUNT(*)
+		FROM workout_sets s
+		JOIN workout_sessions ws ON ws.id = s.session_id
+		JOIN exercises e ON e.id = s.exercise_id
+		CROSS JOIN LATERAL unnest(e.muscle_groups) AS mg(muscle_group)
+		WHERE ws.client_id = $1 AND s.logged_at >= $2 AND s.logged_at < $3
+		GROUP BY week, mg.muscle_group
+		ORDER BY week, mg.muscle_group`, clientID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	volumes := []WeeklyVolume{}
+	for rows.Next() {
+		var v WeeklyVolume
+		if err := rows.Scan(&v.Week, &v.MuscleGroup, &v.VolumeKg, &v.Sets); err != nil {
+			return nil, err
+		}
+		volumes = append(volumes, v)
+	}
+	return volumes, rows.Err()
+}
+
+// PersonalRecords возвращает для каждого упражнения максимальный вес и лучшую оценку
+// максимума на одно повторение
+func (s *Storage) PersonalRecords(ctx context.Context, clientID string) (_ []PersonalRecord, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	rows, err := s.DB.QueryContext(ctx, `SELECT DISTINCT ON (s.exercise_id) s.exercise_id, e.name, s.weight_kg, s.reps, s.logged_at,
+			MAX(`+oneRepMaxExpr+`) OVER (PARTITION BY s.exercise_id)
+		FROM workout_sets s
+		JOIN workout_sessions ws ON ws.id = s.session_id
+		JOIN exercises e ON e.id = s.exercise_id
+		WHERE ws.client_id = $1 AND s.reps > 0
+		ORDER BY s.exercise_id, s.weight_kg DESC, s.reps DESC, s.logged_at`, clientID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := []PersonalRecord{}
+	for rows.Next() {
+		var pr PersonalRecord
+		err := rows.Scan(&pr.ExerciseID, &pr.ExerciseName, &pr.MaxWeightKg, &pr.RepsAtMaxWeight, &pr.AchievedAt,
+			&pr.EstimatedOneRepMax)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, pr)
+	}
+	return records, rows.Err()
+}
+
+// Adherence вычисляет, какую долю дней версии программы клиент выполнил.
+// version 0 означает текущую версию программы.
+func (s *Storage) Adherence(ctx context.Context, clientID, programID string, version int) (_ *Adherence, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	a := &Adherence{ProgramID: programID}
+	err = s.DB.QueryRowContext(ctx, `SELECT v.version,
+			(SELECT COUNT(*) FROM program_days d WHERE d.program_id = p.id AND d.version = v.version),
+			(SELECT COUNT(DISTINCT (ws.week_number, ws.day_number)) FROM workout_sessions ws
+				WHERE ws.client_id = p.client_id AND ws.program_id = p.id AND ws.program_version = v.version
+					AND ws.completed_at IS NOT NULL)
+		FROM programs p
+		JOIN program_versions v ON v.program_id = p.id AND v.version = CASE WHEN $3 = 0 THEN p.current_version ELSE $3 END
+		WHERE p.id = $1 AND p.client_id = $2`, programID, clientID, version).
+		Scan(&a.ProgramVersion, &a.PrescribedDays, &a.CompletedDays)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrProgramNotAssigned
+		}
+		return nil, err
+	}
+
+	a.computePercent()
+	return a, nil
+}
+
+// MaxWeight возвращает максимальный вес, с которым клиент выполнил упражнение хотя бы
+// на одно повторение, или nil, если упражнение еще не записано
+func (s *Storage) MaxWeight(ctx context.Context, clientID, exerciseID string) (_ *float64, err error) {
+	ctx, done := postgres.WithTimeout(ctx, &err)
+	defer done()
+
+	var max sql.NullFloat64
+	err = s.DB.QueryRowContext(ctx, `SELECT MAX(s.weight_kg) FROM workout_sets s
+		JOIN workout_sessions ws ON ws.id = s.session_id
+		WHERE ws.client_id = $1 AND s.exercise_id = $2 AND s.reps > 0`, clientID, exerciseID).Scan(&max)
+	if err != nil || !max.Valid {
+		return nil, err
+	}
+	return &max.Float64, nil
+}
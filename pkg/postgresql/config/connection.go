@@ -8,4 +8,7 @@ type DBConfig struct {
 	Password string `json:"password"`
 	DBName   string `json:"dbname"`
 	SSLMode  string `json:"sslmode"`
+
+	// QueryTimeout — срок одной операции хранилища в формате time.ParseDuration, например "5s"
+	QueryTimeout string `json:"query_timeout,omitempty"`
 }
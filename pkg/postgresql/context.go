@@ -0,0 +1,65 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// QueryTimeout — срок одной операции хранилища, по умолчанию 5 секунд.
+// Задается параметром query_timeout конфигурации; 0 отключает ограничение,
+// и запрос прерывается только отменой контекста HTTP-запроса.
+var QueryTimeout = 5 * time.Second
+
+var (
+	// ErrTimeout возвращается, когда запрос не уложился в QueryTimeout или срок контекста
+	ErrTimeout = errors.New("database query timed out")
+	// ErrCanceled возвращается, когда контекст запроса отменен, например клиент отключился
+	ErrCanceled = errors.New("database query canceled")
+)
+
+// WithTimeout ограничивает ctx сроком QueryTimeout на время одной операции хранилища.
+// Возвращенную функцию вызывают через defer: она освобождает контекст и заменяет ошибку
+// *err, вызванную его завершением, на ErrTimeout или ErrCanceled.
+//
+//	func (s *Storage) Get(ctx context.Context, id int64) (_ *Item, err error) {
+//		ctx, done := postgres.WithTimeout(ctx, &err)
+//		defer done()
+func WithTimeout(ctx context.Context, err *error) (context.Context, func()) {
+	cancel := context.CancelFunc(func() {})
+	if QueryTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, QueryTimeout)
+	}
+	return ctx, func() {
+		*err = ContextError(ctx, *err)
+		cancel()
+	}
+}
+
+// ContextError заменяет ошибку, вызванную завершением ctx, на ErrTimeout или ErrCanceled.
+// Исходный текст ошибки сохраняется в сообщении.
+func ContextError(ctx context.Context, err error) error {
+	if err == nil || errors.Is(err, ErrTimeout) || errors.Is(err, ErrCanceled) {
+		return err
+	}
+
+	cause := ctx.Err()
+	if cause == nil {
+		// Контекст мог завершиться у вызванного кода, например у транзакции
+		switch {
+		case errors.Is(err, context.DeadlineExceeded):
+			cause = context.DeadlineExceeded
+		case errors.Is(err, context.Canceled):
+			cause = context.Canceled
+		}
+	}
+
+	switch cause {
+	case context.DeadlineExceeded:
+		return fmt.Errorf("%w: %v", ErrTimeout, err)
+	case context.Canceled:
+		return fmt.Errorf("%w: %v", ErrCanceled, err)
+	}
+	return err
+}
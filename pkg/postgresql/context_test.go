@@ -0,0 +1,54 @@
+package postgres_test
+
+import (
+	postgres "TrainerConnect/pkg/postgresql"
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestContextError(t *testing.T) {
+	cause := errors.New("pq: canceling statement due to user request")
+
+	// Ошибка без завершения контекста не меняется
+	assert.Equal(t, cause, postgres.ContextError(context.Background(), cause))
+	assert.NoError(t, postgres.ContextError(context.Background(), nil))
+
+	expired, cancel := context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+	err := postgres.ContextError(expired, cause)
+	assert.ErrorIs(t, err, postgres.ErrTimeout)
+	assert.Contains(t, err.Error(), cause.Error())
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = postgres.ContextError(canceled, cause)
+	assert.ErrorIs(t, err, postgres.ErrCanceled)
+	assert.NotErrorIs(t, err, postgres.ErrTimeout)
+
+	// Ошибки контекста, пришедшие снизу, распознаются и без него
+	assert.ErrorIs(t, postgres.ContextError(context.Background(), context.DeadlineExceeded), postgres.ErrTimeout)
+	assert.ErrorIs(t, postgres.ContextError(context.Background(), context.Canceled), postgres.ErrCanceled)
+}
+
+func TestWithTimeout(t *testing.T) {
+	defer func(d time.Duration) { postgres.QueryTimeout = d }(postgres.QueryTimeout)
+	postgres.QueryTimeout = time.Millisecond
+
+	query := func(ctx context.Context) (err error) {
+		ctx, done := postgres.WithTimeout(ctx, &err)
+		defer done()
+
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	assert.ErrorIs(t, query(context.Background()), postgres.ErrTimeout)
+
+	// Отмена контекста запроса раньше срока — это не таймаут
+	postgres.QueryTimeout = time.Minute
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, query(ctx), postgres.ErrCanceled)
+}
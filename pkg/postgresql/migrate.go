@@ -0,0 +1,321 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Ключ advisory-блокировки, под которой выполняются миграции. Второй экземпляр сервера,
+// запущенный одновременно, дождется окончания миграций первого.
+const migrationLockKey = 7_421_001
+
+// Таблица users существовала до появления миграций и в действующих базах создана вручную.
+// Такая таблица принимается за уже примененную миграцию baselineVersion, если в ней есть все
+// столбцы, с которыми работает приложение.
+const (
+	baselineVersion = 1
+	baselineTable   = "users"
+)
+
+var baselineColumns = []string{"user_id", "first_name", "last_name", "username", "password", "salt", "role", "email"}
+
+// Migration — версия схемы базы данных: SQL для перехода на нее и для отката
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus — миграция и время ее применения; AppliedAt nil, если она еще не применена
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+var migrationName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// LoadMigrations читает миграции из файлов вида 0001_name.up.sql и 0001_name.down.sql
+// и возвращает их в порядке версий. У каждой миграции должны быть оба файла.
+func LoadMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, e := range entries {
+		m := migrationName.FindStringSubmatch(e.Name())
+		if e.IsDir() || m == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(m[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: %w", e.Name(), err)
+		}
+		body, err := fs.ReadFile(fsys, e.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		}
+		if mig.Name != m[2] {
+			return nil, fmt.Errorf("migration %d has different names: %s and %s", version, mig.Name, m[2])
+		}
+		if m[3] == "up" {
+			mig.Up = string(body)
+		} else {
+			mig.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Up == "" || mig.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s must have both up and down files", mig.Version, mig.Name)
+		}
+		migrations = append(migrations, *mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Migrator применяет и откатывает миграции, записывая примененные версии в таблицу schema_migrations
+type Migrator struct {
+	DB         *sql.DB
+	Migrations []Migration
+}
+
+// NewMigrator создает мигратор со встроенными в бинарный файл миграциями
+func NewMigrator(db *sql.DB) (*Migrator, error) {
+	fsys, err := fs.Sub(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	migrations, err := LoadMigrations(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{DB: db, Migrations: migrations}, nil
+}
+
+// withLock выполняет fn на отдельном соединении, удерживая advisory-блокировку миграций.
+// Блокировка сессионная, поэтому все запросы идут через одно соединение.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.DB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationLockKey); err != nil {
+		return err
+	}
+	defer func() {
+		// Снимаем блокировку даже при отмене ctx, иначе соединение вернется в пул с ней
+		if _, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLockKey); err != nil {
+			log.Printf("Error releasing migration lock: %v", err)
+		}
+	}()
+
+	_, err = conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    BIGINT PRIMARY KEY,
+		name       TEXT        NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`)
+	if err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+func appliedVersions(ctx context.Context, conn *sql.Conn) (map[int64]time.Time, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]time.Time)
+	for rows.Next() {
+		var version int64
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		applied[version] = at
+	}
+	return applied, rows.Err()
+}
+
+// adoptBaseline записывает миграцию baselineVersion как примененную, если база еще не знает
+// о миграциях, а таблица users уже создана вручную. Возвращает true, если запись сделана.
+func (m *Migrator) adoptBaseline(ctx context.Context, conn *sql.Conn) (bool, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT column_name FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = $1`, baselineTable)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	columns := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return false, err
+		}
+		columns[name] = true
+	}
+	if err := rows.Err(); err != nil {
+		return false, err
+	}
+	if len(columns) == 0 {
+		return false, nil
+	}
+
+	var missing []string
+	for _, c := range baselineColumns {
+		if !columns[c] {
+			missing = append(missing, c)
+		}
+	}
+	if len(missing) > 0 {
+		return false, fmt.Errorf("table %s exists but lacks columns %s and cannot be adopted as migration %d",
+			baselineTable, strings.Join(missing, ", "), baselineVersion)
+	}
+
+	for _, mig := range m.Migrations {
+		if mig.Version != baselineVersion {
+			continue
+		}
+		_, err := conn.ExecContext(ctx, "INSERT INTO schema_migrations (version, name) VALUES ($1, $2)", mig.Version, mig.Name)
+		if err != nil {
+			return false, err
+		}
+		log.Printf("Adopted existing table %s as migration %04d_%s", baselineTable, mig.Version, mig.Name)
+		return true, nil
+	}
+	return false, nil
+}
+
+// run выполняет SQL миграции и изменяет запись о ее версии в одной транзакции
+func run(ctx context.Context, conn *sql.Conn, query, record string, args ...interface{}) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, query); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Up применяет все еще не примененные миграции по порядку и возвращает их.
+// Каждая миграция выполняется в своей транзакции: при ошибке применяются только предыдущие.
+// В базе без записей о миграциях созданная вручную таблица users принимается как есть.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var done []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			adopted, err := m.adoptBaseline(ctx, conn)
+			if err != nil {
+				return err
+			}
+			if adopted {
+				applied[baselineVersion] = time.Now()
+			}
+		}
+		for _, mig := range m.Migrations {
+			if _, ok := applied[mig.Version]; ok {
+				continue
+			}
+			err := run(ctx, conn, mig.Up, "INSERT INTO schema_migrations (version, name) VALUES ($1, $2)", mig.Version, mig.Name)
+			if err != nil {
+				return fmt.Errorf("migration %d_%s: %w", mig.Version, mig.Name, err)
+			}
+			done = append(done, mig)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down откатывает steps последних примененных миграций и возвращает их в порядке отката
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var done []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		versions := make([]int64, 0, len(applied))
+		for v := range applied {
+			versions = append(versions, v)
+		}
+		sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+
+		known := make(map[int64]Migration, len(m.Migrations))
+		for _, mig := range m.Migrations {
+			known[mig.Version] = mig
+		}
+
+		for i := 0; i < steps && i < len(versions); i++ {
+			mig, ok := known[versions[i]]
+			if !ok {
+				return fmt.Errorf("migration %d is applied but unknown to this build", versions[i])
+			}
+			err := run(ctx, conn, mig.Down, "DELETE FROM schema_migrations WHERE version = $1", mig.Version)
+			if err != nil {
+				return fmt.Errorf("migration %d_%s: %w", mig.Version, mig.Name, err)
+			}
+			done = append(done, mig)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Status возвращает все известные миграции с отметкой о применении
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	var status []MigrationStatus
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, mig := range m.Migrations {
+			s := MigrationStatus{Migration: mig}
+			if at, ok := applied[mig.Version]; ok {
+				s.AppliedAt = &at
+			}
+			status = append(status, s)
+		}
+		return nil
+	})
+	return status, err
+}
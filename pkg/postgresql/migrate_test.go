@@ -0,0 +1,129 @@
+package postgres_test
+
+import (
+	postgres "TrainerConnect/pkg/postgresql"
+	"TrainerConnect/pkg/postgresql/config"
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0002_bookings.up.sql":   {Data: []byte("CREATE TABLE bookings ();")},
+		"0002_bookings.down.sql": {Data: []byte("DROP TABLE bookings;")},
+		"0001_users.up.sql":      {Data: []byte("CREATE TABLE users ();")},
+		"0001_users.down.sql":    {Data: []byte("DROP TABLE users;")},
+		"README.md":              {Data: []byte("не миграция")},
+	}
+
+	migrations, err := postgres.LoadMigrations(fsys)
+	require.NoError(t, err)
+	require.Len(t, migrations, 2)
+	assert.Equal(t, postgres.Migration{Version: 1, Name: "users", Up: "CREATE TABLE users ();", Down: "DROP TABLE users;"}, migrations[0])
+	assert.Equal(t, int64(2), migrations[1].Version)
+}
+
+func TestLoadMigrationsErrors(t *testing.T) {
+	// Миграция без отката
+	_, err := postgres.LoadMigrations(fstest.MapFS{
+		"0001_users.up.sql": {Data: []byte("CREATE TABLE users ();")},
+	})
+	assert.Error(t, err)
+
+	// Одна версия с разными именами
+	_, err = postgres.LoadMigrations(fstest.MapFS{
+		"0001_users.up.sql":     {Data: []byte("CREATE TABLE users ();")},
+		"0001_members.down.sql": {Data: []byte("DROP TABLE members;")},
+	})
+	assert.Error(t, err)
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	// Подключение не открывается: проверяются только встроенные файлы
+	db, err := sql.Open("postgres", "")
+	require.NoError(t, err)
+	defer db.Close()
+
+	m, err := postgres.NewMigrator(db)
+	require.NoError(t, err)
+	require.NotEmpty(t, m.Migrations)
+	assert.Equal(t, "users", m.Migrations[0].Name)
+	for i, mig := range m.Migrations {
+		assert.Equal(t, int64(i+1), mig.Version, "versions must have no gaps")
+	}
+}
+
+// schemaDB подключается к тестовой БД с отдельной пустой схемой, которая удаляется после теста.
+// Тест пропускается, если БД недоступна.
+func schemaDB(t *testing.T) *sql.DB {
+	cfg, err := config.ReadConfig("config/database_test.json")
+	if err != nil {
+		t.Skipf("test database is unavailable: %v", err)
+	}
+	admin, err := postgres.NewDB(cfg)
+	if err != nil {
+		t.Skipf("test database is unavailable: %v", err)
+	}
+	t.Cleanup(func() { admin.Close() })
+
+	schema := fmt.Sprintf("migrate_test_%d", time.Now().UnixNano())
+	_, err = admin.Exec("CREATE SCHEMA " + schema)
+	require.NoError(t, err)
+	t.Cleanup(func() { admin.Exec("DROP SCHEMA " + schema + " CASCADE") })
+
+	db, err := sql.Open("postgres", fmt.Sprintf("host=%s port=%s user=%s dbname=%s password=%s sslmode=%s search_path=%s",
+		cfg.Host, cfg.Port, cfg.Username, cfg.DBName, cfg.Password, cfg.SSLMode, schema))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestUpAdoptsExistingUsers(t *testing.T) {
+	db := schemaDB(t)
+	ctx := context.Background()
+
+	// Таблица из базы, созданной до появления миграций
+	_, err := db.Exec(`CREATE TABLE users (
+		user_id SERIAL PRIMARY KEY, first_name TEXT, last_name TEXT, username TEXT,
+		password TEXT, salt TEXT, role TEXT, email TEXT
+	)`)
+	require.NoError(t, err)
+
+	m := &postgres.Migrator{DB: db, Migrations: []postgres.Migration{
+		{Version: 1, Name: "users", Up: "CREATE TABLE users ();", Down: "DROP TABLE users;"},
+		{Version: 2, Name: "notes", Up: "CREATE TABLE notes (user_id INTEGER REFERENCES users (user_id));", Down: "DROP TABLE notes;"},
+	}}
+	applied, err := m.Up(ctx)
+	require.NoError(t, err)
+	require.Len(t, applied, 1)
+	assert.Equal(t, "notes", applied[0].Name)
+
+	status, err := m.Status(ctx)
+	require.NoError(t, err)
+	for _, s := range status {
+		assert.NotNil(t, s.AppliedAt, "migration %d", s.Version)
+	}
+}
+
+func TestUpRejectsIncompleteUsers(t *testing.T) {
+	db := schemaDB(t)
+	_, err := db.Exec("CREATE TABLE users (user_id SERIAL PRIMARY KEY, username TEXT)")
+	require.NoError(t, err)
+
+	m := &postgres.Migrator{DB: db, Migrations: []postgres.Migration{
+		{Version: 1, Name: "users", Up: "CREATE TABLE users ();", Down: "DROP TABLE users;"},
+	}}
+	_, err = m.Up(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "email")
+
+	status, err := m.Status(context.Background())
+	require.NoError(t, err)
+	assert.Nil(t, status[0].AppliedAt)
+}
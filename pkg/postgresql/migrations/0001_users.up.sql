@@ -0,0 +1,12 @@
+CREATE TABLE users (
+    user_id    SERIAL PRIMARY KEY,
+    first_name TEXT        NOT NULL DEFAULT '',
+    last_name  TEXT        NOT NULL DEFAULT '',
+    username   TEXT        NOT NULL UNIQUE,
+    -- password — хэш пароля с солью salt
+    password   TEXT        NOT NULL,
+    salt       TEXT        NOT NULL,
+    role       TEXT        NOT NULL CHECK (role IN ('client', 'trainer', 'admin')),
+    email      TEXT        NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
@@ -0,0 +1,2 @@
+DROP TABLE trainer_certifications;
+DROP TABLE trainer_profiles;
@@ -0,0 +1,34 @@
+CREATE TABLE trainer_profiles (
+    user_id          INTEGER PRIMARY KEY REFERENCES users (user_id) ON DELETE CASCADE,
+    bio              TEXT             NOT NULL DEFAULT '',
+    specialties      TEXT[]           NOT NULL DEFAULT '{}',
+    years_experience INTEGER          NOT NULL DEFAULT 0 CHECK (years_experience >= 0),
+    hourly_rate      BIGINT           NOT NULL DEFAULT 0 CHECK (hourly_rate >= 0),
+    currency         CHAR(3)          NOT NULL DEFAULT 'RUB',
+    -- Поля для поиска тренеров
+    languages        TEXT[]           NOT NULL DEFAULT '{}',
+    city             TEXT             NOT NULL DEFAULT '',
+    latitude         DOUBLE PRECISION CHECK (latitude BETWEEN -90 AND 90),
+    longitude        DOUBLE PRECISION CHECK (longitude BETWEEN -180 AND 180),
+    rating_avg       DOUBLE PRECISION NOT NULL DEFAULT 0,
+    rating_count     INTEGER          NOT NULL DEFAULT 0,
+    created_at       TIMESTAMPTZ      NOT NULL DEFAULT now(),
+    updated_at       TIMESTAMPTZ      NOT NULL DEFAULT now()
+);
+
+CREATE TABLE trainer_certifications (
+    id         SERIAL PRIMARY KEY,
+    trainer_id INTEGER NOT NULL REFERENCES trainer_profiles (user_id) ON DELETE CASCADE,
+    name       TEXT    NOT NULL,
+    issuer     TEXT    NOT NULL DEFAULT '',
+    issued_at  DATE,
+    expires_at DATE
+);
+
+CREATE INDEX trainer_certifications_trainer_id_idx ON trainer_certifications (trainer_id);
+
+CREATE INDEX trainer_profiles_specialties_idx ON trainer_profiles USING GIN (specialties);
+CREATE INDEX trainer_profiles_languages_idx ON trainer_profiles USING GIN (languages);
+CREATE INDEX trainer_profiles_city_idx ON trainer_profiles (lower(city));
+CREATE INDEX trainer_profiles_rating_idx ON trainer_profiles (rating_avg DESC, user_id);
+CREATE INDEX trainer_profiles_price_idx ON trainer_profiles (hourly_rate, user_id);
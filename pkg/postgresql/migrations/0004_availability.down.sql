@@ -0,0 +1,3 @@
+DROP TABLE availability_blackouts;
+DROP TABLE availability_extra_slots;
+DROP TABLE availability_weekly;
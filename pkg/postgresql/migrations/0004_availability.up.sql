@@ -0,0 +1,28 @@
+CREATE TABLE availability_weekly (
+    id         SERIAL PRIMARY KEY,
+    trainer_id INTEGER  NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    weekday    SMALLINT NOT NULL CHECK (weekday BETWEEN 0 AND 6),
+    start_time TIME     NOT NULL,
+    end_time   TIME     NOT NULL CHECK (end_time > start_time),
+    time_zone  TEXT     NOT NULL
+);
+
+CREATE INDEX availability_weekly_trainer_idx ON availability_weekly (trainer_id, weekday);
+
+CREATE TABLE availability_extra_slots (
+    id         SERIAL PRIMARY KEY,
+    trainer_id INTEGER     NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    starts_at  TIMESTAMPTZ NOT NULL,
+    ends_at    TIMESTAMPTZ NOT NULL CHECK (ends_at > starts_at)
+);
+
+CREATE INDEX availability_extra_slots_trainer_idx ON availability_extra_slots (trainer_id, starts_at);
+
+CREATE TABLE availability_blackouts (
+    id         SERIAL PRIMARY KEY,
+    trainer_id INTEGER NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    day        DATE    NOT NULL,
+    time_zone  TEXT    NOT NULL,
+    reason     TEXT    NOT NULL DEFAULT '',
+    UNIQUE (trainer_id, day)
+);
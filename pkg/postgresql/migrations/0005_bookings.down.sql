@@ -0,0 +1,3 @@
+DROP TABLE booking_policies;
+DROP TABLE booking_transitions;
+DROP TABLE bookings;
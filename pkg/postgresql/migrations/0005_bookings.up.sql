@@ -0,0 +1,49 @@
+CREATE EXTENSION IF NOT EXISTS btree_gist;
+
+CREATE TABLE bookings (
+    id               SERIAL PRIMARY KEY,
+    trainer_id       INTEGER     NOT NULL REFERENCES users (user_id),
+    client_id        INTEGER     NOT NULL REFERENCES users (user_id),
+    starts_at        TIMESTAMPTZ NOT NULL,
+    ends_at          TIMESTAMPTZ NOT NULL CHECK (ends_at > starts_at),
+    status           TEXT        NOT NULL CHECK (status IN ('requested', 'confirmed', 'declined', 'cancelled', 'completed', 'no_show')),
+    note             TEXT        NOT NULL DEFAULT '',
+    -- Стоимость занятия и сбор за позднюю отмену
+    price            BIGINT      NOT NULL DEFAULT 0,
+    currency         CHAR(3)     NOT NULL DEFAULT 'RUB',
+    cancellation_fee BIGINT      NOT NULL DEFAULT 0,
+    reschedule_count INTEGER     NOT NULL DEFAULT 0,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at       TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CHECK (trainer_id <> client_id),
+    -- Последний рубеж защиты от двойного бронирования тренера
+    EXCLUDE USING gist (trainer_id WITH =, tstzrange(starts_at, ends_at) WITH &&)
+        WHERE (status IN ('requested', 'confirmed'))
+);
+
+CREATE INDEX bookings_trainer_idx ON bookings (trainer_id, starts_at);
+CREATE INDEX bookings_client_idx ON bookings (client_id, starts_at);
+CREATE INDEX bookings_status_starts_idx ON bookings (status, starts_at);
+
+CREATE TABLE booking_transitions (
+    id          BIGSERIAL PRIMARY KEY,
+    booking_id  INTEGER     NOT NULL REFERENCES bookings (id) ON DELETE CASCADE,
+    from_status TEXT,
+    to_status   TEXT        NOT NULL,
+    actor_id    INTEGER     NOT NULL REFERENCES users (user_id),
+    actor       TEXT        NOT NULL,
+    reason      TEXT        NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX booking_transitions_booking_idx ON booking_transitions (booking_id);
+
+-- Правила отмены и переноса
+CREATE TABLE booking_policies (
+    trainer_id                    INTEGER PRIMARY KEY REFERENCES trainer_profiles (user_id) ON DELETE CASCADE,
+    free_cancellation_hours       INTEGER     NOT NULL CHECK (free_cancellation_hours >= 0),
+    late_cancellation_fee_percent INTEGER     NOT NULL CHECK (late_cancellation_fee_percent BETWEEN 0 AND 100),
+    max_reschedules               INTEGER     NOT NULL CHECK (max_reschedules >= 0),
+    min_reschedule_notice_hours   INTEGER     NOT NULL CHECK (min_reschedule_notice_hours >= 0),
+    updated_at                    TIMESTAMPTZ NOT NULL DEFAULT now()
+);
@@ -0,0 +1 @@
+DROP TABLE trainer_clients;
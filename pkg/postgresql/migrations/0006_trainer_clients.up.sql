@@ -0,0 +1,13 @@
+CREATE TABLE trainer_clients (
+    trainer_id INTEGER     NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    client_id  INTEGER     NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    status     TEXT        NOT NULL CHECK (status IN ('invited', 'active', 'paused', 'ended', 'declined')),
+    invited_by INTEGER     NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    ended_at   TIMESTAMPTZ,
+    PRIMARY KEY (trainer_id, client_id),
+    CHECK (trainer_id <> client_id)
+);
+
+CREATE INDEX trainer_clients_client_idx ON trainer_clients (client_id, status);
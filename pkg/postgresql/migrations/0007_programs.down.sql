@@ -0,0 +1,5 @@
+DROP TABLE program_items;
+DROP TABLE program_days;
+DROP TABLE program_versions;
+DROP TABLE programs;
+DROP TABLE exercises;
@@ -0,0 +1,62 @@
+CREATE TABLE exercises (
+    id            SERIAL PRIMARY KEY,
+    name          TEXT    NOT NULL,
+    muscle_groups TEXT[]  NOT NULL DEFAULT '{}',
+    equipment     TEXT[]  NOT NULL DEFAULT '{}',
+    instructions  TEXT    NOT NULL DEFAULT '',
+    media_url     TEXT    NOT NULL DEFAULT '',
+    created_by    INTEGER NOT NULL REFERENCES users (user_id)
+);
+
+CREATE INDEX exercises_muscle_groups_idx ON exercises USING GIN (muscle_groups);
+
+CREATE TABLE programs (
+    id              SERIAL PRIMARY KEY,
+    trainer_id      INTEGER     NOT NULL REFERENCES users (user_id),
+    client_id       INTEGER REFERENCES users (user_id),
+    title           TEXT        NOT NULL,
+    description     TEXT        NOT NULL DEFAULT '',
+    current_version INTEGER     NOT NULL,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX programs_trainer_idx ON programs (trainer_id);
+CREATE INDEX programs_client_idx ON programs (client_id);
+
+-- Версии программы неизменяемы: правка создает новую версию
+CREATE TABLE program_versions (
+    program_id INTEGER     NOT NULL REFERENCES programs (id) ON DELETE CASCADE,
+    version    INTEGER     NOT NULL,
+    created_by INTEGER     NOT NULL REFERENCES users (user_id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (program_id, version)
+);
+
+CREATE TABLE program_days (
+    program_id  INTEGER  NOT NULL,
+    version     INTEGER  NOT NULL,
+    week_number INTEGER  NOT NULL CHECK (week_number > 0),
+    day_number  SMALLINT NOT NULL CHECK (day_number BETWEEN 1 AND 7),
+    title       TEXT     NOT NULL DEFAULT '',
+    PRIMARY KEY (program_id, version, week_number, day_number),
+    FOREIGN KEY (program_id, version) REFERENCES program_versions (program_id, version) ON DELETE CASCADE
+);
+
+CREATE TABLE program_items (
+    program_id   INTEGER  NOT NULL,
+    version      INTEGER  NOT NULL,
+    week_number  INTEGER  NOT NULL,
+    day_number   SMALLINT NOT NULL,
+    position     INTEGER  NOT NULL,
+    exercise_id  INTEGER  NOT NULL REFERENCES exercises (id),
+    sets         INTEGER  NOT NULL CHECK (sets > 0),
+    reps         TEXT     NOT NULL DEFAULT '',
+    load         TEXT     NOT NULL DEFAULT '',
+    tempo        TEXT     NOT NULL DEFAULT '',
+    rest_seconds INTEGER  NOT NULL DEFAULT 0 CHECK (rest_seconds >= 0),
+    notes        TEXT     NOT NULL DEFAULT '',
+    PRIMARY KEY (program_id, version, week_number, day_number, position),
+    FOREIGN KEY (program_id, version, week_number, day_number)
+        REFERENCES program_days (program_id, version, week_number, day_number) ON DELETE CASCADE
+);
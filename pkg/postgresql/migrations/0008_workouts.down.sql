@@ -0,0 +1,2 @@
+DROP TABLE workout_sets;
+DROP TABLE workout_sessions;
@@ -0,0 +1,31 @@
+CREATE TABLE workout_sessions (
+    id              SERIAL PRIMARY KEY,
+    client_id       INTEGER     NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    program_id      INTEGER,
+    program_version INTEGER,
+    week_number     INTEGER,
+    day_number      SMALLINT,
+    started_at      TIMESTAMPTZ NOT NULL,
+    completed_at    TIMESTAMPTZ,
+    notes           TEXT        NOT NULL DEFAULT '',
+    FOREIGN KEY (program_id, program_version, week_number, day_number)
+        REFERENCES program_days (program_id, version, week_number, day_number)
+);
+
+CREATE INDEX workout_sessions_client_idx ON workout_sessions (client_id, started_at);
+CREATE INDEX workout_sessions_program_idx ON workout_sessions (program_id, program_version);
+
+CREATE TABLE workout_sets (
+    id          BIGSERIAL PRIMARY KEY,
+    session_id  INTEGER          NOT NULL REFERENCES workout_sessions (id) ON DELETE CASCADE,
+    exercise_id INTEGER          NOT NULL REFERENCES exercises (id),
+    set_number  INTEGER          NOT NULL CHECK (set_number > 0),
+    reps        INTEGER          NOT NULL CHECK (reps >= 0),
+    weight_kg   DOUBLE PRECISION NOT NULL DEFAULT 0 CHECK (weight_kg >= 0),
+    rpe         NUMERIC(3, 1) CHECK (rpe BETWEEN 1 AND 10),
+    notes       TEXT             NOT NULL DEFAULT '',
+    logged_at   TIMESTAMPTZ      NOT NULL DEFAULT now()
+);
+
+CREATE INDEX workout_sets_session_idx ON workout_sets (session_id);
+CREATE INDEX workout_sets_exercise_idx ON workout_sets (exercise_id, weight_kg DESC);
@@ -0,0 +1,2 @@
+DROP TABLE metric_goals;
+DROP TABLE metric_entries;
@@ -0,0 +1,24 @@
+CREATE TABLE metric_entries (
+    id          BIGSERIAL PRIMARY KEY,
+    user_id     INTEGER          NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    kind        TEXT             NOT NULL,
+    value       DOUBLE PRECISION NOT NULL CHECK (value > 0),
+    unit        TEXT             NOT NULL,
+    -- value_base — значение в базовой единице показателя (kg, cm, percent, bpm)
+    value_base  DOUBLE PRECISION NOT NULL CHECK (value_base > 0),
+    measured_at TIMESTAMPTZ      NOT NULL,
+    notes       TEXT             NOT NULL DEFAULT ''
+);
+
+CREATE INDEX metric_entries_user_kind_idx ON metric_entries (user_id, kind, measured_at);
+
+CREATE TABLE metric_goals (
+    user_id    INTEGER          NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    kind       TEXT             NOT NULL,
+    target     DOUBLE PRECISION NOT NULL CHECK (target > 0),
+    unit       TEXT             NOT NULL,
+    deadline   DATE,
+    set_by     INTEGER          NOT NULL REFERENCES users (user_id),
+    updated_at TIMESTAMPTZ      NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, kind)
+);
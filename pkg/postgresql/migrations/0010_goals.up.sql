@@ -0,0 +1,34 @@
+CREATE TABLE goals (
+    id                SERIAL PRIMARY KEY,
+    client_id         INTEGER          NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    created_by        INTEGER          NOT NULL REFERENCES users (user_id),
+    title             TEXT             NOT NULL,
+    source            TEXT             NOT NULL CHECK (source IN ('metric', 'exercise')),
+    metric_kind       TEXT,
+    exercise_id       INTEGER REFERENCES exercises (id),
+    unit              TEXT             NOT NULL,
+    baseline          DOUBLE PRECISION,
+    target            DOUBLE PRECISION NOT NULL CHECK (target > 0),
+    start_date        TIMESTAMPTZ      NOT NULL,
+    deadline          TIMESTAMPTZ      NOT NULL,
+    -- status — последний вычисленный статус, status_changed_at позволяет дашборду
+    -- забирать только изменившиеся цели
+    status            TEXT             NOT NULL CHECK (status IN ('on_track', 'at_risk', 'achieved', 'abandoned')),
+    status_changed_at TIMESTAMPTZ      NOT NULL,
+    abandoned_at      TIMESTAMPTZ,
+    CHECK (deadline > start_date),
+    CHECK ((source = 'metric' AND metric_kind IS NOT NULL) OR (source = 'exercise' AND exercise_id IS NOT NULL))
+);
+
+CREATE INDEX goals_client_idx ON goals (client_id, deadline);
+
+CREATE TABLE goal_milestones (
+    id         SERIAL PRIMARY KEY,
+    goal_id    INTEGER          NOT NULL REFERENCES goals (id) ON DELETE CASCADE,
+    title      TEXT             NOT NULL,
+    target     DOUBLE PRECISION NOT NULL CHECK (target > 0),
+    due_date   TIMESTAMPTZ,
+    reached_at TIMESTAMPTZ
+);
+
+CREATE INDEX goal_milestones_goal_idx ON goal_milestones (goal_id);
@@ -0,0 +1,25 @@
+CREATE TABLE conversations (
+    id         SERIAL PRIMARY KEY,
+    trainer_id INTEGER     NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    client_id  INTEGER     NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (trainer_id, client_id),
+    CHECK (trainer_id <> client_id)
+);
+
+CREATE INDEX conversations_client_idx ON conversations (client_id);
+
+CREATE TABLE messages (
+    id              BIGSERIAL PRIMARY KEY,
+    conversation_id INTEGER     NOT NULL REFERENCES conversations (id) ON DELETE CASCADE,
+    sender_id       INTEGER     NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    body            TEXT        NOT NULL DEFAULT '',
+    attachments     JSONB       NOT NULL DEFAULT '[]',
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    read_at         TIMESTAMPTZ
+);
+
+-- Последнее сообщение переписки и страницы истории читаются по этому индексу
+CREATE INDEX messages_conversation_idx ON messages (conversation_id, id DESC);
+-- Непрочитанные сообщения для счетчиков во входящих
+CREATE INDEX messages_unread_idx ON messages (conversation_id, sender_id) WHERE read_at IS NULL;
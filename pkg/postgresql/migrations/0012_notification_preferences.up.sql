@@ -0,0 +1,9 @@
+CREATE TABLE notification_preferences (
+    user_id     INTEGER PRIMARY KEY REFERENCES users (user_id) ON DELETE CASCADE,
+    -- channels — каналы для каждого вида уведомлений, например {"new_message": ["in_app"]}
+    channels    JSONB NOT NULL DEFAULT '{}',
+    quiet_start TEXT,
+    quiet_end   TEXT,
+    time_zone   TEXT,
+    CHECK ((quiet_start IS NULL) = (quiet_end IS NULL) AND (quiet_start IS NULL) = (time_zone IS NULL))
+);
@@ -0,0 +1,20 @@
+CREATE TABLE jobs (
+    id           BIGSERIAL PRIMARY KEY,
+    kind         TEXT        NOT NULL,
+    payload      JSONB       NOT NULL DEFAULT '{}',
+    status       TEXT        NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'running', 'done', 'dead')),
+    run_at       TIMESTAMPTZ NOT NULL DEFAULT now(),
+    attempts     INTEGER     NOT NULL DEFAULT 0,
+    max_attempts INTEGER     NOT NULL DEFAULT 5 CHECK (max_attempts > 0),
+    last_error   TEXT,
+    -- locked_until — до какого момента задачу выполняет забравший ее экземпляр
+    locked_until TIMESTAMPTZ,
+    -- dedupe_key не дает запланировать одну и ту же задачу дважды
+    dedupe_key   TEXT UNIQUE,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX jobs_ready_idx ON jobs (run_at) WHERE status = 'pending';
+CREATE INDEX jobs_running_idx ON jobs (locked_until) WHERE status = 'running';
+CREATE INDEX jobs_status_idx ON jobs (status, updated_at);
@@ -0,0 +1,18 @@
+CREATE TABLE reviews (
+    id            SERIAL PRIMARY KEY,
+    booking_id    INTEGER     NOT NULL UNIQUE REFERENCES bookings (id) ON DELETE CASCADE,
+    trainer_id    INTEGER     NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    client_id     INTEGER     NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    rating        SMALLINT    NOT NULL CHECK (rating BETWEEN 1 AND 5),
+    body          TEXT        NOT NULL DEFAULT '',
+    reply         TEXT,
+    replied_at    TIMESTAMPTZ,
+    hidden        BOOLEAN     NOT NULL DEFAULT false,
+    hidden_reason TEXT,
+    hidden_by     INTEGER REFERENCES users (user_id),
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Публичный список отзывов тренера и пересчет рейтинга по видимым отзывам
+CREATE INDEX reviews_trainer_idx ON reviews (trainer_id, id DESC) WHERE NOT hidden;
+CREATE INDEX reviews_trainer_all_idx ON reviews (trainer_id, id DESC);
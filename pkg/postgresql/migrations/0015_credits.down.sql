@@ -0,0 +1,4 @@
+DROP TABLE credit_entries;
+DROP TABLE credit_grants;
+DROP FUNCTION credit_append_only();
+DROP TABLE products;
@@ -0,0 +1,57 @@
+CREATE TABLE products (
+    id            SERIAL PRIMARY KEY,
+    trainer_id    INTEGER     NOT NULL REFERENCES trainer_profiles (user_id) ON DELETE CASCADE,
+    kind          TEXT        NOT NULL CHECK (kind IN ('single', 'pack', 'subscription')),
+    name          TEXT        NOT NULL,
+    description   TEXT        NOT NULL DEFAULT '',
+    sessions      INTEGER     NOT NULL CHECK (sessions > 0),
+    price         BIGINT      NOT NULL CHECK (price >= 0),
+    currency      CHAR(3)     NOT NULL,
+    validity_days INTEGER     NOT NULL DEFAULT 0 CHECK (validity_days >= 0),
+    active        BOOLEAN     NOT NULL DEFAULT true,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at    TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX products_trainer_idx ON products (trainer_id) WHERE active;
+
+-- Начисления и журнал кредитов не изменяются и не удаляются, чтобы баланс можно было проверить
+CREATE TABLE credit_grants (
+    id         BIGSERIAL PRIMARY KEY,
+    client_id  INTEGER     NOT NULL REFERENCES users (user_id),
+    trainer_id INTEGER     NOT NULL REFERENCES users (user_id),
+    product_id INTEGER     NOT NULL REFERENCES products (id),
+    quantity   INTEGER     NOT NULL CHECK (quantity > 0),
+    expires_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX credit_grants_pair_idx ON credit_grants (client_id, trainer_id, created_at);
+CREATE INDEX credit_grants_expires_idx ON credit_grants (expires_at) WHERE expires_at IS NOT NULL;
+
+CREATE TABLE credit_entries (
+    id         BIGSERIAL PRIMARY KEY,
+    grant_id   BIGINT      NOT NULL REFERENCES credit_grants (id),
+    booking_id INTEGER REFERENCES bookings (id),
+    kind       TEXT        NOT NULL CHECK (kind IN ('purchase', 'hold', 'release', 'refund', 'session',
+                                                    'late_cancellation', 'no_show', 'expiry', 'revoke')),
+    amount     INTEGER     NOT NULL CHECK (amount <> 0),
+    actor_id   INTEGER REFERENCES users (user_id),
+    note       TEXT        NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX credit_entries_grant_idx ON credit_entries (grant_id);
+CREATE INDEX credit_entries_booking_idx ON credit_entries (booking_id) WHERE booking_id IS NOT NULL;
+
+CREATE FUNCTION credit_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION '% is append-only', TG_TABLE_NAME;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER credit_grants_append_only BEFORE UPDATE OR DELETE OR TRUNCATE ON credit_grants
+    FOR EACH STATEMENT EXECUTE FUNCTION credit_append_only();
+
+CREATE TRIGGER credit_entries_append_only BEFORE UPDATE OR DELETE OR TRUNCATE ON credit_entries
+    FOR EACH STATEMENT EXECUTE FUNCTION credit_append_only();
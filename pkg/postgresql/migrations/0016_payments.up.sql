@@ -0,0 +1,31 @@
+CREATE TABLE payments (
+    id           SERIAL PRIMARY KEY,
+    client_id    INTEGER     NOT NULL REFERENCES users (user_id),
+    trainer_id   INTEGER     NOT NULL REFERENCES users (user_id),
+    product_id   INTEGER     NOT NULL REFERENCES products (id),
+    amount       BIGINT      NOT NULL CHECK (amount > 0),
+    currency     CHAR(3)     NOT NULL,
+    status       TEXT        NOT NULL CHECK (status IN ('pending', 'paid', 'failed', 'refunded')),
+    provider     TEXT        NOT NULL,
+    checkout_id  TEXT,
+    checkout_url TEXT,
+    reference    TEXT,
+    grant_id     BIGINT REFERENCES credit_grants (id),
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (provider, checkout_id)
+);
+
+CREATE INDEX payments_client_idx ON payments (client_id, created_at DESC);
+CREATE INDEX payments_trainer_idx ON payments (trainer_id, created_at DESC);
+CREATE INDEX payments_reference_idx ON payments (provider, reference) WHERE reference IS NOT NULL;
+
+-- Обработанные события провайдера: повторная доставка события с тем же ID игнорируется
+CREATE TABLE payment_events (
+    provider    TEXT        NOT NULL,
+    event_id    TEXT        NOT NULL,
+    type        TEXT        NOT NULL,
+    payment_id  INTEGER REFERENCES payments (id),
+    received_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (provider, event_id)
+);
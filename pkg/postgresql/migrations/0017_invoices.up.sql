@@ -0,0 +1,51 @@
+CREATE TABLE invoice_settings (
+    trainer_id INTEGER PRIMARY KEY REFERENCES trainer_profiles (user_id) ON DELETE CASCADE,
+    legal_name TEXT        NOT NULL DEFAULT '',
+    address    TEXT        NOT NULL DEFAULT '',
+    tax_id     TEXT        NOT NULL DEFAULT '',
+    tax_name   TEXT        NOT NULL DEFAULT 'VAT',
+    tax_rate   INTEGER     NOT NULL DEFAULT 0 CHECK (tax_rate BETWEEN 0 AND 10000),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Последний выданный номер счета тренера; строка блокируется до конца транзакции,
+-- поэтому номера идут подряд без пропусков
+CREATE TABLE invoice_counters (
+    trainer_id  INTEGER PRIMARY KEY REFERENCES users (user_id),
+    last_number INTEGER NOT NULL
+);
+
+CREATE TABLE invoices (
+    id             SERIAL PRIMARY KEY,
+    trainer_id     INTEGER     NOT NULL REFERENCES users (user_id),
+    client_id      INTEGER     NOT NULL REFERENCES users (user_id),
+    payment_id     INTEGER     NOT NULL UNIQUE REFERENCES payments (id),
+    seq            INTEGER     NOT NULL,
+    number         TEXT        NOT NULL,
+    currency       CHAR(3)     NOT NULL,
+    seller_name    TEXT        NOT NULL,
+    seller_address TEXT        NOT NULL DEFAULT '',
+    seller_tax_id  TEXT        NOT NULL DEFAULT '',
+    buyer_name     TEXT        NOT NULL,
+    buyer_email    TEXT        NOT NULL DEFAULT '',
+    subtotal       BIGINT      NOT NULL,
+    tax_name       TEXT        NOT NULL,
+    tax_rate       INTEGER     NOT NULL,
+    tax            BIGINT      NOT NULL,
+    total          BIGINT      NOT NULL,
+    issued_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (trainer_id, seq)
+);
+
+CREATE INDEX invoices_trainer_idx ON invoices (trainer_id, issued_at);
+CREATE INDEX invoices_client_idx ON invoices (client_id, issued_at);
+
+CREATE TABLE invoice_lines (
+    invoice_id  INTEGER NOT NULL REFERENCES invoices (id) ON DELETE CASCADE,
+    position    INTEGER NOT NULL,
+    description TEXT    NOT NULL,
+    quantity    INTEGER NOT NULL CHECK (quantity > 0),
+    unit_price  BIGINT  NOT NULL,
+    amount      BIGINT  NOT NULL,
+    PRIMARY KEY (invoice_id, position)
+);